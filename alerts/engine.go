@@ -0,0 +1,195 @@
+package alerts
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// Alert is a fired rule.
+type Alert struct {
+	RuleID  string           `json:"ruleId"`
+	Rule    string           `json:"rule"`
+	Symbol  string           `json:"symbol"`
+	Message string           `json:"message"`
+	FiredAt time.Time        `json:"firedAt"`
+	Ticker  *wsclient.Ticker `json:"ticker"`
+}
+
+type sample struct {
+	at   time.Time
+	last float64
+}
+
+// Engine evaluates the rules on every ticker update. Rules are edge
+// triggered: an alert fires when its condition becomes true and the rule is
+// re-armed once the condition is false again.
+type Engine struct {
+	mutex     *sync.Mutex
+	rules     map[string]*Rule
+	firing    map[string]bool
+	history   map[string][]sample
+	notifiers []Notifier
+	alerts    chan Alert
+}
+
+// NewEngine creates an Engine delivering alerts to the notifiers.
+func NewEngine(notifiers ...Notifier) *Engine {
+	e := &Engine{
+		mutex:     &sync.Mutex{},
+		rules:     make(map[string]*Rule),
+		firing:    make(map[string]bool),
+		history:   make(map[string][]sample),
+		notifiers: notifiers,
+		alerts:    make(chan Alert, 256),
+	}
+	go e.dispatch()
+	return e
+}
+
+// AddNotifier adds a notification channel.
+func (e *Engine) AddNotifier(n Notifier) {
+	e.mutex.Lock()
+	e.notifiers = append(e.notifiers, n)
+	e.mutex.Unlock()
+}
+
+// AddRule parses and registers a rule, assigning it an ID.
+func (e *Engine) AddRule(expr string) (*Rule, error) {
+	rule, err := ParseRule(expr)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 8)
+	if _, err = rand.Read(id); err != nil {
+		return nil, err
+	}
+	rule.ID = hex.EncodeToString(id)
+	e.mutex.Lock()
+	e.rules[rule.ID] = rule
+	e.mutex.Unlock()
+	return rule, nil
+}
+
+// RemoveRule removes a rule, returning false if it does not exist.
+func (e *Engine) RemoveRule(id string) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if _, ok := e.rules[id]; !ok {
+		return false
+	}
+	delete(e.rules, id)
+	delete(e.firing, id)
+	return true
+}
+
+// Rules returns the registered rules ordered by symbol and expression.
+func (e *Engine) Rules() []Rule {
+	e.mutex.Lock()
+	rules := make([]Rule, 0, len(e.rules))
+	for _, rule := range e.rules {
+		rules = append(rules, *rule)
+	}
+	e.mutex.Unlock()
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Symbol != rules[j].Symbol {
+			return rules[i].Symbol < rules[j].Symbol
+		}
+		return rules[i].Expr < rules[j].Expr
+	})
+	return rules
+}
+
+// Evaluate checks the rules of the ticker's symbol. It is meant to be
+// registered as a ticker listener and does not block on notifications.
+func (e *Engine) Evaluate(ticker *wsclient.Ticker) {
+	now := time.Now()
+	e.mutex.Lock()
+	history := e.record(ticker.Symbol, now, ticker.Last.InexactFloat64())
+	var fired []Alert
+	for _, rule := range e.rules {
+		if rule.Symbol != ticker.Symbol {
+			continue
+		}
+		triggered, message := rule.check(ticker, reference(history, now.Add(-rule.window), rule.window))
+		if triggered && !e.firing[rule.ID] {
+			fired = append(fired, Alert{
+				RuleID:  rule.ID,
+				Rule:    rule.Expr,
+				Symbol:  rule.Symbol,
+				Message: message,
+				FiredAt: now.UTC(),
+				Ticker:  ticker,
+			})
+		}
+		e.firing[rule.ID] = triggered
+	}
+	e.mutex.Unlock()
+
+	for _, alert := range fired {
+		select {
+		case e.alerts <- alert:
+		default:
+			log.Printf("alerts: queue full, dropping alert %q", alert.Rule)
+		}
+	}
+}
+
+// record appends the sample and prunes history older than the longest window
+// of the symbol's rules. Must be called with the mutex held.
+func (e *Engine) record(symbol string, now time.Time, last float64) []sample {
+	var maxWindow time.Duration
+	for _, rule := range e.rules {
+		if rule.Symbol == symbol && rule.window > maxWindow {
+			maxWindow = rule.window
+		}
+	}
+	if maxWindow == 0 {
+		delete(e.history, symbol)
+		return nil
+	}
+	history := append(e.history[symbol], sample{at: now, last: last})
+	cutoff := now.Add(-maxWindow)
+	// keep the newest sample older than the cutoff as the window reference
+	i := 0
+	for i+1 < len(history) && !history[i+1].at.After(cutoff) {
+		i++
+	}
+	history = history[i:]
+	e.history[symbol] = history
+	return history
+}
+
+// reference returns the price at the start of the window, or 0 when the
+// history does not cover the whole window yet.
+func reference(history []sample, start time.Time, window time.Duration) float64 {
+	if window == 0 || len(history) == 0 || history[0].at.After(start) {
+		return 0
+	}
+	ref := history[0].last
+	for _, s := range history {
+		if s.at.After(start) {
+			break
+		}
+		ref = s.last
+	}
+	return ref
+}
+
+func (e *Engine) dispatch() {
+	for alert := range e.alerts {
+		e.mutex.Lock()
+		notifiers := e.notifiers
+		e.mutex.Unlock()
+		for _, notifier := range notifiers {
+			if err := notifier.Notify(alert); err != nil {
+				log.Printf("alerts: %s notifier: %v", notifier.Name(), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Notifier is a channel fired alerts are delivered to.
+type Notifier interface {
+	Name() string
+	Notify(alert Alert) error
+}
+
+// LogNotifier writes alerts to the standard logger.
+type LogNotifier struct{}
+
+// Name implements Notifier.
+func (LogNotifier) Name() string { return "log" }
+
+// Notify implements Notifier.
+func (LogNotifier) Notify(alert Alert) error {
+	log.Printf("alert %s: %s", alert.RuleID, alert.Message)
+	return nil
+}
+
+// WebhookNotifier POSTs alerts as JSON to a URL.
+type WebhookNotifier struct {
+	URL        string
+	httpClient *http.Client
+}
+
+// NewWebhookNotifier creates a WebhookNotifier for url.
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name implements Notifier.
+func (n *WebhookNotifier) Name() string { return "webhook" }
+
+// Notify implements Notifier.
+func (n *WebhookNotifier) Notify(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	resp, err := n.httpClient.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
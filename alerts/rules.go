@@ -0,0 +1,116 @@
+// Package alerts evaluates user defined price rules against the ticker feed
+// and fires notifications when they trigger.
+package alerts
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// Rule is a parsed alert rule. Two forms are supported:
+//
+//	BTCUSD last > 70000        threshold on a ticker field (>, >=, <, <=)
+//	ETHBTC drops 5% in 1h      percent change of the last price over a window
+type Rule struct {
+	ID     string `json:"id"`
+	Expr   string `json:"rule"`
+	Symbol string `json:"symbol"`
+
+	field    string
+	operator string
+	value    float64
+	percent  float64 // signed, negative for drops
+	window   time.Duration
+}
+
+var tickerFields = map[string]func(*wsclient.Ticker) float64{
+	"last":        func(t *wsclient.Ticker) float64 { return t.Last.InexactFloat64() },
+	"bid":         func(t *wsclient.Ticker) float64 { return t.Bid.InexactFloat64() },
+	"ask":         func(t *wsclient.Ticker) float64 { return t.Ask.InexactFloat64() },
+	"open":        func(t *wsclient.Ticker) float64 { return t.Open.InexactFloat64() },
+	"low":         func(t *wsclient.Ticker) float64 { return t.Low.InexactFloat64() },
+	"high":        func(t *wsclient.Ticker) float64 { return t.High.InexactFloat64() },
+	"volume":      func(t *wsclient.Ticker) float64 { return t.Volume.InexactFloat64() },
+	"volumequote": func(t *wsclient.Ticker) float64 { return t.VolumeQuote.InexactFloat64() },
+}
+
+// ParseRule parses a rule expression.
+func ParseRule(expr string) (*Rule, error) {
+	parts := strings.Fields(expr)
+	rule := &Rule{Expr: strings.Join(parts, " ")}
+	switch {
+	case len(parts) == 4:
+		rule.Symbol = strings.ToUpper(parts[0])
+		rule.field = strings.ToLower(parts[1])
+		if _, ok := tickerFields[rule.field]; !ok {
+			return nil, fmt.Errorf("unknown ticker field %q", parts[1])
+		}
+		switch parts[2] {
+		case ">", ">=", "<", "<=":
+			rule.operator = parts[2]
+		default:
+			return nil, fmt.Errorf("unknown operator %q", parts[2])
+		}
+		value, err := strconv.ParseFloat(parts[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid threshold %q", parts[3])
+		}
+		rule.value = value
+	case len(parts) == 5 && strings.EqualFold(parts[3], "in"):
+		rule.Symbol = strings.ToUpper(parts[0])
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(parts[2], "%"), 64)
+		if err != nil || !strings.HasSuffix(parts[2], "%") || percent <= 0 {
+			return nil, fmt.Errorf("invalid percentage %q", parts[2])
+		}
+		switch strings.ToLower(parts[1]) {
+		case "drops", "falls":
+			rule.percent = -percent
+		case "rises", "gains":
+			rule.percent = percent
+		default:
+			return nil, fmt.Errorf("unknown direction %q, expected drops or rises", parts[1])
+		}
+		if rule.window, err = time.ParseDuration(parts[4]); err != nil || rule.window <= 0 {
+			return nil, fmt.Errorf("invalid window %q", parts[4])
+		}
+	default:
+		return nil, fmt.Errorf("invalid rule %q, expected \"SYMBOL FIELD OP VALUE\" or \"SYMBOL drops|rises N%% in DURATION\"", expr)
+	}
+	return rule, nil
+}
+
+// check evaluates the rule. For window rules reference is the last price at
+// the start of the window, or 0 when there is not enough history.
+func (r *Rule) check(ticker *wsclient.Ticker, reference float64) (bool, string) {
+	if r.window > 0 {
+		if reference == 0 {
+			return false, ""
+		}
+		last := ticker.Last.InexactFloat64()
+		change := (last - reference) / reference * 100
+		triggered := (r.percent < 0 && change <= r.percent) || (r.percent > 0 && change >= r.percent)
+		return triggered, fmt.Sprintf("%s moved %.2f%% in %s (%s -> %s)",
+			r.Symbol, change, r.window, formatFloat(reference), ticker.Last.String())
+	}
+	value := tickerFields[r.field](ticker)
+	var triggered bool
+	switch r.operator {
+	case ">":
+		triggered = value > r.value
+	case ">=":
+		triggered = value >= r.value
+	case "<":
+		triggered = value < r.value
+	case "<=":
+		triggered = value <= r.value
+	}
+	return triggered, fmt.Sprintf("%s %s is %s (rule: %s)", r.Symbol, r.field, formatFloat(value), r.Expr)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,166 @@
+// Package archive uploads history segments to object storage: Amazon S3,
+// Google Cloud Storage through its XML API with HMAC keys, or any other
+// S3 compatible service such as MinIO.
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Default endpoints of the backends.
+const (
+	S3Endpoint  = "https://s3.amazonaws.com"
+	GCSEndpoint = "https://storage.googleapis.com"
+)
+
+// File is a named file of a segment.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// TarGz bundles files into a gzip compressed tar archive.
+func TarGz(files []File, modTime time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		header := &tar.Header{Name: f.Name, Mode: 0644, Size: int64(len(f.Data)), ModTime: modTime}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Bucket uploads objects to a bucket of an S3 compatible service, signing
+// the requests with AWS Signature Version 4. Objects are addressed by path,
+// endpoint/bucket/key.
+type Bucket struct {
+	Endpoint        string
+	Region          string
+	Name            string
+	AccessKeyID     string
+	SecretAccessKey string
+
+	httpClient *http.Client
+}
+
+// NewBucket creates a Bucket. The region of Google Cloud Storage is "auto".
+func NewBucket(endpoint string, region string, name string, accessKeyID string, secretAccessKey string) *Bucket {
+	return &Bucket{
+		Endpoint:        strings.TrimRight(endpoint, "/"),
+		Region:          region,
+		Name:            name,
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		httpClient:      &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// Put uploads body as the object key.
+func (b *Bucket) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	target, err := url.Parse(b.Endpoint + "/" + escapePath(b.Name+"/"+key))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", contentType)
+	b.sign(req, body, time.Now().UTC())
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("uploading %s: unexpected status %s: %s", key, resp.Status, bytes.TrimSpace(message))
+}
+
+// sign sets the Authorization header of req for the time now.
+func (b *Bucket) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256Hex(body)
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"content-type:" + req.Header.Get("Content-Type"),
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + b.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+b.SecretAccessKey), day)
+	key = hmacSHA256(key, b.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+b.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapePath percent-encodes every byte of path but the unreserved
+// characters and the slashes, as the signature expects.
+func escapePath(path string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == '~' || c == '/' {
+			escaped.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&escaped, "%%%02X", c)
+	}
+	return escaped.String()
+}
@@ -0,0 +1,129 @@
+// Package audit keeps an append-only log of the administrative actions,
+// optionally written to a JSON lines file.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry is an administrative action.
+type Entry struct {
+	ID   uint64    `json:"id"`
+	Time time.Time `json:"time"`
+	// Actor names who acted, RemoteAddr is where the request came from
+	Actor      string `json:"actor"`
+	RemoteAddr string `json:"remoteAddr,omitempty"`
+	Action     string `json:"action"`
+	// Params are the path variables, query parameters and body of the
+	// request, or the outcome of actions without a request
+	Params    map[string]interface{} `json:"params,omitempty"`
+	Status    int                    `json:"status,omitempty"`
+	RequestID string                 `json:"requestId,omitempty"`
+}
+
+// Query selects entries, the zero value matches all of them.
+type Query struct {
+	Since, Until  time.Time
+	Actor, Action string
+	// Limit keeps the most recent entries, all of them when zero
+	Limit int
+}
+
+func (q Query) matches(e Entry) bool {
+	return (q.Since.IsZero() || !e.Time.Before(q.Since)) &&
+		(q.Until.IsZero() || e.Time.Before(q.Until)) &&
+		(q.Actor == "" || e.Actor == q.Actor) &&
+		(q.Action == "" || e.Action == q.Action)
+}
+
+// Log is the audit log. The most recent entries are kept in memory for
+// queries, the file has all of them.
+type Log struct {
+	mutex   *sync.Mutex
+	file    *os.File
+	entries []Entry
+	size    int
+	nextID  uint64
+}
+
+// New creates a Log keeping size entries in memory only.
+func New(size int) *Log {
+	return &Log{mutex: &sync.Mutex{}, size: size, nextID: 1}
+}
+
+// Open creates a Log appending to the file at path, created when it doesn't
+// exist. The last size entries of the file are loaded.
+func Open(path string, size int) (*Log, error) {
+	l := New(size)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			file.Close()
+			return nil, err
+		}
+		l.keep(entry)
+		l.nextID = entry.ID + 1
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	l.file = file
+	return l, nil
+}
+
+func (l *Log) keep(entry Entry) {
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > l.size {
+		l.entries = append(l.entries[:0], l.entries[len(l.entries)-l.size:]...)
+	}
+}
+
+// Record appends entry, setting its ID and its time when unset. The entry
+// is kept in memory even when it can't be written to the file.
+func (l *Log) Record(entry Entry) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	entry.ID = l.nextID
+	l.nextID++
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	l.keep(entry)
+	if l.file == nil {
+		return nil
+	}
+	line, err := json.Marshal(&entry)
+	if err != nil {
+		return err
+	}
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+// Query returns the entries matching q, most recent first.
+func (l *Log) Query(q Query) []Entry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	matched := make([]Entry, 0)
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if !q.matches(l.entries[i]) {
+			continue
+		}
+		matched = append(matched, l.entries[i])
+		if q.Limit > 0 && len(matched) == q.Limit {
+			break
+		}
+	}
+	return matched
+}
@@ -0,0 +1,182 @@
+// Package candles aggregates the live ticker stream into OHLCV candles.
+package candles
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crypto-api-server/storage"
+	"github.com/crypto-api-server/wsclient"
+)
+
+// Periods maps the supported periods, named like HitBTC's candle API, to
+// their duration.
+var Periods = map[string]time.Duration{
+	"M1": time.Minute,
+	"M5": 5 * time.Minute,
+	"H1": time.Hour,
+}
+
+var periodAliases = map[string]string{
+	"1m": "M1",
+	"5m": "M5",
+	"1h": "H1",
+}
+
+// NormalizePeriod returns the canonical name of a period such as "M1" or
+// "1m", and false when it is not supported.
+func NormalizePeriod(period string) (string, bool) {
+	if alias, ok := periodAliases[strings.ToLower(period)]; ok {
+		return alias, true
+	}
+	period = strings.ToUpper(period)
+	_, ok := Periods[period]
+	return period, ok
+}
+
+type series struct {
+	closed  []storage.Candle
+	current *storage.Candle
+}
+
+// Builder keeps the last candles of every symbol and period in memory. The
+// ticker only carries a rolling 24h volume, so candle volumes are the
+// increase of that volume within the candle and are approximate.
+type Builder struct {
+	mutex      *sync.RWMutex
+	maxCandles int
+	series     map[string]map[string]*series
+	lastVolume map[string][2]float64
+	onClose    []func(candle storage.Candle)
+}
+
+// NewBuilder creates a Builder keeping maxCandles closed candles per series.
+func NewBuilder(maxCandles int) *Builder {
+	return &Builder{
+		mutex:      &sync.RWMutex{},
+		maxCandles: maxCandles,
+		series:     make(map[string]map[string]*series),
+		lastVolume: make(map[string][2]float64),
+	}
+}
+
+// OnClose registers a callback for every closed candle. Callbacks run on the
+// feed goroutine and must not block.
+func (b *Builder) OnClose(callback func(candle storage.Candle)) {
+	b.mutex.Lock()
+	b.onClose = append(b.onClose, callback)
+	b.mutex.Unlock()
+}
+
+// Update is registered as a ticker listener.
+func (b *Builder) Update(ticker *wsclient.Ticker) {
+	if ticker.Last.IsZero() {
+		return
+	}
+	// candles are derived estimates and kept as floats
+	last := ticker.Last.InexactFloat64()
+	totalVolume := ticker.Volume.InexactFloat64()
+	totalVolumeQuote := ticker.VolumeQuote.InexactFloat64()
+	at := ticker.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	var closed []storage.Candle
+
+	b.mutex.Lock()
+	var volume, volumeQuote float64
+	if previous, ok := b.lastVolume[ticker.Symbol]; ok {
+		volume = positive(totalVolume - previous[0])
+		volumeQuote = positive(totalVolumeQuote - previous[1])
+	}
+	b.lastVolume[ticker.Symbol] = [2]float64{totalVolume, totalVolumeQuote}
+
+	bySymbol := b.series[ticker.Symbol]
+	if bySymbol == nil {
+		bySymbol = make(map[string]*series)
+		b.series[ticker.Symbol] = bySymbol
+	}
+	for period, duration := range Periods {
+		s := bySymbol[period]
+		if s == nil {
+			s = &series{}
+			bySymbol[period] = s
+		}
+		openTime := at.Truncate(duration).UTC()
+		if s.current != nil && openTime.After(s.current.OpenTime) {
+			closed = append(closed, *s.current)
+			s.closed = append(s.closed, *s.current)
+			if len(s.closed) > b.maxCandles {
+				s.closed = s.closed[len(s.closed)-b.maxCandles:]
+			}
+			s.current = nil
+		}
+		if s.current == nil {
+			s.current = &storage.Candle{
+				Symbol:   ticker.Symbol,
+				Period:   period,
+				OpenTime: openTime,
+				Open:     last,
+				High:     last,
+				Low:      last,
+			}
+		} else if openTime.Before(s.current.OpenTime) {
+			// late update for an already closed candle
+			continue
+		}
+		c := s.current
+		if last > c.High {
+			c.High = last
+		}
+		if last < c.Low {
+			c.Low = last
+		}
+		c.Close = last
+		c.Volume += volume
+		c.VolumeQuote += volumeQuote
+	}
+	callbacks := b.onClose
+	b.mutex.Unlock()
+
+	for _, candle := range closed {
+		for _, callback := range callbacks {
+			callback(candle)
+		}
+	}
+}
+
+// Candles returns up to limit most recent candles of symbol and period,
+// oldest first, including the candle in progress.
+func (b *Builder) Candles(symbol string, period string, limit int) []storage.Candle {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	s := b.series[symbol][period]
+	candles := make([]storage.Candle, 0)
+	if s == nil {
+		return candles
+	}
+	candles = append(candles, s.closed...)
+	if s.current != nil {
+		candles = append(candles, *s.current)
+	}
+	if len(candles) > limit {
+		candles = candles[len(candles)-limit:]
+	}
+	return candles
+}
+
+// Remove drops the candles of symbol.
+func (b *Builder) Remove(symbol string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.series, symbol)
+	delete(b.lastVolume, symbol)
+}
+
+func positive(f float64) float64 {
+	if f < 0 {
+		return 0
+	}
+	return f
+}
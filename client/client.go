@@ -0,0 +1,200 @@
+// Package client is a Go client of the crypto-api-server API, for services
+// reading its tickers without writing the HTTP calls themselves:
+//
+//	c := client.New("http://localhost:8080")
+//	ticker, err := c.GetTicker(ctx, "ETHBTC")
+//
+// Tickers are the wsclient.Ticker model served by the API.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// Error is an error response of the server. Code is a machine-readable code
+// such as unknown_symbol or upstream_unavailable.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Client calls a crypto-api-server.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient makes the client send its requests with httpClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken sends token as the bearer token of the admin endpoints.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// New returns a Client of the server at baseURL, such as
+// http://localhost:8080. Requests time out after 30 seconds unless an HTTP
+// client is given.
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// get decodes the JSON response of path into out.
+func (c *Client) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &Error{StatusCode: resp.StatusCode, RequestID: resp.Header.Get("X-Request-ID")}
+		var errorBody struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &errorBody) == nil {
+			apiErr.Code = errorBody.Error.Code
+			apiErr.Message = errorBody.Error.Message
+		}
+		if apiErr.Message == "" {
+			apiErr.Message = "unexpected response"
+		}
+		return apiErr
+	}
+	return json.Unmarshal(body, out)
+}
+
+// GetTicker returns the ticker of a symbol.
+func (c *Client) GetTicker(ctx context.Context, symbol string) (*wsclient.Ticker, error) {
+	var ticker wsclient.Ticker
+	if err := c.get(ctx, "/currency/"+url.PathEscape(strings.ToUpper(symbol)), &ticker); err != nil {
+		return nil, err
+	}
+	return &ticker, nil
+}
+
+// ListTickers returns the tickers of the symbols of the server feed.
+func (c *Client) ListTickers(ctx context.Context) ([]*wsclient.Ticker, error) {
+	var response struct {
+		Currencies []*wsclient.Ticker `json:"currencies"`
+	}
+	if err := c.get(ctx, "/currency/all", &response); err != nil {
+		return nil, err
+	}
+	return response.Currencies, nil
+}
+
+// TickerEvent is a ticker update of StreamTickers, or the error of a poll.
+type TickerEvent struct {
+	Ticker *wsclient.Ticker
+	Err    error
+}
+
+// StreamTickers polls the tickers of symbols every interval, or every ticker
+// of the feed without symbols, and sends the ones that changed since the
+// previous poll. interval must be positive. Polling goes on after errors,
+// which are sent too. The channel is closed once ctx is done.
+func (c *Client) StreamTickers(ctx context.Context, interval time.Duration, symbols ...string) <-chan TickerEvent {
+	events := make(chan TickerEvent, 16)
+	go func() {
+		defer close(events)
+		seen := make(map[string]time.Time)
+		send := func(event TickerEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		poll := func() bool {
+			var tickers []*wsclient.Ticker
+			if len(symbols) == 0 {
+				var err error
+				if tickers, err = c.ListTickers(ctx); err != nil {
+					if ctx.Err() != nil {
+						return false
+					}
+					return send(TickerEvent{Err: err})
+				}
+			}
+			for _, symbol := range symbols {
+				ticker, err := c.GetTicker(ctx, symbol)
+				if err != nil {
+					if ctx.Err() != nil || !send(TickerEvent{Err: err}) {
+						return false
+					}
+					continue
+				}
+				tickers = append(tickers, ticker)
+			}
+			for _, ticker := range tickers {
+				if last, ok := seen[ticker.Symbol]; ok && last.Equal(ticker.Timestamp) {
+					continue
+				}
+				seen[ticker.Symbol] = ticker.Timestamp
+				if !send(TickerEvent{Ticker: ticker}) {
+					return false
+				}
+			}
+			return true
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for poll() {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
@@ -0,0 +1,187 @@
+// Command cryptoctl queries a crypto-api-server from the command line:
+//
+//	cryptoctl ticker ETHBTC BTCUSD
+//	cryptoctl watch -interval 5s BTCUSD
+//	cryptoctl symbols
+//
+// Tickers are printed as a table, or as JSON with -json. The calls are made
+// with the client package.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/crypto-api-server/client"
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+const usage = `Usage: cryptoctl [-server URL] [-json] <command> [arguments]
+
+Commands:
+  ticker SYMBOL...   print the current ticker of the symbols
+  watch SYMBOL       print the ticker of the symbol on every change, until interrupted
+  symbols            list the symbols served with their full names
+
+Flags:
+`
+
+// changePercent returns the change from open to last, empty without an open
+// price.
+func changePercent(t *wsclient.Ticker) string {
+	if t.Open.IsZero() {
+		return ""
+	}
+	return t.Last.Sub(t.Open).Div(t.Open).Mul(decimal.NewFromInt(100)).StringFixed(2)
+}
+
+const tickerHeader = "SYMBOL\tLAST\tBID\tASK\tCHANGE %\tVOLUME\tTIME"
+
+func tickerRow(t *wsclient.Ticker) string {
+	return strings.Join([]string{
+		t.Symbol, t.Last.String(), t.Bid.String(), t.Ask.String(), changePercent(t), t.Volume.String(), t.Timestamp.Local().Format("15:04:05"),
+	}, "\t")
+}
+
+// printJSON prints v indented.
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func runTicker(ctx context.Context, c *client.Client, jsonOutput bool, symbols []string) error {
+	if len(symbols) == 0 {
+		return fmt.Errorf("ticker: missing symbol")
+	}
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if !jsonOutput {
+		fmt.Fprintln(table, tickerHeader)
+	}
+	for _, symbol := range symbols {
+		t, err := c.GetTicker(ctx, symbol)
+		if err != nil {
+			return fmt.Errorf("%s: %v", symbol, err)
+		}
+		if jsonOutput {
+			if err := printJSON(t); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Fprintln(table, tickerRow(t))
+	}
+	return table.Flush()
+}
+
+// runWatch prints the ticker of the symbol every time it changes.
+func runWatch(ctx context.Context, c *client.Client, jsonOutput bool, interval time.Duration, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("watch: expected one symbol")
+	}
+	// the rows are printed one by one, the columns are padded to a fixed width
+	table := tabwriter.NewWriter(os.Stdout, 14, 4, 2, ' ', 0)
+	if !jsonOutput {
+		fmt.Fprintln(table, tickerHeader)
+		table.Flush()
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	for event := range c.StreamTickers(ctx, interval, args[0]) {
+		if event.Err != nil {
+			fmt.Fprintln(os.Stderr, "cryptoctl:", event.Err)
+			continue
+		}
+		if jsonOutput {
+			if err := encoder.Encode(event.Ticker); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Fprintln(table, tickerRow(event.Ticker))
+		table.Flush()
+	}
+	return nil
+}
+
+func runSymbols(ctx context.Context, c *client.Client, jsonOutput bool) error {
+	tickers, err := c.ListTickers(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(tickers, func(i, j int) bool { return tickers[i].Symbol < tickers[j].Symbol })
+	if jsonOutput {
+		type symbol struct {
+			Symbol   string `json:"symbol"`
+			FullName string `json:"fullname"`
+		}
+		symbols := make([]symbol, 0, len(tickers))
+		for _, t := range tickers {
+			symbols = append(symbols, symbol{Symbol: t.Symbol, FullName: t.FullName})
+		}
+		return printJSON(symbols)
+	}
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "SYMBOL\tNAME")
+	for _, t := range tickers {
+		fmt.Fprintf(table, "%s\t%s\n", t.Symbol, t.FullName)
+	}
+	return table.Flush()
+}
+
+func main() {
+	server := os.Getenv("CRYPTOCTL_SERVER")
+	if server == "" {
+		server = "http://localhost:8080"
+	}
+	flags := flag.NewFlagSet("cryptoctl", flag.ExitOnError)
+	flags.StringVar(&server, "server", server, "URL of the server (CRYPTOCTL_SERVER)")
+	jsonOutput := flags.Bool("json", false, "print JSON instead of tables")
+	interval := flags.Duration("interval", 2*time.Second, "polling interval of watch")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout of each request")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+	args := flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	// flags are also accepted after the command
+	flags.Parse(args[1:])
+	command, args := args[0], flags.Args()
+
+	c := client.New(server, client.WithHTTPClient(&http.Client{Timeout: *timeout}))
+	ctx := context.Background()
+	var err error
+	switch command {
+	case "ticker":
+		err = runTicker(ctx, c, *jsonOutput, args)
+	case "watch":
+		if *interval <= 0 {
+			err = fmt.Errorf("watch: -interval must be positive")
+			break
+		}
+		err = runWatch(ctx, c, *jsonOutput, *interval, args)
+	case "symbols":
+		err = runSymbols(ctx, c, *jsonOutput)
+	default:
+		fmt.Fprintf(os.Stderr, "cryptoctl: unknown command %q\n\n", command)
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cryptoctl:", err)
+		os.Exit(1)
+	}
+}
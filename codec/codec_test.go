@@ -0,0 +1,55 @@
+package codec
+
+import (
+	"strings"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+// testTickers returns a ticker with every field set and one with only the
+// fields that are never omitted.
+func testTickers() (full, bare *wsclient.Ticker) {
+	cachedAt := time.Date(2024, 3, 1, 12, 0, 1, 500e6, time.UTC)
+	ageMs := int64(0)
+	high := decimal.RequireFromString("70000.1")
+	low := decimal.RequireFromString("-0.5")
+	allTimeHigh := decimal.RequireFromString("73750")
+	allTimeLow := decimal.RequireFromString("0.0001")
+	full = &wsclient.Ticker{
+		ID:               "BTC",
+		FullName:         "Bitcoin " + strings.Repeat("é", 40),
+		Ask:              decimal.RequireFromString("65000.12345678"),
+		Bid:              decimal.RequireFromString("64999.9"),
+		Last:             decimal.RequireFromString("65000"),
+		Open:             decimal.RequireFromString("60000"),
+		Low:              decimal.RequireFromString("59000"),
+		High:             decimal.RequireFromString("66000"),
+		Volume:           decimal.RequireFromString("1234.5"),
+		VolumeQuote:      decimal.RequireFromString("80000000"),
+		Timestamp:        time.Date(2024, 3, 1, 12, 0, 0, 250e6, time.UTC),
+		Symbol:           "BTCUSD",
+		FeeCurrency:      "USD",
+		ChangeAbs24h:     decimal.RequireFromString("5000"),
+		ChangePercent24h: decimal.RequireFromString("8.33"),
+		Source:           wsclient.SourceWebsocket,
+		CachedAt:         &cachedAt,
+		AgeMs:            &ageMs,
+		Delisted:         true,
+		Indicators: map[string]decimal.Decimal{
+			"sma20": decimal.RequireFromString("64000.5"),
+			"ema50": decimal.RequireFromString("63000"),
+		},
+		SessionHigh: &high,
+		SessionLow:  &low,
+		AllTimeHigh: &allTimeHigh,
+		AllTimeLow:  &allTimeLow,
+	}
+	bare = &wsclient.Ticker{
+		Symbol:    "ETHBTC",
+		Last:      decimal.RequireFromString("0.05"),
+		Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	return full, bare
+}
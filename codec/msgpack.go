@@ -0,0 +1,165 @@
+package codec
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+// msgpackBuffer appends MessagePack values.
+type msgpackBuffer struct {
+	buf []byte
+}
+
+func (m *msgpackBuffer) header(fix byte, code16 byte, code32 byte, n int) {
+	switch {
+	case n < 16:
+		m.buf = append(m.buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		m.buf = append(m.buf, code16, byte(n>>8), byte(n))
+	default:
+		var tmp [4]byte
+		binary.BigEndian.PutUint32(tmp[:], uint32(n))
+		m.buf = append(m.buf, code32)
+		m.buf = append(m.buf, tmp[:]...)
+	}
+}
+
+func (m *msgpackBuffer) mapHeader(n int) {
+	m.header(0x80, 0xde, 0xdf, n)
+}
+
+func (m *msgpackBuffer) arrayHeader(n int) {
+	m.header(0x90, 0xdc, 0xdd, n)
+}
+
+func (m *msgpackBuffer) string(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		m.buf = append(m.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		m.buf = append(m.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		m.buf = append(m.buf, 0xda, byte(n>>8), byte(n))
+	default:
+		var tmp [4]byte
+		binary.BigEndian.PutUint32(tmp[:], uint32(n))
+		m.buf = append(m.buf, 0xdb)
+		m.buf = append(m.buf, tmp[:]...)
+	}
+	m.buf = append(m.buf, s...)
+}
+
+// decimal writes d as a string, which keeps its exact value as in the JSON
+// representation.
+func (m *msgpackBuffer) decimal(d decimal.Decimal) {
+	m.string(d.String())
+}
+
+func (m *msgpackBuffer) int64(v int64) {
+	if v >= 0 && v < 128 {
+		m.buf = append(m.buf, byte(v))
+		return
+	}
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], uint64(v))
+	m.buf = append(m.buf, 0xd3)
+	m.buf = append(m.buf, tmp[:]...)
+}
+
+func (m *msgpackBuffer) bool(v bool) {
+	if v {
+		m.buf = append(m.buf, 0xc3)
+	} else {
+		m.buf = append(m.buf, 0xc2)
+	}
+}
+
+// ticker writes a ticker as a map keyed like its JSON representation, the
+// fields omitted from the JSON when empty are omitted alike.
+func (m *msgpackBuffer) ticker(t *wsclient.Ticker) {
+	var fields msgpackBuffer
+	n := 0
+	field := func(key string) *msgpackBuffer {
+		n++
+		fields.string(key)
+		return &fields
+	}
+	field("id").string(t.ID)
+	field("fullname").string(t.FullName)
+	field("ask").decimal(t.Ask)
+	field("bid").decimal(t.Bid)
+	field("last").decimal(t.Last)
+	field("open").decimal(t.Open)
+	if !t.Low.IsZero() {
+		field("low").decimal(t.Low)
+	}
+	if !t.High.IsZero() {
+		field("high").decimal(t.High)
+	}
+	field("volume").decimal(t.Volume)
+	field("volumeQuote").decimal(t.VolumeQuote)
+	field("timestamp").string(t.Timestamp.Format(time.RFC3339Nano))
+	field("symbol").string(t.Symbol)
+	field("feecurrency").string(t.FeeCurrency)
+	field("changeAbs24h").decimal(t.ChangeAbs24h)
+	field("changePercent24h").decimal(t.ChangePercent24h)
+	if t.Source != "" {
+		field("source").string(t.Source)
+	}
+	if t.CachedAt != nil {
+		field("cachedAt").string(t.CachedAt.Format(time.RFC3339Nano))
+	}
+	if t.AgeMs != nil {
+		field("ageMs").int64(*t.AgeMs)
+	}
+	if t.Delisted {
+		field("delisted").bool(true)
+	}
+	if len(t.Indicators) > 0 {
+		indicators := field("indicators")
+		indicators.mapHeader(len(t.Indicators))
+		for _, name := range sortedKeys(t.Indicators) {
+			indicators.string(name)
+			indicators.decimal(t.Indicators[name])
+		}
+	}
+	for _, extreme := range []struct {
+		key   string
+		value *decimal.Decimal
+	}{
+		{"sessionHigh", t.SessionHigh},
+		{"sessionLow", t.SessionLow},
+		{"allTimeHigh", t.AllTimeHigh},
+		{"allTimeLow", t.AllTimeLow},
+	} {
+		if extreme.value != nil {
+			field(extreme.key).decimal(*extreme.value)
+		}
+	}
+	m.mapHeader(n)
+	m.buf = append(m.buf, fields.buf...)
+}
+
+// MarshalTickerMsgpack encodes a ticker as a MessagePack map.
+func MarshalTickerMsgpack(t *wsclient.Ticker) []byte {
+	var m msgpackBuffer
+	m.ticker(t)
+	return m.buf
+}
+
+// MarshalTickerListMsgpack encodes tickers as {"currencies": [...]}.
+func MarshalTickerListMsgpack(tickers []*wsclient.Ticker) []byte {
+	var m msgpackBuffer
+	m.mapHeader(1)
+	m.string("currencies")
+	m.arrayHeader(len(tickers))
+	for _, ticker := range tickers {
+		m.ticker(ticker)
+	}
+	return m.buf
+}
@@ -0,0 +1,182 @@
+package codec
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// msgpackReader decodes the MessagePack values written by msgpackBuffer,
+// with the integers as float64 like encoding/json.
+type msgpackReader struct {
+	buf []byte
+}
+
+func (r *msgpackReader) take(n int) ([]byte, error) {
+	if n > len(r.buf) {
+		return nil, fmt.Errorf("%d bytes left, want %d", len(r.buf), n)
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b, nil
+}
+
+func (r *msgpackReader) length(size int) (int, error) {
+	b, err := r.take(size)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, c := range b {
+		n = n<<8 | int(c)
+	}
+	return n, nil
+}
+
+func (r *msgpackReader) value() (interface{}, error) {
+	b, err := r.take(1)
+	if err != nil {
+		return nil, err
+	}
+	switch c := b[0]; {
+	case c <= 0x7f:
+		return float64(c), nil
+	case c&0xf0 == 0x80:
+		return r.mapOf(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return r.arrayOf(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return r.stringOf(int(c & 0x1f))
+	case c == 0xc2:
+		return false, nil
+	case c == 0xc3:
+		return true, nil
+	case c == 0xd3:
+		b, err := r.take(8)
+		if err != nil {
+			return nil, err
+		}
+		return float64(int64(binary.BigEndian.Uint64(b))), nil
+	case c == 0xd9, c == 0xda, c == 0xdb:
+		n, err := r.length(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return r.stringOf(n)
+	case c == 0xdc, c == 0xdd:
+		n, err := r.length(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return r.arrayOf(n)
+	case c == 0xde, c == 0xdf:
+		n, err := r.length(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return r.mapOf(n)
+	default:
+		return nil, fmt.Errorf("unexpected type byte %#x", c)
+	}
+}
+
+func (r *msgpackReader) stringOf(n int) (interface{}, error) {
+	b, err := r.take(n)
+	return string(b), err
+}
+
+func (r *msgpackReader) arrayOf(n int) (interface{}, error) {
+	list := make([]interface{}, n)
+	for i := range list {
+		var err error
+		if list[i], err = r.value(); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func (r *msgpackReader) mapOf(n int) (interface{}, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		s, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("key %v isn't a string", key)
+		}
+		if m[s], err = r.value(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func decodeMsgpack(t *testing.T, data []byte) interface{} {
+	t.Helper()
+	r := &msgpackReader{buf: data}
+	v, err := r.value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.buf) > 0 {
+		t.Fatalf("%d bytes left after the value", len(r.buf))
+	}
+	return v
+}
+
+// jsonValue returns v as decoded from its JSON representation.
+func jsonValue(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+// TestTickerMsgpack checks that the MessagePack maps have the keys and
+// values of the JSON objects.
+func TestTickerMsgpack(t *testing.T) {
+	full, bare := testTickers()
+	for _, ticker := range []*wsclient.Ticker{full, bare} {
+		t.Run(ticker.Symbol, func(t *testing.T) {
+			got := decodeMsgpack(t, MarshalTickerMsgpack(ticker))
+			if want := jsonValue(t, ticker); !reflect.DeepEqual(got, want) {
+				t.Errorf("decoded %v\nwant the JSON %v", got, want)
+			}
+		})
+	}
+}
+
+func TestTickerListMsgpack(t *testing.T) {
+	full, bare := testTickers()
+	for _, tickers := range [][]*wsclient.Ticker{{}, {full, bare}} {
+		got := decodeMsgpack(t, MarshalTickerListMsgpack(tickers))
+		want := jsonValue(t, map[string]interface{}{"currencies": tickers})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("decoded %v\nwant the JSON %v", got, want)
+		}
+	}
+}
+
+func TestMsgpackInt64(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 1 << 40, -1} {
+		var m msgpackBuffer
+		m.int64(v)
+		r := &msgpackReader{buf: m.buf}
+		if got, err := r.value(); err != nil || got != float64(v) {
+			t.Errorf("int64(%d) decoded as %v, %v", v, got, err)
+		}
+	}
+}
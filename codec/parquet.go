@@ -0,0 +1,250 @@
+package codec
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/crypto-api-server/storage"
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+// Parquet files are written with a single row group of required columns,
+// PLAIN encoded and uncompressed, one data page per column. The metadata
+// is serialized with the Thrift compact protocol.
+
+const parquetMagic = "PAR1"
+
+// Parquet physical and converted types.
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+)
+
+// Thrift compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftBuffer appends Thrift compact protocol values. Fields are written in
+// increasing id order within a struct.
+type thriftBuffer struct {
+	buf       []byte
+	lastField []int
+}
+
+func (t *thriftBuffer) uvarint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	t.buf = append(t.buf, tmp[:n]...)
+}
+
+func (t *thriftBuffer) varint(v int64) {
+	t.uvarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftBuffer) field(id int, typ byte) {
+	last := &t.lastField[len(t.lastField)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.varint(int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftBuffer) beginStruct() {
+	t.lastField = append(t.lastField, 0)
+}
+
+func (t *thriftBuffer) endStruct() {
+	t.buf = append(t.buf, 0)
+	t.lastField = t.lastField[:len(t.lastField)-1]
+}
+
+func (t *thriftBuffer) i32(id int, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftBuffer) i64(id int, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftBuffer) string(id int, s string) {
+	t.field(id, thriftBinary)
+	t.uvarint(uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+// list writes the header of a list field of size elements of typ.
+func (t *thriftBuffer) list(id int, typ byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf = append(t.buf, byte(size)<<4|typ)
+		return
+	}
+	t.buf = append(t.buf, 0xf0|typ)
+	t.uvarint(uint64(size))
+}
+
+// parquetColumn is a column of values PLAIN encoded.
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32 // -1 for none
+	data      []byte
+}
+
+func (c *parquetColumn) int64(v int64) {
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], uint64(v))
+	c.data = append(c.data, tmp[:]...)
+}
+
+func (c *parquetColumn) string(s string) {
+	var tmp [4]byte
+	binary.LittleEndian.PutUint32(tmp[:], uint32(len(s)))
+	c.data = append(c.data, tmp[:]...)
+	c.data = append(c.data, s...)
+}
+
+func (c *parquetColumn) timestamp(at time.Time) {
+	c.int64(at.UnixNano() / int64(time.Millisecond))
+}
+
+// marshalParquet writes the columns of rows values as a Parquet file.
+func marshalParquet(columns []*parquetColumn, rows int) []byte {
+	out := []byte(parquetMagic)
+	offsets := make([]int64, len(columns))
+	sizes := make([]int64, len(columns))
+	for i, c := range columns {
+		var header thriftBuffer
+		header.beginStruct()
+		header.i32(1, 0) // DATA_PAGE
+		header.i32(2, int32(len(c.data)))
+		header.i32(3, int32(len(c.data)))
+		header.field(5, thriftStruct)
+		header.beginStruct()
+		header.i32(1, int32(rows))
+		header.i32(2, 0) // PLAIN
+		header.i32(3, 3) // RLE
+		header.i32(4, 3) // RLE
+		header.endStruct()
+		header.endStruct()
+		offsets[i] = int64(len(out))
+		sizes[i] = int64(len(header.buf) + len(c.data))
+		out = append(out, header.buf...)
+		out = append(out, c.data...)
+	}
+
+	var meta thriftBuffer
+	meta.beginStruct()
+	meta.i32(1, 1)
+	meta.list(2, thriftStruct, len(columns)+1)
+	meta.beginStruct()
+	meta.string(4, "schema")
+	meta.i32(5, int32(len(columns)))
+	meta.endStruct()
+	for _, c := range columns {
+		meta.beginStruct()
+		meta.i32(1, c.typ)
+		meta.i32(3, 0) // REQUIRED
+		meta.string(4, c.name)
+		if c.converted >= 0 {
+			meta.i32(6, c.converted)
+		}
+		meta.endStruct()
+	}
+	meta.i64(3, int64(rows))
+	meta.list(4, thriftStruct, 1)
+	meta.beginStruct()
+	meta.list(1, thriftStruct, len(columns))
+	var total int64
+	for i, c := range columns {
+		total += sizes[i]
+		meta.beginStruct()
+		meta.i64(2, offsets[i])
+		meta.field(3, thriftStruct)
+		meta.beginStruct()
+		meta.i32(1, c.typ)
+		meta.list(2, thriftI32, 1)
+		meta.varint(0) // PLAIN
+		meta.list(3, thriftBinary, 1)
+		meta.uvarint(uint64(len(c.name)))
+		meta.buf = append(meta.buf, c.name...)
+		meta.i32(4, 0) // UNCOMPRESSED
+		meta.i64(5, int64(rows))
+		meta.i64(6, sizes[i])
+		meta.i64(7, sizes[i])
+		meta.i64(9, offsets[i])
+		meta.endStruct()
+		meta.endStruct()
+	}
+	meta.i64(2, total)
+	meta.i64(3, int64(rows))
+	meta.endStruct()
+	meta.string(6, "crypto-api-server")
+	meta.endStruct()
+
+	out = append(out, meta.buf...)
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(meta.buf)))
+	out = append(out, length[:]...)
+	return append(out, parquetMagic...)
+}
+
+// priceColumns returns the columns of the prices and volumes, which are
+// written as decimal strings to keep their exact values.
+func priceColumns(names ...string) []*parquetColumn {
+	columns := make([]*parquetColumn, len(names))
+	for i, name := range names {
+		columns[i] = &parquetColumn{name: name, typ: parquetByteArray, converted: parquetUTF8}
+	}
+	return columns
+}
+
+// MarshalTickersParquet encodes ticker updates as a Parquet file with the
+// columns symbol, timestamp (milliseconds), ask, bid, last, open, low, high,
+// volume and volumeQuote, the prices and volumes as decimal strings.
+func MarshalTickersParquet(tickers []*wsclient.Ticker) []byte {
+	symbol := &parquetColumn{name: "symbol", typ: parquetByteArray, converted: parquetUTF8}
+	timestamp := &parquetColumn{name: "timestamp", typ: parquetInt64, converted: parquetTimestampMillis}
+	prices := priceColumns("ask", "bid", "last", "open", "low", "high", "volume", "volumeQuote")
+	for _, t := range tickers {
+		symbol.string(t.Symbol)
+		timestamp.timestamp(t.Timestamp)
+		for i, v := range []decimal.Decimal{t.Ask, t.Bid, t.Last, t.Open, t.Low, t.High, t.Volume, t.VolumeQuote} {
+			prices[i].string(v.String())
+		}
+	}
+	return marshalParquet(append([]*parquetColumn{symbol, timestamp}, prices...), len(tickers))
+}
+
+// MarshalCandlesParquet encodes candles as a Parquet file with the columns
+// symbol, period, timestamp (open time in milliseconds), open, high, low,
+// close, volume and volumeQuote, the prices and volumes as decimal strings.
+func MarshalCandlesParquet(candles []storage.Candle) []byte {
+	symbol := &parquetColumn{name: "symbol", typ: parquetByteArray, converted: parquetUTF8}
+	period := &parquetColumn{name: "period", typ: parquetByteArray, converted: parquetUTF8}
+	timestamp := &parquetColumn{name: "timestamp", typ: parquetInt64, converted: parquetTimestampMillis}
+	prices := priceColumns("open", "high", "low", "close", "volume", "volumeQuote")
+	for _, c := range candles {
+		symbol.string(c.Symbol)
+		period.string(c.Period)
+		timestamp.timestamp(c.OpenTime)
+		for i, v := range []float64{c.Open, c.High, c.Low, c.Close, c.Volume, c.VolumeQuote} {
+			prices[i].string(decimal.NewFromFloat(v).String())
+		}
+	}
+	return marshalParquet(append([]*parquetColumn{symbol, period, timestamp}, prices...), len(candles))
+}
@@ -0,0 +1,330 @@
+package codec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/crypto-api-server/storage"
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+// thriftValues is a decoded Thrift struct, its values by field id: int64
+// for the integers, string for the binaries, []interface{} for the lists
+// and thriftValues for the structs.
+type thriftValues map[int]interface{}
+
+// thriftReader decodes the Thrift compact protocol.
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, fmt.Errorf("unexpected end at %d", r.pos)
+	}
+	r.pos++
+	return r.buf[r.pos-1], nil
+}
+
+func (r *thriftReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid varint at %d", r.pos)
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *thriftReader) varint() (int64, error) {
+	v, err := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (r *thriftReader) value(typ byte) (interface{}, error) {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(r.buf)-r.pos) < n {
+			return nil, fmt.Errorf("binary of %d bytes at %d overflows", n, r.pos)
+		}
+		s := string(r.buf[r.pos : r.pos+int(n)])
+		r.pos += int(n)
+		return s, nil
+	case thriftList:
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(header >> 4)
+		if size == 15 {
+			if size, err = r.uvarint(); err != nil {
+				return nil, err
+			}
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			if list[i], err = r.value(header & 0x0f); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case thriftStruct:
+		return r.structure()
+	}
+	return nil, fmt.Errorf("unexpected type %d at %d", typ, r.pos)
+}
+
+func (r *thriftReader) structure() (thriftValues, error) {
+	values := make(thriftValues)
+	id := 0
+	for {
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if header == 0 {
+			return values, nil
+		}
+		if delta := int(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			id = int(v)
+		}
+		if values[id], err = r.value(header & 0x0f); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parquetFile is the content of a Parquet file written by marshalParquet.
+type parquetFile struct {
+	columns []string
+	rows    int
+	// values are the decoded values by column, int64 or string
+	values map[string][]interface{}
+	// converted are the converted types by column
+	converted map[string]int64
+}
+
+// readParquet decodes a Parquet file the way a reader does: from the
+// footer, then the page of each column chunk.
+func readParquet(data []byte) (*parquetFile, error) {
+	if len(data) < 12 || string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		return nil, fmt.Errorf("no Parquet magic")
+	}
+	length := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := len(data) - 8 - length
+	if footer < 4 {
+		return nil, fmt.Errorf("invalid footer length %d", length)
+	}
+	r := &thriftReader{buf: data[footer : len(data)-8]}
+	meta, err := r.structure()
+	if err != nil {
+		return nil, fmt.Errorf("footer: %v", err)
+	}
+	if r.pos != length {
+		return nil, fmt.Errorf("footer: %d bytes decoded of %d", r.pos, length)
+	}
+	if meta[1] != int64(1) {
+		return nil, fmt.Errorf("version %v", meta[1])
+	}
+
+	file := &parquetFile{
+		rows:      int(meta[3].(int64)),
+		values:    make(map[string][]interface{}),
+		converted: make(map[string]int64),
+	}
+	schema := meta[2].([]interface{})
+	if root := schema[0].(thriftValues); root[5] != int64(len(schema)-1) {
+		return nil, fmt.Errorf("root of %v children, want %d", root[5], len(schema)-1)
+	}
+	types := make(map[string]int64)
+	for _, element := range schema[1:] {
+		element := element.(thriftValues)
+		name := element[4].(string)
+		if element[3] != int64(0) {
+			return nil, fmt.Errorf("column %s: repetition %v, want REQUIRED", name, element[3])
+		}
+		file.columns = append(file.columns, name)
+		types[name] = element[1].(int64)
+		if converted, ok := element[6]; ok {
+			file.converted[name] = converted.(int64)
+		}
+	}
+
+	rowGroups := meta[4].([]interface{})
+	if len(rowGroups) != 1 {
+		return nil, fmt.Errorf("%d row groups, want 1", len(rowGroups))
+	}
+	rowGroup := rowGroups[0].(thriftValues)
+	if rowGroup[3] != int64(file.rows) {
+		return nil, fmt.Errorf("row group of %v rows, want %d", rowGroup[3], file.rows)
+	}
+	chunks := rowGroup[1].([]interface{})
+	if len(chunks) != len(file.columns) {
+		return nil, fmt.Errorf("%d column chunks, want %d", len(chunks), len(file.columns))
+	}
+	var total int64
+	for i, chunk := range chunks {
+		column := chunk.(thriftValues)[3].(thriftValues)
+		name := file.columns[i]
+		if path := column[3].([]interface{}); len(path) != 1 || path[0] != name {
+			return nil, fmt.Errorf("column %d: path %v, want [%s]", i, path, name)
+		}
+		if column[1] != types[name] || column[4] != int64(0) || column[5] != int64(file.rows) {
+			return nil, fmt.Errorf("column %s: type %v, codec %v, %v values", name, column[1], column[4], column[5])
+		}
+		size := column[6].(int64)
+		total += size
+		if column[7] != size {
+			return nil, fmt.Errorf("column %s: compressed size %v, want %d", name, column[7], size)
+		}
+
+		offset := column[9].(int64)
+		if offset < 4 || offset+size > int64(footer) {
+			return nil, fmt.Errorf("column %s: chunk at %d of %d bytes overflows", name, offset, size)
+		}
+		page := &thriftReader{buf: data[offset : offset+size]}
+		header, err := page.structure()
+		if err != nil {
+			return nil, fmt.Errorf("column %s: page header: %v", name, err)
+		}
+		pageSize := int64(len(page.buf) - page.pos)
+		if header[1] != int64(0) || header[2] != pageSize || header[3] != pageSize {
+			return nil, fmt.Errorf("column %s: page type %v of %v bytes, want a data page of %d", name, header[1], header[2], pageSize)
+		}
+		if dataPage := header[5].(thriftValues); dataPage[1] != int64(file.rows) || dataPage[2] != int64(0) {
+			return nil, fmt.Errorf("column %s: page of %v values encoded %v", name, dataPage[1], dataPage[2])
+		}
+		values, err := plainValues(page.buf[page.pos:], types[name], file.rows)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %v", name, err)
+		}
+		file.values[name] = values
+	}
+	if rowGroup[2] != total {
+		return nil, fmt.Errorf("row group of %v bytes, want %d", rowGroup[2], total)
+	}
+	return file, nil
+}
+
+// plainValues decodes rows PLAIN encoded values of typ.
+func plainValues(data []byte, typ int64, rows int) ([]interface{}, error) {
+	values := make([]interface{}, 0, rows)
+	for len(values) < rows {
+		switch typ {
+		case parquetInt64:
+			if len(data) < 8 {
+				return nil, fmt.Errorf("truncated int64")
+			}
+			values = append(values, int64(binary.LittleEndian.Uint64(data)))
+			data = data[8:]
+		case parquetByteArray:
+			if len(data) < 4 {
+				return nil, fmt.Errorf("truncated length")
+			}
+			n := int(binary.LittleEndian.Uint32(data))
+			if len(data) < 4+n {
+				return nil, fmt.Errorf("truncated byte array")
+			}
+			values = append(values, string(data[4:4+n]))
+			data = data[4+n:]
+		default:
+			return nil, fmt.Errorf("unexpected type %d", typ)
+		}
+	}
+	if len(data) > 0 {
+		return nil, fmt.Errorf("%d bytes left after %d values", len(data), rows)
+	}
+	return values, nil
+}
+
+func TestTickersParquet(t *testing.T) {
+	full, bare := testTickers()
+	tickers := []*wsclient.Ticker{full, bare}
+	file, err := readParquet(MarshalTickersParquet(tickers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := []string{"symbol", "timestamp", "ask", "bid", "last", "open", "low", "high", "volume", "volumeQuote"}
+	if !reflect.DeepEqual(file.columns, columns) || file.rows != 2 {
+		t.Fatalf("columns %v of %d rows, want %v of 2", file.columns, file.rows, columns)
+	}
+	if file.converted["timestamp"] != parquetTimestampMillis || file.converted["last"] != parquetUTF8 {
+		t.Errorf("converted types %v", file.converted)
+	}
+	for i, ticker := range tickers {
+		want := map[string]interface{}{
+			"symbol":      ticker.Symbol,
+			"timestamp":   ticker.Timestamp.UnixNano() / int64(time.Millisecond),
+			"ask":         ticker.Ask.String(),
+			"last":        ticker.Last.String(),
+			"volumeQuote": ticker.VolumeQuote.String(),
+		}
+		for column, value := range want {
+			if got := file.values[column][i]; got != value {
+				t.Errorf("row %d: %s %v, want %v", i, column, got, value)
+			}
+		}
+	}
+}
+
+func TestCandlesParquet(t *testing.T) {
+	candles := []storage.Candle{
+		{Symbol: "BTCUSD", Period: "M1", OpenTime: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), Open: 65000.5, High: 66000, Low: 64000.25, Close: 65500, Volume: 0.1, VolumeQuote: 6550},
+		{Symbol: "BTCUSD", Period: "M1", OpenTime: time.Date(2024, 3, 1, 12, 1, 0, 0, time.UTC), Open: 65500, High: 65600, Low: 65400, Close: 65450, Volume: 2, VolumeQuote: 130900},
+	}
+	file, err := readParquet(MarshalCandlesParquet(candles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := []string{"symbol", "period", "timestamp", "open", "high", "low", "close", "volume", "volumeQuote"}
+	if !reflect.DeepEqual(file.columns, columns) || file.rows != 2 {
+		t.Fatalf("columns %v of %d rows, want %v of 2", file.columns, file.rows, columns)
+	}
+	for i, candle := range candles {
+		if got := file.values["timestamp"][i]; got != candle.OpenTime.UnixNano()/int64(time.Millisecond) {
+			t.Errorf("row %d: timestamp %v", i, got)
+		}
+		for column, value := range map[string]float64{"open": candle.Open, "low": candle.Low, "volume": candle.Volume} {
+			got, _ := strconv.ParseFloat(file.values[column][i].(string), 64)
+			if got != value {
+				t.Errorf("row %d: %s %v, want %v", i, column, file.values[column][i], value)
+			}
+		}
+	}
+}
+
+// TestParquetLongLists checks the list headers of more than 14 elements.
+func TestParquetLongLists(t *testing.T) {
+	columns := make([]*parquetColumn, 20)
+	for i := range columns {
+		columns[i] = &parquetColumn{name: fmt.Sprintf("c%d", i), typ: parquetByteArray, converted: -1}
+		columns[i].string(decimal.NewFromInt(int64(i)).String())
+	}
+	file, err := readParquet(marshalParquet(columns, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.columns) != 20 || file.values["c19"][0] != "19" {
+		t.Errorf("columns %v, values %v", file.columns, file.values)
+	}
+	if _, ok := file.converted["c0"]; ok {
+		t.Error("converted type set on a column without one")
+	}
+}
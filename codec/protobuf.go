@@ -0,0 +1,149 @@
+// Package codec implements the binary response encodings (Protocol Buffers and
+// MessagePack) served next to JSON, and the Parquet files of the history
+// exports. The schemas are described in ticker.proto.
+package codec
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// protoBuffer appends protobuf wire format fields, skipping proto3 default values.
+type protoBuffer struct {
+	buf []byte
+}
+
+func (p *protoBuffer) varint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	p.buf = append(p.buf, tmp[:n]...)
+}
+
+func (p *protoBuffer) tag(field int, wireType int) {
+	p.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (p *protoBuffer) bytes(field int, b []byte) {
+	p.tag(field, wireBytes)
+	p.varint(uint64(len(b)))
+	p.buf = append(p.buf, b...)
+}
+
+func (p *protoBuffer) string(field int, s string) {
+	if s == "" {
+		return
+	}
+	p.bytes(field, []byte(s))
+}
+
+// decimal writes d as a string field, which keeps its exact value.
+func (p *protoBuffer) decimal(field int, d decimal.Decimal) {
+	p.string(field, d.String())
+}
+
+func (p *protoBuffer) int64(field int, v int64) {
+	if v == 0 {
+		return
+	}
+	p.tag(field, wireVarint)
+	p.varint(uint64(v))
+}
+
+// optionalInt64 writes v when set, even to 0, for the optional fields.
+func (p *protoBuffer) optionalInt64(field int, v *int64) {
+	if v == nil {
+		return
+	}
+	p.tag(field, wireVarint)
+	p.varint(uint64(*v))
+}
+
+func (p *protoBuffer) bool(field int, v bool) {
+	if !v {
+		return
+	}
+	p.tag(field, wireVarint)
+	p.varint(1)
+}
+
+func (p *protoBuffer) optionalDecimal(field int, d *decimal.Decimal) {
+	if d != nil {
+		p.decimal(field, *d)
+	}
+}
+
+// decimalMap writes a map<string, string> field, its entries sorted by key.
+func (p *protoBuffer) decimalMap(field int, m map[string]decimal.Decimal) {
+	for _, key := range sortedKeys(m) {
+		var entry protoBuffer
+		entry.string(1, key)
+		entry.decimal(2, m[key])
+		p.bytes(field, entry.buf)
+	}
+}
+
+func sortedKeys(m map[string]decimal.Decimal) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// MarshalTickerProto encodes a ticker as a cryptoapi.Ticker message.
+func MarshalTickerProto(t *wsclient.Ticker) []byte {
+	var p protoBuffer
+	p.string(1, t.ID)
+	p.string(2, t.FullName)
+	p.decimal(3, t.Ask)
+	p.decimal(4, t.Bid)
+	p.decimal(5, t.Last)
+	p.decimal(6, t.Open)
+	// empty when zero, like the JSON
+	if !t.Low.IsZero() {
+		p.decimal(7, t.Low)
+	}
+	if !t.High.IsZero() {
+		p.decimal(8, t.High)
+	}
+	p.decimal(9, t.Volume)
+	p.decimal(10, t.VolumeQuote)
+	if !t.Timestamp.IsZero() {
+		p.int64(11, t.Timestamp.UnixNano()/1e6)
+	}
+	p.string(12, t.Symbol)
+	p.string(13, t.FeeCurrency)
+	p.decimal(14, t.ChangeAbs24h)
+	p.decimal(15, t.ChangePercent24h)
+	p.string(16, t.Source)
+	if t.CachedAt != nil {
+		cachedAt := t.CachedAt.UnixNano() / 1e6
+		p.optionalInt64(17, &cachedAt)
+	}
+	p.optionalInt64(18, t.AgeMs)
+	p.bool(19, t.Delisted)
+	p.decimalMap(20, t.Indicators)
+	p.optionalDecimal(21, t.SessionHigh)
+	p.optionalDecimal(22, t.SessionLow)
+	p.optionalDecimal(23, t.AllTimeHigh)
+	p.optionalDecimal(24, t.AllTimeLow)
+	return p.buf
+}
+
+// MarshalTickerListProto encodes tickers as a cryptoapi.TickerList message.
+func MarshalTickerListProto(tickers []*wsclient.Ticker) []byte {
+	var p protoBuffer
+	for _, ticker := range tickers {
+		p.bytes(1, MarshalTickerProto(ticker))
+	}
+	return p.buf
+}
@@ -0,0 +1,158 @@
+package codec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+// protoFields decodes a message into the values of its fields by number,
+// uint64 for the varints and string for the length delimited fields.
+func protoFields(data []byte) (map[int][]interface{}, error) {
+	fields := make(map[int][]interface{})
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid tag")
+		}
+		data = data[n:]
+		field := int(tag >> 3)
+		switch tag & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, fmt.Errorf("field %d: invalid varint", field)
+			}
+			data = data[n:]
+			fields[field] = append(fields[field], v)
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return nil, fmt.Errorf("field %d: invalid length", field)
+			}
+			fields[field] = append(fields[field], string(data[n:n+int(length)]))
+			data = data[n+int(length):]
+		default:
+			return nil, fmt.Errorf("field %d: unexpected wire type %d", field, tag&7)
+		}
+	}
+	return fields, nil
+}
+
+// unmarshalTickerProto decodes a cryptoapi.Ticker message, as a client
+// generated from ticker.proto would.
+func unmarshalTickerProto(data []byte) (*wsclient.Ticker, error) {
+	fields, err := protoFields(data)
+	if err != nil {
+		return nil, err
+	}
+	t := &wsclient.Ticker{}
+	str := func(field int) string {
+		values := fields[field]
+		if len(values) == 0 {
+			return ""
+		}
+		return values[len(values)-1].(string)
+	}
+	num := func(field int) *int64 {
+		values := fields[field]
+		if len(values) == 0 {
+			return nil
+		}
+		v := int64(values[len(values)-1].(uint64))
+		return &v
+	}
+	dec := func(field int) decimal.Decimal {
+		if s := str(field); s != "" {
+			d, err := decimal.NewFromString(s)
+			if err == nil {
+				return d
+			}
+		}
+		return decimal.Zero
+	}
+	optionalDec := func(field int) *decimal.Decimal {
+		if len(fields[field]) == 0 {
+			return nil
+		}
+		d := dec(field)
+		return &d
+	}
+	t.ID = str(1)
+	t.FullName = str(2)
+	t.Ask, t.Bid, t.Last, t.Open = dec(3), dec(4), dec(5), dec(6)
+	t.Low, t.High, t.Volume, t.VolumeQuote = dec(7), dec(8), dec(9), dec(10)
+	if ms := num(11); ms != nil {
+		t.Timestamp = msTime(*ms)
+	}
+	t.Symbol = str(12)
+	t.FeeCurrency = str(13)
+	t.ChangeAbs24h, t.ChangePercent24h = dec(14), dec(15)
+	t.Source = str(16)
+	if ms := num(17); ms != nil {
+		cachedAt := msTime(*ms)
+		t.CachedAt = &cachedAt
+	}
+	t.AgeMs = num(18)
+	t.Delisted = num(19) != nil && *num(19) == 1
+	for _, entry := range fields[20] {
+		entryFields, err := protoFields([]byte(entry.(string)))
+		if err != nil {
+			return nil, err
+		}
+		if t.Indicators == nil {
+			t.Indicators = make(map[string]decimal.Decimal)
+		}
+		key := entryFields[1][0].(string)
+		t.Indicators[key] = decimal.RequireFromString(entryFields[2][0].(string))
+	}
+	t.SessionHigh, t.SessionLow = optionalDec(21), optionalDec(22)
+	t.AllTimeHigh, t.AllTimeLow = optionalDec(23), optionalDec(24)
+	return t, nil
+}
+
+func msTime(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC()
+}
+
+func TestTickerProto(t *testing.T) {
+	full, bare := testTickers()
+	for _, ticker := range []*wsclient.Ticker{full, bare} {
+		t.Run(ticker.Symbol, func(t *testing.T) {
+			got, err := unmarshalTickerProto(MarshalTickerProto(ticker))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the values are compared through their JSON representation,
+			// where equal decimals are equal strings
+			if got, want := jsonValue(t, got), jsonValue(t, ticker); !reflect.DeepEqual(got, want) {
+				t.Errorf("decoded %v\nwant %v", got, want)
+			}
+		})
+	}
+}
+
+func TestTickerListProto(t *testing.T) {
+	full, bare := testTickers()
+	fields, err := protoFields(MarshalTickerListProto([]*wsclient.Ticker{full, bare}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 1 || len(fields[1]) != 2 {
+		t.Fatalf("fields %v, want two currencies", fields)
+	}
+	for i, want := range []*wsclient.Ticker{full, bare} {
+		got, err := unmarshalTickerProto([]byte(fields[1][i].(string)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Symbol != want.Symbol {
+			t.Errorf("currency %d: %s, want %s", i, got.Symbol, want.Symbol)
+		}
+	}
+}
@@ -0,0 +1,1092 @@
+// Package config loads the server configuration from a YAML file, the
+// environment and the command line flags, in increasing order of precedence.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crypto-api-server/alerts"
+	"github.com/crypto-api-server/scheduler"
+	"github.com/crypto-api-server/storage"
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the configuration of the server.
+type Config struct {
+	// Listen is the TCP address of the HTTP server, it can be empty when
+	// the server listens on a Unix socket.
+	Listen string `yaml:"listen"`
+	// Server tunes the HTTP server.
+	Server   Server   `yaml:"server"`
+	Exchange Exchange `yaml:"exchange"`
+	// Symbols are the markets streamed from the websocket feed.
+	Symbols []string `yaml:"symbols"`
+	// CacheTTL is the age after which a cached ticker is refreshed from the
+	// REST API, zero keeps them until the feed updates them.
+	CacheTTL time.Duration `yaml:"cacheTTL"`
+	// AdminToken is the bearer token of the admin and trading endpoints,
+	// which are disabled when empty.
+	AdminToken string `yaml:"adminToken"`
+	// LogLevel is debug or info. debug logs the requests served and dumps
+	// the upstream requests and responses.
+	LogLevel string `yaml:"logLevel"`
+	// RateLimits are the upstream REST requests per second per class.
+	RateLimits RateLimits `yaml:"rateLimits"`
+	// AlertRules are price alert rules, see alerts.ParseRule.
+	AlertRules []string `yaml:"alertRules"`
+	// AlertWebhookURL receives the fired alerts, which are logged otherwise.
+	AlertWebhookURL string `yaml:"alertWebhookURL"`
+	// Telegram delivers the fired alerts and the feed incidents to a chat.
+	Telegram Telegram `yaml:"telegram"`
+	// Webhooks receive the ticker updates, more can be added with the admin
+	// API.
+	Webhooks []Webhook `yaml:"webhooks"`
+	// Upstream tunes the HitBTC REST calls.
+	Upstream Upstream `yaml:"upstream"`
+	// FXRatesURL overrides the ECB daily reference rates feed used by
+	// ?quote=.
+	FXRatesURL string `yaml:"fxRatesURL"`
+	// Tracing exports the spans of the requests.
+	Tracing Tracing `yaml:"tracing"`
+	// Replay serves a recorded feed instead of the HitBTC feed.
+	Replay Replay `yaml:"replay"`
+	// PaperTrading simulates the trading endpoints.
+	PaperTrading PaperTrading `yaml:"paperTrading"`
+	// Record is the directory where the upstream REST responses and feed
+	// notifications are recorded, recording is disabled when empty.
+	Record string `yaml:"record"`
+	// NATS, Kafka, MQTT, Redis and Influx publish the ticker updates to
+	// message brokers and databases.
+	NATS   NATS   `yaml:"nats"`
+	Kafka  Kafka  `yaml:"kafka"`
+	MQTT   MQTT   `yaml:"mqtt"`
+	Redis  Redis  `yaml:"redis"`
+	Influx Influx `yaml:"influx"`
+	// Feed tunes the websocket feed.
+	Feed Feed `yaml:"feed"`
+	// History configures the ticker history kept in memory and stored.
+	History History `yaml:"history"`
+	// PortfolioFile saves the holdings of /portfolio to a JSON file, they
+	// are kept in memory only when empty.
+	PortfolioFile string `yaml:"portfolioFile"`
+	// AuditLogFile appends the audit log of the admin actions to a JSON
+	// lines file, it is kept in memory only when empty.
+	AuditLogFile string `yaml:"auditLogFile"`
+	// Archive uploads the stored history to object storage.
+	Archive Archive `yaml:"archive"`
+	// Jobs schedules the periodic tasks.
+	Jobs Jobs `yaml:"jobs"`
+	// Tenants are the clients of the API. When there are any, requests
+	// need the API key of a tenant or the admin token.
+	Tenants []Tenant `yaml:"tenants"`
+
+	// ValidateOnly is set by the -validate-config flag.
+	ValidateOnly bool `yaml:"-"`
+	// Backfill holds the key=value arguments of the -backfill flag, it is nil
+	// without the flag.
+	Backfill []string `yaml:"-"`
+	// Path is the config file read, empty when there is none.
+	Path string `yaml:"-"`
+	// args are the flags the configuration was loaded with, see Reload.
+	args   []string
+	loaded bool
+}
+
+// Server bounds the time and the headers of the HTTP requests, so that slow
+// clients can't hold connections open. A zero timeout is no timeout.
+type Server struct {
+	// ReadHeaderTimeout is the time allowed to read the request headers,
+	// ReadTimeout to read the whole request.
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout"`
+	ReadTimeout       time.Duration `yaml:"readTimeout"`
+	// WriteTimeout is the time allowed from the end of the request headers
+	// to the end of the response. It must exceed the longest long poll.
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+	// IdleTimeout closes the keep-alive connections idle for that long.
+	IdleTimeout    time.Duration `yaml:"idleTimeout"`
+	MaxHeaderBytes int           `yaml:"maxHeaderBytes"`
+	// TLSCertFile and TLSKeyFile serve HTTPS, with HTTP/2, instead of
+	// plain HTTP.
+	TLSCertFile string `yaml:"tlsCertFile"`
+	TLSKeyFile  string `yaml:"tlsKeyFile"`
+	// H2C serves HTTP/2 without TLS next to HTTP/1, for clients and proxies
+	// speaking cleartext HTTP/2.
+	H2C bool `yaml:"h2c"`
+	// UnixSocket is the path of a Unix socket served like Listen, for a
+	// reverse proxy on the same host. UnixSocketMode is its octal
+	// permissions.
+	UnixSocket     string `yaml:"unixSocket"`
+	UnixSocketMode string `yaml:"unixSocketMode"`
+	// AdminListen serves the routes requiring the admin token on their own
+	// TCP address, they are left out of Listen then. DebugListen serves the
+	// pprof profiles and the expvar metrics, which are served nowhere else.
+	AdminListen string `yaml:"adminListen"`
+	DebugListen string `yaml:"debugListen"`
+}
+
+// SocketMode returns the parsed permissions of the Unix socket.
+func (s Server) SocketMode() (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s.UnixSocketMode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("server.unixSocketMode %q: must be octal permissions such as 0660", s.UnixSocketMode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// Exchange configures the exchange API.
+type Exchange struct {
+	Name       string `yaml:"name"`
+	APIVersion int    `yaml:"apiVersion"`
+	APIKey     string `yaml:"apiKey"`
+	APISecret  string `yaml:"apiSecret"`
+}
+
+// Replay configures the replay of a recorded feed, see
+// wsclient.NewReplayClient.
+type Replay struct {
+	// File is the recorded feed, replay is disabled when empty.
+	File string `yaml:"file"`
+	// Speed scales the recorded pace, 0 replays without waiting.
+	Speed float64 `yaml:"speed"`
+}
+
+// PaperTrading configures the simulated trading account, see
+// papertrading.Engine.
+type PaperTrading struct {
+	Enabled bool `yaml:"enabled"`
+	// Balances are the initial funds of the trading account by currency,
+	// as decimal strings.
+	Balances map[string]string `yaml:"balances"`
+}
+
+// PaperBalances returns the parsed initial paper trading balances.
+func (cfg *Config) PaperBalances() (map[string]decimal.Decimal, error) {
+	balances := make(map[string]decimal.Decimal, len(cfg.PaperTrading.Balances))
+	for currency, value := range cfg.PaperTrading.Balances {
+		amount, err := decimal.NewFromString(value)
+		if err != nil || amount.IsNegative() {
+			return nil, fmt.Errorf("paperTrading.balances %s: %q is not a valid amount", currency, value)
+		}
+		balances[currency] = amount
+	}
+	return balances, nil
+}
+
+// NATS configures the NATS publisher, see publisher.NATSPublisher.
+type NATS struct {
+	// URL is the NATS server, e.g. nats://localhost:4222, publishing is
+	// disabled when empty.
+	URL string `yaml:"url"`
+	// SubjectPrefix starts the subjects, which end with the symbol.
+	SubjectPrefix string `yaml:"subjectPrefix"`
+}
+
+// Kafka configures the Kafka publisher, see publisher.KafkaPublisher.
+type Kafka struct {
+	// Brokers are host:port addresses, publishing is disabled when empty.
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic"`
+}
+
+// MQTT configures the MQTT publisher, see publisher.MQTTPublisher.
+type MQTT struct {
+	// Broker is e.g. tcp://localhost:1883, publishing is disabled when
+	// empty. The updates are published on TopicPrefix/<symbol>.
+	Broker      string `yaml:"broker"`
+	TopicPrefix string `yaml:"topicPrefix"`
+	// QoS is 0, 1 or 2. Retain keeps the last update of each symbol on
+	// the broker.
+	QoS    int  `yaml:"qos"`
+	Retain bool `yaml:"retain"`
+}
+
+// Redis configures the Redis publisher, see publisher.RedisPublisher.
+type Redis struct {
+	// URL is e.g. redis://localhost:6379/0, publishing is disabled when
+	// empty. The updates are PUBLISHed on ChannelPrefix<symbol>.
+	URL           string `yaml:"url"`
+	ChannelPrefix string `yaml:"channelPrefix"`
+}
+
+// Influx configures the InfluxDB publisher, see publisher.InfluxConfig.
+type Influx struct {
+	// URL is e.g. http://localhost:8086, writing is disabled when empty.
+	// The points go to Bucket of Org with Token on InfluxDB 2, or to
+	// Database on InfluxDB 1.
+	URL         string `yaml:"url"`
+	Token       string `yaml:"token"`
+	Org         string `yaml:"org"`
+	Bucket      string `yaml:"bucket"`
+	Database    string `yaml:"database"`
+	Measurement string `yaml:"measurement"`
+}
+
+// Telegram configures the Telegram bot, see telegram.Bot.
+type Telegram struct {
+	// BotToken enables the bot, which talks to the chat ChatID only.
+	BotToken string `yaml:"botToken"`
+	ChatID   int64  `yaml:"chatID"`
+}
+
+// Upstream tunes the HitBTC REST calls, see wsclient.RetryPolicy.
+type Upstream struct {
+	// MaxRetries retries the calls failing with a network error, 429 or
+	// 5xx. Only GET requests are retried unless RetryUnsafe is set.
+	MaxRetries  int  `yaml:"maxRetries"`
+	RetryUnsafe bool `yaml:"retryUnsafe"`
+	// BreakerThreshold consecutive failures stop the calls for
+	// BreakerCooldown, cached data is served flagged as stale meanwhile.
+	// Zero disables the breaker.
+	BreakerThreshold int           `yaml:"breakerThreshold"`
+	BreakerCooldown  time.Duration `yaml:"breakerCooldown"`
+	// ProxyURL sends the calls through a proxy, it overrides HTTPS_PROXY.
+	// Timeout bounds each call.
+	ProxyURL string        `yaml:"proxyURL"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// Tracing configures the OpenTelemetry tracing, see tracing.Setup.
+type Tracing struct {
+	// Endpoint is the base URL of the OTLP/HTTP collector, e.g.
+	// http://localhost:4318. Tracing is disabled when empty.
+	Endpoint string `yaml:"endpoint"`
+}
+
+// Feed tunes the websocket feed.
+type Feed struct {
+	// BufferSize buffers the updates waiting for one of the Workers, when
+	// the buffer is full OverflowPolicy applies, see
+	// wsclient.ParseOverflowPolicy.
+	BufferSize     int    `yaml:"bufferSize"`
+	OverflowPolicy string `yaml:"overflowPolicy"`
+	Workers        int    `yaml:"workers"`
+	// Lazy subscribes the symbols on their first request rather than at
+	// startup.
+	Lazy bool `yaml:"lazy"`
+	// SubscribeConcurrency is the number of subscription requests in flight
+	// at once.
+	SubscribeConcurrency int `yaml:"subscribeConcurrency"`
+	// IdleTimeout unsubscribes the symbols not requested for that long, they
+	// are subscribed again on their next request. Zero keeps them.
+	IdleTimeout time.Duration `yaml:"idleTimeout"`
+	// AutoQuotes are the quote currencies of the new listings found by the
+	// metadataRefresh job which are added to the feed symbols.
+	AutoQuotes []string `yaml:"autoQuotes"`
+}
+
+// History configures the ticker history.
+type History struct {
+	// Backend stores the history: sqlite, in the file SQLitePath, or
+	// postgres, at PostgresDSN, as TimescaleDB hypertables with Timescale.
+	// Nothing is stored when empty, unless SQLitePath is set.
+	Backend     string `yaml:"backend"`
+	SQLitePath  string `yaml:"sqlitePath"`
+	PostgresDSN string `yaml:"postgresDSN"`
+	Timescale   bool   `yaml:"timescale"`
+	// SampleInterval stores at most one update per symbol and interval.
+	SampleInterval time.Duration `yaml:"sampleInterval"`
+	// RecentSize is the number of updates per symbol kept in memory for
+	// /currency/{symbol}/history.
+	RecentSize int `yaml:"recentSize"`
+	// Retention prunes the stored history older than its kind allows, after
+	// downsampling it into longer candles, every RetentionInterval. See
+	// storage.ParseRetentionPolicy, the history is kept when empty.
+	Retention         string        `yaml:"retention"`
+	RetentionInterval time.Duration `yaml:"retentionInterval"`
+	// ExportDir receives the stored history as Parquet files, a directory
+	// per ExportInterval once it is over. Exporting is disabled when empty.
+	ExportDir      string        `yaml:"exportDir"`
+	ExportInterval time.Duration `yaml:"exportInterval"`
+}
+
+// Archive configures the uploads of the stored history, see archive.Bucket.
+// They are scheduled by Jobs.Archive.
+type Archive struct {
+	// Bucket receives the history as a tar.gz of Parquet files under
+	// Prefix, archiving is disabled when empty.
+	Bucket string `yaml:"bucket"`
+	Prefix string `yaml:"prefix"`
+	// Backend is s3 or gcs, which takes the HMAC key of a service account.
+	// Endpoint targets other S3 compatible services, the endpoint and the
+	// region of the backend are used when empty.
+	Backend         string `yaml:"backend"`
+	Endpoint        string `yaml:"endpoint"`
+	Region          string `yaml:"region"`
+	AccessKeyID     string `yaml:"accessKeyId"`
+	SecretAccessKey string `yaml:"secretAccessKey"`
+}
+
+// Jobs are the schedules of the periodic tasks, see scheduler.ParseSchedule.
+// A task without a schedule doesn't run.
+type Jobs struct {
+	// Snapshot writes the cached tickers to a JSON file in SnapshotDir.
+	Snapshot    string `yaml:"snapshot"`
+	SnapshotDir string `yaml:"snapshotDir"`
+	// MetadataRefresh refreshes the symbols and currencies, see
+	// Feed.AutoQuotes.
+	MetadataRefresh string `yaml:"metadataRefresh"`
+	// Archive uploads the history stored since the last upload, see
+	// Config.Archive.
+	Archive string `yaml:"archive"`
+	// Report writes a market summary to a JSON file in ReportDir, it is
+	// logged when ReportDir is empty.
+	Report    string `yaml:"report"`
+	ReportDir string `yaml:"reportDir"`
+}
+
+// Tenant is a client of the API, see tenants.Tenant.
+type Tenant struct {
+	Name string `yaml:"name"`
+	// APIKeys are sent in the X-API-Key header or as a bearer token.
+	APIKeys []string `yaml:"apiKeys"`
+	// Symbols are the markets the tenant can query, all of them when empty.
+	Symbols []string `yaml:"symbols"`
+	// RateLimit is requests per second, unlimited when zero. Burst defaults
+	// to the rate.
+	RateLimit float64 `yaml:"rateLimit"`
+	Burst     int     `yaml:"burst"`
+}
+
+// Webhook receives the ticker updates, see webhooks.Webhook. A plain URL
+// is a webhook of every update of every symbol.
+type Webhook struct {
+	URL string `yaml:"url"`
+	// Symbols are the markets delivered, all of them when empty.
+	Symbols []string `yaml:"symbols"`
+	// Above and Below only deliver the crossings of these last prices.
+	Above *float64 `yaml:"above"`
+	Below *float64 `yaml:"below"`
+	// Secret signs the deliveries, see webhooks.Sign.
+	Secret string `yaml:"secret"`
+}
+
+// UnmarshalYAML accepts a plain URL as well as a mapping.
+func (hook *Webhook) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var url string
+	if err := unmarshal(&url); err == nil {
+		*hook = Webhook{URL: url}
+		return nil
+	}
+	type plain Webhook
+	return unmarshal((*plain)(hook))
+}
+
+// RateLimits are requests per second, the defaults are the documented HitBTC
+// limits.
+type RateLimits struct {
+	MarketData float64 `yaml:"marketData"`
+	Trading    float64 `yaml:"trading"`
+	Other      float64 `yaml:"other"`
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() *Config {
+	return &Config{
+		Listen: ":8080",
+		Server: Server{
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      3 * time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    1 << 20,
+			UnixSocketMode:    "0660",
+		},
+		Exchange: Exchange{Name: "hitbtc", APIVersion: 2},
+		Symbols:  []string{"BTCUSD", "ETHBTC"},
+		LogLevel: "info",
+		Replay:   Replay{Speed: 1},
+		NATS:     NATS{SubjectPrefix: "ticker"},
+		Kafka:    Kafka{Topic: "tickers"},
+		MQTT:     MQTT{TopicPrefix: "tickers", Retain: true},
+		Redis:    Redis{ChannelPrefix: "ticker:"},
+		Feed: Feed{
+			BufferSize:           1024,
+			OverflowPolicy:       string(wsclient.OverflowDropOldest),
+			Workers:              4,
+			SubscribeConcurrency: 16,
+		},
+		Upstream: Upstream{
+			MaxRetries:       wsclient.DefaultRetryPolicy.MaxRetries,
+			BreakerThreshold: 5,
+			BreakerCooldown:  30 * time.Second,
+			Timeout:          30 * time.Second,
+		},
+		Archive: Archive{Backend: "s3"},
+		History: History{
+			RecentSize:        1000,
+			RetentionInterval: time.Hour,
+			ExportInterval:    24 * time.Hour,
+		},
+		PaperTrading: PaperTrading{
+			Balances: map[string]string{"USD": "10000"},
+		},
+		RateLimits: RateLimits{
+			MarketData: 100,
+			Trading:    300,
+			Other:      10,
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the config file, the
+// environment and the flags of args. The config file is the -config flag,
+// or CONFIG_FILE.
+func Load(args []string) (*Config, error) {
+	flags := flag.NewFlagSet("crypto-api-server", flag.ContinueOnError)
+	path := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML config file")
+	listen := flags.String("listen", "", "listen address of the HTTP server")
+	apiVersion := flags.Int("exchange-api-version", 0, "HitBTC API version, 2 or 3")
+	symbols := flags.String("symbols", "", "comma separated symbols streamed from the feed")
+	cacheTTL := flags.Duration("cache-ttl", 0, "age after which cached tickers are refreshed")
+	logLevel := flags.String("log-level", "", "debug or info")
+	replay := flags.String("replay", "", "recorded feed file replayed instead of the HitBTC feed")
+	replaySpeed := flags.Float64("replay-speed", 0, "replay speed, 1 is the recorded pace and 0 as fast as possible")
+	paperTrading := flags.Bool("paper-trading", false, "simulate the trading endpoints with virtual balances")
+	record := flags.String("record", "", "directory where the upstream traffic is recorded")
+	validateOnly := flags.Bool("validate-config", false, "validate the configuration and exit")
+	backfill := flags.Bool("backfill", false, "store HitBTC candles and exit, with symbol=BTCUSD,ETHBTC period=M1 from=<time> [till=<time>] arguments")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return nil, err
+		}
+		cfg.Path = *path
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			cfg.Listen = *listen
+		case "exchange-api-version":
+			cfg.Exchange.APIVersion = *apiVersion
+		case "symbols":
+			cfg.Symbols = splitList(*symbols)
+		case "cache-ttl":
+			cfg.CacheTTL = *cacheTTL
+		case "log-level":
+			cfg.LogLevel = *logLevel
+		case "replay":
+			cfg.Replay.File = *replay
+		case "replay-speed":
+			cfg.Replay.Speed = *replaySpeed
+		case "record":
+			cfg.Record = *record
+		case "paper-trading":
+			cfg.PaperTrading.Enabled = *paperTrading
+		}
+	})
+	cfg.ValidateOnly = *validateOnly
+	if *backfill {
+		cfg.Backfill = append([]string{}, flags.Args()...)
+	}
+	cfg.args = append([]string{}, args...)
+	cfg.loaded = true
+	return cfg, nil
+}
+
+// Reload loads the configuration again from the config file, the
+// environment and the flags cfg was loaded with. Only the configurations
+// returned by Load can be reloaded.
+func (cfg *Config) Reload() (*Config, error) {
+	if !cfg.loaded {
+		return nil, errors.New("the configuration wasn't loaded with config.Load, there is nothing to reload it from")
+	}
+	return Load(cfg.args)
+}
+
+func (cfg *Config) loadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	// the default balances are replaced, not merged: the strict decoding
+	// rejects the keys already in the map
+	balances := cfg.PaperTrading.Balances
+	cfg.PaperTrading.Balances = nil
+	// unknown keys are errors so typos don't go unnoticed
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if cfg.PaperTrading.Balances == nil {
+		cfg.PaperTrading.Balances = balances
+	}
+	return nil
+}
+
+// loadEnv applies the environment variables that are set.
+func (cfg *Config) loadEnv() error {
+	if value, ok := os.LookupEnv("LISTEN_ADDR"); ok {
+		cfg.Listen = value
+	}
+	if value, ok := os.LookupEnv("HITBTC_API_VERSION"); ok && value != "" {
+		version, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid HITBTC_API_VERSION %q", value)
+		}
+		cfg.Exchange.APIVersion = version
+	}
+	if value, ok := os.LookupEnv("API_KEY"); ok {
+		cfg.Exchange.APIKey = value
+	}
+	if value, ok := os.LookupEnv("API_SECRET"); ok {
+		cfg.Exchange.APISecret = value
+	}
+	if value, ok := os.LookupEnv("SYMBOLS"); ok {
+		cfg.Symbols = splitList(value)
+	}
+	if value, ok := os.LookupEnv("CACHE_TTL"); ok && value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid CACHE_TTL %q", value)
+		}
+		cfg.CacheTTL = ttl
+	}
+	if value, ok := os.LookupEnv("ADMIN_TOKEN"); ok {
+		cfg.AdminToken = value
+	}
+	if value, ok := os.LookupEnv("LOG_LEVEL"); ok {
+		cfg.LogLevel = value
+	}
+	if value, ok := os.LookupEnv("REPLAY_FILE"); ok {
+		cfg.Replay.File = value
+	}
+	if value, ok := os.LookupEnv("REPLAY_SPEED"); ok && value != "" {
+		speed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid REPLAY_SPEED %q", value)
+		}
+		cfg.Replay.Speed = speed
+	}
+	if value, ok := os.LookupEnv("PAPER_TRADING"); ok && value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid PAPER_TRADING %q", value)
+		}
+		cfg.PaperTrading.Enabled = enabled
+	}
+	if value, ok := os.LookupEnv("PAPER_BALANCES"); ok && value != "" {
+		cfg.PaperTrading.Balances = make(map[string]string)
+		for _, item := range splitList(value) {
+			parts := strings.SplitN(item, ":", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid PAPER_BALANCES %q, expected CURRENCY:AMOUNT pairs", value)
+			}
+			cfg.PaperTrading.Balances[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	if value, ok := os.LookupEnv("RECORD_DIR"); ok {
+		cfg.Record = value
+	}
+	if value, ok := os.LookupEnv("NATS_URL"); ok {
+		cfg.NATS.URL = value
+	}
+	if value, ok := os.LookupEnv("NATS_SUBJECT_PREFIX"); ok && value != "" {
+		cfg.NATS.SubjectPrefix = value
+	}
+	if err := cfg.loadPublishersEnv(); err != nil {
+		return err
+	}
+	if err := lookupInt("TICKER_BUFFER_SIZE", &cfg.Feed.BufferSize); err != nil {
+		return err
+	}
+	if value, ok := os.LookupEnv("TICKER_OVERFLOW_POLICY"); ok && value != "" {
+		cfg.Feed.OverflowPolicy = value
+	}
+	if err := lookupInt("FEED_WORKERS", &cfg.Feed.Workers); err != nil {
+		return err
+	}
+	if err := lookupBool("FEED_LAZY", &cfg.Feed.Lazy); err != nil {
+		return err
+	}
+	if err := lookupInt("FEED_SUBSCRIBE_CONCURRENCY", &cfg.Feed.SubscribeConcurrency); err != nil {
+		return err
+	}
+	if err := lookupDuration("FEED_IDLE_TIMEOUT", &cfg.Feed.IdleTimeout); err != nil {
+		return err
+	}
+	if err := lookupInt("TICKER_HISTORY_SIZE", &cfg.History.RecentSize); err != nil {
+		return err
+	}
+	lookupString("HISTORY_BACKEND", &cfg.History.Backend)
+	lookupString("HISTORY_SQLITE_PATH", &cfg.History.SQLitePath)
+	lookupString("HISTORY_POSTGRES_DSN", &cfg.History.PostgresDSN)
+	if err := lookupBool("HISTORY_TIMESCALE", &cfg.History.Timescale); err != nil {
+		return err
+	}
+	if err := lookupDuration("HISTORY_SAMPLE_INTERVAL", &cfg.History.SampleInterval); err != nil {
+		return err
+	}
+	lookupString("HISTORY_RETENTION", &cfg.History.Retention)
+	if err := lookupDuration("HISTORY_RETENTION_INTERVAL", &cfg.History.RetentionInterval); err != nil {
+		return err
+	}
+	lookupString("HISTORY_EXPORT_DIR", &cfg.History.ExportDir)
+	if err := lookupDuration("HISTORY_EXPORT_INTERVAL", &cfg.History.ExportInterval); err != nil {
+		return err
+	}
+	if value, ok := os.LookupEnv("FEED_AUTO_QUOTES"); ok {
+		cfg.Feed.AutoQuotes = splitList(strings.ToUpper(value))
+	}
+	lookupString("PORTFOLIO_FILE", &cfg.PortfolioFile)
+	lookupString("AUDIT_LOG_FILE", &cfg.AuditLogFile)
+	lookupString("ARCHIVE_BUCKET", &cfg.Archive.Bucket)
+	lookupString("ARCHIVE_PREFIX", &cfg.Archive.Prefix)
+	if value, ok := os.LookupEnv("ARCHIVE_BACKEND"); ok && value != "" {
+		cfg.Archive.Backend = value
+	}
+	lookupString("ARCHIVE_ENDPOINT", &cfg.Archive.Endpoint)
+	lookupString("ARCHIVE_REGION", &cfg.Archive.Region)
+	lookupString("ARCHIVE_ACCESS_KEY_ID", &cfg.Archive.AccessKeyID)
+	lookupString("ARCHIVE_SECRET_ACCESS_KEY", &cfg.Archive.SecretAccessKey)
+	if value, ok := os.LookupEnv("ALERT_RULES"); ok {
+		cfg.AlertRules = nil
+		for _, rule := range strings.Split(value, ";") {
+			if rule = strings.TrimSpace(rule); rule != "" {
+				cfg.AlertRules = append(cfg.AlertRules, rule)
+			}
+		}
+	}
+	lookupString("ALERT_WEBHOOK_URL", &cfg.AlertWebhookURL)
+	lookupString("TELEGRAM_BOT_TOKEN", &cfg.Telegram.BotToken)
+	if value, ok := os.LookupEnv("TELEGRAM_CHAT_ID"); ok && value != "" {
+		chatID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid TELEGRAM_CHAT_ID %q", value)
+		}
+		cfg.Telegram.ChatID = chatID
+	}
+	if value, ok := os.LookupEnv("WEBHOOK_URLS"); ok {
+		cfg.Webhooks = nil
+		for _, webhookURL := range splitList(value) {
+			cfg.Webhooks = append(cfg.Webhooks, Webhook{URL: webhookURL})
+		}
+	}
+	lookupString("FX_RATES_URL", &cfg.FXRatesURL)
+	lookupString("OTEL_EXPORTER_OTLP_ENDPOINT", &cfg.Tracing.Endpoint)
+	return cfg.loadUpstreamEnv()
+}
+
+// loadPublishersEnv applies the environment variables of the publishers.
+func (cfg *Config) loadPublishersEnv() error {
+	if value, ok := os.LookupEnv("KAFKA_BROKERS"); ok {
+		cfg.Kafka.Brokers = splitList(value)
+	}
+	if value, ok := os.LookupEnv("KAFKA_TOPIC"); ok && value != "" {
+		cfg.Kafka.Topic = value
+	}
+	lookupString("MQTT_BROKER", &cfg.MQTT.Broker)
+	if value, ok := os.LookupEnv("MQTT_TOPIC_PREFIX"); ok && value != "" {
+		cfg.MQTT.TopicPrefix = value
+	}
+	if err := lookupInt("MQTT_QOS", &cfg.MQTT.QoS); err != nil {
+		return err
+	}
+	if err := lookupBool("MQTT_RETAIN", &cfg.MQTT.Retain); err != nil {
+		return err
+	}
+	lookupString("REDIS_URL", &cfg.Redis.URL)
+	if value, ok := os.LookupEnv("REDIS_CHANNEL_PREFIX"); ok && value != "" {
+		cfg.Redis.ChannelPrefix = value
+	}
+	lookupString("INFLUX_URL", &cfg.Influx.URL)
+	lookupString("INFLUX_TOKEN", &cfg.Influx.Token)
+	lookupString("INFLUX_ORG", &cfg.Influx.Org)
+	lookupString("INFLUX_BUCKET", &cfg.Influx.Bucket)
+	lookupString("INFLUX_DATABASE", &cfg.Influx.Database)
+	lookupString("INFLUX_MEASUREMENT", &cfg.Influx.Measurement)
+	return nil
+}
+
+// loadUpstreamEnv applies the UPSTREAM_* environment variables.
+func (cfg *Config) loadUpstreamEnv() error {
+	if err := lookupInt("UPSTREAM_MAX_RETRIES", &cfg.Upstream.MaxRetries); err != nil {
+		return err
+	}
+	if err := lookupBool("UPSTREAM_RETRY_UNSAFE", &cfg.Upstream.RetryUnsafe); err != nil {
+		return err
+	}
+	if err := lookupInt("UPSTREAM_BREAKER_THRESHOLD", &cfg.Upstream.BreakerThreshold); err != nil {
+		return err
+	}
+	if err := lookupDuration("UPSTREAM_BREAKER_COOLDOWN", &cfg.Upstream.BreakerCooldown); err != nil {
+		return err
+	}
+	lookupString("UPSTREAM_PROXY_URL", &cfg.Upstream.ProxyURL)
+	return lookupDuration("UPSTREAM_TIMEOUT", &cfg.Upstream.Timeout)
+}
+
+// lookupString sets target to the environment variable name, if set.
+func lookupString(name string, target *string) {
+	if value, ok := os.LookupEnv(name); ok {
+		*target = value
+	}
+}
+
+// lookupInt sets target to the environment variable name, if set and not
+// empty.
+func lookupInt(name string, target *int) error {
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", name, value)
+		}
+		*target = parsed
+	}
+	return nil
+}
+
+// lookupBool sets target to the environment variable name, if set and not
+// empty.
+func lookupBool(name string, target *bool) error {
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", name, value)
+		}
+		*target = parsed
+	}
+	return nil
+}
+
+// lookupDuration sets target to the environment variable name, if set and
+// not empty.
+func lookupDuration(name string, target *time.Duration) error {
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", name, value)
+		}
+		*target = parsed
+	}
+	return nil
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9]+$`)
+
+// ValidationError lists the problems of a configuration.
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e, "; ")
+}
+
+// Validate checks the configuration, returning a ValidationError listing
+// every problem found.
+func (cfg *Config) Validate() error {
+	var problems ValidationError
+	if cfg.Listen == "" && cfg.Server.UnixSocket == "" {
+		problems = append(problems, "listen: an address is needed without server.unixSocket")
+	} else if cfg.Listen != "" {
+		if _, _, err := net.SplitHostPort(cfg.Listen); err != nil {
+			problems = append(problems, fmt.Sprintf("listen %q: %v", cfg.Listen, err))
+		}
+	}
+	if cfg.Server.ReadHeaderTimeout < 0 || cfg.Server.ReadTimeout < 0 || cfg.Server.WriteTimeout < 0 || cfg.Server.IdleTimeout < 0 {
+		problems = append(problems, "server timeouts can't be negative")
+	}
+	if cfg.Server.MaxHeaderBytes <= 0 {
+		problems = append(problems, "server.maxHeaderBytes must be positive")
+	}
+	if (cfg.Server.TLSCertFile == "") != (cfg.Server.TLSKeyFile == "") {
+		problems = append(problems, "server.tlsCertFile and server.tlsKeyFile must be set together")
+	}
+	if cfg.Server.H2C && cfg.Server.TLSCertFile != "" {
+		problems = append(problems, "server.h2c is for plain HTTP, HTTP/2 is already enabled with TLS")
+	}
+	listeners := map[string]string{"server.adminListen": cfg.Server.AdminListen, "server.debugListen": cfg.Server.DebugListen}
+	for key, addr := range listeners {
+		if addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %q: %v", key, addr, err))
+		} else if addr == cfg.Listen {
+			problems = append(problems, fmt.Sprintf("%s %q: must differ from listen", key, addr))
+		}
+	}
+	if cfg.Server.AdminListen != "" && cfg.Server.AdminListen == cfg.Server.DebugListen {
+		problems = append(problems, "server.adminListen and server.debugListen must differ")
+	}
+	if cfg.Server.UnixSocket != "" {
+		if _, err := cfg.Server.SocketMode(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if cfg.Exchange.Name != "hitbtc" {
+		problems = append(problems, fmt.Sprintf("exchange.name %q: only hitbtc is supported", cfg.Exchange.Name))
+	}
+	if cfg.Exchange.APIVersion != 2 && cfg.Exchange.APIVersion != 3 {
+		problems = append(problems, fmt.Sprintf("exchange.apiVersion %d: must be 2 or 3", cfg.Exchange.APIVersion))
+	}
+	if (cfg.Exchange.APIKey == "") != (cfg.Exchange.APISecret == "") {
+		problems = append(problems, "exchange.apiKey and exchange.apiSecret must be set together")
+	}
+	if len(cfg.Symbols) == 0 {
+		problems = append(problems, "symbols: at least one symbol is needed")
+	}
+	for _, symbol := range cfg.Symbols {
+		if !symbolPattern.MatchString(symbol) {
+			problems = append(problems, fmt.Sprintf("symbols: %q is not an upper case symbol", symbol))
+		}
+	}
+	if cfg.CacheTTL < 0 {
+		problems = append(problems, "cacheTTL can't be negative")
+	}
+	switch cfg.LogLevel {
+	case "debug", "info":
+	default:
+		problems = append(problems, fmt.Sprintf("logLevel %q: must be debug or info", cfg.LogLevel))
+	}
+	if cfg.RateLimits.MarketData <= 0 || cfg.RateLimits.Trading <= 0 || cfg.RateLimits.Other <= 0 {
+		problems = append(problems, "rateLimits must be positive")
+	}
+	if _, err := cfg.PaperBalances(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if cfg.Replay.Speed < 0 {
+		problems = append(problems, "replay.speed can't be negative")
+	}
+	if cfg.NATS.URL != "" && cfg.NATS.SubjectPrefix == "" {
+		problems = append(problems, "nats.subjectPrefix can't be empty")
+	}
+	problems = append(problems, cfg.validatePublishers()...)
+	problems = append(problems, cfg.validateUpstream()...)
+	if cfg.Feed.BufferSize < 0 {
+		problems = append(problems, "feed.bufferSize can't be negative")
+	}
+	if _, err := wsclient.ParseOverflowPolicy(cfg.Feed.OverflowPolicy); err != nil {
+		problems = append(problems, fmt.Sprintf("feed.overflowPolicy: %v, expected block, drop-oldest or drop-newest", err))
+	}
+	if cfg.Feed.Workers < 1 {
+		problems = append(problems, "feed.workers must be positive")
+	}
+	if cfg.Feed.SubscribeConcurrency < 1 {
+		problems = append(problems, "feed.subscribeConcurrency must be positive")
+	}
+	if cfg.Feed.IdleTimeout < 0 {
+		problems = append(problems, "feed.idleTimeout can't be negative")
+	}
+	for _, quote := range cfg.Feed.AutoQuotes {
+		if !symbolPattern.MatchString(quote) {
+			problems = append(problems, fmt.Sprintf("feed.autoQuotes: %q is not an upper case currency", quote))
+		}
+	}
+	if len(cfg.Feed.AutoQuotes) > 0 && cfg.Jobs.MetadataRefresh == "" {
+		problems = append(problems, "feed.autoQuotes requires jobs.metadataRefresh")
+	}
+	problems = append(problems, cfg.validateHistory()...)
+	problems = append(problems, cfg.validateArchive()...)
+	for _, rule := range cfg.AlertRules {
+		if _, err := alerts.ParseRule(rule); err != nil {
+			problems = append(problems, fmt.Sprintf("alertRules %q: %v", rule, err))
+		}
+	}
+	schedules := map[string]string{
+		"jobs.snapshot":        cfg.Jobs.Snapshot,
+		"jobs.metadataRefresh": cfg.Jobs.MetadataRefresh,
+		"jobs.archive":         cfg.Jobs.Archive,
+		"jobs.report":          cfg.Jobs.Report,
+	}
+	for key, spec := range schedules {
+		if spec == "" {
+			continue
+		}
+		if _, err := scheduler.ParseSchedule(spec); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+	if cfg.Jobs.Snapshot != "" && cfg.Jobs.SnapshotDir == "" {
+		problems = append(problems, "jobs.snapshot requires jobs.snapshotDir")
+	}
+	if cfg.Telegram.BotToken != "" && cfg.Telegram.ChatID == 0 {
+		problems = append(problems, "telegram.botToken requires telegram.chatID")
+	}
+	if cfg.Tracing.Endpoint != "" {
+		if u, err := url.Parse(cfg.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("tracing.endpoint %q: must be an http(s) URL", cfg.Tracing.Endpoint))
+		}
+	}
+	problems = append(problems, cfg.validateWebhooks()...)
+	problems = append(problems, cfg.validateTenants()...)
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+func (cfg *Config) validatePublishers() []string {
+	var problems []string
+	if len(cfg.Kafka.Brokers) > 0 && cfg.Kafka.Topic == "" {
+		problems = append(problems, "kafka.topic can't be empty")
+	}
+	if cfg.MQTT.Broker != "" && cfg.MQTT.TopicPrefix == "" {
+		problems = append(problems, "mqtt.topicPrefix can't be empty")
+	}
+	if cfg.MQTT.QoS < 0 || cfg.MQTT.QoS > 2 {
+		problems = append(problems, fmt.Sprintf("mqtt.qos %d: must be 0, 1 or 2", cfg.MQTT.QoS))
+	}
+	return problems
+}
+
+func (cfg *Config) validateUpstream() []string {
+	var problems []string
+	if cfg.Upstream.MaxRetries < 0 {
+		problems = append(problems, "upstream.maxRetries can't be negative")
+	}
+	if cfg.Upstream.BreakerThreshold < 0 {
+		problems = append(problems, "upstream.breakerThreshold can't be negative")
+	}
+	if cfg.Upstream.BreakerCooldown <= 0 {
+		problems = append(problems, "upstream.breakerCooldown must be positive")
+	}
+	if cfg.Upstream.Timeout <= 0 {
+		problems = append(problems, "upstream.timeout must be positive")
+	}
+	if cfg.Upstream.ProxyURL != "" {
+		if u, err := url.Parse(cfg.Upstream.ProxyURL); err != nil || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("upstream.proxyURL %q: must be an absolute URL", cfg.Upstream.ProxyURL))
+		}
+	}
+	return problems
+}
+
+func (cfg *Config) validateHistory() []string {
+	var problems []string
+	switch cfg.History.Backend {
+	case "":
+	case "sqlite":
+		if cfg.History.SQLitePath == "" {
+			problems = append(problems, "history.backend sqlite requires history.sqlitePath")
+		}
+	case "postgres":
+		if cfg.History.PostgresDSN == "" {
+			problems = append(problems, "history.backend postgres requires history.postgresDSN")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("history.backend %q: must be sqlite or postgres", cfg.History.Backend))
+	}
+	if cfg.History.SampleInterval < 0 {
+		problems = append(problems, "history.sampleInterval can't be negative")
+	}
+	if cfg.History.RecentSize < 1 {
+		problems = append(problems, "history.recentSize must be positive")
+	}
+	if cfg.History.Retention != "" {
+		if _, err := storage.ParseRetentionPolicy(cfg.History.Retention); err != nil {
+			problems = append(problems, fmt.Sprintf("history.retention: %v", err))
+		}
+	}
+	if cfg.History.RetentionInterval < time.Minute {
+		problems = append(problems, "history.retentionInterval must be at least 1m")
+	}
+	if cfg.History.ExportInterval < time.Minute {
+		problems = append(problems, "history.exportInterval must be at least 1m")
+	}
+	return problems
+}
+
+func (cfg *Config) validateArchive() []string {
+	var problems []string
+	if cfg.Archive.Bucket == "" {
+		if cfg.Jobs.Archive != "" {
+			problems = append(problems, "jobs.archive requires archive.bucket")
+		}
+		return problems
+	}
+	if cfg.Archive.Backend != "s3" && cfg.Archive.Backend != "gcs" {
+		problems = append(problems, fmt.Sprintf("archive.backend %q: must be s3 or gcs", cfg.Archive.Backend))
+	}
+	if cfg.Archive.AccessKeyID == "" || cfg.Archive.SecretAccessKey == "" {
+		problems = append(problems, "archive.bucket requires archive.accessKeyId and archive.secretAccessKey")
+	}
+	if cfg.Jobs.Archive == "" {
+		problems = append(problems, "archive.bucket requires jobs.archive")
+	}
+	if cfg.History.Backend == "" && cfg.History.SQLitePath == "" {
+		problems = append(problems, "archive.bucket requires history.backend")
+	}
+	return problems
+}
+
+func (cfg *Config) validateWebhooks() []string {
+	var problems []string
+	for i, hook := range cfg.Webhooks {
+		if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("webhooks[%d] %q: must be an absolute http(s) URL", i, hook.URL))
+		}
+		for _, symbol := range hook.Symbols {
+			if !symbolPattern.MatchString(symbol) {
+				problems = append(problems, fmt.Sprintf("webhooks[%d]: %q is not an upper case symbol", i, symbol))
+			}
+		}
+		if hook.Above != nil && hook.Below != nil && *hook.Below > *hook.Above {
+			problems = append(problems, fmt.Sprintf("webhooks[%d]: below can't be greater than above", i))
+		}
+	}
+	return problems
+}
+
+func (cfg *Config) validateTenants() []string {
+	var problems []string
+	names := make(map[string]bool, len(cfg.Tenants))
+	keys := make(map[string]string)
+	for i, tenant := range cfg.Tenants {
+		if tenant.Name == "" {
+			problems = append(problems, fmt.Sprintf("tenants[%d]: name is required", i))
+		} else if names[tenant.Name] {
+			problems = append(problems, fmt.Sprintf("tenants %q: duplicate name", tenant.Name))
+		}
+		names[tenant.Name] = true
+		if len(tenant.APIKeys) == 0 {
+			problems = append(problems, fmt.Sprintf("tenants %q: at least one API key is needed", tenant.Name))
+		}
+		for _, key := range tenant.APIKeys {
+			switch owner, ok := keys[key]; {
+			case key == "":
+				problems = append(problems, fmt.Sprintf("tenants %q: API keys can't be empty", tenant.Name))
+			case ok:
+				problems = append(problems, fmt.Sprintf("tenants %q: API key already used by %q", tenant.Name, owner))
+			case key == cfg.AdminToken:
+				problems = append(problems, fmt.Sprintf("tenants %q: API key is the admin token", tenant.Name))
+			}
+			keys[key] = tenant.Name
+		}
+		for _, symbol := range tenant.Symbols {
+			if !symbolPattern.MatchString(symbol) {
+				problems = append(problems, fmt.Sprintf("tenants %q: %q is not an upper case symbol", tenant.Name, symbol))
+			}
+		}
+		if tenant.RateLimit < 0 || tenant.Burst < 0 {
+			problems = append(problems, fmt.Sprintf("tenants %q: rateLimit and burst can't be negative", tenant.Name))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setEnv sets the environment variables for the test, an empty value
+// unsets the variable.
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for name, value := range env {
+		t.Setenv(name, value)
+		if value == "" {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+// writeConfig writes a config file holding yaml.
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	setEnv(t, map[string]string{"CONFIG_FILE": "", "LISTEN_ADDR": "", "SYMBOLS": "", "CACHE_TTL": "", "LOG_LEVEL": ""})
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != ":8080" || !reflect.DeepEqual(cfg.Symbols, []string{"BTCUSD", "ETHBTC"}) || cfg.Path != "" {
+		t.Errorf("listen %q, symbols %v, path %q, want the defaults", cfg.Listen, cfg.Symbols, cfg.Path)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("the defaults are invalid: %v", err)
+	}
+}
+
+// TestLoadPrecedence checks that the flags override the environment, which
+// overrides the config file, which overrides the defaults.
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+listen: ":1111"
+symbols: [AAAUSD]
+cacheTTL: 10s
+logLevel: debug
+paperTrading:
+  balances:
+    BTC: "1"
+feed:
+  workers: 8
+`)
+	setEnv(t, map[string]string{
+		"CONFIG_FILE":  path,
+		"LISTEN_ADDR":  ":2222",
+		"SYMBOLS":      "BBBUSD, CCCUSD",
+		"FEED_WORKERS": "",
+		"CACHE_TTL":    "",
+		"LOG_LEVEL":    "",
+		"REPLAY_SPEED": "2",
+	})
+	cfg, err := Load([]string{"-listen", ":3333", "-replay-speed", "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"listen from the flag", cfg.Listen, ":3333"},
+		{"replay speed from the flag set to zero", cfg.Replay.Speed, 0.0},
+		{"symbols from the environment", cfg.Symbols, []string{"BBBUSD", "CCCUSD"}},
+		{"cache TTL from the file", cfg.CacheTTL, 10 * time.Second},
+		{"log level from the file", cfg.LogLevel, "debug"},
+		{"feed workers from the file", cfg.Feed.Workers, 8},
+		{"feed buffer by default", cfg.Feed.BufferSize, 1024},
+		{"paper balances replaced by the file", cfg.PaperTrading.Balances, map[string]string{"BTC": "1"}},
+		{"path", cfg.Path, path},
+	}
+	for _, test := range tests {
+		if !reflect.DeepEqual(test.got, test.want) {
+			t.Errorf("%s: %v, want %v", test.name, test.got, test.want)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	// the -config flag overrides CONFIG_FILE
+	other := writeConfig(t, `listen: ":4444"`)
+	setEnv(t, map[string]string{"LISTEN_ADDR": ""})
+	if cfg, err = Load([]string{"-config", other}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != ":4444" || cfg.Path != other {
+		t.Errorf("listen %q from %q, want :4444 from the -config file", cfg.Listen, cfg.Path)
+	}
+}
+
+func TestLoadWebhooks(t *testing.T) {
+	path := writeConfig(t, `
+webhooks:
+  - https://example.com/all
+  - url: https://example.com/hook
+    symbols: [BTCUSD]
+    above: 70000
+    below: 60000.5
+    secret: s3cret
+`)
+	setEnv(t, map[string]string{"CONFIG_FILE": path, "WEBHOOK_URLS": ""})
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	above, below := 70000.0, 60000.5
+	want := []Webhook{
+		{URL: "https://example.com/all"},
+		{URL: "https://example.com/hook", Symbols: []string{"BTCUSD"}, Above: &above, Below: &below, Secret: "s3cret"},
+	}
+	if !reflect.DeepEqual(cfg.Webhooks, want) {
+		t.Errorf("webhooks %+v, want %+v", cfg.Webhooks, want)
+	}
+
+	// the environment replaces them with plain URLs
+	setEnv(t, map[string]string{"WEBHOOK_URLS": "https://example.com/a, https://example.com/b"})
+	if cfg, err = Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []Webhook{{URL: "https://example.com/a"}, {URL: "https://example.com/b"}}; !reflect.DeepEqual(cfg.Webhooks, want) {
+		t.Errorf("webhooks %+v, want %+v", cfg.Webhooks, want)
+	}
+
+	setEnv(t, map[string]string{"CONFIG_FILE": writeConfig(t, "webhooks:\n  - url: https://example.com\n    sercet: s3cret\n"), "WEBHOOK_URLS": ""})
+	if _, err = Load(nil); err == nil || !strings.Contains(err.Error(), "field sercet not found") {
+		t.Errorf("got %v, want an error for the unknown webhook key", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{name: "unknown key", yaml: "listne: \":1111\"\n", want: "field listne not found"},
+		{name: "invalid YAML", yaml: "symbols: [BTCUSD\n", want: "config.yaml"},
+		{name: "invalid duration", yaml: "cacheTTL: soon\n", want: "config.yaml"},
+		{name: "invalid CACHE_TTL", env: map[string]string{"CACHE_TTL": "soon"}, want: `invalid CACHE_TTL "soon"`},
+		{name: "invalid HITBTC_API_VERSION", env: map[string]string{"HITBTC_API_VERSION": "v3"}, want: `invalid HITBTC_API_VERSION "v3"`},
+		{name: "invalid PAPER_BALANCES", env: map[string]string{"PAPER_BALANCES": "USD=100"}, want: "expected CURRENCY:AMOUNT pairs"},
+		{name: "invalid FEED_WORKERS", env: map[string]string{"FEED_WORKERS": "many"}, want: "FEED_WORKERS"},
+		{name: "unknown flag", args: []string{"-lisen", ":1111"}, want: "flag provided but not defined"},
+		{name: "invalid flag", args: []string{"-cache-ttl", "soon"}, want: "invalid value"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := map[string]string{"CONFIG_FILE": ""}
+			if test.yaml != "" {
+				env["CONFIG_FILE"] = writeConfig(t, test.yaml)
+			}
+			for name, value := range test.env {
+				env[name] = value
+			}
+			setEnv(t, env)
+			_, err := Load(test.args)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got %v, want an error containing %q", err, test.want)
+			}
+		})
+	}
+
+	setEnv(t, map[string]string{"CONFIG_FILE": filepath.Join(t.TempDir(), "missing.yaml")})
+	if _, err := Load(nil); err == nil {
+		t.Error("no error for a missing config file")
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := writeConfig(t, `listen: ":1111"`)
+	setEnv(t, map[string]string{"CONFIG_FILE": "", "LISTEN_ADDR": ""})
+	cfg, err := Load([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`listen: ":2222"`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := cfg.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Listen != ":2222" {
+		t.Errorf("reloaded listen %q, want :2222", reloaded.Listen)
+	}
+	if _, err := Default().Reload(); err == nil {
+		t.Error("no error reloading a configuration built in code")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(cfg *Config)
+		want   string
+	}{
+		{"no listener", func(cfg *Config) { cfg.Listen = "" }, "an address is needed"},
+		{"invalid listen", func(cfg *Config) { cfg.Listen = "8080" }, `listen "8080"`},
+		{"negative timeout", func(cfg *Config) { cfg.Server.ReadTimeout = -time.Second }, "server timeouts can't be negative"},
+		{"TLS key without certificate", func(cfg *Config) { cfg.Server.TLSKeyFile = "key.pem" }, "must be set together"},
+		{"admin on the main listener", func(cfg *Config) { cfg.Server.AdminListen = cfg.Listen }, "must differ from listen"},
+		{"API version", func(cfg *Config) { cfg.Exchange.APIVersion = 1 }, "must be 2 or 3"},
+		{"API key without secret", func(cfg *Config) { cfg.Exchange.APIKey = "key" }, "exchange.apiKey and exchange.apiSecret"},
+		{"no symbols", func(cfg *Config) { cfg.Symbols = nil }, "at least one symbol"},
+		{"lower case symbol", func(cfg *Config) { cfg.Symbols = []string{"btcusd"} }, `"btcusd" is not an upper case symbol`},
+		{"negative cache TTL", func(cfg *Config) { cfg.CacheTTL = -time.Second }, "cacheTTL can't be negative"},
+		{"log level", func(cfg *Config) { cfg.LogLevel = "warn" }, `logLevel "warn"`},
+		{"rate limit", func(cfg *Config) { cfg.RateLimits.Trading = 0 }, "rateLimits must be positive"},
+		{"paper balance", func(cfg *Config) { cfg.PaperTrading.Balances = map[string]string{"USD": "-1"} }, "is not a valid amount"},
+		{"overflow policy", func(cfg *Config) { cfg.Feed.OverflowPolicy = "drop-all" }, "feed.overflowPolicy"},
+		{"feed workers", func(cfg *Config) { cfg.Feed.Workers = 0 }, "feed.workers must be positive"},
+		{"breaker cooldown", func(cfg *Config) { cfg.Upstream.BreakerCooldown = 0 }, "upstream.breakerCooldown must be positive"},
+		{"proxy URL", func(cfg *Config) { cfg.Upstream.ProxyURL = "proxy:3128" }, "must be an absolute URL"},
+		{"history backend", func(cfg *Config) { cfg.History.Backend = "mysql" }, `history.backend "mysql"`},
+		{"sqlite without path", func(cfg *Config) { cfg.History.Backend = "sqlite" }, "requires history.sqlitePath"},
+		{"alert rule", func(cfg *Config) { cfg.AlertRules = []string{"BTCUSD ~ 1"} }, "alertRules"},
+		{"job schedule", func(cfg *Config) { cfg.Jobs.Report = "sometimes" }, "jobs.report"},
+		{"snapshot without directory", func(cfg *Config) { cfg.Jobs.Snapshot = "@hourly" }, "requires jobs.snapshotDir"},
+		{"tracing endpoint", func(cfg *Config) { cfg.Tracing.Endpoint = "collector:4318" }, "must be an http(s) URL"},
+		{"webhook URL", func(cfg *Config) { cfg.Webhooks = []Webhook{{URL: "example.com/hook"}} }, `webhooks[0] "example.com/hook": must be an absolute http(s) URL`},
+		{"webhook symbol", func(cfg *Config) { cfg.Webhooks = []Webhook{{URL: "https://example.com", Symbols: []string{"btcusd"}}} }, `webhooks[0]: "btcusd" is not an upper case symbol`},
+		{"webhook thresholds", func(cfg *Config) {
+			above, below := 1.0, 2.0
+			cfg.Webhooks = []Webhook{{URL: "https://example.com", Above: &above, Below: &below}}
+		}, "webhooks[0]: below can't be greater than above"},
+		{"tenant without key", func(cfg *Config) { cfg.Tenants = []Tenant{{Name: "acme"}} }, "at least one API key"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := Default()
+			test.change(cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got %v, want an error containing %q", err, test.want)
+			}
+		})
+	}
+
+	// every problem is listed
+	cfg := Default()
+	cfg.Symbols = nil
+	cfg.LogLevel = "warn"
+	problems, ok := cfg.Validate().(ValidationError)
+	if !ok || len(problems) != 2 {
+		t.Errorf("problems %v, want the two of them", problems)
+	}
+}
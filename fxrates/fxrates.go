@@ -0,0 +1,131 @@
+// Package fxrates keeps fiat reference exchange rates, as published daily by
+// the European Central Bank.
+package fxrates
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ECBDailyURL is the ECB feed of the latest euro foreign exchange reference rates.
+const ECBDailyURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+type ecbEnvelope struct {
+	Cube struct {
+		Cube struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string  `xml:"currency,attr"`
+				Rate     float64 `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+// Rates holds the units of each currency per euro.
+type Rates struct {
+	mutex   *sync.RWMutex
+	url     string
+	client  *http.Client
+	perEUR  map[string]float64
+	updated time.Time
+}
+
+// NewRates creates the rates loaded from the ECB daily feed at url.
+func NewRates(url string) *Rates {
+	if url == "" {
+		url = ECBDailyURL
+	}
+	return &Rates{
+		mutex:  &sync.RWMutex{},
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		perEUR: map[string]float64{"EUR": 1},
+	}
+}
+
+// Refresh downloads the latest reference rates.
+func (r *Rates) Refresh() error {
+	resp, err := r.client.Get(r.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fxrates: %s returned %s", r.url, resp.Status)
+	}
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return err
+	}
+	if len(envelope.Cube.Cube.Rates) == 0 {
+		return fmt.Errorf("fxrates: no rates in %s", r.url)
+	}
+	perEUR := map[string]float64{"EUR": 1}
+	for _, rate := range envelope.Cube.Cube.Rates {
+		if rate.Rate > 0 {
+			perEUR[rate.Currency] = rate.Rate
+		}
+	}
+	updated, err := time.Parse("2006-01-02", envelope.Cube.Cube.Time)
+	if err != nil {
+		updated = time.Now()
+	}
+	r.mutex.Lock()
+	r.perEUR = perEUR
+	r.updated = updated
+	r.mutex.Unlock()
+	return nil
+}
+
+// Run refreshes the rates every interval, logging failures, until ctx is
+// done.
+func (r *Rates) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Refresh(); err != nil {
+			log.Printf("fxrates: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Supports reports whether currency has a reference rate.
+func (r *Rates) Supports(currency string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	_, ok := r.perEUR[currency]
+	return ok
+}
+
+// Rate returns the units of to per unit of from.
+func (r *Rates) Rate(from string, to string) (float64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	fromRate, ok := r.perEUR[from]
+	if !ok {
+		return 0, fmt.Errorf("no reference rate for %s", from)
+	}
+	toRate, ok := r.perEUR[to]
+	if !ok {
+		return 0, fmt.Errorf("no reference rate for %s", to)
+	}
+	return toRate / fromRate, nil
+}
+
+// Updated returns the date of the reference rates, zero before the first refresh.
+func (r *Rates) Updated() time.Time {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.updated
+}
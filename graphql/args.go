@@ -0,0 +1,47 @@
+package graphql
+
+import "fmt"
+
+// StringArg returns the string argument name, or "" when it is absent.
+func StringArg(args map[string]interface{}, name string) (string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	return "", fmt.Errorf("argument %q must be a String", name)
+}
+
+// StringListArg returns the [String] argument name. A single string is
+// accepted as a list of one, as allowed by GraphQL input coercion.
+func StringListArg(args map[string]interface{}, name string) ([]string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("argument %q must be a [String]", name)
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("argument %q must be a [String]", name)
+}
+
+// FloatArg returns the numeric argument name and whether it was given.
+func FloatArg(args map[string]interface{}, name string) (float64, bool, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return 0, false, nil
+	case float64:
+		return v, true, nil
+	}
+	return 0, false, fmt.Errorf("argument %q must be a number", name)
+}
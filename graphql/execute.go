@@ -0,0 +1,172 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Object is a resolved GraphQL object. Field values are scalars, Objects,
+// slices of Objects or Resolvers evaluated with the field arguments.
+type Object map[string]interface{}
+
+// Resolver computes a field value from its arguments.
+type Resolver func(args map[string]interface{}) (interface{}, error)
+
+// Schema holds the resolvers of the root query type.
+type Schema struct {
+	Query Object
+}
+
+// Request is the standard GraphQL request body.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is the standard GraphQL response body.
+type Response struct {
+	Data   interface{} `json:"data"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// Error describes a query that could not be parsed or executed.
+type Error struct {
+	Message string `json:"message"`
+}
+
+// Execute parses and runs the query against the schema. Execution stops at
+// the first error, which is reported in Response.Errors.
+func (s *Schema) Execute(req Request) *Response {
+	fields, err := Parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: "Syntax error: " + err.Error()}}}
+	}
+	data, err := complete(s.Query, &Field{Name: "query", Selections: fields}, req.Variables)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	return &Response{Data: data}
+}
+
+func complete(value interface{}, field *Field, variables map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case Object:
+		if len(field.Selections) == 0 {
+			return nil, fmt.Errorf("field %q must have a selection of subfields", field.Name)
+		}
+		result := &orderedObject{values: make(map[string]interface{})}
+		for _, sel := range field.Selections {
+			fieldValue, ok := v[sel.Name]
+			if !ok {
+				return nil, fmt.Errorf("cannot query field %q on %q", sel.Name, field.Name)
+			}
+			if resolve, ok := fieldValue.(Resolver); ok {
+				args, err := resolveVariables(sel.Arguments, variables)
+				if err != nil {
+					return nil, err
+				}
+				// a field without arguments has a nil map
+				resolvedArgs, _ := args.(map[string]interface{})
+				if fieldValue, err = resolve(resolvedArgs); err != nil {
+					return nil, fmt.Errorf("%s: %v", sel.ResponseKey(), err)
+				}
+			}
+			completed, err := complete(fieldValue, sel, variables)
+			if err != nil {
+				return nil, err
+			}
+			result.set(sel.ResponseKey(), completed)
+		}
+		return result, nil
+	case []Object:
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			completed, err := complete(item, field, variables)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, completed)
+		}
+		return list, nil
+	}
+	if len(field.Selections) > 0 {
+		return nil, fmt.Errorf("field %q is a scalar and must not have a selection", field.Name)
+	}
+	return value, nil
+}
+
+// resolveVariables replaces variable references in argument values.
+func resolveVariables(value interface{}, variables map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case variable:
+		val, ok := variables[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined", string(v))
+		}
+		return val, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := resolveVariables(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = resolved
+		}
+		return list, nil
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved, err := resolveVariables(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = resolved
+		}
+		return obj, nil
+	}
+	return value, nil
+}
+
+// orderedObject keeps the result fields in query order, as required by the spec.
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *orderedObject) set(key string, value interface{}) {
+	if _, exists := o.values[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
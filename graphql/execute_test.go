@@ -0,0 +1,171 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testSchema resolves tickers of two symbols, each with a market object.
+func testSchema() *Schema {
+	tickers := map[string]Object{
+		"BTCUSD": {"symbol": "BTCUSD", "last": "65000", "market": Object{"base": "BTC", "quote": "USD"}},
+		"ETHBTC": {"symbol": "ETHBTC", "last": "0.05", "market": Object{"base": "ETH", "quote": "BTC"}},
+	}
+	return &Schema{Query: Object{
+		"ticker": Resolver(func(args map[string]interface{}) (interface{}, error) {
+			symbol, err := StringArg(args, "symbol")
+			if err != nil {
+				return nil, err
+			}
+			if symbol == "" {
+				return nil, errors.New("missing symbol")
+			}
+			ticker, ok := tickers[symbol]
+			if !ok {
+				return nil, nil
+			}
+			return ticker, nil
+		}),
+		"tickers": Resolver(func(args map[string]interface{}) (interface{}, error) {
+			symbols, err := StringListArg(args, "symbols")
+			if err != nil {
+				return nil, err
+			}
+			if symbols == nil {
+				symbols = []string{"BTCUSD", "ETHBTC"}
+			}
+			list := make([]Object, 0, len(symbols))
+			for _, symbol := range symbols {
+				if ticker, ok := tickers[symbol]; ok {
+					list = append(list, ticker)
+				}
+			}
+			return list, nil
+		}),
+		"version": "1",
+	}}
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      string
+	}{
+		{
+			name:  "field selection",
+			query: `{ ticker(symbol: "BTCUSD") { last } }`,
+			want:  `{"ticker":{"last":"65000"}}`,
+		},
+		{
+			name:  "aliases in query order",
+			query: `{ eth: ticker(symbol: "ETHBTC") { price: last symbol } btc: ticker(symbol: "BTCUSD") { symbol } }`,
+			want:  `{"eth":{"price":"0.05","symbol":"ETHBTC"},"btc":{"symbol":"BTCUSD"}}`,
+		},
+		{
+			name:  "nested selection",
+			query: `{ ticker(symbol: "ETHBTC") { market { quote base } } }`,
+			want:  `{"ticker":{"market":{"quote":"BTC","base":"ETH"}}}`,
+		},
+		{
+			name:  "list",
+			query: `{ tickers { symbol } }`,
+			want:  `{"tickers":[{"symbol":"BTCUSD"},{"symbol":"ETHBTC"}]}`,
+		},
+		{
+			name:      "variables",
+			query:     `query Q($symbol: String!, $symbols: [String]) { ticker(symbol: $symbol) { last } tickers(symbols: $symbols) { symbol } }`,
+			variables: map[string]interface{}{"symbol": "ETHBTC", "symbols": []interface{}{"BTCUSD"}},
+			want:      `{"ticker":{"last":"0.05"},"tickers":[{"symbol":"BTCUSD"}]}`,
+		},
+		{
+			name:      "variable in a list",
+			query:     `query Q($s: String) { tickers(symbols: [$s]) { symbol } }`,
+			variables: map[string]interface{}{"s": "ETHBTC"},
+			want:      `{"tickers":[{"symbol":"ETHBTC"}]}`,
+		},
+		{
+			name:  "single string as a list",
+			query: `{ tickers(symbols: "ETHBTC") { symbol } }`,
+			want:  `{"tickers":[{"symbol":"ETHBTC"}]}`,
+		},
+		{
+			name:  "null object",
+			query: `{ ticker(symbol: "XYZUSD") { last } }`,
+			want:  `{"ticker":null}`,
+		},
+		{
+			name:  "scalar",
+			query: `{ version }`,
+			want:  `{"version":"1"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := testSchema().Execute(Request{Query: test.query, Variables: test.variables})
+			if len(response.Errors) > 0 {
+				t.Fatalf("errors %v", response.Errors)
+			}
+			data, err := json.Marshal(response.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != test.want {
+				t.Errorf("data %s, want %s", data, test.want)
+			}
+		})
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      string
+	}{
+		{"syntax", `{ ticker(symbol: "BTCUSD") { last }`, nil, "Syntax error: "},
+		{"unknown field", `{ ticker(symbol: "BTCUSD") { price } }`, nil, `cannot query field "price" on "ticker"`},
+		{"unknown root field", `{ orders { id } }`, nil, `cannot query field "orders" on "query"`},
+		{"object without selection", `{ ticker(symbol: "BTCUSD") }`, nil, `field "ticker" must have a selection of subfields`},
+		{"scalar with selection", `{ ticker(symbol: "BTCUSD") { last { value } } }`, nil, `field "last" is a scalar and must not have a selection`},
+		{"undefined variable", `query Q($symbol: String) { ticker(symbol: $symbol) { last } }`, nil, "variable $symbol is not defined"},
+		{"argument type", `{ ticker(symbol: 1) { last } }`, nil, `ticker: argument "symbol" must be a String`},
+		{"list argument type", `{ tickers(symbols: [1]) { symbol } }`, nil, `tickers: argument "symbols" must be a [String]`},
+		{"variable type", `query Q($s: String) { ticker(symbol: $s) { last } }`, map[string]interface{}{"s": true}, `argument "symbol" must be a String`},
+		{"resolver error with alias", `{ btc: ticker { last } }`, nil, "btc: missing symbol"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := testSchema().Execute(Request{Query: test.query, Variables: test.variables})
+			if response.Data != nil {
+				t.Errorf("data %v, want none", response.Data)
+			}
+			if len(response.Errors) != 1 || !strings.Contains(response.Errors[0].Message, test.want) {
+				t.Errorf("errors %v, want one containing %q", response.Errors, test.want)
+			}
+		})
+	}
+}
+
+func TestFloatArg(t *testing.T) {
+	for _, test := range []struct {
+		args  map[string]interface{}
+		value float64
+		given bool
+		err   bool
+	}{
+		{map[string]interface{}{"n": 1.5}, 1.5, true, false},
+		{map[string]interface{}{}, 0, false, false},
+		{nil, 0, false, false},
+		{map[string]interface{}{"n": "1.5"}, 0, false, true},
+	} {
+		value, given, err := FloatArg(test.args, "n")
+		if value != test.value || given != test.given || (err != nil) != test.err {
+			t.Errorf("FloatArg(%v) = %v, %v, %v", test.args, value, given, err)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+// Package graphql implements the subset of GraphQL needed to query the
+// server's data: queries with field selection, aliases, arguments and
+// variables. Mutations, fragments and introspection are not supported.
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Field is a single selection in a query, e.g. `btc: ticker(symbol: "BTCUSD") { last }`.
+type Field struct {
+	Alias      string
+	Name       string
+	Arguments  map[string]interface{}
+	Selections []*Field
+}
+
+// ResponseKey returns the key used for the field in the result.
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// variable is a reference to an operation variable, resolved at execution time.
+type variable string
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.pos++
+			continue
+		}
+		if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+			continue
+		}
+		break
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF}, nil
+	}
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+		l.pos++
+		return token{tokenPunct, string(c)}, nil
+	case c == '.':
+		if strings.HasPrefix(l.src[l.pos:], "...") {
+			l.pos += 3
+			return token{tokenPunct, "..."}, nil
+		}
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{tokenName, l.src[start:l.pos]}, nil
+	case c == '-' || isDigit(c):
+		kind := tokenInt
+		l.pos++
+		for l.pos < len(l.src) {
+			d := l.src[l.pos]
+			if d == '.' || d == 'e' || d == 'E' || ((d == '+' || d == '-') && kind == tokenFloat) {
+				kind = tokenFloat
+			} else if !isDigit(d) {
+				break
+			}
+			l.pos++
+		}
+		return token{kind, l.src[start:l.pos]}, nil
+	case c == '"':
+		return l.string()
+	}
+	return token{}, fmt.Errorf("unexpected character %q at position %d", c, start)
+}
+
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	l.pos++
+	var sb strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch c {
+		case '"':
+			l.pos++
+			return token{tokenString, sb.String()}, nil
+		case '\\':
+			end := l.pos + 2
+			if l.pos+1 < len(l.src) && l.src[l.pos+1] == 'u' {
+				end = l.pos + 6
+			}
+			if end > len(l.src) {
+				return token{}, fmt.Errorf("unterminated string at position %d", start)
+			}
+			unquoted, err := strconv.Unquote(`"` + l.src[l.pos:end] + `"`)
+			if err != nil {
+				return token{}, fmt.Errorf("invalid escape sequence at position %d", l.pos)
+			}
+			sb.WriteString(unquoted)
+			l.pos = end
+		case '\n':
+			return token{}, fmt.Errorf("unterminated string at position %d", start)
+		default:
+			sb.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, fmt.Errorf("unterminated string at position %d", start)
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+// Parse parses a query document and returns its top level selections.
+func Parse(query string) ([]*Field, error) {
+	p := &parser{lex: &lexer{src: query}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokenName {
+		switch p.tok.value {
+		case "query":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if p.tok.kind == tokenName {
+				if err := p.advance(); err != nil {
+					return nil, err
+				}
+			}
+			if p.is("(") {
+				if err := p.skipVariableDefinitions(); err != nil {
+					return nil, err
+				}
+			}
+		case "mutation", "subscription":
+			return nil, fmt.Errorf("%s operations are not supported", p.tok.value)
+		case "fragment":
+			return nil, fmt.Errorf("fragments are not supported")
+		}
+	}
+	fields, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q after operation, only a single operation is supported", p.tok.value)
+	}
+	return fields, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) is(punct string) bool {
+	return p.tok.kind == tokenPunct && p.tok.value == punct
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.is(punct) {
+		return p.unexpected(punct)
+	}
+	return p.advance()
+}
+
+func (p *parser) unexpected(want string) error {
+	if p.tok.kind == tokenEOF {
+		return fmt.Errorf("expected %q, found end of query", want)
+	}
+	return fmt.Errorf("expected %q, found %q", want, p.tok.value)
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.unexpected("name")
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+// skipVariableDefinitions consumes `($a: String, $b: [Int!] = 1)`. Variable
+// types are not checked, values are taken as given in the request.
+func (p *parser) skipVariableDefinitions() error {
+	depth := 0
+	for {
+		switch {
+		case p.tok.kind == tokenEOF:
+			return p.unexpected(")")
+		case p.is("("):
+			depth++
+		case p.is(")"):
+			depth--
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func (p *parser) selectionSet() ([]*Field, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var fields []*Field
+	for !p.is("}") {
+		if p.is("...") {
+			return nil, fmt.Errorf("fragments are not supported")
+		}
+		field, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, p.advance()
+}
+
+func (p *parser) field() (*Field, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	field := &Field{Name: name}
+	if p.is(":") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		field.Alias = name
+		if field.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.is("(") {
+		if field.Arguments, err = p.arguments(); err != nil {
+			return nil, err
+		}
+	}
+	if p.is("@") {
+		return nil, fmt.Errorf("directives are not supported")
+	}
+	if p.is("{") {
+		if field.Selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return field, nil
+}
+
+func (p *parser) arguments() (map[string]interface{}, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	args := make(map[string]interface{})
+	for !p.is(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.value(); err != nil {
+			return nil, err
+		}
+	}
+	return args, p.advance()
+}
+
+func (p *parser) value() (interface{}, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenPunct:
+		switch tok.value {
+		case "$":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			return variable(name), err
+		case "[":
+			return p.list()
+		case "{":
+			return p.object()
+		}
+	case tokenInt:
+		v, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int %q", tok.value)
+		}
+		return float64(v), p.advance()
+	case tokenFloat:
+		v, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q", tok.value)
+		}
+		return v, p.advance()
+	case tokenString:
+		return tok.value, p.advance()
+	case tokenName:
+		var v interface{}
+		switch tok.value {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		default:
+			// enum values are passed to resolvers as plain strings
+			v = tok.value
+		}
+		return v, p.advance()
+	}
+	return nil, p.unexpected("value")
+}
+
+func (p *parser) list() (interface{}, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	list := make([]interface{}, 0)
+	for !p.is("]") {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, p.advance()
+}
+
+func (p *parser) object() (interface{}, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	obj := make(map[string]interface{})
+	for !p.is("}") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		if obj[name], err = p.value(); err != nil {
+			return nil, err
+		}
+	}
+	return obj, p.advance()
+}
@@ -0,0 +1,154 @@
+package graphql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []*Field
+	}{
+		{
+			name:  "shorthand",
+			query: `{ ticker(symbol: "BTCUSD") { last } }`,
+			want:  []*Field{{Name: "ticker", Arguments: map[string]interface{}{"symbol": "BTCUSD"}, Selections: []*Field{{Name: "last"}}}},
+		},
+		{
+			name:  "named query with variables",
+			query: `query Prices($symbol: String!, $ids: [String!] = ["ETH"]) { ticker(symbol: $symbol) { last } }`,
+			want:  []*Field{{Name: "ticker", Arguments: map[string]interface{}{"symbol": variable("symbol")}, Selections: []*Field{{Name: "last"}}}},
+		},
+		{
+			name:  "anonymous query",
+			query: `query { currencies { id } }`,
+			want:  []*Field{{Name: "currencies", Selections: []*Field{{Name: "id"}}}},
+		},
+		{
+			name:  "aliases",
+			query: `{ btc: ticker(symbol: "BTCUSD") { price: last } eth: ticker(symbol: "ETHBTC") { last } }`,
+			want: []*Field{
+				{Alias: "btc", Name: "ticker", Arguments: map[string]interface{}{"symbol": "BTCUSD"}, Selections: []*Field{{Alias: "price", Name: "last"}}},
+				{Alias: "eth", Name: "ticker", Arguments: map[string]interface{}{"symbol": "ETHBTC"}, Selections: []*Field{{Name: "last"}}},
+			},
+		},
+		{
+			name:  "nested selections",
+			query: "{\n  tickers {\n    market { base { id } quote { id } }\n  }\n}",
+			want: []*Field{{Name: "tickers", Selections: []*Field{{Name: "market", Selections: []*Field{
+				{Name: "base", Selections: []*Field{{Name: "id"}}},
+				{Name: "quote", Selections: []*Field{{Name: "id"}}},
+			}}}}},
+		},
+		{
+			name:  "argument values",
+			query: `{ f(i: 42, n: -1, x: 1.5e3, s: "a\"bé", t: true, u: false, z: null, e: ASC, l: [1, "a"], o: {k: $v}) }`,
+			want: []*Field{{Name: "f", Arguments: map[string]interface{}{
+				"i": float64(42),
+				"n": float64(-1),
+				"x": 1500.0,
+				"s": `a"bé`,
+				"t": true,
+				"u": false,
+				"z": nil,
+				"e": "ASC",
+				"l": []interface{}{float64(1), "a"},
+				"o": map[string]interface{}{"k": variable("v")},
+			}}},
+		},
+		{
+			name:  "comments and commas",
+			query: "# prices\n{ a, b # the bid\n }",
+			want:  []*Field{{Name: "a"}, {Name: "b"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields, err := Parse(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(fields, test.want) {
+				t.Errorf("Parse(%q) = %s, want %s", test.query, dump(fields), dump(test.want))
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"empty", ``, `expected "{", found end of query`},
+		{"unclosed selection", `{ ticker { last }`, `expected "name", found end of query`},
+		{"unclosed arguments", `{ ticker(symbol: "BTCUSD" { last } }`, `expected "name", found "{"`},
+		{"missing argument value", `{ ticker(symbol: ) { last } }`, `expected "value", found ")"`},
+		{"missing colon", `{ ticker(symbol "BTCUSD") }`, `expected ":", found "BTCUSD"`},
+		{"unterminated string", `{ ticker(symbol: "BTC) }`, "unterminated string"},
+		{"string over lines", "{ ticker(symbol: \"BTC\n\") }", "unterminated string"},
+		{"invalid escape", `{ ticker(symbol: "\q") }`, "invalid escape sequence"},
+		{"truncated escape", `{ ticker(symbol: "\u00`, "unterminated string"},
+		{"invalid number", `{ f(n: -) }`, `invalid int "-"`},
+		{"invalid float", `{ f(n: 1.2.3e) }`, `invalid float "1.2.3e"`},
+		{"unexpected character", `{ ticker; }`, "unexpected character ';'"},
+		{"lone dot", `{ .last }`, "unexpected character '.'"},
+		{"unclosed variables", `query Q($a: String { a }`, `expected ")", found end of query`},
+		{"two operations", `{ a } { b }`, "only a single operation is supported"},
+		{"mutation", `mutation { placeOrder }`, "mutation operations are not supported"},
+		{"subscription", `subscription { tickers }`, "subscription operations are not supported"},
+		{"fragment definition", `fragment F on Ticker { last }`, "fragments are not supported"},
+		{"fragment spread", `{ ticker(symbol: "BTCUSD") { ...F } }`, "fragments are not supported"},
+		{"inline fragment", `{ ticker(symbol: "BTCUSD") { ... on Ticker { last } } }`, "fragments are not supported"},
+		{"directive", `{ ticker(symbol: "BTCUSD") @skip(if: true) { last } }`, "directives are not supported"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.query)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Parse(%q): got %v, want an error containing %q", test.query, err, test.want)
+			}
+		})
+	}
+}
+
+// TestParseTruncated checks that each prefix of a query fails to parse
+// without panicking.
+func TestParseTruncated(t *testing.T) {
+	query := `query Q($s: String = "x") { btc: ticker(symbol: $s, l: [1, 2.5, {k: "é"}]) { last market { id } } }`
+	for i := 0; i < len(query); i++ {
+		if _, err := Parse(query[:i]); err == nil {
+			t.Errorf("Parse(%q): no error for a truncated query", query[:i])
+		}
+	}
+	if _, err := Parse(query); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func dump(fields []*Field) string {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, field := range fields {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		if field.Alias != "" {
+			sb.WriteString(field.Alias + ":")
+		}
+		sb.WriteString(field.Name)
+		if field.Arguments != nil {
+			fmt.Fprint(&sb, field.Arguments)
+		}
+		if field.Selections != nil {
+			sb.WriteString(dump(field.Selections))
+		}
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
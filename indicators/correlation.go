@@ -0,0 +1,63 @@
+package indicators
+
+import (
+	"math"
+	"time"
+)
+
+// Correlation is the Pearson correlation of the interval returns of two
+// symbols over the intervals where both have one.
+type Correlation struct {
+	Value   float64 `json:"value"`
+	Returns int     `json:"returns"`
+}
+
+// CorrelationMatrix correlates the returns of the interval closes of every
+// pair of series, oldest first. The correlation of a pair with fewer than
+// two common returns, or a constant price, is left out.
+func CorrelationMatrix(series map[string][]PricePoint, interval time.Duration) map[string]map[string]Correlation {
+	returns := make(map[string]map[time.Time]float64, len(series))
+	for symbol, points := range series {
+		returns[symbol] = logReturns(intervalCloses(points, interval), interval)
+	}
+	matrix := make(map[string]map[string]Correlation, len(series))
+	for a := range series {
+		matrix[a] = make(map[string]Correlation, len(series))
+	}
+	for a := range series {
+		for b := range series {
+			if a > b {
+				continue
+			}
+			if c, ok := correlate(returns[a], returns[b]); ok {
+				matrix[a][b] = c
+				matrix[b][a] = c
+			}
+		}
+	}
+	return matrix
+}
+
+func correlate(a, b map[time.Time]float64) (Correlation, bool) {
+	var xs, ys []float64
+	for at, x := range a {
+		if y, ok := b[at]; ok {
+			xs = append(xs, x)
+			ys = append(ys, y)
+		}
+	}
+	if len(xs) < 2 {
+		return Correlation{}, false
+	}
+	mx, my := mean(xs), mean(ys)
+	var cov, vx, vy float64
+	for i := range xs {
+		cov += (xs[i] - mx) * (ys[i] - my)
+		vx += (xs[i] - mx) * (xs[i] - mx)
+		vy += (ys[i] - my) * (ys[i] - my)
+	}
+	if vx == 0 || vy == 0 {
+		return Correlation{}, false
+	}
+	return Correlation{Value: cov / math.Sqrt(vx*vy), Returns: len(xs)}, true
+}
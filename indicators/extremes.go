@@ -0,0 +1,148 @@
+package indicators
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// PricePoint is a last price and the time it was traded.
+type PricePoint struct {
+	Price     float64   `json:"price,string"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ExtremesResult is the range of the last price of a symbol since the
+// server started, and of all time when the history is persisted.
+type ExtremesResult struct {
+	SessionHigh PricePoint  `json:"sessionHigh"`
+	SessionLow  PricePoint  `json:"sessionLow"`
+	AllTimeHigh *PricePoint `json:"allTimeHigh,omitempty"`
+	AllTimeLow  *PricePoint `json:"allTimeLow,omitempty"`
+}
+
+// ExtremesLoader returns the highest and lowest prices stored for symbol,
+// and false when none is.
+type ExtremesLoader func(symbol string) (high PricePoint, low PricePoint, ok bool, err error)
+
+type priceRange struct {
+	high, low PricePoint
+}
+
+func (r *priceRange) observe(p PricePoint) {
+	if p.Price > r.high.Price {
+		r.high = p
+	}
+	if p.Price < r.low.Price {
+		r.low = p
+	}
+}
+
+// Extremes tracks the highest and lowest last price per symbol. With a
+// loader the all-time range of a symbol is loaded on its first update and
+// widened by the following ones.
+type Extremes struct {
+	mutex   *sync.Mutex
+	loader  ExtremesLoader
+	session map[string]*priceRange
+	allTime map[string]*priceRange
+	loading map[string]bool
+}
+
+// NewExtremes creates an Extremes tracker of the session ranges.
+func NewExtremes() *Extremes {
+	return &Extremes{
+		mutex:   &sync.Mutex{},
+		session: make(map[string]*priceRange),
+		allTime: make(map[string]*priceRange),
+		loading: make(map[string]bool),
+	}
+}
+
+// SetLoader sets the loader of the all-time ranges, before the first update.
+func (e *Extremes) SetLoader(loader ExtremesLoader) {
+	e.mutex.Lock()
+	e.loader = loader
+	e.mutex.Unlock()
+}
+
+// Update is registered as a ticker listener.
+func (e *Extremes) Update(ticker *wsclient.Ticker) {
+	if ticker.Last.IsZero() {
+		return
+	}
+	at := ticker.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	p := PricePoint{Price: ticker.Last.InexactFloat64(), Timestamp: at}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	session, ok := e.session[ticker.Symbol]
+	if !ok {
+		e.session[ticker.Symbol] = &priceRange{high: p, low: p}
+	} else {
+		session.observe(p)
+	}
+	if allTime, ok := e.allTime[ticker.Symbol]; ok {
+		allTime.observe(p)
+	} else if e.loader != nil && !e.loading[ticker.Symbol] {
+		// listeners must not block on the database
+		e.loading[ticker.Symbol] = true
+		go e.load(ticker.Symbol)
+	}
+}
+
+// load sets the all-time range of symbol from the loader, widened by the
+// session range. It is retried on the next update when it fails.
+func (e *Extremes) load(symbol string) {
+	high, low, ok, err := e.loader(symbol)
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if err != nil {
+		log.Printf("extremes: loading %s: %v", symbol, err)
+		delete(e.loading, symbol)
+		return
+	}
+	session, found := e.session[symbol]
+	if !found || !e.loading[symbol] {
+		// removed while loading
+		return
+	}
+	allTime := &priceRange{high: session.high, low: session.low}
+	if ok {
+		allTime.observe(high)
+		allTime.observe(low)
+	}
+	e.allTime[symbol] = allTime
+}
+
+// Get returns the extremes of symbol, and false before its first update.
+// The all-time range is only set once loaded.
+func (e *Extremes) Get(symbol string) (ExtremesResult, bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	session, ok := e.session[symbol]
+	if !ok {
+		return ExtremesResult{}, false
+	}
+	result := ExtremesResult{SessionHigh: session.high, SessionLow: session.low}
+	if allTime, ok := e.allTime[symbol]; ok {
+		high, low := allTime.high, allTime.low
+		result.AllTimeHigh = &high
+		result.AllTimeLow = &low
+	}
+	return result, true
+}
+
+// Remove drops the ranges of symbol. An all-time range still loading is
+// discarded.
+func (e *Extremes) Remove(symbol string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	delete(e.session, symbol)
+	delete(e.allTime, symbol)
+	delete(e.loading, symbol)
+}
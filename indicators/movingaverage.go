@@ -0,0 +1,146 @@
+package indicators
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// MovingAverage is a simple (sma) or exponential (ema) moving average of the
+// last Period closes, named like sma20 or ema50.
+type MovingAverage struct {
+	Name   string
+	Kind   string
+	Period int
+}
+
+// ParseMovingAverages parses a comma separated list of moving averages such
+// as sma20,ema50, whose periods are 1 to maxPeriod.
+func ParseMovingAverages(value string, maxPeriod int) ([]MovingAverage, error) {
+	var averages []MovingAverage
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if len(name) < 4 || (name[:3] != "sma" && name[:3] != "ema") {
+			return nil, fmt.Errorf("unknown indicator %q, expected sma or ema and a period", name)
+		}
+		period, err := strconv.Atoi(name[3:])
+		if err != nil || period < 1 || period > maxPeriod {
+			return nil, fmt.Errorf("indicator %q: the period must be 1 to %d", name, maxPeriod)
+		}
+		averages = append(averages, MovingAverage{Name: name, Kind: name[:3], Period: period})
+	}
+	if len(averages) == 0 {
+		return nil, fmt.Errorf("no indicator")
+	}
+	return averages, nil
+}
+
+type maClose struct {
+	start time.Time
+	price float64
+}
+
+// MovingAverages keeps the last maxPeriod one-minute closes of the last
+// price per symbol. The close of the current minute is the latest price, so
+// the averages follow the live feed.
+type MovingAverages struct {
+	mutex     *sync.Mutex
+	maxPeriod int
+	closes    map[string][]maClose
+}
+
+// NewMovingAverages creates a tracker of averages up to maxPeriod minutes.
+func NewMovingAverages(maxPeriod int) *MovingAverages {
+	return &MovingAverages{
+		mutex:     &sync.Mutex{},
+		maxPeriod: maxPeriod,
+		closes:    make(map[string][]maClose),
+	}
+}
+
+// MaxPeriod returns the longest period that can be computed.
+func (m *MovingAverages) MaxPeriod() int {
+	return m.maxPeriod
+}
+
+// Update is registered as a ticker listener.
+func (m *MovingAverages) Update(ticker *wsclient.Ticker) {
+	if ticker.Last.IsZero() {
+		return
+	}
+	at := ticker.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	start := at.Truncate(time.Minute)
+	price := ticker.Last.InexactFloat64()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	closes := m.closes[ticker.Symbol]
+	if n := len(closes); n > 0 {
+		switch last := closes[n-1].start; {
+		case start.Equal(last):
+			closes[n-1].price = price
+			return
+		case start.Before(last):
+			return
+		}
+	}
+	closes = append(closes, maClose{start: start, price: price})
+	if len(closes) > m.maxPeriod {
+		closes = closes[len(closes)-m.maxPeriod:]
+	}
+	m.closes[ticker.Symbol] = closes
+}
+
+// Compute returns the averages of symbol by name. Averages over more minutes
+// than observed so far are left out.
+func (m *MovingAverages) Compute(symbol string, averages []MovingAverage) map[string]float64 {
+	m.mutex.Lock()
+	closes := make([]float64, len(m.closes[symbol]))
+	for i, c := range m.closes[symbol] {
+		closes[i] = c.price
+	}
+	m.mutex.Unlock()
+
+	values := make(map[string]float64, len(averages))
+	for _, average := range averages {
+		if len(closes) < average.Period {
+			continue
+		}
+		if average.Kind == "sma" {
+			values[average.Name] = mean(closes[len(closes)-average.Period:])
+			continue
+		}
+		// seeded with the simple average of the oldest closes kept
+		alpha := 2 / float64(average.Period+1)
+		ema := mean(closes[:average.Period])
+		for _, price := range closes[average.Period:] {
+			ema += alpha * (price - ema)
+		}
+		values[average.Name] = ema
+	}
+	return values
+}
+
+// Remove drops the closes of symbol.
+func (m *MovingAverages) Remove(symbol string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.closes, symbol)
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
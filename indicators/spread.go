@@ -0,0 +1,140 @@
+package indicators
+
+import (
+	"sync"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// spreadBucket sums the spreads of the updates of one minute.
+type spreadBucket struct {
+	start      time.Time
+	spreadSum  float64
+	percentSum float64
+	count      int
+}
+
+type spreadQuote struct {
+	at  time.Time
+	bid float64
+	ask float64
+}
+
+// SpreadResult is the current spread of a symbol and its average over a
+// window.
+type SpreadResult struct {
+	Bid                  float64   `json:"bid,string"`
+	Ask                  float64   `json:"ask,string"`
+	Spread               float64   `json:"spread,string"`
+	SpreadPercent        float64   `json:"spreadPercent"`
+	Timestamp            time.Time `json:"timestamp"`
+	AverageSpread        float64   `json:"averageSpread,string"`
+	AverageSpreadPercent float64   `json:"averageSpreadPercent"`
+	Samples              int       `json:"samples"`
+	From                 time.Time `json:"from"`
+}
+
+// Spread tracks the bid/ask spread per symbol for windows up to maxWindow.
+// Updates are summed per minute to bound the memory used by long windows,
+// so averages start on a minute boundary. The percentage is relative to the
+// mid price, like /stats.
+type Spread struct {
+	mutex     *sync.Mutex
+	maxWindow time.Duration
+	buckets   map[string][]spreadBucket
+	last      map[string]spreadQuote
+}
+
+// NewSpread creates a Spread tracker keeping maxWindow of samples.
+func NewSpread(maxWindow time.Duration) *Spread {
+	return &Spread{
+		mutex:     &sync.Mutex{},
+		maxWindow: maxWindow,
+		buckets:   make(map[string][]spreadBucket),
+		last:      make(map[string]spreadQuote),
+	}
+}
+
+// MaxWindow returns the longest window that can be averaged.
+func (s *Spread) MaxWindow() time.Duration {
+	return s.maxWindow
+}
+
+// Update is registered as a ticker listener. Tickers without both sides of
+// the book, or with a crossed book, are skipped.
+func (s *Spread) Update(ticker *wsclient.Ticker) {
+	bid, ask := ticker.Bid.InexactFloat64(), ticker.Ask.InexactFloat64()
+	if bid <= 0 || ask < bid {
+		return
+	}
+	at := ticker.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	spread := ask - bid
+	percent := spread / ((ask + bid) / 2) * 100
+	start := at.Truncate(time.Minute)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.last[ticker.Symbol] = spreadQuote{at: at, bid: bid, ask: ask}
+	buckets := s.buckets[ticker.Symbol]
+	if n := len(buckets); n > 0 && !start.After(buckets[n-1].start) {
+		buckets[n-1].spreadSum += spread
+		buckets[n-1].percentSum += percent
+		buckets[n-1].count++
+		return
+	}
+	buckets = append(buckets, spreadBucket{start: start, spreadSum: spread, percentSum: percent, count: 1})
+	cutoff := at.Add(-s.maxWindow)
+	i := 0
+	for i < len(buckets) && buckets[i].start.Before(cutoff) {
+		i++
+	}
+	s.buckets[ticker.Symbol] = buckets[i:]
+}
+
+// Compute returns the current spread of symbol with its average over the
+// window ending now, and false when there is no quote for the symbol.
+func (s *Spread) Compute(symbol string, window time.Duration, now time.Time) (SpreadResult, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	quote, ok := s.last[symbol]
+	if !ok {
+		return SpreadResult{}, false
+	}
+	result := SpreadResult{
+		Bid:       quote.bid,
+		Ask:       quote.ask,
+		Spread:    quote.ask - quote.bid,
+		Timestamp: quote.at,
+	}
+	result.SpreadPercent = result.Spread / ((quote.ask + quote.bid) / 2) * 100
+	start := now.Add(-window).Truncate(time.Minute)
+	var spreadSum, percentSum float64
+	for _, b := range s.buckets[symbol] {
+		if b.start.Before(start) || b.start.After(now) {
+			continue
+		}
+		if result.Samples == 0 {
+			result.From = b.start
+		}
+		spreadSum += b.spreadSum
+		percentSum += b.percentSum
+		result.Samples += b.count
+	}
+	if result.Samples > 0 {
+		result.AverageSpread = spreadSum / float64(result.Samples)
+		result.AverageSpreadPercent = percentSum / float64(result.Samples)
+	}
+	return result, true
+}
+
+// Remove drops the spreads of symbol.
+func (s *Spread) Remove(symbol string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.buckets, symbol)
+	delete(s.last, symbol)
+}
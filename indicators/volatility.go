@@ -0,0 +1,79 @@
+package indicators
+
+import (
+	"math"
+	"time"
+)
+
+// year annualizes volatilities, crypto markets trade every day.
+const year = 365 * 24 * time.Hour
+
+// VolatilityResult is the realized volatility of a symbol: the standard
+// deviation of the log returns between the closes of consecutive intervals.
+type VolatilityResult struct {
+	Volatility float64   `json:"volatility"`
+	Annualized float64   `json:"annualized"`
+	Returns    int       `json:"returns"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+}
+
+// intervalCloses resamples points, oldest first, to the last price of every
+// interval, stamped with the start of the interval. Intervals without a
+// point are left out.
+func intervalCloses(points []PricePoint, interval time.Duration) []PricePoint {
+	var closes []PricePoint
+	for _, p := range points {
+		if p.Price <= 0 {
+			continue
+		}
+		start := p.Timestamp.Truncate(interval)
+		if n := len(closes); n > 0 && closes[n-1].Timestamp.Equal(start) {
+			closes[n-1].Price = p.Price
+			continue
+		}
+		closes = append(closes, PricePoint{Price: p.Price, Timestamp: start})
+	}
+	return closes
+}
+
+// logReturns returns the log returns of consecutive interval closes, keyed
+// by the start of the later interval. Returns over a gap are left out.
+func logReturns(closes []PricePoint, interval time.Duration) map[time.Time]float64 {
+	returns := make(map[time.Time]float64)
+	for i := 1; i < len(closes); i++ {
+		if closes[i].Timestamp.Sub(closes[i-1].Timestamp) != interval {
+			continue
+		}
+		returns[closes[i].Timestamp] = math.Log(closes[i].Price / closes[i-1].Price)
+	}
+	return returns
+}
+
+// RealizedVolatility computes the volatility of points, oldest first, from
+// the returns of interval closes. It returns false with fewer than two
+// returns.
+func RealizedVolatility(points []PricePoint, interval time.Duration) (VolatilityResult, bool) {
+	closes := intervalCloses(points, interval)
+	returns := logReturns(closes, interval)
+	if len(returns) < 2 {
+		return VolatilityResult{}, false
+	}
+	values := make([]float64, 0, len(returns))
+	for _, r := range returns {
+		values = append(values, r)
+	}
+	m := mean(values)
+	var squares float64
+	for _, v := range values {
+		squares += (v - m) * (v - m)
+	}
+	volatility := math.Sqrt(squares / float64(len(values)-1))
+	return VolatilityResult{
+		Volatility: volatility,
+		Annualized: volatility * math.Sqrt(float64(year)/float64(interval)),
+		Returns:    len(values),
+		From:       closes[0].Timestamp,
+		To:         closes[len(closes)-1].Timestamp,
+	}, true
+}
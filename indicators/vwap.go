@@ -0,0 +1,107 @@
+// Package indicators computes rolling market indicators from the live ticker feed.
+package indicators
+
+import (
+	"sync"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+type vwapSample struct {
+	at     time.Time
+	price  float64
+	volume float64
+}
+
+// VWAPResult is the volume weighted average price over a window.
+type VWAPResult struct {
+	VWAP    float64   `json:"vwap,string"`
+	Volume  float64   `json:"volume,string"`
+	Samples int       `json:"samples"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+}
+
+// VWAP tracks traded volume per symbol for windows up to maxWindow. The
+// ticker only carries a rolling 24h volume, so the volume traded between two
+// updates is taken as the increase of that volume, at the later last price.
+type VWAP struct {
+	mutex      *sync.Mutex
+	maxWindow  time.Duration
+	samples    map[string][]vwapSample
+	lastVolume map[string]float64
+}
+
+// NewVWAP creates a VWAP tracker keeping maxWindow of samples.
+func NewVWAP(maxWindow time.Duration) *VWAP {
+	return &VWAP{
+		mutex:      &sync.Mutex{},
+		maxWindow:  maxWindow,
+		samples:    make(map[string][]vwapSample),
+		lastVolume: make(map[string]float64),
+	}
+}
+
+// MaxWindow returns the longest window that can be computed.
+func (v *VWAP) MaxWindow() time.Duration {
+	return v.maxWindow
+}
+
+// Update is registered as a ticker listener.
+func (v *VWAP) Update(ticker *wsclient.Ticker) {
+	at := ticker.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	volume := ticker.Volume.InexactFloat64()
+	previous, seen := v.lastVolume[ticker.Symbol]
+	v.lastVolume[ticker.Symbol] = volume
+	if !seen || volume <= previous || ticker.Last.IsZero() {
+		return
+	}
+	samples := append(v.samples[ticker.Symbol], vwapSample{at: at, price: ticker.Last.InexactFloat64(), volume: volume - previous})
+	cutoff := at.Add(-v.maxWindow)
+	i := 0
+	for i < len(samples) && samples[i].at.Before(cutoff) {
+		i++
+	}
+	v.samples[ticker.Symbol] = samples[i:]
+}
+
+// Compute returns the VWAP of symbol over the window ending now, and false
+// when no volume was traded in the window.
+func (v *VWAP) Compute(symbol string, window time.Duration, now time.Time) (VWAPResult, bool) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	start := now.Add(-window)
+	var result VWAPResult
+	var notional float64
+	for _, s := range v.samples[symbol] {
+		if s.at.Before(start) || s.at.After(now) {
+			continue
+		}
+		if result.Samples == 0 {
+			result.From = s.at
+		}
+		result.To = s.at
+		notional += s.price * s.volume
+		result.Volume += s.volume
+		result.Samples++
+	}
+	if result.Volume == 0 {
+		return VWAPResult{}, false
+	}
+	result.VWAP = notional / result.Volume
+	return result, true
+}
+
+// Remove drops the samples of symbol.
+func (v *VWAP) Remove(symbol string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	delete(v.samples, symbol)
+	delete(v.lastVolume, symbol)
+}
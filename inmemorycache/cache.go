@@ -2,51 +2,262 @@ package inmemorycache
 
 import (
 	"errors"
+	"hash/fnv"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/crypto-api-server/wsclient"
 )
 
+// shardCount is the number of independently locked maps of a CurrencyCache.
+const shardCount = 32
+
+type cacheShard struct {
+	mutex    *sync.RWMutex
+	internal map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	ticker   *wsclient.Ticker
+	cachedAt time.Time
+	seq      uint64
+}
+
+// Entry is a copy of a cached ticker with the time it was stored and the
+// sequence number of that update.
+type Entry struct {
+	Symbol   string
+	Ticker   *wsclient.Ticker
+	CachedAt time.Time
+	Seq      uint64
+}
+
 // CurrencyCache represents a local summary cache for every exchange. To allow dinamic polling from multiple sources (REST + Websocket)
+// Symbols are spread over shards so that updates of different symbols rarely contend on the same lock.
+// Every Set is numbered from a monotonic sequence so that the changes since a
+// point can be listed.
 type CurrencyCache struct {
-	mutex    *sync.RWMutex
-	internal map[string]*wsclient.Ticker
+	// first for the 64-bit alignment required by atomic on 32-bit platforms
+	seq    uint64
+	shards []*cacheShard
 }
 
 // NewCurrencyCache creates a new SummaryCache Object
 func NewCurrencyCache() *CurrencyCache {
-	return &CurrencyCache{
-		mutex:    &sync.RWMutex{},
-		internal: make(map[string]*wsclient.Ticker),
+	shards := make([]*cacheShard, shardCount)
+	for i := range shards {
+		shards[i] = &cacheShard{
+			mutex:    &sync.RWMutex{},
+			internal: make(map[string]*cacheEntry),
+		}
 	}
+	return &CurrencyCache{shards: shards}
+}
+
+// shard returns the shard holding currencySymbol.
+func (sc *CurrencyCache) shard(currencySymbol string) *cacheShard {
+	hash := fnv.New32a()
+	hash.Write([]byte(currencySymbol))
+	return sc.shards[hash.Sum32()%uint32(len(sc.shards))]
 }
 
 // Set sets a value for the specified key.
 func (sc *CurrencyCache) Set(currencySymbol string, data *wsclient.Ticker) *wsclient.Ticker {
-	sc.mutex.Lock()
-	old := sc.internal[currencySymbol]
-	sc.internal[currencySymbol] = data
-	sc.mutex.Unlock()
-	return old
+	shard := sc.shard(currencySymbol)
+	shard.mutex.Lock()
+	old := shard.internal[currencySymbol]
+	// numbered under the shard lock, so an update is stored once Changes
+	// can see its number
+	seq := atomic.AddUint64(&sc.seq, 1)
+	shard.internal[currencySymbol] = &cacheEntry{ticker: data, cachedAt: time.Now(), seq: seq}
+	shard.mutex.Unlock()
+	if old == nil {
+		return nil
+	}
+	return old.ticker
+}
+
+// SetIfNewer sets data for the specified key unless the cached ticker has a
+// later timestamp, returning whether it was set. The check and the update
+// are atomic, so concurrent updates can't replace a newer ticker.
+func (sc *CurrencyCache) SetIfNewer(currencySymbol string, data *wsclient.Ticker) bool {
+	shard := sc.shard(currencySymbol)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	if old, ok := shard.internal[currencySymbol]; ok && data.Timestamp.Before(old.ticker.Timestamp) {
+		return false
+	}
+	seq := atomic.AddUint64(&sc.seq, 1)
+	shard.internal[currencySymbol] = &cacheEntry{ticker: data, cachedAt: time.Now(), seq: seq}
+	return true
+}
+
+// MarkDelisted flags the cached ticker of the specified key as delisted,
+// returning false when there is none. The flag is set on a copy of the
+// ticker cached at the time, under the shard lock, so a concurrent update
+// isn't replaced by an older ticker. The update is numbered like a Set, but
+// keeps its cache time.
+func (sc *CurrencyCache) MarkDelisted(currencySymbol string) bool {
+	shard := sc.shard(currencySymbol)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	old, ok := shard.internal[currencySymbol]
+	if !ok {
+		return false
+	}
+	if old.ticker.Delisted {
+		return true
+	}
+	copied := *old.ticker
+	copied.Delisted = true
+	seq := atomic.AddUint64(&sc.seq, 1)
+	shard.internal[currencySymbol] = &cacheEntry{ticker: &copied, cachedAt: old.cachedAt, seq: seq}
+	return true
+}
+
+// Delete removes the value of the specified key, returning false when there
+// was none.
+func (sc *CurrencyCache) Delete(currencySymbol string) bool {
+	shard := sc.shard(currencySymbol)
+	shard.mutex.Lock()
+	_, isSet := shard.internal[currencySymbol]
+	delete(shard.internal, currencySymbol)
+	shard.mutex.Unlock()
+	return isSet
 }
 
 // Get gets the value for the specified key.
 func (sc *CurrencyCache) Get(currencySymbol string) (*wsclient.Ticker, bool) {
-	sc.mutex.RLock()
-	ret, isSet := sc.internal[currencySymbol]
-	sc.mutex.RUnlock()
-	return ret, isSet
+	shard := sc.shard(currencySymbol)
+	shard.mutex.RLock()
+	entry, isSet := shard.internal[currencySymbol]
+	shard.mutex.RUnlock()
+	if !isSet {
+		return nil, false
+	}
+	return entry.ticker, true
 }
 
-// GetAll gets the value for the whole data.
-func (sc *CurrencyCache) GetAll() ([]*wsclient.Ticker, error) {
-	allData := make([]*wsclient.Ticker, 0)
-	for i, _ := range sc.internal {
-		sc.mutex.RLock()
-		ret := sc.internal[i]
-		allData = append(allData, ret)
-		sc.mutex.RUnlock()
+// Lookup returns a copy of the entry of the specified key.
+func (sc *CurrencyCache) Lookup(currencySymbol string) (Entry, bool) {
+	shard := sc.shard(currencySymbol)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+	entry, isSet := shard.internal[currencySymbol]
+	if !isSet {
+		return Entry{}, false
 	}
+	copied := *entry.ticker
+	return Entry{Symbol: currencySymbol, Ticker: &copied, CachedAt: entry.cachedAt, Seq: entry.seq}, true
+}
+
+// Snapshot returns a copy of every ticker sorted by symbol. All shards are
+// locked while copying, so the result is a consistent point-in-time view.
+func (sc *CurrencyCache) Snapshot() []*wsclient.Ticker {
+	entries := sc.Entries()
+	allData := make([]*wsclient.Ticker, 0, len(entries))
+	for _, entry := range entries {
+		allData = append(allData, entry.Ticker)
+	}
+	return allData
+}
+
+// Entries returns a copy of every entry sorted by symbol, as a consistent
+// point-in-time view like Snapshot.
+func (sc *CurrencyCache) Entries() []Entry {
+	for _, shard := range sc.shards {
+		shard.mutex.RLock()
+	}
+	entries := make([]Entry, 0)
+	for _, shard := range sc.shards {
+		for symbol, entry := range shard.internal {
+			copied := *entry.ticker
+			entries = append(entries, Entry{Symbol: symbol, Ticker: &copied, CachedAt: entry.cachedAt, Seq: entry.seq})
+		}
+	}
+	for _, shard := range sc.shards {
+		shard.mutex.RUnlock()
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Symbol < entries[j].Symbol })
+	return entries
+}
+
+// Changes returns a copy of the entries updated after the sequence number
+// since, oldest update first, and the sequence number of the last update. A
+// since ahead of the sequence, such as a cursor of a previous run, lists
+// every entry. Removed entries aren't reported.
+func (sc *CurrencyCache) Changes(since uint64) ([]Entry, uint64) {
+	for _, shard := range sc.shards {
+		shard.mutex.RLock()
+	}
+	last := atomic.LoadUint64(&sc.seq)
+	if since > last {
+		since = 0
+	}
+	var entries []Entry
+	for _, shard := range sc.shards {
+		for symbol, entry := range shard.internal {
+			if entry.seq <= since {
+				continue
+			}
+			copied := *entry.ticker
+			entries = append(entries, Entry{Symbol: symbol, Ticker: &copied, CachedAt: entry.cachedAt, Seq: entry.seq})
+		}
+	}
+	for _, shard := range sc.shards {
+		shard.mutex.RUnlock()
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+	return entries, last
+}
+
+// Range calls fn with a copy of every entry, sorted by symbol, until fn
+// returns false. Unlike Entries the entries are copied one at a time, so
+// ranging over a large cache doesn't hold all the tickers in memory, and an
+// entry updated during the range is seen as it is when reached.
+func (sc *CurrencyCache) Range(fn func(Entry) bool) {
+	for _, symbol := range sc.symbols() {
+		entry, ok := sc.Lookup(symbol)
+		if !ok {
+			continue
+		}
+		if !fn(entry) {
+			return
+		}
+	}
+}
+
+// symbols returns the cached symbols, sorted.
+func (sc *CurrencyCache) symbols() []string {
+	var symbols []string
+	for _, shard := range sc.shards {
+		shard.mutex.RLock()
+		for symbol := range shard.internal {
+			symbols = append(symbols, symbol)
+		}
+		shard.mutex.RUnlock()
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Flush removes every entry, returning how many there were.
+func (sc *CurrencyCache) Flush() int {
+	flushed := 0
+	for _, shard := range sc.shards {
+		shard.mutex.Lock()
+		flushed += len(shard.internal)
+		shard.internal = make(map[string]*cacheEntry)
+		shard.mutex.Unlock()
+	}
+	return flushed
+}
+
+// GetAll gets the value for the whole data, sorted by symbol.
+func (sc *CurrencyCache) GetAll() ([]*wsclient.Ticker, error) {
+	allData := sc.Snapshot()
 	if len(allData) == 0 {
 		return nil, errors.New("no data present")
 	}
@@ -0,0 +1,315 @@
+package inmemorycache
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// The benchmarks compare the sharded cache with the same cache behind a
+// single lock, run them with -cpu 1,4,16 to see the contention grow:
+//
+//	go test -run '^$' -bench . -cpu 1,4,16 ./inmemorycache
+
+const benchmarkSymbols = 1000
+
+var symbols = func() []string {
+	symbols := make([]string, benchmarkSymbols)
+	for i := range symbols {
+		symbols[i] = fmt.Sprintf("SYM%dUSD", i)
+	}
+	return symbols
+}()
+
+// singleLockCache returns a cache with one shard, which is a map behind a
+// single lock.
+func singleLockCache() *CurrencyCache {
+	return &CurrencyCache{shards: []*cacheShard{{mutex: &sync.RWMutex{}, internal: make(map[string]*cacheEntry)}}}
+}
+
+var caches = []struct {
+	name string
+	new  func() *CurrencyCache
+}{
+	{"sharded", NewCurrencyCache},
+	{"single-lock", singleLockCache},
+}
+
+func filledCache(newCache func() *CurrencyCache) *CurrencyCache {
+	cache := newCache()
+	for _, symbol := range symbols {
+		cache.Set(symbol, &wsclient.Ticker{Symbol: symbol, Timestamp: time.Now()})
+	}
+	return cache
+}
+
+// TestEntriesSorted checks that the entries of every shard are merged in
+// symbol order, for the sharded cache and the single lock one.
+func TestEntriesSorted(t *testing.T) {
+	for _, c := range caches {
+		t.Run(c.name, func(t *testing.T) {
+			cache := filledCache(c.new)
+			cache.Delete(symbols[1])
+			want := make([]string, 0, len(symbols)-1)
+			for _, symbol := range symbols {
+				if symbol != symbols[1] {
+					want = append(want, symbol)
+				}
+			}
+			sort.Strings(want)
+
+			entries := cache.Entries()
+			tickers := cache.Snapshot()
+			all, err := cache.GetAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(want) || len(tickers) != len(want) || len(all) != len(want) {
+				t.Fatalf("%d entries, %d tickers and %d of all, want %d", len(entries), len(tickers), len(all), len(want))
+			}
+			for i, symbol := range want {
+				if entries[i].Symbol != symbol || entries[i].Ticker.Symbol != symbol || tickers[i].Symbol != symbol || all[i].Symbol != symbol {
+					t.Fatalf("%d: entry %s of %s, ticker %s and %s, want %s", i, entries[i].Symbol, entries[i].Ticker.Symbol, tickers[i].Symbol, all[i].Symbol, symbol)
+				}
+			}
+		})
+	}
+}
+
+// TestEntriesCopied checks that the entries listed don't share the cached
+// tickers.
+func TestEntriesCopied(t *testing.T) {
+	cache := NewCurrencyCache()
+	cache.Set("BTCUSD", &wsclient.Ticker{Symbol: "BTCUSD", FullName: "Bitcoin"})
+	cache.Entries()[0].Ticker.FullName = "changed"
+	cache.Snapshot()[0].FullName = "changed"
+	entry, _ := cache.Lookup("BTCUSD")
+	entry.Ticker.FullName = "changed"
+	if ticker, _ := cache.Get("BTCUSD"); ticker.FullName != "Bitcoin" {
+		t.Errorf("cached ticker changed to %q", ticker.FullName)
+	}
+	if cache.Flush() != 1 {
+		t.Error("flushed another number of entries than the one set")
+	}
+	if _, err := cache.GetAll(); err == nil {
+		t.Error("no error listing an empty cache")
+	}
+}
+
+func TestSetIfNewer(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		offset time.Duration
+		set    bool
+	}{
+		{"first", 0, true},
+		{"older", -time.Second, false},
+		{"equal", 0, true},
+		{"newer", time.Second, true},
+		{"older than the newer", 0, false},
+	}
+	cache := NewCurrencyCache()
+	var current string
+	for _, test := range tests {
+		ticker := &wsclient.Ticker{Symbol: "BTCUSD", FullName: test.name, Timestamp: at.Add(test.offset)}
+		if set := cache.SetIfNewer("BTCUSD", ticker); set != test.set {
+			t.Errorf("%s: set %v, want %v", test.name, set, test.set)
+		}
+		if test.set {
+			current = test.name
+		}
+		if ticker, _ := cache.Get("BTCUSD"); ticker.FullName != current {
+			t.Errorf("%s: cached %q, want %q", test.name, ticker.FullName, current)
+		}
+	}
+	// Set replaces unconditionally
+	if old := cache.Set("BTCUSD", &wsclient.Ticker{FullName: "set", Timestamp: at.Add(-time.Hour)}); old == nil || old.FullName != current {
+		t.Errorf("Set returned %v, want the %q ticker", old, current)
+	}
+}
+
+func TestMarkDelisted(t *testing.T) {
+	cache := NewCurrencyCache()
+	if cache.MarkDelisted("BTCUSD") {
+		t.Error("marked a symbol that isn't cached")
+	}
+	ticker := &wsclient.Ticker{Symbol: "BTCUSD", FullName: "first"}
+	cache.Set("BTCUSD", ticker)
+	before, _ := cache.Lookup("BTCUSD")
+	if !cache.MarkDelisted("BTCUSD") {
+		t.Fatal("BTCUSD not marked")
+	}
+	after, _ := cache.Lookup("BTCUSD")
+	if !after.Ticker.Delisted || after.Ticker.FullName != "first" || ticker.Delisted {
+		t.Errorf("cached %+v, want a delisted copy of the cached ticker", after.Ticker)
+	}
+	if after.Seq != before.Seq+1 || !after.CachedAt.Equal(before.CachedAt) {
+		t.Errorf("seq %d cached at %v, want %d cached at %v", after.Seq, after.CachedAt, before.Seq+1, before.CachedAt)
+	}
+	// marked once
+	cache.MarkDelisted("BTCUSD")
+	if again, _ := cache.Lookup("BTCUSD"); again.Seq != after.Seq {
+		t.Errorf("seq %d after marking again, want %d", again.Seq, after.Seq)
+	}
+}
+
+// entrySymbols are the symbols of entries, in order.
+func entrySymbols(entries []Entry) []string {
+	symbols := make([]string, len(entries))
+	for i, entry := range entries {
+		symbols[i] = entry.Symbol
+	}
+	return symbols
+}
+
+func TestChanges(t *testing.T) {
+	cache := NewCurrencyCache()
+	if entries, last := cache.Changes(0); len(entries) != 0 || last != 0 {
+		t.Fatalf("got %v, %d for an empty cache", entries, last)
+	}
+	for _, symbol := range []string{"ETHBTC", "BTCUSD", "XRPUSD"} {
+		cache.Set(symbol, &wsclient.Ticker{Symbol: symbol})
+	}
+	entries, cursor := cache.Changes(0)
+	if got := entrySymbols(entries); !reflect.DeepEqual(got, []string{"ETHBTC", "BTCUSD", "XRPUSD"}) || cursor != 3 {
+		t.Fatalf("changes %v up to %d, want the three updates in order up to 3", got, cursor)
+	}
+	for i, entry := range entries {
+		if entry.Seq != uint64(i+1) {
+			t.Errorf("%s: seq %d, want %d", entry.Symbol, entry.Seq, i+1)
+		}
+	}
+
+	// an updated symbol moves after the others, the removed ones aren't listed
+	cache.Set("ETHBTC", &wsclient.Ticker{Symbol: "ETHBTC"})
+	cache.SetIfNewer("LTCUSD", &wsclient.Ticker{Symbol: "LTCUSD"})
+	cache.Set("XRPUSD", &wsclient.Ticker{Symbol: "XRPUSD"})
+	cache.Delete("XRPUSD")
+	tests := []struct {
+		name  string
+		since uint64
+		want  []string
+	}{
+		{"since the cursor", cursor, []string{"ETHBTC", "LTCUSD"}},
+		{"since the start", 0, []string{"BTCUSD", "ETHBTC", "LTCUSD"}},
+		{"since the last update", 6, []string{}},
+		{"since ahead of the sequence", 100, []string{"BTCUSD", "ETHBTC", "LTCUSD"}},
+	}
+	for _, test := range tests {
+		entries, last := cache.Changes(test.since)
+		if got := entrySymbols(entries); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: changes %v, want %v", test.name, got, test.want)
+		}
+		if last != 6 {
+			t.Errorf("%s: last %d, want 6", test.name, last)
+		}
+		for i := 1; i < len(entries); i++ {
+			if entries[i].Seq <= entries[i-1].Seq {
+				t.Errorf("%s: seq %d after %d", test.name, entries[i].Seq, entries[i-1].Seq)
+			}
+		}
+	}
+
+	// a flush keeps the sequence, so a cursor of before it lists the entries set since
+	cache.Flush()
+	cache.Set("BTCUSD", &wsclient.Ticker{Symbol: "BTCUSD"})
+	if entries, last := cache.Changes(cursor); !reflect.DeepEqual(entrySymbols(entries), []string{"BTCUSD"}) || last != 7 {
+		t.Errorf("changes %v up to %d after a flush, want [BTCUSD] up to 7", entrySymbols(entries), last)
+	}
+}
+
+func TestRange(t *testing.T) {
+	cache := NewCurrencyCache()
+	for _, symbol := range []string{"XRPUSD", "BTCUSD", "LTCUSD", "ETHBTC"} {
+		cache.Set(symbol, &wsclient.Ticker{Symbol: symbol})
+	}
+	var all []Entry
+	cache.Range(func(entry Entry) bool {
+		all = append(all, entry)
+		return true
+	})
+	if got := entrySymbols(all); !reflect.DeepEqual(got, []string{"BTCUSD", "ETHBTC", "LTCUSD", "XRPUSD"}) {
+		t.Errorf("ranged over %v, want the symbols in order", got)
+	}
+	if all[0].Seq != 2 || all[0].Ticker.Symbol != "BTCUSD" {
+		t.Errorf("first entry %s of seq %d, want BTCUSD of 2", all[0].Ticker.Symbol, all[0].Seq)
+	}
+
+	// stops when fn returns false, and sees the entries as they are when
+	// reached
+	var ranged []string
+	cache.Range(func(entry Entry) bool {
+		ranged = append(ranged, entry.Symbol)
+		if entry.Symbol == "BTCUSD" {
+			cache.Delete("ETHBTC")
+			cache.Set("LTCUSD", &wsclient.Ticker{Symbol: "LTCUSD", FullName: "updated"})
+			return true
+		}
+		if entry.Ticker.FullName != "updated" {
+			t.Errorf("%s ranged over before its update", entry.Symbol)
+		}
+		return false
+	})
+	if !reflect.DeepEqual(ranged, []string{"BTCUSD", "LTCUSD"}) {
+		t.Errorf("ranged over %v, want [BTCUSD LTCUSD]", ranged)
+	}
+}
+
+// benchmarkParallel runs op from parallel goroutines, each counting its
+// operations from a different offset, which picks the symbol.
+func benchmarkParallel(b *testing.B, cache *CurrencyCache, op func(cache *CurrencyCache, i int)) {
+	var offset uint32
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(atomic.AddUint32(&offset, 7919))
+		for pb.Next() {
+			op(cache, i)
+			i++
+		}
+	})
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	for _, c := range caches {
+		b.Run(c.name, func(b *testing.B) {
+			benchmarkParallel(b, filledCache(c.new), func(cache *CurrencyCache, i int) {
+				cache.Get(symbols[i%len(symbols)])
+			})
+		})
+	}
+}
+
+func BenchmarkSetParallel(b *testing.B) {
+	for _, c := range caches {
+		b.Run(c.name, func(b *testing.B) {
+			ticker := &wsclient.Ticker{Timestamp: time.Now()}
+			benchmarkParallel(b, filledCache(c.new), func(cache *CurrencyCache, i int) {
+				cache.Set(symbols[i%len(symbols)], ticker)
+			})
+		})
+	}
+}
+
+// BenchmarkMixedParallel is closer to the server load: the feed updates
+// while the requests read, one Set for nine Gets.
+func BenchmarkMixedParallel(b *testing.B) {
+	for _, c := range caches {
+		b.Run(c.name, func(b *testing.B) {
+			ticker := &wsclient.Ticker{Timestamp: time.Now()}
+			benchmarkParallel(b, filledCache(c.new), func(cache *CurrencyCache, i int) {
+				if symbol := symbols[i%len(symbols)]; i%10 == 0 {
+					cache.Set(symbol, ticker)
+				} else {
+					cache.Get(symbol)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package inmemorycache
+
+import (
+	"sync"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// TickerHistory keeps the last updates of every symbol in fixed size ring
+// buffers.
+type TickerHistory struct {
+	mutex *sync.RWMutex
+	size  int
+	rings map[string]*ring
+}
+
+type ring struct {
+	tickers []wsclient.Ticker
+	next    int
+}
+
+// NewTickerHistory creates a TickerHistory keeping size updates per symbol.
+func NewTickerHistory(size int) *TickerHistory {
+	return &TickerHistory{
+		mutex: &sync.RWMutex{},
+		size:  size,
+		rings: make(map[string]*ring),
+	}
+}
+
+// Size is the number of updates kept per symbol.
+func (th *TickerHistory) Size() int {
+	return th.size
+}
+
+// Add records a copy of the ticker, overwriting the oldest update of its
+// symbol once the buffer is full. It is registered as a ticker listener.
+func (th *TickerHistory) Add(ticker *wsclient.Ticker) {
+	th.mutex.Lock()
+	defer th.mutex.Unlock()
+	r, ok := th.rings[ticker.Symbol]
+	if !ok {
+		r = &ring{tickers: make([]wsclient.Ticker, 0, th.size)}
+		th.rings[ticker.Symbol] = r
+	}
+	if len(r.tickers) < th.size {
+		r.tickers = append(r.tickers, *ticker)
+		return
+	}
+	r.tickers[r.next] = *ticker
+	r.next = (r.next + 1) % th.size
+}
+
+// Last returns copies of the last limit updates of symbol, oldest first.
+func (th *TickerHistory) Last(symbol string, limit int) []*wsclient.Ticker {
+	th.mutex.RLock()
+	defer th.mutex.RUnlock()
+	r, ok := th.rings[symbol]
+	if !ok {
+		return []*wsclient.Ticker{}
+	}
+	count := len(r.tickers)
+	if limit > count {
+		limit = count
+	}
+	history := make([]*wsclient.Ticker, 0, limit)
+	for i := count - limit; i < count; i++ {
+		copied := r.tickers[(r.next+i)%count]
+		history = append(history, &copied)
+	}
+	return history
+}
+
+// Remove drops the updates of symbol.
+func (th *TickerHistory) Remove(symbol string) {
+	th.mutex.Lock()
+	delete(th.rings, symbol)
+	th.mutex.Unlock()
+}
@@ -0,0 +1,46 @@
+package inmemorycache
+
+import (
+	"sync"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// Notifier wakes up the goroutines waiting for the next update of a symbol,
+// such as long-polling requests.
+type Notifier struct {
+	mutex   *sync.Mutex
+	waiters map[string]chan struct{}
+}
+
+// NewNotifier creates a Notifier.
+func NewNotifier() *Notifier {
+	return &Notifier{
+		mutex:   &sync.Mutex{},
+		waiters: make(map[string]chan struct{}),
+	}
+}
+
+// Wait returns a channel closed on the next update of symbol. Waiters of the
+// same symbol share the channel.
+func (n *Notifier) Wait(symbol string) <-chan struct{} {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	ch, ok := n.waiters[symbol]
+	if !ok {
+		ch = make(chan struct{})
+		n.waiters[symbol] = ch
+	}
+	return ch
+}
+
+// Notify wakes up the waiters of the symbol of ticker. It is registered as a
+// ticker listener.
+func (n *Notifier) Notify(ticker *wsclient.Ticker) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if ch, ok := n.waiters[ticker.Symbol]; ok {
+		close(ch)
+		delete(n.waiters, ticker.Symbol)
+	}
+}
@@ -1,146 +1,87 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/crypto-api-server/wrappers"
-	"github.com/crypto-api-server/wsclient"
-	"github.com/gorilla/mux"
+	"github.com/crypto-api-server/config"
+	"github.com/crypto-api-server/server"
 )
 
-const MaxBodyBytes = int64(65536)
-
-var (
-	API_KEY    = "XXXXX"
-	API_SECRET = "XXXXX"
-)
-
-type HandleRequests struct {
-	HitWrapper *wrappers.Wrappers
-}
-
-func (h *HandleRequests) handleRequests() {
-	myRouter := mux.NewRouter().StrictSlash(true)
-	myRouter.HandleFunc("/currency/all", h.handleAllCurrency).Methods("GET")
-	myRouter.HandleFunc("/currency/{symbol}", h.handleCurrencyBySymbol).Methods("GET")
-	log.Fatal(http.ListenAndServe(":8080", myRouter))
-}
-
 func main() {
-	fmt.Println("API : http://localhost:8080")
-	fmt.Println("ETHBTC API : http://localhost:8080/currency/ETHBTC")
-	fmt.Println("All API : http://localhost:8080/currency/all")
-	h := &HandleRequests{
-		HitWrapper: wrappers.NewHitBtcV2Wrapper(API_KEY, API_SECRET),
-	}
-	err := h.HitWrapper.CacheAllSymbols()
-	if err != nil {
-		fmt.Println(err)
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
 	}
-	err = h.HitWrapper.CacheFullName()
 	if err != nil {
-		fmt.Println(err)
+		log.Println(err)
+		os.Exit(2)
 	}
-	err = h.subscribeMarketFeeds()
-	if err != nil {
-		fmt.Println(err)
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
 	}
-
-	h.handleRequests()
-}
-
-type Response struct {
-	Currencies []*wsclient.Ticker `json:"currencies"`
-}
-type ErrorResponse struct {
-	Error string `json:"error"`
-}
-
-func (h *HandleRequests) handleAllCurrency(w http.ResponseWriter, req *http.Request) {
-	currencies, err := h.GetAllCurrencies()
-	if err != nil {
-		errorBody, _ := json.Marshal(&ErrorResponse{Error: err.Error()})
-		writeResponse(w, http.StatusInternalServerError, errorBody)
+	if cfg.ValidateOnly {
+		fmt.Println("configuration is valid")
 		return
 	}
-	if len(currencies) == 0 {
-		errorBody, _ := json.Marshal(&ErrorResponse{Error: "No data Found"})
-		writeResponse(w, http.StatusNotFound, errorBody)
+	if cfg.Backfill != nil {
+		if err := server.Backfill(cfg); err != nil {
+			log.Fatal("backfill: ", err)
+		}
 		return
 	}
-
-	var response Response
-	response.Currencies = currencies
-	currenciesJSON, err := json.Marshal(response)
+	if cfg.Listen != "" {
+		base := baseURL(cfg)
+		fmt.Println("API : " + base)
+		fmt.Println("ETHBTC API : " + base + "/currency/ETHBTC")
+		fmt.Println("All API : " + base + "/currency/all")
+		fmt.Println("GraphQL API : " + base + "/graphql")
+		fmt.Println("OpenAPI spec : " + base + "/openapi.json")
+	} else {
+		fmt.Println("API : unix:" + cfg.Server.UnixSocket)
+	}
+	srv, err := server.New(cfg)
 	if err != nil {
-		errorBody, _ := json.Marshal(&ErrorResponse{Error: err.Error()})
-		writeResponse(w, http.StatusInternalServerError, errorBody)
-		return
+		log.Fatal(err)
 	}
-	writeResponse(w, http.StatusOK, currenciesJSON)
-}
 
-func (h *HandleRequests) handleCurrencyBySymbol(w http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
-	key := vars["symbol"]
-	var currenciesJSON []byte
-	if h.HitWrapper.Contains(h.HitWrapper.AllSymbols, key) {
-		currency, err := h.HitWrapper.GetMarketSummary(key)
-		if err != nil {
-			errorBody, _ := json.Marshal(&ErrorResponse{Error: err.Error()})
-			writeResponse(w, http.StatusInternalServerError, errorBody)
-			return
-		}
-		if currency == nil {
-			errorBody, _ := json.Marshal(&ErrorResponse{Error: "No data Found"})
-			writeResponse(w, http.StatusNotFound, errorBody)
-			return
-		}
-		currenciesJSON, err = json.Marshal(currency)
-		if err != nil {
-			errorBody, _ := json.Marshal(&ErrorResponse{Error: err.Error()})
-			writeResponse(w, http.StatusInternalServerError, errorBody)
-			return
-		}
-	} else {
-		errorBody, _ := json.Marshal(&ErrorResponse{Error: "Not a valid Symbol"})
-		writeResponse(w, http.StatusNotFound, errorBody)
-		return
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go reloadOnHangup(srv)
+	if err := srv.Run(ctx); err != nil {
+		log.Fatal(err)
 	}
-
-	writeResponse(w, http.StatusOK, currenciesJSON)
 }
 
-func (h *HandleRequests) subscribeMarketFeeds() error {
-	err := h.HitWrapper.FeedConnect()
-	if err != nil {
-		return err
+// baseURL returns the URL of the API served on the listen address of cfg,
+// with localhost for the unspecified addresses.
+func baseURL(cfg *config.Config) string {
+	scheme := "http"
+	if cfg.Server.TLSCertFile != "" {
+		scheme = "https"
+	}
+	// validated with the configuration
+	host, port, _ := net.SplitHostPort(cfg.Listen)
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "localhost"
 	}
-	return nil
+	return scheme + "://" + net.JoinHostPort(host, port)
 }
 
-// isSymbolValid checks if a string is present in a slice
-// func isSymbolValid(s []string, str string) bool {
-// 	for _, v := range s {
-// 		if v == str {
-// 			return true
-// 		}
-// 	}
-// 	return false
-// }
-
-func (h *HandleRequests) GetAllCurrencies() ([]*wsclient.Ticker, error) {
-	data, err := h.HitWrapper.GetCurrenciesFromCache()
-	if err != nil {
-		return nil, err
+// reloadOnHangup reloads the configuration on every SIGHUP.
+func reloadOnHangup(srv *server.Server) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	for range hangups {
+		if _, err := srv.Reload("SIGHUP"); err != nil {
+			log.Println("config reload failed:", err)
+		}
 	}
-	return data, nil
-}
-func writeResponse(w http.ResponseWriter, code int, response []byte) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	w.Write(response)
 }
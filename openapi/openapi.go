@@ -0,0 +1,284 @@
+// Package openapi builds an OpenAPI 3 document from the registered mux routes,
+// so the published spec can't drift from the router.
+package openapi
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Info describes the API in the generated document.
+type Info struct {
+	Title   string
+	Version string
+}
+
+// Param documents a query parameter. Minimum applies to integer parameters,
+// Format is a free-form hint such as "symbol" or "duration".
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+	Type        string
+	Format      string
+	Minimum     int
+	Enum        []string
+}
+
+// Operation documents a named route. Response and RequestBody are sample
+// values whose types are reflected into JSON schemas.
+type Operation struct {
+	Summary     string
+	Description string
+	QueryParams []Param
+	RequestBody interface{}
+	Response    interface{}
+	ContentType []string
+}
+
+var pathVar = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// Generate walks the router and documents every named route found in ops.
+// Unnamed or undocumented routes are listed with their methods only.
+func Generate(router *mux.Router, info Info, ops map[string]Operation, errorResponse interface{}) (map[string]interface{}, error) {
+	g := &generator{schemas: make(map[string]interface{})}
+	errorSchema := g.schema(reflect.TypeOf(errorResponse))
+	paths := make(map[string]interface{})
+
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// routes without a method matcher, such as subrouter prefixes
+			return nil
+		}
+		path := pathVar.ReplaceAllString(tpl, "{$1}")
+		item, _ := paths[path].(map[string]interface{})
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+		op := ops[route.GetName()]
+		for _, method := range methods {
+			item[strings.ToLower(method)] = g.operation(route.GetName(), method, tpl, op, errorSchema)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   info.Title,
+			"version": info.Version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": g.schemas,
+		},
+	}, nil
+}
+
+type generator struct {
+	schemas map[string]interface{}
+}
+
+func (g *generator) operation(name string, method string, tpl string, op Operation, errorSchema interface{}) map[string]interface{} {
+	var params []interface{}
+	for _, match := range pathVar.FindAllStringSubmatch(tpl, -1) {
+		params = append(params, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, p := range op.QueryParams {
+		paramType := p.Type
+		if paramType == "" {
+			paramType = "string"
+		}
+		schema := map[string]interface{}{"type": paramType}
+		if paramType == "integer" {
+			schema["minimum"] = p.Minimum
+		}
+		if p.Format != "" {
+			schema["format"] = p.Format
+		}
+		if len(p.Enum) > 0 {
+			schema["enum"] = p.Enum
+		}
+		params = append(params, map[string]interface{}{
+			"name":        p.Name,
+			"in":          "query",
+			"required":    p.Required,
+			"description": p.Description,
+			"schema":      schema,
+		})
+	}
+
+	contentTypes := op.ContentType
+	if len(contentTypes) == 0 {
+		contentTypes = []string{"application/json"}
+	}
+	success := map[string]interface{}{"description": "Successful response"}
+	if op.Response != nil {
+		content := make(map[string]interface{})
+		schema := g.schema(reflect.TypeOf(op.Response))
+		for _, contentType := range contentTypes {
+			content[contentType] = map[string]interface{}{"schema": schema}
+		}
+		success["content"] = content
+	}
+	errorContent := map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": errorSchema},
+	}
+
+	operation := map[string]interface{}{
+		"responses": map[string]interface{}{
+			"200":     success,
+			"default": map[string]interface{}{"description": "Error", "content": errorContent},
+		},
+	}
+	if name != "" {
+		operation["operationId"] = name + method[:1] + strings.ToLower(method[1:])
+	}
+	if op.Summary != "" {
+		operation["summary"] = op.Summary
+	}
+	if op.Description != "" {
+		operation["description"] = op.Description
+	}
+	if len(params) > 0 {
+		operation["parameters"] = params
+	}
+	if op.RequestBody != nil && method != http.MethodGet {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": g.schema(reflect.TypeOf(op.RequestBody))},
+			},
+		}
+	}
+	return operation
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// schema returns the JSON schema of t, registering named structs as components.
+func (g *generator) schema(t reflect.Type) interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Implements(textMarshalerType):
+		// such as decimals, encoded as JSON strings
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		if _, ok := g.schemas[t.Name()]; !ok {
+			// reserve the name first so recursive types terminate
+			g.schemas[t.Name()] = nil
+			g.schemas[t.Name()] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": fmt.Sprintf("#/components/schemas/%s", t.Name())}
+	}
+	return g.inlineSchema(t)
+}
+
+func (g *generator) inlineSchema(t reflect.Type) interface{} {
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+	return map[string]interface{}{}
+}
+
+func (g *generator) structSchema(t reflect.Type) interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	g.addFields(t, properties, &required)
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addFields adds the JSON fields of t, flattening embedded structs like encoding/json.
+func (g *generator) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, tagged := field.Tag.Lookup("json")
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && (!tagged || strings.Split(tag, ",")[0] == "") {
+			g.addFields(field.Type, properties, required)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		omitEmpty, asString := false, false
+		if tagged {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				switch opt {
+				case "omitempty":
+					omitEmpty = true
+				case "string":
+					asString = true
+				}
+			}
+		}
+		if asString {
+			properties[name] = map[string]interface{}{"type": "string"}
+		} else {
+			properties[name] = g.schema(field.Type)
+		}
+		if !omitEmpty && field.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,506 @@
+// Package papertrading simulates the trading account of the exchange: orders
+// are filled against the live tickers and settled in virtual balances, so
+// strategies can be tested without real funds.
+package papertrading
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+// The errors mimic the ones of the exchange so the handlers treat them alike.
+var (
+	errInsufficientFunds = &wsclient.APIError{StatusCode: http.StatusBadRequest, Code: 20001, Message: "Insufficient funds"}
+	errOrderNotFound     = &wsclient.APIError{StatusCode: http.StatusBadRequest, Code: 20002, Message: "Order not found"}
+	errDuplicateOrder    = &wsclient.APIError{StatusCode: http.StatusBadRequest, Code: 20008, Message: "Duplicate clientOrderId"}
+	errUnknownSymbol     = &wsclient.APIError{StatusCode: http.StatusBadRequest, Code: 2001, Message: "Symbol not found"}
+	errNoPrice           = &wsclient.APIError{StatusCode: http.StatusBadRequest, Code: 20010, Message: "No price", Description: "no ticker received for the symbol yet"}
+	errUnsupported       = &wsclient.APIError{StatusCode: http.StatusBadRequest, Code: 20000, Message: "Not supported in paper trading"}
+)
+
+// order is an active order and the funds it holds.
+type order struct {
+	wsclient.Order
+	// reserved is held in the quote currency for buys, the base currency
+	// for sells.
+	reserved  decimal.Decimal
+	triggered bool
+}
+
+// Engine is a simulated trading account. Market orders and marketable limit
+// orders fill at the best ask or bid of the last ticker as taker, resting
+// limit orders fill at their price as maker once the ticker crosses it.
+// Quantities are filled at once, the order book depth isn't simulated. Fees
+// are charged in the quote currency at the rates of the symbol.
+type Engine struct {
+	mutex   sync.Mutex
+	symbol  func(symbol string) (wsclient.Symbol, bool)
+	tickers map[string]wsclient.Ticker
+	trading map[string]*wsclient.Balance
+	account map[string]*wsclient.Balance
+	active  []*order
+	closed  []wsclient.Order
+	trades  []wsclient.AccountTrade
+	nextID  int64
+}
+
+// NewEngine creates an Engine trading the symbols returned by symbol, which
+// is called when the orders are placed so the symbols may be loaded later.
+// balances are the initial available funds of the trading account.
+func NewEngine(symbol func(symbol string) (wsclient.Symbol, bool), balances map[string]decimal.Decimal) *Engine {
+	e := &Engine{
+		symbol:  symbol,
+		tickers: make(map[string]wsclient.Ticker),
+		trading: make(map[string]*wsclient.Balance),
+		account: make(map[string]*wsclient.Balance),
+	}
+	for currency, amount := range balances {
+		e.balance(e.trading, currency).Available = amount
+	}
+	return e
+}
+
+func (e *Engine) balance(balances map[string]*wsclient.Balance, currency string) *wsclient.Balance {
+	balance, ok := balances[currency]
+	if !ok {
+		balance = &wsclient.Balance{Currency: currency}
+		balances[currency] = balance
+	}
+	return balance
+}
+
+// Update fills the active orders of the ticker's symbol that it crosses, and
+// expires the GTD and Day orders that are over. It is registered as a ticker
+// listener.
+func (e *Engine) Update(ticker *wsclient.Ticker) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.tickers[ticker.Symbol] = *ticker
+	now := time.Now()
+	active := e.active[:0]
+	for _, o := range e.active {
+		if o.Symbol == ticker.Symbol || e.expired(o, now) {
+			e.process(o, *ticker, now)
+		}
+		if o.Status == "new" {
+			active = append(active, o)
+		} else {
+			e.closed = append(e.closed, o.Order)
+		}
+	}
+	e.active = active
+}
+
+func (e *Engine) expired(o *order, now time.Time) bool {
+	switch o.TimeInForce {
+	case "GTD":
+		return o.ExpireTime != nil && now.After(*o.ExpireTime)
+	case "Day":
+		created := o.CreatedAt.UTC()
+		return now.UTC().After(time.Date(created.Year(), created.Month(), created.Day()+1, 0, 0, 0, 0, time.UTC))
+	}
+	return false
+}
+
+// process triggers, fills or expires an active order on a ticker update.
+func (e *Engine) process(o *order, ticker wsclient.Ticker, now time.Time) {
+	if e.expired(o, now) {
+		e.close(o, "expired", now)
+		return
+	}
+	if ticker.Symbol != o.Symbol {
+		return
+	}
+	if !o.triggered {
+		if o.Side == "buy" && ticker.Last.LessThan(*o.StopPrice) || o.Side == "sell" && ticker.Last.GreaterThan(*o.StopPrice) {
+			return
+		}
+		o.triggered = true
+		if o.Type == "stopMarket" || e.marketable(o, ticker) {
+			e.take(o, ticker, now)
+		}
+		return
+	}
+	if e.marketable(o, ticker) {
+		// resting orders are makers, they fill at their price
+		e.fill(o, o.Price, false, now)
+	}
+}
+
+// marketable reports whether a limit order crosses the best price of the
+// ticker.
+func (e *Engine) marketable(o *order, ticker wsclient.Ticker) bool {
+	if o.Side == "buy" {
+		return ticker.Ask.IsPositive() && ticker.Ask.LessThanOrEqual(o.Price)
+	}
+	return ticker.Bid.IsPositive() && ticker.Bid.GreaterThanOrEqual(o.Price)
+}
+
+// take fills an order at the best price of the ticker as taker.
+func (e *Engine) take(o *order, ticker wsclient.Ticker, now time.Time) {
+	price := ticker.Bid
+	if o.Side == "buy" {
+		price = ticker.Ask
+	}
+	if !price.IsPositive() {
+		e.close(o, "expired", now)
+		return
+	}
+	e.fill(o, price, true, now)
+}
+
+// fill settles the whole order at price. Buys whose cost outgrew the funds
+// they hold, like stop market orders, take the difference from the
+// available balance or expire.
+func (e *Engine) fill(o *order, price decimal.Decimal, taker bool, now time.Time) {
+	symbol, _ := e.symbol(o.Symbol)
+	rate := symbol.ProvideLiquidityRate
+	if taker {
+		rate = symbol.TakeLiquidityRate
+	}
+	cost := o.Quantity.Mul(price)
+	fee := cost.Mul(rate)
+	base := e.balance(e.trading, symbol.BaseCurrency)
+	quote := e.balance(e.trading, symbol.QuoteCurrency)
+	if o.Side == "buy" {
+		if cost.Add(fee).GreaterThan(o.reserved.Add(quote.Available)) {
+			e.close(o, "expired", now)
+			return
+		}
+		quote.Reserved = quote.Reserved.Sub(o.reserved)
+		quote.Available = quote.Available.Add(o.reserved).Sub(cost).Sub(fee)
+		base.Available = base.Available.Add(o.Quantity)
+	} else {
+		base.Reserved = base.Reserved.Sub(o.reserved)
+		quote.Available = quote.Available.Add(cost).Sub(fee)
+	}
+	o.reserved = decimal.Zero
+	o.CumQuantity = o.Quantity
+	o.Status = "filled"
+	o.UpdatedAt = now
+	e.nextID++
+	e.trades = append(e.trades, wsclient.AccountTrade{
+		ID:            e.nextID,
+		OrderID:       o.ID,
+		ClientOrderID: o.ClientOrderID,
+		Symbol:        o.Symbol,
+		Side:          o.Side,
+		Quantity:      o.Quantity,
+		Price:         price,
+		Fee:           fee,
+		Taker:         taker,
+		Timestamp:     now,
+	})
+}
+
+// close ends an unfilled order, releasing its funds.
+func (e *Engine) close(o *order, status string, now time.Time) {
+	symbol, _ := e.symbol(o.Symbol)
+	currency := symbol.BaseCurrency
+	if o.Side == "buy" {
+		currency = symbol.QuoteCurrency
+	}
+	balance := e.balance(e.trading, currency)
+	balance.Reserved = balance.Reserved.Sub(o.reserved)
+	balance.Available = balance.Available.Add(o.reserved)
+	o.reserved = decimal.Zero
+	o.Status = status
+	o.UpdatedAt = now
+}
+
+// PlaceOrder places a simulated order, filling it at once when it is a
+// market order or a marketable limit order.
+func (e *Engine) PlaceOrder(ctx context.Context, request wsclient.OrderRequest) (wsclient.Order, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	symbol, ok := e.symbol(request.Symbol)
+	if !ok {
+		return wsclient.Order{}, errUnknownSymbol
+	}
+	if request.ClientOrderID == "" {
+		request.ClientOrderID = newClientOrderID()
+	}
+	for _, active := range e.active {
+		if active.ClientOrderID == request.ClientOrderID {
+			return wsclient.Order{}, errDuplicateOrder
+		}
+	}
+	if request.Type == "" {
+		request.Type = "limit"
+	}
+	if request.TimeInForce == "" {
+		request.TimeInForce = "GTC"
+	}
+	ticker, priced := e.tickers[request.Symbol]
+	market := request.Type == "market" || request.Type == "stopMarket"
+	if market && !priced {
+		return wsclient.Order{}, errNoPrice
+	}
+
+	// buys hold their cost and taker fee, market ones at the current ask
+	reserved := request.Quantity
+	currency := symbol.BaseCurrency
+	if request.Side == "buy" {
+		price := request.Price
+		if market {
+			price = ticker.Ask
+		}
+		reserved = request.Quantity.Mul(price).Mul(decimal.NewFromInt(1).Add(symbol.TakeLiquidityRate))
+		currency = symbol.QuoteCurrency
+	}
+	balance := e.balance(e.trading, currency)
+	if balance.Available.LessThan(reserved) {
+		return wsclient.Order{}, errInsufficientFunds
+	}
+	balance.Available = balance.Available.Sub(reserved)
+	balance.Reserved = balance.Reserved.Add(reserved)
+
+	now := time.Now()
+	e.nextID++
+	o := &order{
+		Order: wsclient.Order{
+			ID:            e.nextID,
+			ClientOrderID: request.ClientOrderID,
+			Symbol:        request.Symbol,
+			Side:          request.Side,
+			Status:        "new",
+			Type:          request.Type,
+			TimeInForce:   request.TimeInForce,
+			Quantity:      request.Quantity,
+			Price:         request.Price,
+			CumQuantity:   decimal.Zero,
+			PostOnly:      request.PostOnly,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		},
+		reserved:  reserved,
+		triggered: request.Type == "limit" || request.Type == "market",
+	}
+	if !o.triggered {
+		stopPrice := request.StopPrice
+		o.StopPrice = &stopPrice
+	}
+	if request.TimeInForce == "GTD" {
+		expireTime := request.ExpireTime
+		o.ExpireTime = &expireTime
+	}
+
+	switch {
+	case request.Type == "market":
+		e.take(o, ticker, now)
+	case request.Type == "limit" && priced && e.marketable(o, ticker):
+		if request.PostOnly {
+			e.close(o, "expired", now)
+		} else {
+			e.take(o, ticker, now)
+		}
+	case request.Type == "limit" && (request.TimeInForce == "IOC" || request.TimeInForce == "FOK"):
+		e.close(o, "expired", now)
+	case priced:
+		e.process(o, ticker, now)
+	}
+	if o.Status == "new" {
+		e.active = append(e.active, o)
+	} else {
+		e.closed = append(e.closed, o.Order)
+	}
+	return o.Order, nil
+}
+
+func newClientOrderID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// GetActiveOrders returns the active orders of symbol, or all of them when
+// symbol is empty.
+func (e *Engine) GetActiveOrders(ctx context.Context, symbol string) ([]wsclient.Order, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	orders := []wsclient.Order{}
+	for _, o := range e.active {
+		if symbol == "" || o.Symbol == symbol {
+			orders = append(orders, o.Order)
+		}
+	}
+	return orders, nil
+}
+
+// CancelOrder cancels an active order by client order id.
+func (e *Engine) CancelOrder(ctx context.Context, clientOrderID string) (wsclient.Order, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for i, o := range e.active {
+		if o.ClientOrderID == clientOrderID {
+			e.close(o, "canceled", time.Now())
+			e.active = append(e.active[:i], e.active[i+1:]...)
+			e.closed = append(e.closed, o.Order)
+			return o.Order, nil
+		}
+	}
+	return wsclient.Order{}, errOrderNotFound
+}
+
+// CancelAllOrders cancels the active orders of symbol, or all of them when
+// symbol is empty.
+func (e *Engine) CancelAllOrders(ctx context.Context, symbol string) ([]wsclient.Order, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	now := time.Now()
+	canceled := []wsclient.Order{}
+	active := e.active[:0]
+	for _, o := range e.active {
+		if symbol != "" && o.Symbol != symbol {
+			active = append(active, o)
+			continue
+		}
+		e.close(o, "canceled", now)
+		e.closed = append(e.closed, o.Order)
+		canceled = append(canceled, o.Order)
+	}
+	e.active = active
+	return canceled, nil
+}
+
+// page applies the limit and offset of filter to n items, newest first.
+func page(filter wsclient.HistoryFilter, n int) (int, int) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+	start := filter.Offset
+	if start > n {
+		start = n
+	}
+	end := start + limit
+	if end > n {
+		end = n
+	}
+	return start, end
+}
+
+func matches(filter wsclient.HistoryFilter, symbol string, at time.Time) bool {
+	if filter.Symbol != "" && symbol != filter.Symbol {
+		return false
+	}
+	if !filter.From.IsZero() && at.Before(filter.From) {
+		return false
+	}
+	return filter.Till.IsZero() || !at.After(filter.Till)
+}
+
+// GetOrderHistory returns the closed orders matching filter, newest first.
+func (e *Engine) GetOrderHistory(ctx context.Context, filter wsclient.HistoryFilter) ([]wsclient.Order, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	orders := []wsclient.Order{}
+	for i := len(e.closed) - 1; i >= 0; i-- {
+		if matches(filter, e.closed[i].Symbol, e.closed[i].UpdatedAt) {
+			orders = append(orders, e.closed[i])
+		}
+	}
+	start, end := page(filter, len(orders))
+	return orders[start:end], nil
+}
+
+// GetTradeHistory returns the simulated trades matching filter, newest first.
+func (e *Engine) GetTradeHistory(ctx context.Context, filter wsclient.HistoryFilter) ([]wsclient.AccountTrade, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	trades := []wsclient.AccountTrade{}
+	for i := len(e.trades) - 1; i >= 0; i-- {
+		if matches(filter, e.trades[i].Symbol, e.trades[i].Timestamp) {
+			trades = append(trades, e.trades[i])
+		}
+	}
+	start, end := page(filter, len(trades))
+	return trades[start:end], nil
+}
+
+// GetTradingFee returns the rates of the symbol.
+func (e *Engine) GetTradingFee(ctx context.Context, symbol string) (wsclient.TradingFee, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	s, ok := e.symbol(symbol)
+	if !ok {
+		return wsclient.TradingFee{}, errUnknownSymbol
+	}
+	return wsclient.TradingFee{TakeLiquidityRate: s.TakeLiquidityRate, ProvideLiquidityRate: s.ProvideLiquidityRate}, nil
+}
+
+func (e *Engine) balances(balances map[string]*wsclient.Balance) []wsclient.Balance {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	list := make([]wsclient.Balance, 0, len(balances))
+	for _, balance := range balances {
+		list = append(list, *balance)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Currency < list[j].Currency })
+	return list
+}
+
+// GetTradingBalance returns the virtual balances of the trading account.
+func (e *Engine) GetTradingBalance(ctx context.Context) ([]wsclient.Balance, error) {
+	return e.balances(e.trading), nil
+}
+
+// GetAccountBalance returns the virtual balances of the main account, which
+// only holds the funds transferred to it.
+func (e *Engine) GetAccountBalance(ctx context.Context) ([]wsclient.Balance, error) {
+	return e.balances(e.account), nil
+}
+
+// Transfer moves virtual funds between the main and trading accounts.
+func (e *Engine) Transfer(ctx context.Context, currency string, amount decimal.Decimal, transferType string) (wsclient.Transfer, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	from, to := e.account, e.trading
+	if transferType == wsclient.TransferExchangeToBank {
+		from, to = e.trading, e.account
+	}
+	source := e.balance(from, currency)
+	if source.Available.LessThan(amount) {
+		return wsclient.Transfer{}, errInsufficientFunds
+	}
+	source.Available = source.Available.Sub(amount)
+	target := e.balance(to, currency)
+	target.Available = target.Available.Add(amount)
+	return wsclient.Transfer{ID: newClientOrderID()}, nil
+}
+
+// GetDepositAddress isn't supported, funds can't leave the simulation.
+func (e *Engine) GetDepositAddress(ctx context.Context, currency string) (wsclient.DepositAddress, error) {
+	return wsclient.DepositAddress{}, errUnsupported
+}
+
+// NewDepositAddress isn't supported.
+func (e *Engine) NewDepositAddress(ctx context.Context, currency string) (wsclient.DepositAddress, error) {
+	return wsclient.DepositAddress{}, errUnsupported
+}
+
+// Withdraw isn't supported.
+func (e *Engine) Withdraw(ctx context.Context, request wsclient.WithdrawRequest) (wsclient.Withdraw, error) {
+	return wsclient.Withdraw{}, errUnsupported
+}
+
+// CommitWithdraw isn't supported.
+func (e *Engine) CommitWithdraw(ctx context.Context, id string) error {
+	return errUnsupported
+}
+
+// RollbackWithdraw isn't supported.
+func (e *Engine) RollbackWithdraw(ctx context.Context, id string) error {
+	return errUnsupported
+}
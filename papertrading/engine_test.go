@@ -0,0 +1,368 @@
+package papertrading
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+var testSymbols = map[string]wsclient.Symbol{
+	"BTCUSD": {
+		Id: "BTCUSD", BaseCurrency: "BTC", QuoteCurrency: "USD",
+		TakeLiquidityRate: decimal.RequireFromString("0.01"), ProvideLiquidityRate: decimal.RequireFromString("0.005"),
+	},
+	"ETHBTC": {Id: "ETHBTC", BaseCurrency: "ETH", QuoteCurrency: "BTC"},
+}
+
+func lookupSymbol(symbol string) (wsclient.Symbol, bool) {
+	s, ok := testSymbols[symbol]
+	return s, ok
+}
+
+func ticker(symbol, bid, ask, last string) *wsclient.Ticker {
+	return &wsclient.Ticker{
+		Symbol: symbol,
+		Bid:    decimal.RequireFromString(bid),
+		Ask:    decimal.RequireFromString(ask),
+		Last:   decimal.RequireFromString(last),
+	}
+}
+
+// newTestEngine returns an engine holding 100000 USD and 10 BTC, with
+// BTCUSD bid at 99, asked at 101 and last traded at 100.
+func newTestEngine() *Engine {
+	e := NewEngine(lookupSymbol, map[string]decimal.Decimal{
+		"USD": decimal.NewFromInt(100000),
+		"BTC": decimal.NewFromInt(10),
+	})
+	e.Update(ticker("BTCUSD", "99", "101", "100"))
+	return e
+}
+
+// balances returns the trading balances of e by currency.
+func balances(t *testing.T, e *Engine) map[string]wsclient.Balance {
+	t.Helper()
+	list, err := e.GetTradingBalance(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	balances := make(map[string]wsclient.Balance)
+	for _, balance := range list {
+		if balance.Available.IsNegative() || balance.Reserved.IsNegative() {
+			t.Errorf("%s balance: %s available, %s reserved", balance.Currency, balance.Available, balance.Reserved)
+		}
+		balances[balance.Currency] = balance
+	}
+	return balances
+}
+
+// expire makes the active orders of e due to expire.
+func expire(e *Engine) {
+	past := time.Now().Add(-time.Minute)
+	for _, o := range e.active {
+		o.ExpireTime = &past
+		o.CreatedAt = o.CreatedAt.AddDate(0, 0, -1)
+	}
+}
+
+// TestPlaceOrder places an order of 1 BTC of each type and time in force,
+// feeds it the updates, and checks the order, its trade and that the funds
+// of each currency, available and reserved, are only moved by the trade.
+// Takers pay a fee of 1%, makers of 0.5%.
+func TestPlaceOrder(t *testing.T) {
+	one := decimal.NewFromInt(1)
+	tests := []struct {
+		name    string
+		request wsclient.OrderRequest
+		// age, when set, runs on the engine once the order is placed
+		age     func(e *Engine)
+		updates []*wsclient.Ticker
+		status  string
+		// price is the price of the trade, empty when not filled
+		price string
+		usd   string
+		btc   string
+	}{
+		{
+			name:    "market buy",
+			request: wsclient.OrderRequest{Side: "buy", Type: "market"},
+			status:  "filled", price: "101", usd: "99897.99", btc: "11",
+		},
+		{
+			name:    "market sell",
+			request: wsclient.OrderRequest{Side: "sell", Type: "market"},
+			status:  "filled", price: "99", usd: "100098.01", btc: "9",
+		},
+		{
+			name:    "marketable limit buy",
+			request: wsclient.OrderRequest{Side: "buy", Price: decimal.NewFromInt(105)},
+			status:  "filled", price: "101", usd: "99897.99", btc: "11",
+		},
+		{
+			name:    "resting limit buy",
+			request: wsclient.OrderRequest{Side: "buy", Price: decimal.NewFromInt(95)},
+			status:  "new", usd: "100000", btc: "10",
+		},
+		{
+			name:    "limit buy crossed",
+			request: wsclient.OrderRequest{Side: "buy", Price: decimal.NewFromInt(95)},
+			updates: []*wsclient.Ticker{ticker("BTCUSD", "94", "95", "95")},
+			status:  "filled", price: "95", usd: "99904.525", btc: "11",
+		},
+		{
+			name:    "limit sell crossed",
+			request: wsclient.OrderRequest{Side: "sell", Price: decimal.NewFromInt(105)},
+			updates: []*wsclient.Ticker{ticker("BTCUSD", "106", "107", "106")},
+			status:  "filled", price: "105", usd: "100104.475", btc: "9",
+		},
+		{
+			name:    "marketable post only",
+			request: wsclient.OrderRequest{Side: "buy", Price: decimal.NewFromInt(105), PostOnly: true},
+			status:  "expired", usd: "100000", btc: "10",
+		},
+		{
+			name:    "resting post only",
+			request: wsclient.OrderRequest{Side: "buy", Price: decimal.NewFromInt(95), PostOnly: true},
+			status:  "new", usd: "100000", btc: "10",
+		},
+		{
+			name:    "marketable IOC",
+			request: wsclient.OrderRequest{Side: "buy", Price: decimal.NewFromInt(105), TimeInForce: "IOC"},
+			status:  "filled", price: "101", usd: "99897.99", btc: "11",
+		},
+		{
+			name:    "unmarketable IOC",
+			request: wsclient.OrderRequest{Side: "buy", Price: decimal.NewFromInt(95), TimeInForce: "IOC"},
+			status:  "expired", usd: "100000", btc: "10",
+		},
+		{
+			name:    "marketable FOK",
+			request: wsclient.OrderRequest{Side: "sell", Price: decimal.NewFromInt(95), TimeInForce: "FOK"},
+			status:  "filled", price: "99", usd: "100098.01", btc: "9",
+		},
+		{
+			name:    "unmarketable FOK",
+			request: wsclient.OrderRequest{Side: "sell", Price: decimal.NewFromInt(105), TimeInForce: "FOK"},
+			status:  "expired", usd: "100000", btc: "10",
+		},
+		{
+			name:    "stop limit buy untriggered",
+			request: wsclient.OrderRequest{Side: "buy", Type: "stopLimit", StopPrice: decimal.NewFromInt(110), Price: decimal.NewFromInt(112)},
+			updates: []*wsclient.Ticker{ticker("BTCUSD", "108", "110", "109")},
+			status:  "new", usd: "100000", btc: "10",
+		},
+		{
+			name:    "stop limit buy triggered",
+			request: wsclient.OrderRequest{Side: "buy", Type: "stopLimit", StopPrice: decimal.NewFromInt(110), Price: decimal.NewFromInt(112)},
+			updates: []*wsclient.Ticker{ticker("BTCUSD", "109", "111", "110")},
+			status:  "filled", price: "111", usd: "99887.89", btc: "11",
+		},
+		{
+			name:    "stop limit sell triggered then crossed",
+			request: wsclient.OrderRequest{Side: "sell", Type: "stopLimit", StopPrice: decimal.NewFromInt(90), Price: decimal.NewFromInt(95)},
+			updates: []*wsclient.Ticker{ticker("BTCUSD", "89", "91", "90"), ticker("BTCUSD", "96", "97", "96")},
+			status:  "filled", price: "95", usd: "100094.525", btc: "9",
+		},
+		{
+			// the order holds the cost at the ask of 101 when placed, the
+			// rest is taken from the available funds
+			name:    "stop market buy triggered",
+			request: wsclient.OrderRequest{Side: "buy", Type: "stopMarket", StopPrice: decimal.NewFromInt(110)},
+			updates: []*wsclient.Ticker{ticker("BTCUSD", "109", "111", "110")},
+			status:  "filled", price: "111", usd: "99887.89", btc: "11",
+		},
+		{
+			name:    "stop market sell triggered",
+			request: wsclient.OrderRequest{Side: "sell", Type: "stopMarket", StopPrice: decimal.NewFromInt(90)},
+			updates: []*wsclient.Ticker{ticker("BTCUSD", "89", "91", "90")},
+			status:  "filled", price: "89", usd: "100088.11", btc: "9",
+		},
+		{
+			name:    "GTD expired",
+			request: wsclient.OrderRequest{Side: "buy", Price: decimal.NewFromInt(95), TimeInForce: "GTD", ExpireTime: time.Now().Add(time.Hour)},
+			age:     expire,
+			updates: []*wsclient.Ticker{ticker("BTCUSD", "98", "100", "99")},
+			status:  "expired", usd: "100000", btc: "10",
+		},
+		{
+			name:    "GTD expired by another symbol",
+			request: wsclient.OrderRequest{Side: "sell", Price: decimal.NewFromInt(105), TimeInForce: "GTD", ExpireTime: time.Now().Add(time.Hour)},
+			age:     expire,
+			updates: []*wsclient.Ticker{ticker("ETHBTC", "0.04", "0.06", "0.05")},
+			status:  "expired", usd: "100000", btc: "10",
+		},
+		{
+			name:    "GTD pending",
+			request: wsclient.OrderRequest{Side: "buy", Price: decimal.NewFromInt(95), TimeInForce: "GTD", ExpireTime: time.Now().Add(time.Hour)},
+			updates: []*wsclient.Ticker{ticker("BTCUSD", "98", "100", "99")},
+			status:  "new", usd: "100000", btc: "10",
+		},
+		{
+			name:    "Day expired",
+			request: wsclient.OrderRequest{Side: "buy", Price: decimal.NewFromInt(95), TimeInForce: "Day"},
+			age:     expire,
+			updates: []*wsclient.Ticker{ticker("BTCUSD", "98", "100", "99")},
+			status:  "expired", usd: "100000", btc: "10",
+		},
+		{
+			name:    "Day expired before its cross",
+			request: wsclient.OrderRequest{Side: "buy", Price: decimal.NewFromInt(95), TimeInForce: "Day"},
+			age:     expire,
+			updates: []*wsclient.Ticker{ticker("BTCUSD", "94", "95", "95")},
+			status:  "expired", usd: "100000", btc: "10",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newTestEngine()
+			test.request.Symbol = "BTCUSD"
+			test.request.Quantity = one
+			placed, err := e.PlaceOrder(context.Background(), test.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.age != nil {
+				test.age(e)
+			}
+			for _, update := range test.updates {
+				e.Update(update)
+			}
+
+			var status string
+			active, _ := e.GetActiveOrders(context.Background(), "")
+			history, _ := e.GetOrderHistory(context.Background(), wsclient.HistoryFilter{})
+			switch {
+			case len(active) == 1 && len(history) == 0:
+				status = active[0].Status
+			case len(active) == 0 && len(history) == 1:
+				status = history[0].Status
+			default:
+				t.Fatalf("%d active and %d closed orders, want the order in either", len(active), len(history))
+			}
+			if status != test.status {
+				t.Errorf("status %q, want %q", status, test.status)
+			}
+
+			trades, _ := e.GetTradeHistory(context.Background(), wsclient.HistoryFilter{})
+			if test.price == "" {
+				if len(trades) != 0 {
+					t.Errorf("%d trades, want none", len(trades))
+				}
+			} else if len(trades) != 1 {
+				t.Errorf("%d trades, want 1", len(trades))
+			} else if trade := trades[0]; trade.OrderID != placed.ID || !trade.Quantity.Equal(one) || !trade.Price.Equal(decimal.RequireFromString(test.price)) {
+				t.Errorf("trade of order %d: %s at %s, want order %d, 1 at %s", trade.OrderID, trade.Quantity, trade.Price, placed.ID, test.price)
+			}
+
+			for currency, want := range map[string]string{"USD": test.usd, "BTC": test.btc} {
+				balance := balances(t, e)[currency]
+				if total := balance.Available.Add(balance.Reserved); !total.Equal(decimal.RequireFromString(want)) {
+					t.Errorf("%s: %s available + %s reserved, want %s", currency, balance.Available, balance.Reserved, want)
+				}
+				if status != "new" && !balance.Reserved.IsZero() {
+					t.Errorf("%s: %s reserved by the closed order", currency, balance.Reserved)
+				}
+			}
+		})
+	}
+}
+
+// TestPlaceOrderReserve checks the funds held by resting orders.
+func TestPlaceOrderReserve(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  wsclient.OrderRequest
+		currency string
+		reserved string
+	}{
+		// buys hold their cost and the taker fee
+		{"limit buy", wsclient.OrderRequest{Side: "buy", Price: decimal.NewFromInt(95)}, "USD", "95.95"},
+		{"stop limit buy", wsclient.OrderRequest{Side: "buy", Type: "stopLimit", StopPrice: decimal.NewFromInt(110), Price: decimal.NewFromInt(112)}, "USD", "113.12"},
+		{"stop market buy", wsclient.OrderRequest{Side: "buy", Type: "stopMarket", StopPrice: decimal.NewFromInt(110)}, "USD", "102.01"},
+		// sells hold their quantity
+		{"limit sell", wsclient.OrderRequest{Side: "sell", Price: decimal.NewFromInt(105)}, "BTC", "1"},
+		{"stop market sell", wsclient.OrderRequest{Side: "sell", Type: "stopMarket", StopPrice: decimal.NewFromInt(90)}, "BTC", "1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newTestEngine()
+			test.request.Symbol = "BTCUSD"
+			test.request.Quantity = decimal.NewFromInt(1)
+			placed, err := e.PlaceOrder(context.Background(), test.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if balance := balances(t, e)[test.currency]; !balance.Reserved.Equal(decimal.RequireFromString(test.reserved)) {
+				t.Errorf("%s reserved %s, want %s", test.currency, balance.Reserved, test.reserved)
+			}
+
+			if _, err := e.CancelOrder(context.Background(), placed.ClientOrderID); err != nil {
+				t.Fatal(err)
+			}
+			after := balances(t, e)
+			if !after["USD"].Available.Equal(decimal.NewFromInt(100000)) || !after["BTC"].Available.Equal(decimal.NewFromInt(10)) {
+				t.Errorf("after the cancel: %s USD and %s BTC available, want the funds released", after["USD"].Available, after["BTC"].Available)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderErrors(t *testing.T) {
+	e := newTestEngine()
+	tests := []struct {
+		name    string
+		request wsclient.OrderRequest
+		want    error
+	}{
+		{"unknown symbol", wsclient.OrderRequest{Symbol: "XYZUSD", Side: "buy", Quantity: decimal.NewFromInt(1), Price: decimal.NewFromInt(1)}, errUnknownSymbol},
+		{"no price", wsclient.OrderRequest{Symbol: "ETHBTC", Side: "buy", Type: "market", Quantity: decimal.NewFromInt(1)}, errNoPrice},
+		{"insufficient quote", wsclient.OrderRequest{Symbol: "BTCUSD", Side: "buy", Quantity: decimal.NewFromInt(1000), Price: decimal.NewFromInt(100)}, errInsufficientFunds},
+		{"insufficient base", wsclient.OrderRequest{Symbol: "BTCUSD", Side: "sell", Quantity: decimal.NewFromInt(11), Price: decimal.NewFromInt(100)}, errInsufficientFunds},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := e.PlaceOrder(context.Background(), test.request); !errors.Is(err, test.want) {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+
+	request := wsclient.OrderRequest{ClientOrderID: "order", Symbol: "BTCUSD", Side: "buy", Quantity: decimal.NewFromInt(1), Price: decimal.NewFromInt(95)}
+	if _, err := e.PlaceOrder(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.PlaceOrder(context.Background(), request); !errors.Is(err, errDuplicateOrder) {
+		t.Errorf("same client order id: got %v, want %v", err, errDuplicateOrder)
+	}
+	if balance := balances(t, e)["USD"]; !balance.Available.Add(balance.Reserved).Equal(decimal.NewFromInt(100000)) {
+		t.Errorf("USD: %s available + %s reserved after the rejected orders, want 100000", balance.Available, balance.Reserved)
+	}
+}
+
+// TestStopMarketOutgrowsFunds checks that a stop market buy whose cost
+// outgrew the funds expires, releasing what it held.
+func TestStopMarketOutgrowsFunds(t *testing.T) {
+	e := NewEngine(lookupSymbol, map[string]decimal.Decimal{"USD": decimal.NewFromInt(110)})
+	e.Update(ticker("BTCUSD", "99", "101", "100"))
+	if _, err := e.PlaceOrder(context.Background(), wsclient.OrderRequest{
+		Symbol: "BTCUSD", Side: "buy", Type: "stopMarket", Quantity: decimal.NewFromInt(1), StopPrice: decimal.NewFromInt(110),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	e.Update(ticker("BTCUSD", "119", "120", "120"))
+
+	history, _ := e.GetOrderHistory(context.Background(), wsclient.HistoryFilter{})
+	if len(history) != 1 || history[0].Status != "expired" {
+		t.Fatalf("closed orders %v, want the order expired", history)
+	}
+	if trades, _ := e.GetTradeHistory(context.Background(), wsclient.HistoryFilter{}); len(trades) != 0 {
+		t.Errorf("%d trades, want none", len(trades))
+	}
+	if balance := balances(t, e)["USD"]; !balance.Available.Equal(decimal.NewFromInt(110)) || !balance.Reserved.IsZero() {
+		t.Errorf("USD: %s available, %s reserved, want 110 available", balance.Available, balance.Reserved)
+	}
+}
@@ -0,0 +1,134 @@
+// Package portfolio keeps the holdings valued by the /portfolio endpoints,
+// optionally saved to a JSON file.
+package portfolio
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// Holding is an amount of a currency.
+type Holding struct {
+	Currency string          `json:"currency"`
+	Amount   decimal.Decimal `json:"amount"`
+}
+
+// Portfolio is a set of holdings, one per currency.
+type Portfolio struct {
+	mutex    *sync.Mutex
+	path     string
+	holdings map[string]decimal.Decimal
+}
+
+// New creates an empty Portfolio kept in memory.
+func New() *Portfolio {
+	return &Portfolio{
+		mutex:    &sync.Mutex{},
+		holdings: make(map[string]decimal.Decimal),
+	}
+}
+
+// Open loads the Portfolio saved at path, which is created on the first
+// change when it doesn't exist. Every change is saved to path.
+func Open(path string) (*Portfolio, error) {
+	p := New()
+	p.path = path
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var holdings []Holding
+	if err := json.Unmarshal(data, &holdings); err != nil {
+		return nil, err
+	}
+	for _, holding := range holdings {
+		p.holdings[holding.Currency] = holding.Amount
+	}
+	return p, nil
+}
+
+// Holdings returns the holdings sorted by currency.
+func (p *Portfolio) Holdings() []Holding {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.list()
+}
+
+func (p *Portfolio) list() []Holding {
+	holdings := make([]Holding, 0, len(p.holdings))
+	for currency, amount := range p.holdings {
+		holdings = append(holdings, Holding{Currency: currency, Amount: amount})
+	}
+	sort.Slice(holdings, func(i, j int) bool { return holdings[i].Currency < holdings[j].Currency })
+	return holdings
+}
+
+// Set sets the amount held of currency. The change is undone when it can't
+// be saved.
+func (p *Portfolio) Set(currency string, amount decimal.Decimal) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	previous, existed := p.holdings[currency]
+	p.holdings[currency] = amount
+	if err := p.save(); err != nil {
+		if existed {
+			p.holdings[currency] = previous
+		} else {
+			delete(p.holdings, currency)
+		}
+		return err
+	}
+	return nil
+}
+
+// Remove removes the holding of currency, returning false when there is
+// none.
+func (p *Portfolio) Remove(currency string) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	previous, existed := p.holdings[currency]
+	if !existed {
+		return false, nil
+	}
+	delete(p.holdings, currency)
+	if err := p.save(); err != nil {
+		p.holdings[currency] = previous
+		return true, err
+	}
+	return true, nil
+}
+
+// save writes the holdings to a temporary file renamed over path, so a
+// crash never leaves a truncated file.
+func (p *Portfolio) save() error {
+	if p.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(p.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(p.path), filepath.Base(p.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p.path)
+}
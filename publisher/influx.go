@@ -0,0 +1,158 @@
+package publisher
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+var tagEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+// InfluxConfig configures the InfluxDB writer. Bucket and Org select the v2
+// write API, Database the v1 one.
+type InfluxConfig struct {
+	URL           string
+	Token         string
+	Org           string
+	Bucket        string
+	Database      string
+	Measurement   string
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// InfluxPublisher writes ticker points in line protocol, batching them by
+// size and time, e.g.
+//
+//	ticker,symbol=ETHBTC ask=0.0513,bid=0.0512,last=0.0512,volume=1234.5,volumeQuote=63.2 1634567890123
+type InfluxPublisher struct {
+	config     InfluxConfig
+	writeURL   string
+	httpClient *http.Client
+
+	mutex *sync.Mutex
+	batch bytes.Buffer
+	lines int
+	done  chan struct{}
+}
+
+// NewInfluxPublisher creates the writer and starts its flush loop.
+func NewInfluxPublisher(config InfluxConfig) (*InfluxPublisher, error) {
+	base, err := url.Parse(strings.TrimSuffix(config.URL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"precision": {"ms"}}
+	if config.Bucket != "" {
+		base.Path += "/api/v2/write"
+		query.Set("org", config.Org)
+		query.Set("bucket", config.Bucket)
+	} else if config.Database != "" {
+		base.Path += "/write"
+		query.Set("db", config.Database)
+	} else {
+		return nil, fmt.Errorf("influx: a bucket or a database is required")
+	}
+	base.RawQuery = query.Encode()
+	if config.Measurement == "" {
+		config.Measurement = "ticker"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	p := &InfluxPublisher{
+		config:     config,
+		writeURL:   base.String(),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		mutex:      &sync.Mutex{},
+		done:       make(chan struct{}),
+	}
+	go p.flushLoop()
+	return p, nil
+}
+
+// Publish implements Publisher. The point is buffered until the next flush.
+func (p *InfluxPublisher) Publish(ticker *wsclient.Ticker) error {
+	at := ticker.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	p.mutex.Lock()
+	fmt.Fprintf(&p.batch, "%s,symbol=%s ask=%s,bid=%s,last=%s,open=%s,low=%s,high=%s,volume=%s,volumeQuote=%s %d\n",
+		tagEscaper.Replace(p.config.Measurement), tagEscaper.Replace(ticker.Symbol),
+		ticker.Ask, ticker.Bid, ticker.Last, ticker.Open, ticker.Low, ticker.High,
+		ticker.Volume, ticker.VolumeQuote,
+		at.UnixNano()/int64(time.Millisecond))
+	p.lines++
+	full := p.lines >= p.config.BatchSize
+	p.mutex.Unlock()
+	if full {
+		return p.flush()
+	}
+	return nil
+}
+
+func (p *InfluxPublisher) flushLoop() {
+	ticker := time.NewTicker(p.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.flush(); err != nil {
+				// the points are dropped, the next batch is unaffected
+				log.Printf("influx publisher: %v", err)
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// flush writes the buffered points.
+func (p *InfluxPublisher) flush() error {
+	p.mutex.Lock()
+	if p.lines == 0 {
+		p.mutex.Unlock()
+		return nil
+	}
+	body := append([]byte(nil), p.batch.Bytes()...)
+	p.batch.Reset()
+	p.lines = 0
+	p.mutex.Unlock()
+
+	req, err := http.NewRequest(http.MethodPost, p.writeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if p.config.Token != "" {
+		req.Header.Set("Authorization", "Token "+p.config.Token)
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("influx write: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Close implements Publisher, flushing the buffered points.
+func (p *InfluxPublisher) Close() error {
+	close(p.done)
+	return p.flush()
+}
@@ -0,0 +1,46 @@
+package publisher
+
+import (
+	"context"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaPublisher writes ticker updates as JSON to a Kafka topic, keyed by
+// symbol so every update of a market lands on the same partition.
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher creates a publisher for topic on the given brokers.
+func NewKafkaPublisher(brokers []string, topic string) *KafkaPublisher {
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: 50 * time.Millisecond,
+			RequiredAcks: kafka.RequireOne,
+		},
+	}
+}
+
+// Publish implements Publisher.
+func (p *KafkaPublisher) Publish(ticker *wsclient.Ticker) error {
+	value, err := marshalTicker(ticker)
+	if err != nil {
+		return err
+	}
+	return p.writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   []byte(ticker.Symbol),
+		Value: value,
+		Time:  ticker.Timestamp,
+	})
+}
+
+// Close implements Publisher.
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
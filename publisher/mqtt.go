@@ -0,0 +1,64 @@
+package publisher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const mqttPublishTimeout = 5 * time.Second
+
+// MQTTPublisher publishes ticker updates as JSON on "<prefix>/<symbol>"
+// topics. With retain set the broker keeps the last value of every topic, so
+// new subscribers get the current price immediately.
+type MQTTPublisher struct {
+	client mqtt.Client
+	prefix string
+	qos    byte
+	retain bool
+}
+
+// NewMQTTPublisher connects to the broker, e.g. tcp://localhost:1883.
+func NewMQTTPublisher(broker string, topicPrefix string, qos byte, retain bool) (*MQTTPublisher, error) {
+	if qos > 2 {
+		return nil, fmt.Errorf("invalid MQTT QoS %d", qos)
+	}
+	hostname, _ := os.Hostname()
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(fmt.Sprintf("crypto-api-server-%s-%d", hostname, os.Getpid())).
+		SetAutoReconnect(true).
+		SetConnectTimeout(10 * time.Second)
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(10 * time.Second) {
+		return nil, errors.New("timeout connecting to MQTT broker")
+	}
+	if err := token.Error(); err != nil {
+		return nil, err
+	}
+	return &MQTTPublisher{client: client, prefix: topicPrefix, qos: qos, retain: retain}, nil
+}
+
+// Publish implements Publisher.
+func (p *MQTTPublisher) Publish(ticker *wsclient.Ticker) error {
+	payload, err := marshalTicker(ticker)
+	if err != nil {
+		return err
+	}
+	token := p.client.Publish(p.prefix+"/"+ticker.Symbol, p.qos, p.retain, payload)
+	if !token.WaitTimeout(mqttPublishTimeout) {
+		return errors.New("timeout publishing to MQTT broker")
+	}
+	return token.Error()
+}
+
+// Close implements Publisher.
+func (p *MQTTPublisher) Close() error {
+	p.client.Disconnect(250)
+	return nil
+}
@@ -0,0 +1,36 @@
+package publisher
+
+import (
+	"github.com/crypto-api-server/wsclient"
+	"github.com/nats-io/nats.go"
+)
+
+// NATSPublisher publishes ticker updates as JSON on "<prefix>.<symbol>"
+// subjects, e.g. ticker.ETHBTC.
+type NATSPublisher struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+// NewNATSPublisher connects to the NATS server at url.
+func NewNATSPublisher(url string, subjectPrefix string) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("crypto-api-server"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	return &NATSPublisher{conn: conn, prefix: subjectPrefix}, nil
+}
+
+// Publish implements Publisher.
+func (p *NATSPublisher) Publish(ticker *wsclient.Ticker) error {
+	data, err := marshalTicker(ticker)
+	if err != nil {
+		return err
+	}
+	return p.conn.Publish(p.prefix+"."+ticker.Symbol, data)
+}
+
+// Close implements Publisher. Pending messages are flushed before closing.
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
@@ -0,0 +1,77 @@
+// Package publisher forwards ticker updates to external message brokers.
+package publisher
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// Publisher sends ticker updates to a broker.
+type Publisher interface {
+	Publish(ticker *wsclient.Ticker) error
+	Close() error
+}
+
+// Queue decouples a Publisher from the feed goroutine: updates are buffered
+// and published in the background, and dropped when the buffer is full.
+type Queue struct {
+	name      string
+	publisher Publisher
+	updates   chan *wsclient.Ticker
+	done      chan struct{}
+	// mutex guards closed, the updates notified after Close are dropped
+	mutex  sync.RWMutex
+	closed bool
+}
+
+// NewQueue starts publishing updates to p from a buffer of size updates.
+func NewQueue(name string, p Publisher, size int) *Queue {
+	q := &Queue{
+		name:      name,
+		publisher: p,
+		updates:   make(chan *wsclient.Ticker, size),
+		done:      make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+func (q *Queue) run() {
+	defer close(q.done)
+	for ticker := range q.updates {
+		if err := q.publisher.Publish(ticker); err != nil {
+			log.Printf("%s publisher: %s: %v", q.name, ticker.Symbol, err)
+		}
+	}
+}
+
+// Notify queues the ticker without blocking.
+func (q *Queue) Notify(ticker *wsclient.Ticker) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+	if q.closed {
+		return
+	}
+	select {
+	case q.updates <- ticker:
+	default:
+		log.Printf("%s publisher: queue full, dropping %s update", q.name, ticker.Symbol)
+	}
+}
+
+// Close publishes the buffered updates and closes the publisher.
+func (q *Queue) Close() error {
+	q.mutex.Lock()
+	q.closed = true
+	close(q.updates)
+	q.mutex.Unlock()
+	<-q.done
+	return q.publisher.Close()
+}
+
+func marshalTicker(ticker *wsclient.Ticker) ([]byte, error) {
+	return json.Marshal(ticker)
+}
@@ -0,0 +1,43 @@
+package publisher
+
+import (
+	"context"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisPublisher PUBLISHes ticker updates as JSON on "<prefix><symbol>"
+// channels, e.g. ticker:ETHBTC, so other processes can react to cache updates.
+type RedisPublisher struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisPublisher connects to the server at url, e.g. redis://localhost:6379/0.
+func NewRedisPublisher(url string, channelPrefix string) (*RedisPublisher, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	if err = client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &RedisPublisher{client: client, prefix: channelPrefix}, nil
+}
+
+// Publish implements Publisher.
+func (p *RedisPublisher) Publish(ticker *wsclient.Ticker) error {
+	message, err := marshalTicker(ticker)
+	if err != nil {
+		return err
+	}
+	return p.client.Publish(context.Background(), p.prefix+ticker.Symbol, message).Err()
+}
+
+// Close implements Publisher.
+func (p *RedisPublisher) Close() error {
+	return p.client.Close()
+}
@@ -0,0 +1,181 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnknownJob is returned by Trigger for a job that isn't scheduled.
+var ErrUnknownJob = errors.New("unknown job")
+
+// ErrJobRunning is returned by Trigger while the job runs.
+var ErrJobRunning = errors.New("job is running")
+
+// Func is the task of a job. The context is canceled after the timeout of
+// the job.
+type Func func(ctx context.Context) error
+
+// JobStatus is the state of a scheduled job.
+type JobStatus struct {
+	Name     string     `json:"name"`
+	Schedule string     `json:"schedule"`
+	Running  bool       `json:"running"`
+	NextRun  *time.Time `json:"nextRun,omitempty"`
+	LastRun  *time.Time `json:"lastRun,omitempty"`
+	// LastDuration is the duration of the last run in seconds
+	LastDuration float64 `json:"lastDuration"`
+	LastError    string  `json:"lastError,omitempty"`
+	Runs         int     `json:"runs"`
+	Failures     int     `json:"failures"`
+}
+
+type job struct {
+	status   JobStatus
+	schedule *Schedule
+	run      Func
+	timeout  time.Duration
+}
+
+// Scheduler runs jobs on their schedules. A job never overlaps itself, a
+// run that is due while the previous one is still running is skipped.
+type Scheduler struct {
+	mutex   *sync.Mutex
+	jobs    map[string]*job
+	started bool
+	// ctx is the context given to Start, the runs are canceled when it is
+	// done.
+	ctx context.Context
+}
+
+// New creates an empty Scheduler.
+func New() *Scheduler {
+	return &Scheduler{mutex: &sync.Mutex{}, jobs: make(map[string]*job), ctx: context.Background()}
+}
+
+// Add schedules run as name on spec, see ParseSchedule. Jobs are added
+// before Start.
+func (s *Scheduler) Add(name string, spec string, timeout time.Duration, run Func) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.jobs[name]; ok {
+		return errors.New("job " + name + " is already scheduled")
+	}
+	s.jobs[name] = &job{
+		status:   JobStatus{Name: name, Schedule: spec},
+		schedule: schedule,
+		run:      run,
+		timeout:  timeout,
+	}
+	return nil
+}
+
+// Start runs every job on its schedule in its own goroutine, until ctx is
+// done.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	s.ctx = ctx
+	for _, j := range s.jobs {
+		go s.loop(ctx, j)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	for {
+		s.mutex.Lock()
+		next := j.schedule.Next(time.Now())
+		if next.IsZero() {
+			j.status.NextRun = nil
+			s.mutex.Unlock()
+			log.Printf("scheduler: %s never runs again", j.status.Name)
+			return
+		}
+		j.status.NextRun = &next
+		s.mutex.Unlock()
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if !s.begin(j) {
+			log.Printf("scheduler: %s is still running, skipped", j.status.Name)
+			continue
+		}
+		s.execute(ctx, j)
+	}
+}
+
+// begin marks j running, it returns false when it already is.
+func (s *Scheduler) begin(j *job) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if j.status.Running {
+		return false
+	}
+	j.status.Running = true
+	return true
+}
+
+// execute runs j, which begin marked running, and records the outcome.
+func (s *Scheduler) execute(ctx context.Context, j *job) {
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, j.timeout)
+	err := j.run(ctx)
+	cancel()
+	duration := time.Since(started)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	j.status.Running = false
+	j.status.LastRun = &started
+	j.status.LastDuration = duration.Seconds()
+	j.status.Runs++
+	j.status.LastError = ""
+	if err != nil {
+		j.status.Failures++
+		j.status.LastError = err.Error()
+		log.Printf("scheduler: %s failed after %s: %v", j.status.Name, duration, err)
+	}
+}
+
+// Trigger runs the job name now, outside of its schedule.
+func (s *Scheduler) Trigger(name string) error {
+	s.mutex.Lock()
+	j, ok := s.jobs[name]
+	ctx := s.ctx
+	s.mutex.Unlock()
+	if !ok {
+		return ErrUnknownJob
+	}
+	if !s.begin(j) {
+		return ErrJobRunning
+	}
+	go s.execute(ctx, j)
+	return nil
+}
+
+// Status returns the status of every job, sorted by name.
+func (s *Scheduler) Status() []JobStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		statuses = append(statuses, j.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/crypto-api-server/alerts"
+	"github.com/crypto-api-server/config"
+	"github.com/crypto-api-server/webhooks"
+	"github.com/gorilla/mux"
+)
+
+// requireAdmin rejects requests without the admin bearer token. The admin API
+// is disabled when no admin token is configured. Requests other than GET are
+// recorded in the audit log.
+func (h *HandleRequests) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if h.adminToken == "" {
+			writeError(w, http.StatusForbidden, CodeForbidden, "Admin API is disabled")
+			return
+		}
+		if !h.isAdmin(req) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+			return
+		}
+		if req.Method == http.MethodGet || req.Method == http.MethodHead {
+			next(w, req)
+			return
+		}
+		h.auditRequest(w, req, next)
+	}
+}
+
+// isAdmin tells whether req carries the admin bearer token.
+func (h *HandleRequests) isAdmin(req *http.Request) bool {
+	if h.adminToken == "" {
+		return false
+	}
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1
+}
+
+// registerWebhooks registers the webhooks of the configuration, like the
+// ones created on the admin API.
+func (h *HandleRequests) registerWebhooks(hooks []config.Webhook) {
+	for _, hook := range hooks {
+		_, err := h.Webhooks.Register(webhooks.Webhook{
+			URL:     hook.URL,
+			Symbols: hook.Symbols,
+			Secret:  hook.Secret,
+			Above:   hook.Above,
+			Below:   hook.Below,
+		})
+		if err != nil {
+			log.Printf("webhooks: %s: %v", hook.URL, err)
+		}
+	}
+}
+
+type WebhooksResponse struct {
+	Webhooks []webhooks.Webhook `json:"webhooks"`
+}
+
+func (h *HandleRequests) handleListWebhooks(w http.ResponseWriter, req *http.Request) {
+	responseJSON, err := json.Marshal(&WebhooksResponse{Webhooks: h.Webhooks.List()})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+func (h *HandleRequests) handleCreateWebhook(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, MaxBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, err.Error())
+		return
+	}
+	var hook webhooks.Webhook
+	if err = json.Unmarshal(body, &hook); err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid webhook body")
+		return
+	}
+	for _, symbol := range hook.Symbols {
+		if !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+			writeError(w, http.StatusBadRequest, CodeUnknownSymbol, "Not a valid Symbol: "+symbol)
+			return
+		}
+	}
+	created, err := h.Webhooks.Register(hook)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, err.Error())
+		return
+	}
+	responseJSON, err := json.Marshal(created)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusCreated, responseJSON)
+}
+
+func (h *HandleRequests) handleDeleteWebhook(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+	if !h.Webhooks.Unregister(id) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Webhook not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type AlertRulesResponse struct {
+	Rules []alerts.Rule `json:"rules"`
+}
+
+type AlertRuleRequest struct {
+	Rule string `json:"rule"`
+}
+
+func (h *HandleRequests) handleListAlertRules(w http.ResponseWriter, req *http.Request) {
+	responseJSON, err := json.Marshal(&AlertRulesResponse{Rules: h.Alerts.Rules()})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+func (h *HandleRequests) handleCreateAlertRule(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, MaxBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, err.Error())
+		return
+	}
+	var ruleReq AlertRuleRequest
+	if err = json.Unmarshal(body, &ruleReq); err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid alert rule body")
+		return
+	}
+	parsed, err := alerts.ParseRule(ruleReq.Rule)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, err.Error())
+		return
+	}
+	if !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), parsed.Symbol) {
+		writeError(w, http.StatusBadRequest, CodeUnknownSymbol, "Not a valid Symbol: "+parsed.Symbol)
+		return
+	}
+	rule, err := h.Alerts.AddRule(ruleReq.Rule)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, err.Error())
+		return
+	}
+	responseJSON, err := json.Marshal(rule)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusCreated, responseJSON)
+}
+
+func (h *HandleRequests) handleDeleteAlertRule(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+	if !h.Alerts.RemoveRule(id) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Alert rule not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type CacheEntryResponse struct {
+	Symbol string `json:"symbol"`
+	// Size is the length of the JSON encoding of the ticker, in bytes.
+	Size     int       `json:"size"`
+	CachedAt time.Time `json:"cachedAt"`
+	// AgeSeconds is the time since the ticker was cached.
+	AgeSeconds float64 `json:"ageSeconds"`
+	// DataAgeSeconds is the time since the exchange timestamp of the ticker.
+	DataAgeSeconds float64 `json:"dataAgeSeconds"`
+}
+
+type CacheResponse struct {
+	Count   int                  `json:"count"`
+	Size    int                  `json:"size"`
+	Entries []CacheEntryResponse `json:"entries"`
+}
+
+type CacheFlushResponse struct {
+	Flushed int `json:"flushed"`
+}
+
+func (h *HandleRequests) handleCacheInfo(w http.ResponseWriter, req *http.Request) {
+	now := time.Now()
+	response := CacheResponse{Entries: []CacheEntryResponse{}}
+	for _, entry := range h.HitWrapper.CacheEntries() {
+		encoded, err := json.Marshal(entry.Ticker)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+			return
+		}
+		response.Entries = append(response.Entries, CacheEntryResponse{
+			Symbol:         entry.Symbol,
+			Size:           len(encoded),
+			CachedAt:       entry.CachedAt,
+			AgeSeconds:     now.Sub(entry.CachedAt).Seconds(),
+			DataAgeSeconds: now.Sub(entry.Ticker.Timestamp).Seconds(),
+		})
+		response.Size += len(encoded)
+	}
+	response.Count = len(response.Entries)
+	responseJSON, err := json.Marshal(&response)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+func (h *HandleRequests) handleCacheFlush(w http.ResponseWriter, req *http.Request) {
+	symbol := strings.ToUpper(req.URL.Query().Get("symbol"))
+	flushed := h.HitWrapper.FlushCache(symbol)
+	if symbol != "" && flushed == 0 {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Symbol not cached: "+symbol)
+		return
+	}
+	responseJSON, err := json.Marshal(&CacheFlushResponse{Flushed: flushed})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
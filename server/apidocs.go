@@ -0,0 +1,408 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/crypto-api-server/alerts"
+	"github.com/crypto-api-server/graphql"
+	"github.com/crypto-api-server/openapi"
+	"github.com/crypto-api-server/portfolio"
+	"github.com/crypto-api-server/webhooks"
+	"github.com/crypto-api-server/wsclient"
+)
+
+var apiInfo = openapi.Info{Title: "Crypto API server", Version: "1.0.0"}
+
+var formatParam = openapi.Param{
+	Name:        "format",
+	Description: "Response encoding, overrides the Accept header",
+	Enum:        []string{formatJSON, formatCSV, formatProtobuf, formatMsgpack, formatNDJSON},
+}
+
+var fieldsParam = openapi.Param{
+	Name:        "fields",
+	Description: "Comma separated ticker fields to return, such as symbol,last,volume, for JSON, CSV and NDJSON responses",
+	Format:      "ticker-fields",
+}
+
+var indicatorsParam = openapi.Param{
+	Name:        "indicators",
+	Description: "Comma separated moving averages of the one-minute closes to include, such as sma20,ema50, for JSON responses",
+	Format:      "indicators",
+}
+
+var intervalParam = openapi.Param{
+	Name:        "interval",
+	Description: "Interval of the returns such as 1m or 1h (default 1m), at most half the window",
+	Format:      "duration",
+}
+
+var quoteParam = openapi.Param{
+	Name:        "quote",
+	Description: "Fiat currency such as EUR, GBP or INR to report prices in, using ECB reference rates",
+	Format:      "currency",
+}
+
+var accountHistoryParams = []openapi.Param{
+	{Name: "symbol", Description: "Only list the records of this symbol", Format: "symbol"},
+	{Name: "from", Description: "Start time, RFC 3339 or Unix milliseconds", Format: "timestamp"},
+	{Name: "till", Description: "End time, RFC 3339 or Unix milliseconds", Format: "timestamp"},
+	{Name: "limit", Description: "Maximum number of records (default 100, max 1000)", Type: "integer", Minimum: 1},
+	{Name: "offset", Description: "Number of records to skip", Type: "integer"},
+}
+
+var tickerContentTypes = []string{
+	"application/json", "text/csv", "application/x-protobuf", "application/msgpack", "application/x-ndjson",
+}
+
+// routeDocs documents the routes registered in newRouter, keyed by route name.
+var routeDocs = map[string]openapi.Operation{
+	"currencyAll": {
+		Summary: "List the cached tickers of all supported symbols",
+		QueryParams: []openapi.Param{
+			formatParam, quoteParam, fieldsParam, indicatorsParam,
+			{Name: "baseCurrency", Description: "Only list the markets of this base currency, such as ETH", Format: "currency"},
+			{Name: "quoteCurrency", Description: "Only list the markets quoted in this currency, such as USD", Format: "currency"},
+			{Name: "group_by", Description: "Group the tickers by quote currency, BTC, ETH, USDT and USD first, for JSON responses", Enum: []string{"quote"}},
+		},
+		Response:    Response{},
+		ContentType: tickerContentTypes,
+	},
+	"currencyBySymbol": {
+		Summary:     "Get the ticker of a symbol",
+		QueryParams: []openapi.Param{formatParam, quoteParam, fieldsParam, indicatorsParam},
+		Response:    wsclient.Ticker{},
+		ContentType: tickerContentTypes,
+	},
+	"currencyHistory": {
+		Summary:     "Recent ticker updates of a symbol, oldest first",
+		Description: "Served from an in-memory ring buffer of the last history.recentSize updates per symbol (default 1000).",
+		QueryParams: []openapi.Param{
+			{Name: "limit", Description: "Maximum number of updates (default 100, at most the buffer size), the most recent are kept", Type: "integer", Minimum: 1},
+		},
+		Response: HistoryResponse{},
+	},
+	"currencyPoll": {
+		Summary:     "Wait for the next ticker update of a symbol",
+		Description: "Long polling: returns the ticker as soon as its timestamp is after since, right away when it already is. Answers 204 without a body when no update came within the timeout, poll again with the same since.",
+		QueryParams: []openapi.Param{
+			{Name: "since", Description: "Timestamp of the last ticker received, RFC 3339 or Unix milliseconds", Format: "timestamp"},
+			{Name: "timeout", Description: "How long to wait for an update such as 30s (default 30s, max 2m)", Format: "duration"},
+		},
+		Response: wsclient.Ticker{},
+	},
+	"symbolSearch": {
+		Summary:     "Search symbols by id or currency name",
+		Description: "Exact ids rank first, then symbols of the base currency, id prefixes, currency name prefixes, quote currency, substrings and finally ids or names within a few typos.",
+		QueryParams: []openapi.Param{
+			{Name: "q", Description: "Text to search, such as eth or ethereum", Required: true},
+			{Name: "limit", Description: "Maximum number of results (default 20, max 100)", Type: "integer", Minimum: 1},
+		},
+		Response: SymbolSearchResponse{},
+	},
+	"symbol": {
+		Summary:     "Metadata of a symbol",
+		Description: "Tick size, quantity increment, base, quote and fee currencies and default fee rates, as listed by HitBTC at startup or on the last reload.",
+		Response:    SymbolResponse{},
+	},
+	"currency": {
+		Summary:     "Metadata of a currency",
+		Description: "Whether deposits (payin), withdrawals (payout) and transfers are enabled, the confirmations required for deposits, the withdrawal fee and whether the currency is delisted.",
+		Response:    wsclient.Currency{},
+	},
+	"history": {
+		Summary: "Stored ticker history of a symbol, oldest first",
+		QueryParams: []openapi.Param{
+			{Name: "from", Description: "Start time, RFC 3339 or Unix milliseconds", Format: "timestamp"},
+			{Name: "till", Description: "End time, RFC 3339 or Unix milliseconds, defaults to now", Format: "timestamp"},
+			{Name: "limit", Description: "Maximum number of updates (default 100, max 1000), the most recent are kept", Type: "integer", Minimum: 1},
+		},
+		Response: HistoryResponse{},
+	},
+	"exportParquet": {
+		Summary:     "Download the stored history of a symbol as a Parquet file",
+		Description: "Ticker updates (symbol, timestamp, ask, bid, last, open, low, high, volume, volumeQuote) or, with period, the candles built from the feed and stored by the PostgreSQL backend (symbol, period, timestamp, open, high, low, close, volume, volumeQuote). At most 100000 rows, the most recent are kept.",
+		QueryParams: []openapi.Param{
+			{Name: "period", Description: "Export the stored candles of this period instead of the ticker updates", Enum: []string{"M1", "M5", "H1", "1m", "5m", "1h"}},
+			{Name: "from", Description: "Start time, RFC 3339 or Unix milliseconds", Format: "timestamp"},
+			{Name: "till", Description: "End time, RFC 3339 or Unix milliseconds, defaults to now", Format: "timestamp"},
+		},
+		ContentType: []string{parquetContentType},
+	},
+	"candlesLive": {
+		Summary:     "OHLCV candles built from the live ticker feed, oldest first",
+		Description: "The last candle is still in progress. Volumes are derived from the rolling 24h volume and are approximate.",
+		QueryParams: []openapi.Param{
+			{Name: "period", Description: "Candle period", Enum: []string{"M1", "M5", "H1", "1m", "5m", "1h"}},
+			{Name: "limit", Description: "Maximum number of candles (default 100, max 1000)", Type: "integer", Minimum: 1},
+		},
+		Response: CandlesResponse{},
+	},
+	"candlesCSV": {
+		Summary:     "Download the OHLCV candles of a symbol as CSV, oldest first",
+		Description: "Pages through the candles of HitBTC. Candles built from the live feed, stored or in progress, fill the gaps, HitBTC's are kept for the same open time.",
+		QueryParams: []openapi.Param{
+			{Name: "period", Description: "Candle period, 1M is a month", Enum: []string{"M1", "M3", "M5", "M15", "M30", "H1", "H4", "D1", "D7", "1M", "1m", "5m", "1h"}},
+			{Name: "from", Description: "Start time, RFC 3339 or Unix milliseconds, defaults to 1000 periods before till", Format: "timestamp"},
+			{Name: "till", Description: "End time, RFC 3339 or Unix milliseconds, defaults to now", Format: "timestamp"},
+		},
+		ContentType: []string{"text/csv"},
+	},
+	"vwap": {
+		Summary:     "Rolling volume weighted average price of a symbol",
+		Description: "Computed from the live feed, traded volume is derived from the rolling 24h volume.",
+		QueryParams: []openapi.Param{{Name: "window", Description: "Window duration such as 15m or 1h (default 1h, max 24h)", Format: "duration"}},
+		Response:    VWAPResponse{},
+	},
+	"spread": {
+		Summary:     "Bid/ask spread of a symbol",
+		Description: "Current absolute spread and spread in percent of the mid price, with their averages over the window computed from the live feed.",
+		QueryParams: []openapi.Param{{Name: "window", Description: "Averaging window such as 15m or 1h (default 1h, max 24h)", Format: "duration"}},
+		Response:    SpreadResponse{},
+	},
+	"extremes": {
+		Summary:     "Highest and lowest prices of a symbol",
+		Description: "Range of the last price since the server started, from the live feed, and of all time when the ticker history is persisted.",
+		Response:    ExtremesResponse{},
+	},
+	"volatility": {
+		Summary:     "Realized volatility of a symbol",
+		Description: "Standard deviation of the log returns between the closes of consecutive intervals, computed from the stored ticker history, and annualized over 365 days.",
+		QueryParams: []openapi.Param{
+			{Name: "window", Description: "Window of stored history such as 1h or 24h (default 1h, max 720h)", Format: "duration"},
+			intervalParam,
+		},
+		Response: VolatilityResponse{},
+	},
+	"correlation": {
+		Summary:     "Correlation matrix of the returns of symbols",
+		Description: "Pearson correlation of the log returns between consecutive interval closes of every pair of symbols, computed from the stored ticker history over the intervals where both traded.",
+		QueryParams: []openapi.Param{
+			{Name: "symbols", Description: "Comma separated symbols, 2 to 20, such as BTCUSD,ETHUSD,LTCUSD", Required: true},
+			{Name: "window", Description: "Window of stored history such as 1h or 24h (default 24h, max 720h)", Format: "duration"},
+			intervalParam,
+		},
+		Response: CorrelationResponse{},
+	},
+	"depth": {
+		Summary:     "Cumulative order book depth of a symbol",
+		Description: "Fetches the order book and accumulates the size of each side from the best price outwards, in base and quote currency, for depth charts.",
+		QueryParams: []openapi.Param{{Name: "levels", Description: "Price levels per side (default 20, max 500)", Type: "integer", Minimum: 1}},
+		Response:    DepthResponse{},
+	},
+	"changes": {
+		Summary:     "Tickers updated since a cursor, oldest update first",
+		Description: "Every cache update is numbered from a monotonic sequence. Pass the returned cursor as since to get only the following changes; a cursor from before a restart lists every ticker again.",
+		QueryParams: []openapi.Param{
+			{Name: "since", Description: "Cursor of the previous response (default 0, every ticker)", Type: "integer"},
+			{Name: "limit", Description: "Maximum number of tickers (default 1000, max 5000), more is set when some are left", Type: "integer", Minimum: 1},
+		},
+		Response: ChangesResponse{},
+	},
+	"movers": {
+		Summary: "Top gainers and losers by percent change from open to last",
+		QueryParams: []openapi.Param{
+			{Name: "window", Description: "Change window", Enum: []string{"24h"}},
+			{Name: "limit", Description: "Maximum number of gainers and of losers (default 10, max 100)", Type: "integer", Minimum: 1},
+		},
+		Response: MoversResponse{},
+	},
+	"stats": {
+		Summary:  "Aggregate statistics across all cached symbols",
+		Response: StatsResponse{},
+	},
+	"convert": {
+		Summary:     "Convert an amount between two currencies",
+		Description: "Uses a direct pair or routes through one intermediate currency (BTC, USD, USDT and ETH first), at last prices.",
+		QueryParams: []openapi.Param{
+			{Name: "from", Description: "Source currency", Required: true, Format: "currency"},
+			{Name: "to", Description: "Target currency", Required: true, Format: "currency"},
+			{Name: "amount", Description: "Amount of the source currency (default 1)", Type: "number"},
+		},
+		Response: ConvertResponse{},
+	},
+	"graphql": {
+		Summary:     "Query tickers, symbols and currencies with GraphQL",
+		QueryParams: []openapi.Param{{Name: "query", Description: "GraphQL query, for GET requests"}},
+		RequestBody: graphql.Request{},
+		Response:    graphql.Response{},
+	},
+	"openapi": {
+		Summary: "This OpenAPI document",
+	},
+	"orderCreate": {
+		Summary:     "Place an order",
+		Description: "Requires the admin token. Quantity and prices are checked against the quantity increment and tick size of the symbol.",
+		RequestBody: OrderRequest{},
+		Response:    wsclient.Order{},
+	},
+	"orders": {
+		Summary:     "List the active orders",
+		QueryParams: []openapi.Param{{Name: "symbol", Description: "Only list the orders of this symbol", Format: "symbol"}},
+		Response:    OrdersResponse{},
+	},
+	"orderHistory": {
+		Summary:     "List the closed orders, newest first",
+		QueryParams: accountHistoryParams,
+		Response:    OrdersResponse{},
+	},
+	"myTrades": {
+		Summary:     "List the executed trades of the account, newest first",
+		Description: "Each trade carries the fee charged and whether the order took or provided liquidity.",
+		QueryParams: accountHistoryParams,
+		Response:    MyTradesResponse{},
+	},
+	"fees": {
+		Summary:     "Get the commission rates of the account for a symbol",
+		Description: "account holds the rates charged to the API key, default the rates of the symbol metadata.",
+		Response:    FeeResponse{},
+	},
+	"orderCancelAll": {
+		Summary:     "Cancel the active orders",
+		QueryParams: []openapi.Param{{Name: "symbol", Description: "Only cancel the orders of this symbol", Format: "symbol"}},
+		Response:    OrdersResponse{},
+	},
+	"orderCancel": {
+		Summary:  "Cancel an order by client order id",
+		Response: wsclient.Order{},
+	},
+	"balanceTrading": {
+		Summary:  "Balances of the trading account",
+		Response: BalanceResponse{},
+	},
+	"balanceAccount": {
+		Summary:  "Balances of the main account, with the currency full names",
+		Response: AccountBalanceResponse{},
+	},
+	"depositAddress": {
+		Summary:  "Current deposit address of a currency",
+		Response: wsclient.DepositAddress{},
+	},
+	"depositAddressNew": {
+		Summary:  "Generate a new deposit address for a currency",
+		Response: wsclient.DepositAddress{},
+	},
+	"transfer": {
+		Summary:     "Move funds between the main and trading accounts",
+		Description: `"to": "trading" moves funds from the main account to the trading account, "to": "account" back.`,
+		RequestBody: TransferRequest{},
+		Response:    TransferResponse{},
+	},
+	"withdraw": {
+		Summary:     "Create a crypto withdrawal",
+		Description: "The withdrawal is pending until committed with POST /withdraw/{id}/commit, or cancelled with DELETE /withdraw/{id}.",
+		RequestBody: WithdrawRequest{},
+		Response:    WithdrawResponse{},
+	},
+	"withdrawCommit": {
+		Summary:  "Commit a pending withdrawal",
+		Response: WithdrawResponse{},
+	},
+	"withdrawRollback": {
+		Summary:  "Cancel a pending withdrawal",
+		Response: WithdrawResponse{},
+	},
+	"portfolio": {
+		Summary:  "Registered holdings",
+		Response: PortfolioResponse{},
+	},
+	"portfolioValue": {
+		Summary:     "Value of the holdings",
+		Description: "Values the holdings at the cached last prices, through an intermediate currency when there is no direct pair. The 24h change compares with the open prices. Holdings without a price are listed with an error and left out of the totals.",
+		QueryParams: []openapi.Param{{Name: "quote", Description: "Currency of the values, a listed currency or a fiat currency of the reference rates (default USD)", Format: "currency"}},
+		Response:    PortfolioValueResponse{},
+	},
+	"portfolioSet": {
+		Summary:     "Set the amount held of a currency",
+		RequestBody: HoldingRequest{},
+		Response:    portfolio.Holding{},
+	},
+	"portfolioDelete": {
+		Summary: "Remove the holding of a currency",
+	},
+	"adminReload": {
+		Summary:     "Reload the configuration",
+		Description: "Applies the feed symbols, upstream rate limits and alert rules of the configuration, like SIGHUP. The other settings need a restart.",
+		Response:    ReloadResponse{},
+	},
+	"adminAudit": {
+		Summary:     "Query the audit log",
+		Description: "Lists the admin actions, most recent first, with their actor, time, parameters and response status. Every admin request other than GET is recorded, as are the SIGHUP reloads; secrets in the parameters are redacted.",
+		QueryParams: []openapi.Param{
+			{Name: "since", Description: "Earliest action, RFC 3339 or Unix milliseconds", Format: "timestamp"},
+			{Name: "until", Description: "Actions before this time, RFC 3339 or Unix milliseconds", Format: "timestamp"},
+			{Name: "actor", Description: "Actor of the actions, the X-Audit-Actor header of the requests or SIGHUP"},
+			{Name: "action", Description: "Action, the name of the route such as adminCacheFlush"},
+			{Name: "limit", Description: "Maximum number of entries (default 100, max 1000)", Type: "integer", Minimum: 1},
+		},
+		Response: AuditResponse{},
+	},
+	"adminUsage": {
+		Summary:     "Usage of the tenant API keys",
+		Description: "Requests, bytes served, errors, rate limited requests and top endpoints of every API key since the start of the server, most requests first. Keys are identified by the start of their SHA-256.",
+		QueryParams: []openapi.Param{
+			{Name: "top", Description: "Endpoints listed per key (default 5, max 50)", Type: "integer", Minimum: 1},
+		},
+		Response: UsageResponse{},
+	},
+	"adminJobs": {
+		Summary:     "Status of the scheduled jobs",
+		Description: "Schedule, next and last run, duration and error of the last run, and the number of runs and failures of every job configured under jobs.",
+		Response:    JobsResponse{},
+	},
+	"adminJobRun": {
+		Summary:     "Run a scheduled job now",
+		Description: "Starts a run of the job outside of its schedule and answers 202 with the status of the jobs, or 409 job_running while it runs.",
+		Response:    JobsResponse{},
+	},
+	"adminCache": {
+		Summary:     "Inspect the ticker cache",
+		Description: "Lists the cached symbols with the size of their ticker, the time they were cached and the age of their data.",
+		Response:    CacheResponse{},
+	},
+	"adminCacheFlush": {
+		Summary:     "Flush the ticker cache",
+		Description: "Removes the ticker of the symbol query parameter, or every ticker without it. Feed symbols are cached again on their next update.",
+		Response:    CacheFlushResponse{},
+	},
+	"adminWebhooks": {
+		Summary:  "List the registered webhooks",
+		Response: WebhooksResponse{},
+	},
+	"adminWebhookCreate": {
+		Summary:     "Register a webhook",
+		RequestBody: webhooks.Webhook{},
+		Response:    webhooks.Webhook{},
+	},
+	"adminWebhookDelete": {
+		Summary: "Remove a webhook",
+	},
+	"adminAlerts": {
+		Summary:  "List the price alert rules",
+		Response: AlertRulesResponse{},
+	},
+	"adminAlertCreate": {
+		Summary:     "Add a price alert rule",
+		Description: `Rules are "SYMBOL FIELD OP VALUE" (e.g. "BTCUSD last > 70000") or "SYMBOL drops|rises N% in DURATION" (e.g. "ETHBTC drops 5% in 1h").`,
+		RequestBody: AlertRuleRequest{},
+		Response:    alerts.Rule{},
+	},
+	"adminAlertDelete": {
+		Summary: "Remove a price alert rule",
+	},
+}
+
+func (h *HandleRequests) handleOpenAPI(w http.ResponseWriter, req *http.Request) {
+	spec, err := openapi.Generate(h.router, apiInfo, routeDocs, ErrorResponse{})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, specJSON)
+}
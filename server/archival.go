@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crypto-api-server/archive"
+	"github.com/crypto-api-server/config"
+)
+
+// maxPendingSegments bounds the segments retried after failed uploads, the
+// oldest are given up.
+const maxPendingSegments = 100
+
+// archiver uploads the history stored between two archive jobs as a
+// compressed segment.
+type archiver struct {
+	h      *HandleRequests
+	bucket *archive.Bucket
+	prefix string
+
+	// mutex serializes the uploads
+	mutex *sync.Mutex
+	// next is the start of the segment in progress, pending holds the
+	// segments left to upload, oldest first
+	next    time.Time
+	pending []segment
+}
+
+// segment is the history stored in [from, till).
+type segment struct {
+	from, till time.Time
+}
+
+// newArchiver returns the archiver of the archive bucket of settings, nil when
+// there is none. The uploads are run by the archive job.
+func (h *HandleRequests) newArchiver(settings config.Archive) (*archiver, error) {
+	if settings.Bucket == "" {
+		return nil, nil
+	}
+	if h.History == nil {
+		return nil, errors.New("archive.bucket requires history.backend")
+	}
+	endpoint, region := settings.Endpoint, settings.Region
+	switch settings.Backend {
+	case "", "s3":
+		if endpoint == "" {
+			endpoint = archive.S3Endpoint
+		}
+		if region == "" {
+			region = "us-east-1"
+		}
+	case "gcs":
+		if endpoint == "" {
+			endpoint = archive.GCSEndpoint
+		}
+		if region == "" {
+			region = "auto"
+		}
+	default:
+		return nil, fmt.Errorf("unknown archive.backend %q", settings.Backend)
+	}
+	return &archiver{
+		h:      h,
+		bucket: archive.NewBucket(endpoint, region, settings.Bucket, settings.AccessKeyID, settings.SecretAccessKey),
+		prefix: strings.Trim(settings.Prefix, "/"),
+		mutex:  &sync.Mutex{},
+		next:   time.Now().Truncate(time.Minute),
+	}, nil
+}
+
+// key returns the object key of the segment starting at from.
+func (a *archiver) key(from time.Time) string {
+	name := segmentName(from) + ".tar.gz"
+	if a.prefix == "" {
+		return name
+	}
+	return a.prefix + "/" + name
+}
+
+// archiveDue ends the segment in progress at now, truncated to the minute,
+// and uploads the segments that aren't uploaded yet, starting with the one
+// in progress when the archiver was created. Failed uploads are retried on
+// the next call.
+func (a *archiver) archiveDue(now time.Time) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if till := now.Truncate(time.Minute); till.After(a.next) {
+		a.pending = append(a.pending, segment{from: a.next, till: till})
+		a.next = till
+	}
+	if len(a.pending) > maxPendingSegments {
+		log.Printf("archive: giving up %d segments", len(a.pending)-maxPendingSegments)
+		a.pending = a.pending[len(a.pending)-maxPendingSegments:]
+	}
+	var failed []segment
+	for _, s := range a.pending {
+		if err := a.upload(s); err != nil {
+			log.Printf("archive: %s: %v", a.key(s.from), err)
+			failed = append(failed, s)
+		}
+	}
+	a.pending = failed
+	if len(failed) > 0 {
+		return fmt.Errorf("%d segments failed to upload", len(failed))
+	}
+	return nil
+}
+
+// upload exports the segment s and uploads it, unless it is empty.
+func (a *archiver) upload(s segment) error {
+	from := s.from
+	files, err := a.h.segmentFiles(from, s.till)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	body, err := archive.TarGz(files, from)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	if err := a.bucket.Put(ctx, a.key(from), body, "application/gzip"); err != nil {
+		return err
+	}
+	log.Printf("archive: uploaded %s, %d files, %d bytes", a.key(from), len(files), len(body))
+	return nil
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/crypto-api-server/audit"
+	"github.com/crypto-api-server/requestid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// auditLogSize is the number of audit entries kept in memory for
+	// /admin/audit.
+	auditLogSize      = 10000
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+	// auditActorHeader names the operator acting with the admin token.
+	auditActorHeader = "X-Audit-Actor"
+	redacted         = "[redacted]"
+)
+
+// AuditResponse lists audit entries, most recent first.
+type AuditResponse struct {
+	Entries []audit.Entry `json:"entries"`
+}
+
+// auditRequest runs next and records the request in the audit log with its
+// parameters and the status of the response.
+func (h *HandleRequests) auditRequest(w http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, MaxBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, err.Error())
+		return
+	}
+	// the handler reads the body again, past the limit when it is longer
+	req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+	writer := &usageWriter{ResponseWriter: w}
+	next(writer, req)
+
+	action := req.Method + " " + req.URL.Path
+	if route := mux.CurrentRoute(req); route != nil && route.GetName() != "" {
+		action = route.GetName()
+	}
+	status := writer.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	h.recordAudit(audit.Entry{
+		Actor:      auditActor(req),
+		RemoteAddr: req.RemoteAddr,
+		Action:     action,
+		Params:     auditParams(req, body),
+		Status:     status,
+		RequestID:  requestid.FromContext(req.Context()),
+	})
+}
+
+func (h *HandleRequests) recordAudit(entry audit.Entry) {
+	if err := h.Audit.Record(entry); err != nil {
+		log.Println("audit:", err)
+	}
+}
+
+// auditActor returns the X-Audit-Actor of req, or "admin" when it is
+// missing or invalid.
+func auditActor(req *http.Request) string {
+	if actor := req.Header.Get(auditActorHeader); requestid.Valid(actor) {
+		return actor
+	}
+	return "admin"
+}
+
+// auditParams collects the path variables, the query parameters and the
+// fields of the JSON body of req. Secrets are redacted.
+func auditParams(req *http.Request, body []byte) map[string]interface{} {
+	params := make(map[string]interface{})
+	for name, value := range mux.Vars(req) {
+		params[name] = value
+	}
+	for name, values := range req.URL.Query() {
+		params[name] = strings.Join(values, ",")
+	}
+	var fields map[string]interface{}
+	if json.Unmarshal(body, &fields) == nil {
+		for name, value := range fields {
+			params[name] = value
+		}
+	}
+	for name := range params {
+		if sensitive(name) {
+			params[name] = redacted
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+func sensitive(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "secret") || strings.Contains(name, "token") ||
+		strings.Contains(name, "password") || strings.Contains(name, "apikey")
+}
+
+func (h *HandleRequests) handleAudit(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	since, ok := parseTimeParam(query.Get("since"), time.Time{})
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid since")
+		return
+	}
+	until, ok := parseTimeParam(query.Get("until"), time.Time{})
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid until")
+		return
+	}
+	limit, ok := parseLimitParam(query.Get("limit"), defaultAuditLimit, maxAuditLimit)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid limit")
+		return
+	}
+	entries := h.Audit.Query(audit.Query{
+		Since:  since,
+		Until:  until,
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		Limit:  limit,
+	})
+	responseJSON, err := json.Marshal(&AuditResponse{Entries: entries})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
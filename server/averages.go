@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/crypto-api-server/indicators"
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+// maxAveragePeriod is the longest moving average, in minutes.
+const maxAveragePeriod = 200
+
+// movingAveragesParam returns the moving averages of ?indicators=, nil when
+// none is requested. Only JSON responses include them.
+func movingAveragesParam(req *http.Request, format string) ([]indicators.MovingAverage, bool) {
+	value := req.URL.Query().Get("indicators")
+	if value == "" {
+		return nil, true
+	}
+	averages, err := indicators.ParseMovingAverages(value, maxAveragePeriod)
+	return averages, err == nil && format == formatJSON
+}
+
+// addIndicators sets the requested moving averages on copies of tickers.
+func (h *HandleRequests) addIndicators(tickers []*wsclient.Ticker, averages []indicators.MovingAverage) []*wsclient.Ticker {
+	if averages == nil {
+		return tickers
+	}
+	enriched := make([]*wsclient.Ticker, 0, len(tickers))
+	for _, ticker := range tickers {
+		copied := *ticker
+		copied.Indicators = make(map[string]decimal.Decimal, len(averages))
+		for name, value := range h.Averages.Compute(ticker.Symbol, averages) {
+			copied.Indicators[name] = decimal.NewFromFloat(value)
+		}
+		enriched = append(enriched, &copied)
+	}
+	return enriched
+}
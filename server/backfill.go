@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/crypto-api-server/config"
+	"github.com/crypto-api-server/storage"
+	"github.com/crypto-api-server/wsclient"
+)
+
+// backfillRequest is parsed from the arguments of -backfill.
+type backfillRequest struct {
+	symbols []string
+	period  string
+	from    time.Time
+	till    time.Time
+}
+
+// parseBackfillArgs parses the symbol=, period=, from= and till= arguments.
+// symbol and from are required, period defaults to M1 and till to now.
+func parseBackfillArgs(args []string) (backfillRequest, error) {
+	request := backfillRequest{period: "M1", till: time.Now()}
+	for _, arg := range args {
+		arg = strings.TrimLeft(arg, "-")
+		i := strings.Index(arg, "=")
+		if i < 0 {
+			return request, fmt.Errorf("invalid backfill argument %q, expected key=value", arg)
+		}
+		key, value := arg[:i], arg[i+1:]
+		var ok bool
+		switch key {
+		case "symbol", "symbols":
+			for _, symbol := range strings.Split(value, ",") {
+				if symbol = strings.ToUpper(strings.TrimSpace(symbol)); symbol != "" {
+					request.symbols = append(request.symbols, symbol)
+				}
+			}
+			ok = len(request.symbols) > 0
+		case "period":
+			request.period, ok = upstreamPeriod(value)
+		case "from":
+			request.from, ok = parseTimeParam(value, time.Time{})
+		case "till":
+			request.till, ok = parseTimeParam(value, time.Now())
+		default:
+			return request, fmt.Errorf("unknown backfill argument %q", key)
+		}
+		if !ok {
+			return request, fmt.Errorf("invalid backfill %s %q", key, value)
+		}
+	}
+	if len(request.symbols) == 0 {
+		return request, errors.New("backfill requires symbol=")
+	}
+	if request.from.IsZero() || !request.from.Before(request.till) {
+		return request, errors.New("backfill requires from= before till")
+	}
+	return request, nil
+}
+
+// backfill stores the HitBTC candles of the -backfill arguments into the
+// history storage, which must keep candles. The REST calls go through the
+// configured rate limits.
+func (h *HandleRequests) backfill(cfg *config.Config) error {
+	request, err := parseBackfillArgs(cfg.Backfill)
+	if err != nil {
+		return err
+	}
+	store, err := openHistory(cfg.History)
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		return errors.New("backfill requires history.backend")
+	}
+	defer store.Close()
+	candleStore, ok := store.(storage.CandleStore)
+	if !ok {
+		return errors.New("backfill requires a history.backend that stores candles")
+	}
+	limits := map[string]float64{
+		wsclient.LimitMarketData: cfg.RateLimits.MarketData,
+		wsclient.LimitTrading:    cfg.RateLimits.Trading,
+		wsclient.LimitOther:      cfg.RateLimits.Other,
+	}
+	for class, rate := range limits {
+		if err := h.HitWrapper.SetRateLimit(class, rate); err != nil {
+			return err
+		}
+	}
+	if err := h.HitWrapper.CacheAllSymbols(context.Background()); err != nil {
+		return err
+	}
+	for _, symbol := range request.symbols {
+		if !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+			return fmt.Errorf("unknown symbol %s", symbol)
+		}
+	}
+	for _, symbol := range request.symbols {
+		stored, err := backfillSymbol(h, candleStore, symbol, request)
+		if err != nil {
+			return fmt.Errorf("%s: %v after %d candles", symbol, err, stored)
+		}
+		log.Printf("backfill: %s %s: %d candles stored", symbol, request.period, stored)
+	}
+	return nil
+}
+
+// backfillSymbol pages through the candles of symbol, oldest first, and
+// stores every page. It returns the number of candles stored.
+func backfillSymbol(h *HandleRequests, store storage.CandleStore, symbol string, request backfillRequest) (int, error) {
+	period := wsclient.CandlePeriods[request.period]
+	stored := 0
+	for from := request.from; !from.After(request.till); {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		page, err := h.HitWrapper.GetCandles(ctx, symbol, request.period, from, request.till, wsclient.MaxCandlesLimit)
+		cancel()
+		if err != nil {
+			return stored, err
+		}
+		if len(page) == 0 {
+			break
+		}
+		candles := make([]storage.Candle, 0, len(page))
+		for _, c := range page {
+			candles = append(candles, upstreamCandle(symbol, request.period, c))
+		}
+		if err := store.AppendCandles(candles); err != nil {
+			return stored, err
+		}
+		stored += len(candles)
+		last := page[len(page)-1].Timestamp
+		log.Printf("backfill: %s %s: %d candles stored, up to %s", symbol, request.period, stored, last.UTC().Format(time.RFC3339))
+		if len(page) < wsclient.MaxCandlesLimit {
+			break
+		}
+		from = last.Add(period)
+	}
+	return stored, nil
+}
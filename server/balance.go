@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+type BalanceResponse struct {
+	Balances []wsclient.Balance `json:"balances"`
+}
+
+func (h *HandleRequests) handleTradingBalance(w http.ResponseWriter, req *http.Request) {
+	balances, err := h.HitWrapper.GetTradingBalance(req.Context())
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	if balances == nil {
+		balances = []wsclient.Balance{}
+	}
+	responseJSON, err := json.Marshal(&BalanceResponse{Balances: balances})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+// AccountBalance is a main account balance with the currency full name.
+type AccountBalance struct {
+	wsclient.Balance
+	FullName string `json:"fullName"`
+}
+
+type AccountBalanceResponse struct {
+	Balances []AccountBalance `json:"balances"`
+}
+
+func (h *HandleRequests) handleAccountBalance(w http.ResponseWriter, req *http.Request) {
+	balances, err := h.HitWrapper.GetAccountBalance(req.Context())
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	response := AccountBalanceResponse{Balances: make([]AccountBalance, 0, len(balances))}
+	for _, balance := range balances {
+		response.Balances = append(response.Balances, AccountBalance{
+			Balance:  balance,
+			FullName: h.HitWrapper.CurrencyFullName(balance.Currency),
+		})
+	}
+	responseJSON, err := json.Marshal(&response)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+func (h *HandleRequests) handleDepositAddress(w http.ResponseWriter, req *http.Request) {
+	currency := mux.Vars(req)["currency"]
+	info, ok := h.HitWrapper.Currency(currency)
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeUnknownCurrency, "Not a valid Currency")
+		return
+	}
+	if !info.PayinEnabled {
+		writeError(w, http.StatusConflict, CodeCurrencyDisabled, "Deposits of "+currency+" are disabled")
+		return
+	}
+	address, err := h.HitWrapper.GetDepositAddress(req.Context(), currency, req.Method == http.MethodPost)
+	// addresses are account specific and must not be kept by shared caches
+	w.Header().Set("Cache-Control", "no-store")
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	responseJSON, err := json.Marshal(address)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+type TransferRequest struct {
+	Currency string          `json:"currency"`
+	Amount   decimal.Decimal `json:"amount"`
+	To       string          `json:"to"` // trading or account
+}
+
+type TransferResponse struct {
+	ID string `json:"id"`
+	TransferRequest
+}
+
+func (h *HandleRequests) handleTransfer(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, MaxBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, err.Error())
+		return
+	}
+	var transferReq TransferRequest
+	if err = json.Unmarshal(body, &transferReq); err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid transfer body")
+		return
+	}
+	info, ok := h.HitWrapper.Currency(transferReq.Currency)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeUnknownCurrency, "Not a valid Currency")
+		return
+	}
+	if !info.TransferEnabled {
+		writeError(w, http.StatusConflict, CodeCurrencyDisabled, "Transfers of "+transferReq.Currency+" are disabled")
+		return
+	}
+	if !transferReq.Amount.IsPositive() {
+		writeError(w, http.StatusBadRequest, CodeValidation, "amount must be positive")
+		return
+	}
+	var transferType string
+	switch transferReq.To {
+	case "trading":
+		transferType = wsclient.TransferBankToExchange
+	case "account":
+		transferType = wsclient.TransferExchangeToBank
+	default:
+		writeError(w, http.StatusBadRequest, CodeValidation, "to must be trading or account")
+		return
+	}
+	transfer, err := h.HitWrapper.Transfer(req.Context(), transferReq.Currency, transferReq.Amount, transferType)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	responseJSON, err := json.Marshal(&TransferResponse{ID: transfer.ID, TransferRequest: transferReq})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusCreated, responseJSON)
+}
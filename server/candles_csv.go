@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"encoding/csv"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crypto-api-server/candles"
+	"github.com/crypto-api-server/requestid"
+	"github.com/crypto-api-server/storage"
+	"github.com/crypto-api-server/wsclient"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultCSVCandles = 1000
+	// maxCSVCandles bounds the upstream pages read by a download.
+	maxCSVCandles = 100000
+)
+
+var candleCSVHeader = []string{"timestamp", "open", "high", "low", "close", "volume", "volumeQuote"}
+
+// upstreamPeriod returns the HitBTC name of a candle period, accepting the
+// aliases of the live candles. 1M is a month, 1m a minute.
+func upstreamPeriod(period string) (string, bool) {
+	if _, ok := wsclient.CandlePeriods[period]; ok {
+		return period, true
+	}
+	if normalized, ok := candles.NormalizePeriod(period); ok {
+		return normalized, true
+	}
+	period = strings.ToUpper(period)
+	_, ok := wsclient.CandlePeriods[period]
+	return period, ok
+}
+
+// upstreamCandle converts a candle of the exchange.
+func upstreamCandle(symbol string, period string, c wsclient.WSCandle) storage.Candle {
+	return storage.Candle{
+		Symbol:      symbol,
+		Period:      period,
+		OpenTime:    c.Timestamp.UTC(),
+		Open:        c.Open.InexactFloat64(),
+		High:        c.Max.InexactFloat64(),
+		Low:         c.Min.InexactFloat64(),
+		Close:       c.Close.InexactFloat64(),
+		Volume:      c.Volume.InexactFloat64(),
+		VolumeQuote: c.VolumeQuote.InexactFloat64(),
+	}
+}
+
+func candleCSVRecord(c storage.Candle) []string {
+	format := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+	return []string{
+		c.OpenTime.Format(time.RFC3339), format(c.Open), format(c.High), format(c.Low),
+		format(c.Close), format(c.Volume), format(c.VolumeQuote),
+	}
+}
+
+// localCandles returns the candles of symbol and period opened in [from,
+// till] that were built here, stored or live, oldest first.
+func (h *HandleRequests) localCandles(ctx context.Context, symbol string, period string, from, till time.Time) []storage.Candle {
+	if _, ok := candles.Periods[period]; !ok {
+		return nil
+	}
+	byTime := make(map[time.Time]storage.Candle)
+	if store, ok := h.History.(storage.CandleStore); ok {
+		stored, err := store.Candles(symbol, period, from, till, maxCSVCandles)
+		if err != nil {
+			log.Printf("[%s] candles: reading %s %s: %v", requestid.FromContext(ctx), symbol, period, err)
+		}
+		for _, c := range stored {
+			byTime[c.OpenTime] = c
+		}
+	}
+	for _, c := range h.Candles.Candles(symbol, period, maxCSVCandles) {
+		if !c.OpenTime.Before(from) && !c.OpenTime.After(till) {
+			byTime[c.OpenTime] = c
+		}
+	}
+	local := make([]storage.Candle, 0, len(byTime))
+	for _, c := range byTime {
+		local = append(local, c)
+	}
+	sort.Slice(local, func(i, j int) bool { return local[i].OpenTime.Before(local[j].OpenTime) })
+	return local
+}
+
+// handleCandlesCSV streams the candles of a symbol as CSV, paging through
+// the candles of the exchange. Candles built here fill the gaps, such as
+// the candle in progress, the exchange's are kept for the same open time.
+func (h *HandleRequests) handleCandlesCSV(w http.ResponseWriter, req *http.Request) {
+	symbol := mux.Vars(req)["symbol"]
+	if !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	query := req.URL.Query()
+	period := "M1"
+	if query.Get("period") != "" {
+		var ok bool
+		if period, ok = upstreamPeriod(query.Get("period")); !ok {
+			writeError(w, http.StatusBadRequest, CodeValidation, "Invalid period, expected M1, M3, M5, M15, M30, H1, H4, D1, D7 or 1M")
+			return
+		}
+	}
+	till, ok := parseTimeParam(query.Get("till"), time.Now())
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid till")
+		return
+	}
+	from, ok := parseTimeParam(query.Get("from"), till.Add(-defaultCSVCandles*wsclient.CandlePeriods[period]))
+	if !ok || from.After(till) {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid from")
+		return
+	}
+
+	local := h.localCandles(req.Context(), symbol, period, from, till)
+	writer := csv.NewWriter(w)
+	started := false
+	start := func() {
+		started = true
+		w.Header().Set("Content-Type", formatContentTypes[formatCSV])
+		w.Header().Set("Content-Disposition", `attachment; filename="`+symbol+"-"+period+`.csv"`)
+		w.WriteHeader(http.StatusOK)
+		writer.Write(candleCSVHeader)
+	}
+	// emitLocal writes the local candles opened before t, skipping the one
+	// opened at t which the exchange has too
+	emitLocal := func(t time.Time) {
+		for len(local) > 0 && !local[0].OpenTime.After(t) {
+			if local[0].OpenTime.Before(t) {
+				writer.Write(candleCSVRecord(local[0]))
+			}
+			local = local[1:]
+		}
+	}
+
+	written := 0
+	for pageFrom := from; written < maxCSVCandles && !pageFrom.After(till); {
+		page, err := h.HitWrapper.GetCandles(req.Context(), symbol, period, pageFrom, till, wsclient.MaxCandlesLimit)
+		if err != nil {
+			if !started && len(local) == 0 {
+				writeUpstreamError(w, err)
+				return
+			}
+			// the rest comes from the local candles
+			log.Printf("[%s] candles: fetching %s %s: %v", requestid.FromContext(req.Context()), symbol, period, err)
+			break
+		}
+		if len(page) == 0 {
+			break
+		}
+		if !started {
+			start()
+		}
+		for _, c := range page {
+			candle := upstreamCandle(symbol, period, c)
+			emitLocal(candle.OpenTime)
+			writer.Write(candleCSVRecord(candle))
+			written++
+		}
+		writer.Flush()
+		pageFrom = page[len(page)-1].Timestamp.Add(wsclient.CandlePeriods[period])
+		if len(page) < wsclient.MaxCandlesLimit || req.Context().Err() != nil {
+			break
+		}
+	}
+	if !started {
+		start()
+	}
+	emitLocal(till.Add(time.Nanosecond))
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("[%s] candles: streaming %s %s: %v", requestid.FromContext(req.Context()), symbol, period, err)
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/crypto-api-server/candles"
+	"github.com/crypto-api-server/storage"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultCandlesLimit = 100
+	maxCandlesLimit     = 1000
+)
+
+type CandlesResponse struct {
+	Symbol  string           `json:"symbol"`
+	Period  string           `json:"period"`
+	Candles []storage.Candle `json:"candles"`
+}
+
+func (h *HandleRequests) handleLiveCandles(w http.ResponseWriter, req *http.Request) {
+	symbol := mux.Vars(req)["symbol"]
+	if !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	query := req.URL.Query()
+	period := "M1"
+	if query.Get("period") != "" {
+		var ok bool
+		if period, ok = candles.NormalizePeriod(query.Get("period")); !ok {
+			writeError(w, http.StatusBadRequest, CodeValidation, "Invalid period, expected M1, M5 or H1")
+			return
+		}
+	}
+	limit, ok := parseLimitParam(query.Get("limit"), defaultCandlesLimit, maxCandlesLimit)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid limit")
+		return
+	}
+
+	response := &CandlesResponse{
+		Symbol:  symbol,
+		Period:  period,
+		Candles: h.Candles.Candles(symbol, period, limit),
+	}
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
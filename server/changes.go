@@ -0,0 +1,52 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+const (
+	defaultChangesLimit = 1000
+	maxChangesLimit     = 5000
+)
+
+// ChangesResponse lists the tickers updated since a cursor. Cursor is passed
+// as since to get the following changes, More tells that some are left.
+type ChangesResponse struct {
+	Cursor     uint64             `json:"cursor"`
+	More       bool               `json:"more"`
+	Currencies []*wsclient.Ticker `json:"currencies"`
+}
+
+func (h *HandleRequests) handleChanges(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	var since uint64
+	if value := query.Get("since"); value != "" {
+		var err error
+		if since, err = strconv.ParseUint(value, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, CodeValidation, "Invalid since")
+			return
+		}
+	}
+	limit, ok := parseLimitParam(query.Get("limit"), defaultChangesLimit, maxChangesLimit)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid limit")
+		return
+	}
+
+	// pages keep their size before scoping, the cursor covers the symbols
+	// left out
+	tickers, cursor, more := h.HitWrapper.Changes(since, limit)
+	tickers = scopeTickers(req, tickers)
+	h.recordAccess(tickers)
+	responseJSON, err := json.Marshal(&ChangesResponse{Cursor: cursor, More: more, Currencies: tickers})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	h.flagStale(w)
+	writeResponse(w, http.StatusOK, responseJSON)
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/crypto-api-server/tenants"
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+// preferredIntermediates are tried first when no direct pair exists.
+var preferredIntermediates = []string{"BTC", "USD", "USDT", "ETH"}
+
+type ConversionLeg struct {
+	Symbol string          `json:"symbol"`
+	Side   string          `json:"side"`
+	Price  decimal.Decimal `json:"price"`
+	From   string          `json:"from"`
+	To     string          `json:"to"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+type ConvertResponse struct {
+	From   string          `json:"from"`
+	To     string          `json:"to"`
+	Amount decimal.Decimal `json:"amount"`
+	Result decimal.Decimal `json:"result"`
+	Route  []ConversionLeg `json:"route"`
+}
+
+// conversionLeg is a step of a route before pricing.
+type conversionLeg struct {
+	symbol wsclient.Symbol
+	sell   bool // from is the base currency of symbol
+}
+
+// findRoutes returns the direct route, or else the routes through one
+// intermediate currency, preferred intermediates first. Only the symbols
+// tenant can query are traded.
+func (h *HandleRequests) findRoutes(tenant *tenants.Tenant, from string, to string) [][]conversionLeg {
+	pairs := make(map[string]map[string]conversionLeg)
+	addPair := func(a string, b string, leg conversionLeg) {
+		if pairs[a] == nil {
+			pairs[a] = make(map[string]conversionLeg)
+		}
+		pairs[a][b] = leg
+	}
+	for _, symbol := range h.HitWrapper.Symbols() {
+		if !tenant.Allows(symbol.Id) {
+			continue
+		}
+		addPair(symbol.BaseCurrency, symbol.QuoteCurrency, conversionLeg{symbol: symbol, sell: true})
+		addPair(symbol.QuoteCurrency, symbol.BaseCurrency, conversionLeg{symbol: symbol, sell: false})
+	}
+
+	if leg, ok := pairs[from][to]; ok {
+		return [][]conversionLeg{{leg}}
+	}
+	var intermediates []string
+	for currency := range pairs[from] {
+		if _, ok := pairs[currency][to]; ok {
+			intermediates = append(intermediates, currency)
+		}
+	}
+	rank := func(currency string) int {
+		for i, preferred := range preferredIntermediates {
+			if currency == preferred {
+				return i
+			}
+		}
+		return len(preferredIntermediates)
+	}
+	sort.Slice(intermediates, func(i, j int) bool {
+		if rank(intermediates[i]) != rank(intermediates[j]) {
+			return rank(intermediates[i]) < rank(intermediates[j])
+		}
+		return intermediates[i] < intermediates[j]
+	})
+	routes := make([][]conversionLeg, 0, len(intermediates))
+	for _, currency := range intermediates {
+		routes = append(routes, []conversionLeg{pairs[from][currency], pairs[currency][to]})
+	}
+	return routes
+}
+
+// priceRoute converts amount along the route at the last prices.
+func (h *HandleRequests) priceRoute(ctx context.Context, route []conversionLeg, amount decimal.Decimal) ([]ConversionLeg, decimal.Decimal, error) {
+	legs := make([]ConversionLeg, 0, len(route))
+	for _, step := range route {
+		ticker, err := h.HitWrapper.GetMarketSummary(ctx, step.symbol.Id)
+		if err != nil {
+			return nil, decimal.Zero, err
+		}
+		if ticker == nil || ticker.Last.IsZero() {
+			return nil, decimal.Zero, fmt.Errorf("no price for %s", step.symbol.Id)
+		}
+		leg := ConversionLeg{Symbol: step.symbol.Id, Price: ticker.Last}
+		if step.sell {
+			leg.Side, leg.From, leg.To = "sell", step.symbol.BaseCurrency, step.symbol.QuoteCurrency
+			amount = amount.Mul(ticker.Last)
+		} else {
+			leg.Side, leg.From, leg.To = "buy", step.symbol.QuoteCurrency, step.symbol.BaseCurrency
+			amount = amount.Div(ticker.Last)
+		}
+		leg.Amount = amount
+		legs = append(legs, leg)
+	}
+	return legs, amount, nil
+}
+
+func (h *HandleRequests) handleConvert(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	from := strings.ToUpper(query.Get("from"))
+	to := strings.ToUpper(query.Get("to"))
+	if from == "" || to == "" || from == to {
+		writeError(w, http.StatusBadRequest, CodeValidation, "from and to must be two different currencies")
+		return
+	}
+	amount := decimal.NewFromInt(1)
+	if query.Get("amount") != "" {
+		var err error
+		if amount, err = decimal.NewFromString(query.Get("amount")); err != nil || !amount.IsPositive() {
+			writeError(w, http.StatusBadRequest, CodeValidation, "Invalid amount")
+			return
+		}
+	}
+
+	routes := h.findRoutes(tenants.FromContext(req.Context()), from, to)
+	if len(routes) == 0 {
+		writeError(w, http.StatusNotFound, CodeNotFound, "No conversion route from "+from+" to "+to)
+		return
+	}
+	var lastErr error
+	for _, route := range routes {
+		legs, result, err := h.priceRoute(req.Context(), route, amount)
+		if err != nil {
+			// try the next route, a market may have no price
+			lastErr = err
+			continue
+		}
+		responseJSON, err := json.Marshal(&ConvertResponse{From: from, To: to, Amount: amount, Result: result, Route: legs})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+			return
+		}
+		writeResponse(w, http.StatusOK, responseJSON)
+		return
+	}
+	writeError(w, http.StatusBadGateway, CodeUpstreamUnavailable, lastErr.Error())
+}
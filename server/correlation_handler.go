@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/crypto-api-server/indicators"
+	"github.com/crypto-api-server/tenants"
+)
+
+// maxCorrelationSymbols bounds the history read by a /correlation request.
+const maxCorrelationSymbols = 20
+
+type CorrelationResponse struct {
+	Window   string   `json:"window"`
+	Interval string   `json:"interval"`
+	Symbols  []string `json:"symbols"`
+	// Matrix holds the correlation of every pair of symbols, missing for
+	// pairs without enough common returns.
+	Matrix map[string]map[string]indicators.Correlation `json:"matrix"`
+}
+
+func (h *HandleRequests) handleCorrelation(w http.ResponseWriter, req *http.Request) {
+	if h.History == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "History storage is not enabled")
+		return
+	}
+	var symbols []string
+	for _, symbol := range strings.Split(req.URL.Query().Get("symbols"), ",") {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol != "" && !h.HitWrapper.Contains(symbols, symbol) {
+			symbols = append(symbols, symbol)
+		}
+	}
+	if len(symbols) < 2 || len(symbols) > maxCorrelationSymbols {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid symbols",
+			ErrorDetail{Field: "symbols", Message: "must list 2 to 20 symbols"})
+		return
+	}
+	tenant := tenants.FromContext(req.Context())
+	for _, symbol := range symbols {
+		if !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+			writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol: "+symbol)
+			return
+		}
+		if !tenant.Allows(symbol) {
+			writeError(w, http.StatusForbidden, CodeForbidden, "Symbol not allowed: "+symbol)
+			return
+		}
+	}
+	window, interval, ok := parseAnalyticsParams(req, 24*time.Hour)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid window or interval, expected a window up to "+maxAnalyticsWindow.String()+" of at least two intervals")
+		return
+	}
+	series := make(map[string][]indicators.PricePoint, len(symbols))
+	for _, symbol := range symbols {
+		points, err := h.storedPrices(symbol, window)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+			return
+		}
+		series[symbol] = points
+	}
+	responseJSON, err := json.Marshal(&CorrelationResponse{
+		Window:   window.String(),
+		Interval: interval.String(),
+		Symbols:  symbols,
+		Matrix:   indicators.CorrelationMatrix(series, interval),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
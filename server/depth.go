@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultDepthLevels = 20
+	maxDepthLevels     = 500
+)
+
+// DepthLevel is a price level of the order book with the quantity available
+// up to it, in base and in quote currency.
+type DepthLevel struct {
+	Price           decimal.Decimal `json:"price"`
+	Size            decimal.Decimal `json:"size"`
+	CumulativeSize  decimal.Decimal `json:"cumulativeSize"`
+	CumulativeQuote decimal.Decimal `json:"cumulativeQuote"`
+}
+
+type DepthResponse struct {
+	Symbol    string       `json:"symbol"`
+	Timestamp time.Time    `json:"timestamp"`
+	Bids      []DepthLevel `json:"bids"`
+	Asks      []DepthLevel `json:"asks"`
+}
+
+// cumulativeDepth accumulates the levels from the best price outwards.
+func cumulativeDepth(levels []wsclient.OrderBookLevel) []DepthLevel {
+	depth := make([]DepthLevel, 0, len(levels))
+	size, quote := decimal.Zero, decimal.Zero
+	for _, level := range levels {
+		size = size.Add(level.Size)
+		quote = quote.Add(level.Size.Mul(level.Price))
+		depth = append(depth, DepthLevel{
+			Price:           level.Price,
+			Size:            level.Size,
+			CumulativeSize:  size,
+			CumulativeQuote: quote,
+		})
+	}
+	return depth
+}
+
+func (h *HandleRequests) handleDepth(w http.ResponseWriter, req *http.Request) {
+	symbol := mux.Vars(req)["symbol"]
+	if !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	levels, ok := parseLimitParam(req.URL.Query().Get("levels"), defaultDepthLevels, maxDepthLevels)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid levels")
+		return
+	}
+	book, err := h.HitWrapper.GetOrderBook(req.Context(), symbol, levels)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	responseJSON, err := json.Marshal(&DepthResponse{
+		Symbol:    book.Symbol,
+		Timestamp: book.Timestamp,
+		Bids:      cumulativeDepth(book.Bid),
+		Asks:      cumulativeDepth(book.Ask),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/crypto-api-server/requestid"
+	"github.com/crypto-api-server/wrappers"
+	"github.com/crypto-api-server/wsclient"
+)
+
+// Codes of the error responses, stable for clients to switch on.
+const (
+	CodeValidation          = "validation_failed"
+	CodeUnknownSymbol       = "unknown_symbol"
+	CodeUnknownCurrency     = "unknown_currency"
+	CodeSymbolDelisted      = "symbol_delisted"
+	CodeNotFound            = "not_found"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeRateLimited         = "rate_limited"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodePayloadTooLarge     = "payload_too_large"
+	CodeUnsupportedMedia    = "unsupported_media_type"
+	CodeCurrencyDisabled    = "currency_disabled"
+	CodeUpstreamUnavailable = "upstream_unavailable"
+	CodeUpstreamRejected    = "upstream_rejected"
+	CodeUpstreamRateLimited = "upstream_rate_limited"
+	CodeStaleData           = "stale_data"
+	CodeJobRunning          = "job_running"
+	CodeInternal            = "internal_error"
+)
+
+// ErrorDetail is a detail of an error, Field names the invalid parameter of
+// validation failures.
+type ErrorDetail struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// APIError describes the error of a request.
+type APIError struct {
+	Code      string        `json:"code"`
+	Message   string        `json:"message"`
+	Details   []ErrorDetail `json:"details,omitempty"`
+	RequestID string        `json:"requestId,omitempty"`
+}
+
+// ErrorResponse is the body of the error responses.
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// writeError writes an error response with the request ID of w, if any. The
+// ID is read from the response header set by withRequestID, which stays
+// visible through the writers wrapped by the other middlewares.
+func writeError(w http.ResponseWriter, status int, code string, message string, details ...ErrorDetail) {
+	apiErr := APIError{Code: code, Message: message, Details: details, RequestID: w.Header().Get(requestid.Header)}
+	errorBody, _ := json.Marshal(&ErrorResponse{Error: apiErr})
+	writeResponse(w, status, errorBody)
+}
+
+// upstreamStatus maps an exchange error to the status of the response:
+// unknown symbols are not found, rejected requests are the client's fault,
+// rate limiting, an open circuit or stale data are unavailable, anything
+// else, including our own credentials being refused, is a bad gateway.
+func upstreamStatus(err error) int {
+	var staleErr *wrappers.StaleDataError
+	switch {
+	case errors.Is(err, wsclient.ErrSymbolNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, wsclient.ErrRateLimited), errors.Is(err, wsclient.ErrUpstreamUnavailable), errors.As(err, &staleErr):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, wsclient.ErrAuth):
+		return http.StatusBadGateway
+	}
+	var apiErr *wsclient.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusBadRequest, http.StatusNotFound:
+			return apiErr.StatusCode
+		}
+	}
+	return http.StatusBadGateway
+}
+
+// writeUpstreamError writes the error of an exchange call with the status
+// of upstreamStatus, passing on the Retry-After delay of HitBTC.
+func writeUpstreamError(w http.ResponseWriter, err error) {
+	var delistedErr *wrappers.DelistedError
+	if errors.As(err, &delistedErr) {
+		writeError(w, http.StatusGone, CodeSymbolDelisted, err.Error(),
+			ErrorDetail{Message: "delisted since " + delistedErr.Since.Format(time.RFC3339)})
+		return
+	}
+	var staleErr *wrappers.StaleDataError
+	if errors.As(err, &staleErr) {
+		writeError(w, http.StatusServiceUnavailable, CodeStaleData, err.Error(),
+			ErrorDetail{Message: "cached at " + staleErr.CachedAt.Format(time.RFC3339)})
+		return
+	}
+	var statusErr *wsclient.StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(statusErr.RetryAfter.Seconds()))))
+	}
+	status := upstreamStatus(err)
+	var apiErr *wsclient.APIError
+	code := CodeUpstreamUnavailable
+	switch {
+	case errors.Is(err, wsclient.ErrSymbolNotFound):
+		code = CodeUnknownSymbol
+	case errors.Is(err, wsclient.ErrRateLimited):
+		code = CodeUpstreamRateLimited
+	case errors.As(err, &apiErr) && status != http.StatusBadGateway && status != http.StatusServiceUnavailable:
+		code = CodeUpstreamRejected
+	}
+	writeError(w, status, code, err.Error())
+}
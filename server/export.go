@@ -0,0 +1,201 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/crypto-api-server/archive"
+	"github.com/crypto-api-server/candles"
+	"github.com/crypto-api-server/codec"
+	"github.com/crypto-api-server/config"
+	"github.com/crypto-api-server/storage"
+	"github.com/gorilla/mux"
+)
+
+// maxExportRows bounds the rows of a Parquet export, the most recent are kept.
+const maxExportRows = 100000
+
+const parquetContentType = "application/vnd.apache.parquet"
+
+var errNoCandleStore = errors.New("Candle storage is not enabled")
+
+// exportParquet encodes the stored tickers, or candles of period when
+// period is set, of symbol in [from, till] as a Parquet file. It returns
+// nil when there are none.
+func (h *HandleRequests) exportParquet(symbol string, period string, from, till time.Time) ([]byte, error) {
+	if period == "" {
+		tickers, err := h.History.History(symbol, from, till, maxExportRows)
+		if err != nil || len(tickers) == 0 {
+			return nil, err
+		}
+		return codec.MarshalTickersParquet(tickers), nil
+	}
+	store, ok := h.History.(storage.CandleStore)
+	if !ok {
+		return nil, errNoCandleStore
+	}
+	stored, err := store.Candles(symbol, period, from, till, maxExportRows)
+	if err != nil || len(stored) == 0 {
+		return nil, err
+	}
+	return codec.MarshalCandlesParquet(stored), nil
+}
+
+func exportFileName(symbol string, period string) string {
+	if period == "" {
+		return symbol + "-tickers.parquet"
+	}
+	return symbol + "-" + period + ".parquet"
+}
+
+// handleExportParquet downloads the stored history of a symbol as a Parquet
+// file, the ticker updates or, with ?period=, the candles built here.
+func (h *HandleRequests) handleExportParquet(w http.ResponseWriter, req *http.Request) {
+	if h.History == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "History storage is not enabled")
+		return
+	}
+	symbol := mux.Vars(req)["symbol"]
+	if !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	query := req.URL.Query()
+	period := query.Get("period")
+	if period != "" {
+		var ok bool
+		if period, ok = candles.NormalizePeriod(period); !ok {
+			writeError(w, http.StatusBadRequest, CodeValidation, "Invalid period, expected M1, M5 or H1")
+			return
+		}
+	}
+	from, ok := parseTimeParam(query.Get("from"), time.Unix(0, 0))
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid from")
+		return
+	}
+	till, ok := parseTimeParam(query.Get("till"), time.Now())
+	if !ok || till.Before(from) {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid till")
+		return
+	}
+
+	file, err := h.exportParquet(symbol, period, from, till)
+	if err == errNoCandleStore {
+		writeError(w, http.StatusNotFound, CodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	if file == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "No history stored for "+symbol+" in this range")
+		return
+	}
+	w.Header().Set("Content-Type", parquetContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+exportFileName(symbol, period)+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(file)
+}
+
+// startParquetExport writes the stored history to Parquet files in the
+// export directory of history every export interval, when set, until ctx is
+// done.
+func (h *HandleRequests) startParquetExport(ctx context.Context, history config.History) error {
+	if history.ExportDir == "" {
+		return nil
+	}
+	if h.History == nil {
+		return errors.New("history.exportDir requires history.backend")
+	}
+	if err := os.MkdirAll(history.ExportDir, 0755); err != nil {
+		return err
+	}
+	go h.runParquetExport(ctx, history.ExportDir, history.ExportInterval)
+	return nil
+}
+
+// runParquetExport exports every segment of interval once it is over,
+// until ctx is done.
+func (h *HandleRequests) runParquetExport(ctx context.Context, dir string, interval time.Duration) {
+	for {
+		next := time.Now().Truncate(interval).Add(interval)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		from := next.Add(-interval)
+		files, err := h.segmentFiles(from, next)
+		if err != nil {
+			log.Printf("export: %v", err)
+		}
+		if err := writeSegment(dir, from, files); err != nil {
+			log.Printf("export: %v", err)
+			continue
+		}
+		log.Printf("export: %d files written for %s", len(files), from.UTC().Format(time.RFC3339))
+	}
+}
+
+// segmentName names the segment starting at from.
+func segmentName(from time.Time) string {
+	return from.UTC().Format("20060102T150405Z")
+}
+
+// segmentFiles exports the tickers, and candles when they are stored, of
+// the feed symbols in [from, till). The files are returned with the error
+// of the exports that failed.
+func (h *HandleRequests) segmentFiles(from, till time.Time) ([]archive.File, error) {
+	periods := []string{""}
+	if _, ok := h.History.(storage.CandleStore); ok {
+		for period := range candles.Periods {
+			periods = append(periods, period)
+		}
+	}
+	// the segment excludes its end, which starts the next one
+	till = till.Add(-time.Millisecond)
+	var files []archive.File
+	failed := 0
+	for _, symbol := range h.HitWrapper.SupportedSymbols() {
+		for _, period := range periods {
+			file, err := h.exportParquet(symbol, period, from, till)
+			if err != nil {
+				failed++
+				log.Printf("export: %s %s: %v", symbol, period, err)
+				continue
+			}
+			if file != nil {
+				files = append(files, archive.File{Name: exportFileName(symbol, period), Data: file})
+			}
+		}
+	}
+	if failed > 0 {
+		return files, fmt.Errorf("%d exports failed", failed)
+	}
+	return files, nil
+}
+
+// writeSegment writes files to the directory of the segment starting at from.
+func writeSegment(dir string, from time.Time, files []archive.File) error {
+	segment := filepath.Join(dir, segmentName(from))
+	if err := os.MkdirAll(segment, 0755); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := ioutil.WriteFile(filepath.Join(segment, file.Name), file.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
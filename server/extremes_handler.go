@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/crypto-api-server/indicators"
+	"github.com/crypto-api-server/storage"
+	"github.com/crypto-api-server/wsclient"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+type ExtremesResponse struct {
+	Symbol string `json:"symbol"`
+	indicators.ExtremesResult
+}
+
+// loadExtremes adapts an ExtremesStore to the tracker of the extremes.
+func loadExtremes(store storage.ExtremesStore) indicators.ExtremesLoader {
+	return func(symbol string) (indicators.PricePoint, indicators.PricePoint, bool, error) {
+		high, low, ok, err := store.Extremes(symbol)
+		return indicators.PricePoint(high), indicators.PricePoint(low), ok, err
+	}
+}
+
+// addExtremes sets the session and all-time ranges on tickers copied from
+// the cache.
+func (h *HandleRequests) addExtremes(tickers []*wsclient.Ticker) {
+	for _, ticker := range tickers {
+		result, ok := h.Extremes.Get(ticker.Symbol)
+		if !ok {
+			continue
+		}
+		ticker.SessionHigh = decimalPtr(result.SessionHigh.Price)
+		ticker.SessionLow = decimalPtr(result.SessionLow.Price)
+		if result.AllTimeHigh != nil {
+			ticker.AllTimeHigh = decimalPtr(result.AllTimeHigh.Price)
+			ticker.AllTimeLow = decimalPtr(result.AllTimeLow.Price)
+		}
+	}
+}
+
+func decimalPtr(f float64) *decimal.Decimal {
+	d := decimal.NewFromFloat(f)
+	return &d
+}
+
+func (h *HandleRequests) handleExtremes(w http.ResponseWriter, req *http.Request) {
+	symbol := mux.Vars(req)["symbol"]
+	if !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	result, ok := h.Extremes.Get(symbol)
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeNotFound, "No price received for symbol")
+		return
+	}
+	responseJSON, err := json.Marshal(&ExtremesResponse{Symbol: symbol, ExtremesResult: result})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
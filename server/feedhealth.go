@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// feedMonitor raises an incident when the websocket feed stops delivering
+// updates for staleAfter, and another one when it recovers.
+type feedMonitor struct {
+	mutex      *sync.Mutex
+	lastUpdate time.Time
+	stale      bool
+	staleAfter time.Duration
+	handlers   []func(message string)
+}
+
+func newFeedMonitor(staleAfter time.Duration) *feedMonitor {
+	return &feedMonitor{
+		mutex:      &sync.Mutex{},
+		lastUpdate: time.Now(),
+		staleAfter: staleAfter,
+	}
+}
+
+// OnIncident registers a handler for incident messages.
+func (m *feedMonitor) OnIncident(handler func(message string)) {
+	m.mutex.Lock()
+	m.handlers = append(m.handlers, handler)
+	m.mutex.Unlock()
+}
+
+// Observe is registered as a ticker listener.
+func (m *feedMonitor) Observe(ticker *wsclient.Ticker) {
+	m.mutex.Lock()
+	m.lastUpdate = time.Now()
+	recovered := m.stale
+	m.stale = false
+	handlers := m.handlers
+	m.mutex.Unlock()
+	if recovered {
+		for _, handler := range handlers {
+			go handler("Ticker feed recovered")
+		}
+	}
+}
+
+// Run checks the feed every interval until ctx is done.
+func (m *feedMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		m.mutex.Lock()
+		since := time.Since(m.lastUpdate)
+		raise := !m.stale && since > m.staleAfter
+		if raise {
+			m.stale = true
+		}
+		handlers := m.handlers
+		m.mutex.Unlock()
+		if raise {
+			message := fmt.Sprintf("Ticker feed stale: no update for %s", since.Round(time.Second))
+			for _, handler := range handlers {
+				handler(message)
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/gorilla/mux"
+)
+
+// FeeResponse holds the commission rates of the account for a symbol next to
+// the default rates of the symbol metadata.
+type FeeResponse struct {
+	Symbol      string              `json:"symbol"`
+	FeeCurrency string              `json:"feeCurrency"`
+	Account     wsclient.TradingFee `json:"account"`
+	Default     wsclient.TradingFee `json:"default"`
+}
+
+func (h *HandleRequests) handleFees(w http.ResponseWriter, req *http.Request) {
+	symbol := mux.Vars(req)["symbol"]
+	info, ok := h.HitWrapper.Symbol(symbol)
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	fee, err := h.HitWrapper.GetTradingFee(req.Context(), symbol)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	responseJSON, err := json.Marshal(&FeeResponse{
+		Symbol:      symbol,
+		FeeCurrency: info.FeeCurrency,
+		Account:     *fee,
+		Default: wsclient.TradingFee{
+			TakeLiquidityRate:    info.TakeLiquidityRate,
+			ProvideLiquidityRate: info.ProvideLiquidityRate,
+		},
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
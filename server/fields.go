@@ -0,0 +1,123 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// tickerFields are the JSON names of the ticker fields, in struct order.
+var tickerFields = jsonFieldNames(reflect.TypeOf(wsclient.Ticker{}))
+
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseFieldsParam parses a comma separated ?fields= list into ticker field
+// names, matched case-insensitively. It returns nil when all the fields are
+// wanted.
+func parseFieldsParam(value string) ([]string, bool) {
+	if value == "" {
+		return nil, true
+	}
+	var fields []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, field := range tickerFields {
+			if strings.EqualFold(name, field) {
+				fields = append(fields, field)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return fields, len(fields) > 0
+}
+
+// tickerFieldsParam returns the ?fields= of req, which the binary formats
+// don't support.
+func tickerFieldsParam(req *http.Request, format string) ([]string, bool) {
+	fields, ok := parseFieldsParam(req.URL.Query().Get("fields"))
+	if !ok {
+		return nil, false
+	}
+	return fields, fields == nil || format == formatJSON || format == formatCSV || format == formatNDJSON
+}
+
+// tickerJSONValues returns the JSON values of the fields of t by name. The
+// fields the ticker omits, such as the cache age of a ticker fetched from
+// the REST API, are missing.
+func tickerJSONValues(t *wsclient.Ticker) (map[string]json.RawMessage, error) {
+	full, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(full, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// selectTickerFields encodes the given fields of t as a JSON object, in the
+// requested order. Fields the ticker omits are left out.
+func selectTickerFields(t *wsclient.Ticker, fields []string) (json.RawMessage, error) {
+	values, err := tickerJSONValues(t)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, field := range fields {
+		value, ok := values[field]
+		if !ok {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(field)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// encodeTickersJSON renders the tickers like encodeTickers, keeping only
+// fields.
+func encodeTickersJSON(tickers []*wsclient.Ticker, fields []string, single bool) ([]byte, error) {
+	selected := make([]json.RawMessage, 0, len(tickers))
+	for _, ticker := range tickers {
+		object, err := selectTickerFields(ticker, fields)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, object)
+	}
+	if single {
+		return selected[0], nil
+	}
+	return json.Marshal(struct {
+		Currencies []json.RawMessage `json:"currencies"`
+	}{selected})
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/crypto-api-server/codec"
+	"github.com/crypto-api-server/wsclient"
+)
+
+const (
+	formatJSON     = "json"
+	formatCSV      = "csv"
+	formatProtobuf = "protobuf"
+	formatMsgpack  = "msgpack"
+	formatNDJSON   = "ndjson"
+)
+
+var formatContentTypes = map[string]string{
+	formatJSON:     "application/json",
+	formatCSV:      "text/csv; charset=utf-8",
+	formatProtobuf: "application/x-protobuf",
+	formatMsgpack:  "application/msgpack",
+	formatNDJSON:   "application/x-ndjson",
+}
+
+// ndjsonFlushLines is how many NDJSON lines are written between flushes.
+const ndjsonFlushLines = 100
+
+// tickerCSVHeader are the CSV columns, the JSON fields of the ticker so that
+// both formats carry the same fields.
+var tickerCSVHeader = tickerFields
+
+// responseFormat picks the output format from the ?format= query parameter,
+// falling back to the Accept header. JSON is the default.
+func responseFormat(req *http.Request) (string, bool) {
+	if format := strings.ToLower(req.URL.Query().Get("format")); format != "" {
+		_, ok := formatContentTypes[format]
+		return format, ok
+	}
+	accept := req.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "text/csv"):
+		return formatCSV, true
+	case strings.Contains(accept, "application/x-protobuf"), strings.Contains(accept, "application/protobuf"):
+		return formatProtobuf, true
+	case strings.Contains(accept, "application/msgpack"), strings.Contains(accept, "application/x-msgpack"):
+		return formatMsgpack, true
+	case strings.Contains(accept, "application/x-ndjson"), strings.Contains(accept, "application/ndjson"):
+		return formatNDJSON, true
+	}
+	return formatJSON, true
+}
+
+// encodeTickers renders tickers in the given format. A single ticker is
+// encoded on its own, otherwise the list is wrapped like Response. Non-nil
+// fields restrict the JSON and CSV output to these ticker fields.
+func encodeTickers(format string, tickers []*wsclient.Ticker, fields []string, single bool) ([]byte, error) {
+	switch format {
+	case formatCSV:
+		return encodeTickersCSV(tickers, fields)
+	case formatProtobuf:
+		if single {
+			return codec.MarshalTickerProto(tickers[0]), nil
+		}
+		return codec.MarshalTickerListProto(tickers), nil
+	case formatMsgpack:
+		if single {
+			return codec.MarshalTickerMsgpack(tickers[0]), nil
+		}
+		return codec.MarshalTickerListMsgpack(tickers), nil
+	case formatNDJSON:
+		var buf bytes.Buffer
+		for _, ticker := range tickers {
+			if err := writeTickerLine(&buf, ticker, fields); err != nil {
+				return nil, err
+			}
+		}
+		return buf.Bytes(), nil
+	}
+	if fields != nil {
+		return encodeTickersJSON(tickers, fields, single)
+	}
+	if single {
+		return json.Marshal(tickers[0])
+	}
+	return json.Marshal(Response{Currencies: tickers})
+}
+
+// tickerCSVRecord flattens a ticker into a row matching tickerCSVHeader, with
+// the JSON values of its fields. Strings are unquoted, objects such as the
+// indicators are left as JSON, and the fields the ticker omits are empty.
+func tickerCSVRecord(t *wsclient.Ticker) ([]string, error) {
+	values, err := tickerJSONValues(t)
+	if err != nil {
+		return nil, err
+	}
+	record := make([]string, len(tickerCSVHeader))
+	for i, name := range tickerCSVHeader {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		var text string
+		if json.Unmarshal(value, &text) == nil {
+			record[i] = text
+			continue
+		}
+		record[i] = string(value)
+	}
+	return record, nil
+}
+
+// csvColumns returns the indexes in tickerCSVHeader of fields, in their
+// order, or of every column when fields is nil. Fields without a column are
+// skipped.
+func csvColumns(fields []string) []int {
+	var columns []int
+	if fields == nil {
+		for i := range tickerCSVHeader {
+			columns = append(columns, i)
+		}
+		return columns
+	}
+	for _, field := range fields {
+		for i, name := range tickerCSVHeader {
+			if strings.EqualFold(name, field) {
+				columns = append(columns, i)
+			}
+		}
+	}
+	return columns
+}
+
+// pickColumns returns the values of record at columns.
+func pickColumns(record []string, columns []int) []string {
+	picked := make([]string, len(columns))
+	for i, column := range columns {
+		picked[i] = record[column]
+	}
+	return picked
+}
+
+// encodeTickersCSV renders the tickers as CSV with a header row, keeping the
+// columns of fields.
+func encodeTickersCSV(tickers []*wsclient.Ticker, fields []string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	columns := csvColumns(fields)
+	if err := writer.Write(pickColumns(tickerCSVHeader, columns)); err != nil {
+		return nil, err
+	}
+	for _, ticker := range tickers {
+		record, err := tickerCSVRecord(ticker)
+		if err != nil {
+			return nil, err
+		}
+		if err := writer.Write(pickColumns(record, columns)); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeTickerLine writes the ticker, or its fields when not nil, as a line of
+// JSON.
+func writeTickerLine(w io.Writer, ticker *wsclient.Ticker, fields []string) error {
+	var line []byte
+	var err error
+	if fields != nil {
+		line, err = selectTickerFields(ticker, fields)
+	} else {
+		line, err = json.Marshal(ticker)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// streamTickersNDJSON writes the cached tickers passing filter one per line as
+// they are read from the cache, so the full list is never held in memory.
+// Tickers are converted to quote when it is set, those without a rate are
+// left out like in the JSON list.
+func (h *HandleRequests) streamTickersNDJSON(w http.ResponseWriter, req *http.Request, quote string, fields []string, filter marketFilter) {
+	flusher, _ := w.(http.Flusher)
+	cached, written := 0, 0
+	var streamErr error
+	h.HitWrapper.RangeCurrencies(func(ticker *wsclient.Ticker) bool {
+		cached++
+		if !h.keep(filter, ticker) {
+			return true
+		}
+		h.HitWrapper.RecordAccess(ticker.Symbol)
+		h.addExtremes([]*wsclient.Ticker{ticker})
+		if quote != "" {
+			converted, err := h.convertTickers(req.Context(), []*wsclient.Ticker{ticker}, quote)
+			if err != nil || len(converted) == 0 {
+				return true
+			}
+			ticker = converted[0]
+		}
+		if written == 0 {
+			h.flagStale(w)
+			w.Header().Set("Content-Type", formatContentTypes[formatNDJSON])
+			w.WriteHeader(http.StatusOK)
+		}
+		if streamErr = writeTickerLine(w, ticker, fields); streamErr != nil {
+			return false
+		}
+		written++
+		if flusher != nil && written%ndjsonFlushLines == 0 {
+			flusher.Flush()
+		}
+		return req.Context().Err() == nil
+	})
+	if cached == 0 {
+		writeError(w, http.StatusNotFound, CodeNotFound, "No data Found")
+		return
+	}
+	if written == 0 {
+		// no market passed the filter
+		writeFormattedResponse(w, http.StatusOK, formatNDJSON, nil)
+		return
+	}
+	if streamErr != nil {
+		log.Printf("streaming tickers: %v", streamErr)
+	}
+}
+
+// writeFormattedResponse writes a successful body with the content type of format.
+func writeFormattedResponse(w http.ResponseWriter, code int, format string, response []byte) {
+	w.Header().Set("Content-Type", formatContentTypes[format])
+	w.WriteHeader(code)
+	w.Write(response)
+}
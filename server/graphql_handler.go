@@ -0,0 +1,272 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/crypto-api-server/graphql"
+	"github.com/crypto-api-server/tenants"
+	"github.com/crypto-api-server/wsclient"
+)
+
+// graphQLSchema exposes tickers, symbols and currency metadata.
+//
+//	tickers(symbols: [String], baseCurrency: String, quoteCurrency: String): [Ticker]
+//	ticker(symbol: String!): Ticker
+//	symbols(ids: [String], baseCurrency: String, quoteCurrency: String): [Symbol]
+//	symbol(id: String!): Symbol
+//	currencies(ids: [String]): [Currency]
+//	currency(id: String!): Currency
+//
+// Tickers and symbols are those allowed to tenant, the upstream calls are
+// canceled with ctx.
+func (h *HandleRequests) graphQLSchema(ctx context.Context, tenant *tenants.Tenant) *graphql.Schema {
+	scoped := func(resolve func(context.Context, *tenants.Tenant, map[string]interface{}) (interface{}, error)) graphql.Resolver {
+		return func(args map[string]interface{}) (interface{}, error) {
+			return resolve(ctx, tenant, args)
+		}
+	}
+	return &graphql.Schema{Query: graphql.Object{
+		"tickers":    scoped(h.resolveTickers),
+		"ticker":     scoped(h.resolveTicker),
+		"symbols":    scoped(h.resolveSymbols),
+		"symbol":     scoped(h.resolveSymbol),
+		"currencies": graphql.Resolver(h.resolveCurrencies),
+		"currency":   graphql.Resolver(h.resolveCurrency),
+	}}
+}
+
+func (h *HandleRequests) handleGraphQL(w http.ResponseWriter, req *http.Request) {
+	var gqlReq graphql.Request
+	if req.Method == http.MethodPost {
+		body, err := io.ReadAll(io.LimitReader(req.Body, MaxBodyBytes))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeValidation, err.Error())
+			return
+		}
+		if err = json.Unmarshal(body, &gqlReq); err != nil {
+			writeError(w, http.StatusBadRequest, CodeValidation, "Invalid GraphQL request body")
+			return
+		}
+	} else {
+		gqlReq.Query = req.URL.Query().Get("query")
+		if variables := req.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &gqlReq.Variables); err != nil {
+				writeError(w, http.StatusBadRequest, CodeValidation, "Invalid GraphQL variables")
+				return
+			}
+		}
+	}
+	if strings.TrimSpace(gqlReq.Query) == "" {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Missing GraphQL query")
+		return
+	}
+
+	responseJSON, err := json.Marshal(h.graphQLSchema(req.Context(), tenants.FromContext(req.Context())).Execute(gqlReq))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+func (h *HandleRequests) resolveTickers(ctx context.Context, tenant *tenants.Tenant, args map[string]interface{}) (interface{}, error) {
+	symbols, err := graphql.StringListArg(args, "symbols")
+	if err != nil {
+		return nil, err
+	}
+	base, err := graphql.StringArg(args, "baseCurrency")
+	if err != nil {
+		return nil, err
+	}
+	quote, err := graphql.StringArg(args, "quoteCurrency")
+	if err != nil {
+		return nil, err
+	}
+	// an empty cache is not an error for a filtered listing
+	tickers, _ := h.GetAllCurrencies()
+
+	objects := make([]graphql.Object, 0, len(tickers))
+	for _, ticker := range tickers {
+		if len(symbols) > 0 && !h.HitWrapper.Contains(symbols, ticker.Symbol) || !tenant.Allows(ticker.Symbol) {
+			continue
+		}
+		info, _ := h.HitWrapper.Symbol(ticker.Symbol)
+		if base != "" && !strings.EqualFold(info.BaseCurrency, base) {
+			continue
+		}
+		if quote != "" && !strings.EqualFold(info.QuoteCurrency, quote) {
+			continue
+		}
+		h.HitWrapper.RecordAccess(ticker.Symbol)
+		objects = append(objects, h.tickerObject(ticker))
+	}
+	return objects, nil
+}
+
+func (h *HandleRequests) resolveTicker(ctx context.Context, tenant *tenants.Tenant, args map[string]interface{}) (interface{}, error) {
+	symbol, err := graphql.StringArg(args, "symbol")
+	if err != nil {
+		return nil, err
+	}
+	if !h.knownSymbol(symbol) {
+		return nil, errors.New("Not a valid Symbol")
+	}
+	if !tenant.Allows(symbol) {
+		return nil, errors.New("Symbol not allowed")
+	}
+	ticker, err := h.HitWrapper.GetMarketSummary(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if ticker == nil {
+		return nil, nil
+	}
+	return h.tickerObject(ticker), nil
+}
+
+func (h *HandleRequests) resolveSymbols(ctx context.Context, tenant *tenants.Tenant, args map[string]interface{}) (interface{}, error) {
+	ids, err := graphql.StringListArg(args, "ids")
+	if err != nil {
+		return nil, err
+	}
+	base, err := graphql.StringArg(args, "baseCurrency")
+	if err != nil {
+		return nil, err
+	}
+	quote, err := graphql.StringArg(args, "quoteCurrency")
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]graphql.Object, 0)
+	for _, id := range h.HitWrapper.AllSymbols() {
+		if len(ids) > 0 && !h.HitWrapper.Contains(ids, id) || !tenant.Allows(id) {
+			continue
+		}
+		info, _ := h.HitWrapper.Symbol(id)
+		if base != "" && !strings.EqualFold(info.BaseCurrency, base) {
+			continue
+		}
+		if quote != "" && !strings.EqualFold(info.QuoteCurrency, quote) {
+			continue
+		}
+		objects = append(objects, h.symbolObject(info))
+	}
+	return objects, nil
+}
+
+func (h *HandleRequests) resolveSymbol(ctx context.Context, tenant *tenants.Tenant, args map[string]interface{}) (interface{}, error) {
+	id, err := graphql.StringArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+	info, ok := h.HitWrapper.Symbol(id)
+	if !ok || !tenant.Allows(id) {
+		return nil, nil
+	}
+	return h.symbolObject(info), nil
+}
+
+func (h *HandleRequests) resolveCurrencies(args map[string]interface{}) (interface{}, error) {
+	ids, err := graphql.StringListArg(args, "ids")
+	if err != nil {
+		return nil, err
+	}
+	currencies := h.HitWrapper.Currencies()
+	currencyIDs := make([]string, 0, len(currencies))
+	for id := range currencies {
+		if len(ids) > 0 && !h.HitWrapper.Contains(ids, id) {
+			continue
+		}
+		currencyIDs = append(currencyIDs, id)
+	}
+	sort.Strings(currencyIDs)
+	objects := make([]graphql.Object, 0, len(currencyIDs))
+	for _, id := range currencyIDs {
+		objects = append(objects, currencyObject(currencies[id]))
+	}
+	return objects, nil
+}
+
+func (h *HandleRequests) resolveCurrency(args map[string]interface{}) (interface{}, error) {
+	id, err := graphql.StringArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+	currency, ok := h.HitWrapper.Currency(id)
+	if !ok {
+		return nil, nil
+	}
+	return currencyObject(currency), nil
+}
+
+func (h *HandleRequests) tickerObject(t *wsclient.Ticker) graphql.Object {
+	timestamp := ""
+	if !t.Timestamp.IsZero() {
+		timestamp = t.Timestamp.Format(time.RFC3339Nano)
+	}
+	obj := graphql.Object{
+		"id":               t.ID,
+		"fullName":         t.FullName,
+		"symbol":           t.Symbol,
+		"feeCurrency":      t.FeeCurrency,
+		"ask":              t.Ask,
+		"bid":              t.Bid,
+		"last":             t.Last,
+		"open":             t.Open,
+		"low":              t.Low,
+		"high":             t.High,
+		"volume":           t.Volume,
+		"volumeQuote":      t.VolumeQuote,
+		"timestamp":        timestamp,
+		"market":           nil,
+		"changeAbs24h":     t.ChangeAbs24h,
+		"changePercent24h": t.ChangePercent24h,
+	}
+	if info, ok := h.HitWrapper.Symbol(t.Symbol); ok {
+		obj["market"] = h.symbolObject(info)
+	}
+	return obj
+}
+
+func (h *HandleRequests) symbolObject(s wsclient.Symbol) graphql.Object {
+	obj := graphql.Object{
+		"id":                   s.Id,
+		"baseCurrency":         s.BaseCurrency,
+		"quoteCurrency":        s.QuoteCurrency,
+		"quantityIncrement":    s.QuantityIncrement,
+		"tickSize":             s.TickSize,
+		"takeLiquidityRate":    s.TakeLiquidityRate,
+		"provideLiquidityRate": s.ProvideLiquidityRate,
+		"feeCurrency":          s.FeeCurrency,
+		"base":                 nil,
+		"quote":                nil,
+	}
+	if currency, ok := h.HitWrapper.Currency(s.BaseCurrency); ok {
+		obj["base"] = currencyObject(currency)
+	}
+	if currency, ok := h.HitWrapper.Currency(s.QuoteCurrency); ok {
+		obj["quote"] = currencyObject(currency)
+	}
+	return obj
+}
+
+func currencyObject(c wsclient.Currency) graphql.Object {
+	return graphql.Object{
+		"id":                 c.Id,
+		"fullName":           c.FullName,
+		"crypto":             c.Crypto,
+		"payinEnabled":       c.PayinEnabled,
+		"payinPaymentId":     c.PayinPaymentId,
+		"payinConfirmations": c.PayinConfirmations,
+		"payoutEnabled":      c.PayoutEnabled,
+		"payoutIsPaymentId":  c.PayoutIsPaymentId,
+		"transferEnabled":    c.TransferEnabled,
+	}
+}
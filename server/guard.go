@@ -0,0 +1,62 @@
+package server
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// allowedMethods are the methods of the API routes, the others are rejected
+// before routing.
+var allowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+
+// guardRequests rejects the requests no route accepts before they are
+// routed: methods the API doesn't use, bodies announced over MaxBodyBytes
+// and bodies that aren't JSON. Bodies of unknown length fail to read past
+// MaxBodyBytes.
+func (h *HandleRequests) guardRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !methodAllowed(req.Method) {
+			w.Header().Set("Allow", strings.Join(allowedMethods, ", "))
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed: "+req.Method)
+			return
+		}
+		if req.ContentLength > MaxBodyBytes {
+			writeError(w, http.StatusRequestEntityTooLarge, CodePayloadTooLarge,
+				"Request body is larger than "+strconv.FormatInt(MaxBodyBytes, 10)+" bytes")
+			return
+		}
+		// ContentLength is -1 for bodies of unknown length
+		if req.ContentLength != 0 {
+			mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+			if err != nil || mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, CodeUnsupportedMedia,
+					"Request bodies must be application/json")
+				return
+			}
+		}
+		req.Body = http.MaxBytesReader(w, req.Body, MaxBodyBytes)
+		next.ServeHTTP(w, req)
+	})
+}
+
+func methodAllowed(method string) bool {
+	for _, allowed := range allowedMethods {
+		if method == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// handleNotFound answers the requests matching no route.
+func handleNotFound(w http.ResponseWriter, req *http.Request) {
+	writeError(w, http.StatusNotFound, CodeNotFound, "No endpoint at "+req.URL.Path)
+}
+
+// handleMethodNotAllowed answers the requests matching the path of a route
+// but none of its methods.
+func handleMethodNotAllowed(w http.ResponseWriter, req *http.Request) {
+	writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed on "+req.URL.Path+": "+req.Method)
+}
@@ -0,0 +1,15 @@
+//go:build go1.24
+// +build go1.24
+
+package server
+
+import "net/http"
+
+// enableH2C lets server accept HTTP/2 without TLS next to HTTP/1.
+func enableH2C(server *http.Server) error {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	server.Protocols = protocols
+	return nil
+}
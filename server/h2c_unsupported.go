@@ -0,0 +1,14 @@
+//go:build !go1.24
+// +build !go1.24
+
+package server
+
+import (
+	"errors"
+	"net/http"
+)
+
+// enableH2C fails, cleartext HTTP/2 needs the net/http of Go 1.24.
+func enableH2C(server *http.Server) error {
+	return errors.New("server.h2c needs a build with Go 1.24 or later")
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+type HistoryResponse struct {
+	Symbol  string             `json:"symbol"`
+	History []*wsclient.Ticker `json:"history"`
+}
+
+// parseTimeParam accepts RFC 3339 timestamps or Unix milliseconds.
+func parseTimeParam(value string, fallback time.Time) (time.Time, bool) {
+	if value == "" {
+		return fallback, true
+	}
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(0, ms*int64(time.Millisecond)), true
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	return t, err == nil
+}
+
+// parseLimitParam parses ?limit=, applying the default and the maximum.
+func parseLimitParam(value string, defaultLimit int, maxLimit int) (int, bool) {
+	if value == "" {
+		return defaultLimit, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 0, false
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	return limit, true
+}
+
+func (h *HandleRequests) handleHistory(w http.ResponseWriter, req *http.Request) {
+	if h.History == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "History storage is not enabled")
+		return
+	}
+	symbol := mux.Vars(req)["symbol"]
+	if !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	query := req.URL.Query()
+	from, ok := parseTimeParam(query.Get("from"), time.Unix(0, 0))
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid from")
+		return
+	}
+	till, ok := parseTimeParam(query.Get("till"), time.Now())
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid till")
+		return
+	}
+	limit, ok := parseLimitParam(query.Get("limit"), defaultHistoryLimit, maxHistoryLimit)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid limit")
+		return
+	}
+
+	history, err := h.History.History(symbol, from, till, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	responseJSON, err := json.Marshal(&HistoryResponse{Symbol: symbol, History: history})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+func (h *HandleRequests) handleRecentHistory(w http.ResponseWriter, req *http.Request) {
+	symbol := mux.Vars(req)["symbol"]
+	if !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	limit, ok := parseLimitParam(req.URL.Query().Get("limit"), defaultHistoryLimit, h.Recent.Size())
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid limit")
+		return
+	}
+
+	responseJSON, err := json.Marshal(&HistoryResponse{Symbol: symbol, History: h.Recent.Last(symbol, limit)})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
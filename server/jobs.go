@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/crypto-api-server/config"
+	"github.com/crypto-api-server/scheduler"
+	"github.com/gorilla/mux"
+)
+
+// jobTimeout bounds a run of a scheduled job.
+const jobTimeout = 30 * time.Minute
+
+// SummaryReport is the market summary written by the report job.
+type SummaryReport struct {
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Stats       *StatsResponse `json:"stats"`
+	Gainers     []Mover        `json:"gainers"`
+	Losers      []Mover        `json:"losers"`
+}
+
+type JobsResponse struct {
+	Jobs []scheduler.JobStatus `json:"jobs"`
+}
+
+// startJobs schedules the configured jobs until ctx is done. The archive job
+// needs the archiver of the archive bucket.
+func (h *HandleRequests) startJobs(ctx context.Context, jobs config.Jobs, feed config.Feed, archiver *archiver) error {
+	if jobs.Snapshot != "" {
+		dir := jobs.SnapshotDir
+		if err := h.Jobs.Add("snapshot", jobs.Snapshot, jobTimeout, func(ctx context.Context) error {
+			return h.writeSnapshot(dir, time.Now())
+		}); err != nil {
+			return err
+		}
+	}
+	if jobs.MetadataRefresh != "" {
+		quotes := feed.AutoQuotes
+		if err := h.Jobs.Add("metadataRefresh", jobs.MetadataRefresh, jobTimeout, func(ctx context.Context) error {
+			return h.refreshMetadata(quotes)
+		}); err != nil {
+			return err
+		}
+	}
+	if jobs.Archive != "" {
+		if archiver == nil {
+			return errors.New("jobs.archive requires archive.bucket")
+		}
+		if err := h.Jobs.Add("archive", jobs.Archive, jobTimeout, func(ctx context.Context) error {
+			return archiver.archiveDue(time.Now())
+		}); err != nil {
+			return err
+		}
+	}
+	if jobs.Report != "" {
+		dir := jobs.ReportDir
+		if err := h.Jobs.Add("report", jobs.Report, jobTimeout, func(ctx context.Context) error {
+			return h.writeReport(dir, time.Now())
+		}); err != nil {
+			return err
+		}
+	}
+	h.Jobs.Start(ctx)
+	return nil
+}
+
+// writeSnapshot writes the cached tickers to a JSON file in dir, named
+// after now.
+func (h *HandleRequests) writeSnapshot(dir string, now time.Time) error {
+	tickers, err := h.HitWrapper.GetCurrenciesFromCache()
+	if err != nil {
+		return err
+	}
+	return writeJobFile(dir, "tickers-"+now.UTC().Format("20060102T150405Z")+".json", tickers)
+}
+
+// writeReport writes the market summary to a JSON file in dir, named after
+// now, or logs it when dir is empty.
+func (h *HandleRequests) writeReport(dir string, now time.Time) error {
+	tickers, err := h.HitWrapper.GetCurrenciesFromCache()
+	if err != nil {
+		return err
+	}
+	report := SummaryReport{GeneratedAt: now, Stats: h.computeStats(tickers, now)}
+	report.Gainers, report.Losers = computeMovers(tickers, defaultMoversLimit)
+	if dir != "" {
+		return writeJobFile(dir, "report-"+now.UTC().Format("20060102T150405Z")+".json", &report)
+	}
+	encoded, err := json.Marshal(&report)
+	if err != nil {
+		return err
+	}
+	log.Printf("report: %s", encoded)
+	return nil
+}
+
+func writeJobFile(dir string, name string, value interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, name), encoded, 0644)
+}
+
+func (h *HandleRequests) handleJobs(w http.ResponseWriter, req *http.Request) {
+	responseJSON, err := json.Marshal(&JobsResponse{Jobs: h.Jobs.Status()})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+// handleRunJob starts a run of a job outside of its schedule and answers
+// with the status of the jobs.
+func (h *HandleRequests) handleRunJob(w http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+	switch h.Jobs.Trigger(name) {
+	case scheduler.ErrUnknownJob:
+		writeError(w, http.StatusNotFound, CodeNotFound, "Job not scheduled: "+name)
+		return
+	case scheduler.ErrJobRunning:
+		writeError(w, http.StatusConflict, CodeJobRunning, "Job is running: "+name)
+		return
+	}
+	responseJSON, err := json.Marshal(&JobsResponse{Jobs: h.Jobs.Status()})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusAccepted, responseJSON)
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"time"
+
+	"github.com/crypto-api-server/config"
+)
+
+// shutdownTimeout bounds the wait for the requests in progress when Run
+// stops.
+const shutdownTimeout = 10 * time.Second
+
+// Run serves the API on the TCP address and the Unix socket of the
+// configuration, and the admin routes and the debug endpoints on their own
+// addresses when configured. It returns when ctx is done, after the requests
+// in progress complete, or when a listener fails, and closes the server
+// then, see Close. HTTP/2 is negotiated on TLS, and accepted on plain HTTP
+// with h2c.
+func (s *Server) Run(ctx context.Context) error {
+	cfg := s.cfg
+	listeners, err := listen(cfg)
+	if err != nil {
+		return err
+	}
+	handlers := make(map[net.Listener]http.Handler)
+	for _, listener := range listeners {
+		handlers[listener] = s.Handler()
+	}
+	if cfg.Server.AdminListen != "" {
+		listener, err := net.Listen("tcp", cfg.Server.AdminListen)
+		if err != nil {
+			closeAll(handlers)
+			return err
+		}
+		handlers[listener] = s.h.withRequestID(s.h.guardRequests(s.h.newRouter(false, true)))
+	}
+	if cfg.Server.DebugListen != "" {
+		listener, err := net.Listen("tcp", cfg.Server.DebugListen)
+		if err != nil {
+			closeAll(handlers)
+			return err
+		}
+		handlers[listener] = s.h.debugHandler()
+	}
+
+	servers := make([]*http.Server, 0, len(handlers))
+	failed := make(chan error, len(handlers))
+	for listener, handler := range handlers {
+		server := newHTTPServer(cfg.Server, handler)
+		if cfg.Server.H2C {
+			if err := enableH2C(server); err != nil {
+				closeAll(handlers)
+				return err
+			}
+		}
+		servers = append(servers, server)
+		go func(server *http.Server, listener net.Listener) {
+			failed <- serve(server, listener, cfg.Server)
+		}(server, listener)
+	}
+
+	select {
+	case err = <-failed:
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		server.Shutdown(shutdownCtx)
+	}
+	if closeErr := s.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func closeAll(handlers map[net.Listener]http.Handler) {
+	for listener := range handlers {
+		listener.Close()
+	}
+}
+
+// debugHandler serves the runtime profiles of net/http/pprof and the expvar
+// metrics, with the figures of /stats.
+func (h *HandleRequests) debugHandler() http.Handler {
+	debug := http.NewServeMux()
+	debug.HandleFunc("/debug/pprof/", pprof.Index)
+	debug.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	debug.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	debug.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	debug.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	debug.HandleFunc("/debug/vars", h.handleVars)
+	return debug
+}
+
+// handleVars serves the expvar variables like expvar.Handler, with the
+// figures of /stats of this server as "stats". They aren't published with
+// expvar, whose variables are global to the process.
+func (h *HandleRequests) handleVars(w http.ResponseWriter, req *http.Request) {
+	// monitoring scrapes don't count as accesses, the figures are those of
+	// /stats unscoped
+	tickers, _ := h.HitWrapper.GetCurrenciesFromCache()
+	stats, err := json.Marshal(h.computeStats(tickers, time.Now()))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprintf(w, "{\n%q: %s", "stats", stats)
+	expvar.Do(func(kv expvar.KeyValue) {
+		if kv.Key != "stats" {
+			fmt.Fprintf(w, ",\n%q: %s", kv.Key, kv.Value)
+		}
+	})
+	fmt.Fprintf(w, "\n}\n")
+}
+
+// newHTTPServer returns a server of handler with the limits of limits.
+func newHTTPServer(limits config.Server, handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: limits.ReadHeaderTimeout,
+		ReadTimeout:       limits.ReadTimeout,
+		WriteTimeout:      limits.WriteTimeout,
+		IdleTimeout:       limits.IdleTimeout,
+		MaxHeaderBytes:    limits.MaxHeaderBytes,
+	}
+}
+
+// serve accepts the connections of listener, over TLS when configured.
+func serve(server *http.Server, listener net.Listener, settings config.Server) error {
+	if settings.TLSCertFile != "" {
+		return server.ServeTLS(listener, settings.TLSCertFile, settings.TLSKeyFile)
+	}
+	return server.Serve(listener)
+}
+
+// listen opens the TCP address and the Unix socket of cfg, when set.
+func listen(cfg *config.Config) ([]net.Listener, error) {
+	var listeners []net.Listener
+	if cfg.Listen != "" {
+		listener, err := net.Listen("tcp", cfg.Listen)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	if cfg.Server.UnixSocket != "" {
+		// validated with the configuration
+		mode, _ := cfg.Server.SocketMode()
+		listener, err := listenUnix(cfg.Server.UnixSocket, mode)
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// listenUnix listens on a Unix socket at path with the permissions of mode.
+// A socket left at path by a previous run is replaced, other files are not.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/crypto-api-server/tenants"
+	"github.com/crypto-api-server/wsclient"
+)
+
+// marketFilter keeps the tickers of the markets with the base and quote
+// currencies of ?baseCurrency= and ?quoteCurrency=, when set, and of the
+// symbols allowed to the tenant of the request. Symbols without metadata are
+// left out by any currency filter.
+type marketFilter struct {
+	base, quote string
+	tenant      *tenants.Tenant
+}
+
+func parseMarketFilter(req *http.Request) marketFilter {
+	query := req.URL.Query()
+	return marketFilter{
+		base:   strings.ToUpper(query.Get("baseCurrency")),
+		quote:  strings.ToUpper(query.Get("quoteCurrency")),
+		tenant: tenants.FromContext(req.Context()),
+	}
+}
+
+func (f marketFilter) empty() bool {
+	return f.base == "" && f.quote == "" && !f.tenant.Scoped()
+}
+
+// keep tells whether the market of ticker passes the filter.
+func (h *HandleRequests) keep(f marketFilter, ticker *wsclient.Ticker) bool {
+	if !f.tenant.Allows(ticker.Symbol) {
+		return false
+	}
+	if f.base == "" && f.quote == "" {
+		return true
+	}
+	info, ok := h.HitWrapper.Symbol(ticker.Symbol)
+	if !ok {
+		return false
+	}
+	return (f.base == "" || info.BaseCurrency == f.base) && (f.quote == "" || info.QuoteCurrency == f.quote)
+}
+
+// filterMarkets returns the tickers passing the filter.
+func (h *HandleRequests) filterMarkets(f marketFilter, tickers []*wsclient.Ticker) []*wsclient.Ticker {
+	if f.empty() {
+		return tickers
+	}
+	kept := make([]*wsclient.Ticker, 0, len(tickers))
+	for _, ticker := range tickers {
+		if h.keep(f, ticker) {
+			kept = append(kept, ticker)
+		}
+	}
+	return kept
+}
+
+// quoteOrder ranks the main quote currencies first, like exchange UIs do.
+// The other groups follow in alphabetical order.
+var quoteOrder = []string{"BTC", "ETH", "USDT", "USD"}
+
+// MarketGroup holds the tickers of the markets of a quote currency.
+type MarketGroup struct {
+	Quote      string             `json:"quote"`
+	Currencies []*wsclient.Ticker `json:"currencies"`
+}
+
+// GroupedResponse lists the tickers grouped by quote currency.
+type GroupedResponse struct {
+	Groups []MarketGroup `json:"groups"`
+}
+
+// groupByQuote buckets the tickers under the quote currency of their market,
+// keeping their order within a group. Symbols without metadata are left out.
+func (h *HandleRequests) groupByQuote(tickers []*wsclient.Ticker) []MarketGroup {
+	groups := make(map[string]*MarketGroup)
+	for _, ticker := range tickers {
+		info, ok := h.HitWrapper.Symbol(ticker.Symbol)
+		if !ok {
+			continue
+		}
+		group, ok := groups[info.QuoteCurrency]
+		if !ok {
+			group = &MarketGroup{Quote: info.QuoteCurrency}
+			groups[info.QuoteCurrency] = group
+		}
+		group.Currencies = append(group.Currencies, ticker)
+	}
+	sorted := make([]MarketGroup, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, *group)
+	}
+	rank := func(quote string) int {
+		for i, q := range quoteOrder {
+			if q == quote {
+				return i
+			}
+		}
+		return len(quoteOrder)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		ri, rj := rank(sorted[i].Quote), rank(sorted[j].Quote)
+		if ri != rj {
+			return ri < rj
+		}
+		return sorted[i].Quote < sorted[j].Quote
+	})
+	return sorted
+}
+
+// encodeGroups renders the groups as JSON, keeping only fields when not nil.
+func encodeGroups(groups []MarketGroup, fields []string) ([]byte, error) {
+	if fields == nil {
+		return json.Marshal(GroupedResponse{Groups: groups})
+	}
+	type selectedGroup struct {
+		Quote      string            `json:"quote"`
+		Currencies []json.RawMessage `json:"currencies"`
+	}
+	selected := make([]selectedGroup, 0, len(groups))
+	for _, group := range groups {
+		currencies := make([]json.RawMessage, 0, len(group.Currencies))
+		for _, ticker := range group.Currencies {
+			object, err := selectTickerFields(ticker, fields)
+			if err != nil {
+				return nil, err
+			}
+			currencies = append(currencies, object)
+		}
+		selected = append(selected, selectedGroup{Quote: group.Quote, Currencies: currencies})
+	}
+	return json.Marshal(struct {
+		Groups []selectedGroup `json:"groups"`
+	}{selected})
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+// refreshMetadata caches the symbols and currencies again, which drops the
+// delisted symbols from the feed, rebuilds the symbol index and adds the new
+// listings quoted in one of quotes to the feed symbols.
+func (h *HandleRequests) refreshMetadata(quotes []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	listed, delisted, err := h.HitWrapper.RefreshMetadata(ctx)
+	if err != nil {
+		return err
+	}
+	h.rebuildSymbolIndex()
+	if len(delisted) > 0 {
+		log.Printf("delisted: %s", strings.Join(delisted, ", "))
+	}
+	if len(listed) == 0 {
+		return nil
+	}
+	log.Printf("new listings: %s", strings.Join(listed, ", "))
+	var streamed []string
+	for _, symbol := range listed {
+		if h.HitWrapper.Contains(quotes, h.HitWrapper.Symbols()[symbol].QuoteCurrency) {
+			streamed = append(streamed, symbol)
+		}
+	}
+	if len(streamed) == 0 {
+		return nil
+	}
+	// serialized with the configuration reloads, which reset the feed
+	// symbols to the configured ones
+	h.reloadMutex.Lock()
+	defer h.reloadMutex.Unlock()
+	_, _, err = h.HitWrapper.UpdateFeedSymbols(append(h.HitWrapper.SupportedSymbols(), streamed...))
+	return err
+}
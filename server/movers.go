@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultMoversLimit = 10
+	maxMoversLimit     = 100
+)
+
+type Mover struct {
+	Symbol        string          `json:"symbol"`
+	FullName      string          `json:"fullname"`
+	Open          decimal.Decimal `json:"open"`
+	Last          decimal.Decimal `json:"last"`
+	ChangePercent decimal.Decimal `json:"changePercent"`
+}
+
+type MoversResponse struct {
+	Window  string  `json:"window"`
+	Gainers []Mover `json:"gainers"`
+	Losers  []Mover `json:"losers"`
+}
+
+// computeMovers splits the tickers by their 24h percent change, largest
+// moves first. Tickers without an open price have no change and are skipped.
+func computeMovers(tickers []*wsclient.Ticker, limit int) ([]Mover, []Mover) {
+	gainers := make([]Mover, 0)
+	losers := make([]Mover, 0)
+	for _, t := range tickers {
+		mover := Mover{
+			Symbol:        t.Symbol,
+			FullName:      t.FullName,
+			Open:          t.Open,
+			Last:          t.Last,
+			ChangePercent: t.ChangePercent24h,
+		}
+		switch mover.ChangePercent.Sign() {
+		case 1:
+			gainers = append(gainers, mover)
+		case -1:
+			losers = append(losers, mover)
+		}
+	}
+	sort.Slice(gainers, func(i, j int) bool { return gainers[i].ChangePercent.GreaterThan(gainers[j].ChangePercent) })
+	sort.Slice(losers, func(i, j int) bool { return losers[i].ChangePercent.LessThan(losers[j].ChangePercent) })
+	if len(gainers) > limit {
+		gainers = gainers[:limit]
+	}
+	if len(losers) > limit {
+		losers = losers[:limit]
+	}
+	return gainers, losers
+}
+
+func (h *HandleRequests) handleMovers(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	// HitBTC's open is the price 24 hours ago, no other window is available
+	if window := query.Get("window"); window != "" && window != "24h" {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid window, only 24h is supported")
+		return
+	}
+	limit, ok := parseLimitParam(query.Get("limit"), defaultMoversLimit, maxMoversLimit)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid limit")
+		return
+	}
+	// an empty cache has no movers
+	currencies, _ := h.GetAllCurrencies()
+	currencies = scopeTickers(req, currencies)
+	h.recordAccess(currencies)
+
+	var response MoversResponse
+	response.Window = "24h"
+	response.Gainers, response.Losers = computeMovers(currencies, limit)
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
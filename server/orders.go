@@ -0,0 +1,260 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+type OrderRequest struct {
+	ClientOrderID string          `json:"clientOrderId,omitempty"`
+	Symbol        string          `json:"symbol"`
+	Side          string          `json:"side"`
+	Type          string          `json:"type,omitempty"`
+	TimeInForce   string          `json:"timeInForce,omitempty"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	Price         decimal.Decimal `json:"price,omitempty"`
+	StopPrice     decimal.Decimal `json:"stopPrice,omitempty"`
+	ExpireTime    *time.Time      `json:"expireTime,omitempty"`
+	PostOnly      bool            `json:"postOnly,omitempty"`
+}
+
+var orderTypes = map[string]bool{"limit": true, "market": true, "stopLimit": true, "stopMarket": true}
+
+var timesInForce = map[string]bool{"GTC": true, "IOC": true, "FOK": true, "Day": true, "GTD": true}
+
+// validateOrder checks the order against the cached symbol metadata so that
+// malformed orders are rejected before reaching the exchange.
+func (h *HandleRequests) validateOrder(order *OrderRequest) error {
+	symbol, ok := h.HitWrapper.Symbol(order.Symbol)
+	if !ok {
+		return fmt.Errorf("Not a valid Symbol: %s", order.Symbol)
+	}
+	if order.Side != "buy" && order.Side != "sell" {
+		return fmt.Errorf("side must be buy or sell")
+	}
+	if order.Type == "" {
+		order.Type = "limit"
+	}
+	if !orderTypes[order.Type] {
+		return fmt.Errorf("type must be limit, market, stopLimit or stopMarket")
+	}
+	if order.TimeInForce != "" && !timesInForce[order.TimeInForce] {
+		return fmt.Errorf("timeInForce must be GTC, IOC, FOK, Day or GTD")
+	}
+	if (order.TimeInForce == "GTD") != (order.ExpireTime != nil) {
+		return fmt.Errorf("expireTime is required with, and only with, timeInForce GTD")
+	}
+	if !order.Quantity.IsPositive() {
+		return fmt.Errorf("quantity must be positive")
+	}
+	if symbol.QuantityIncrement.IsPositive() && !order.Quantity.Mod(symbol.QuantityIncrement).IsZero() {
+		return fmt.Errorf("quantity must be a multiple of %s", symbol.QuantityIncrement)
+	}
+	limit := order.Type == "limit" || order.Type == "stopLimit"
+	if limit && !order.Price.IsPositive() {
+		return fmt.Errorf("price is required for %s orders", order.Type)
+	}
+	if !limit && !order.Price.IsZero() {
+		return fmt.Errorf("price is not allowed for %s orders", order.Type)
+	}
+	stop := order.Type == "stopLimit" || order.Type == "stopMarket"
+	if stop && !order.StopPrice.IsPositive() {
+		return fmt.Errorf("stopPrice is required for %s orders", order.Type)
+	}
+	if !stop && !order.StopPrice.IsZero() {
+		return fmt.Errorf("stopPrice is not allowed for %s orders", order.Type)
+	}
+	for _, price := range []decimal.Decimal{order.Price, order.StopPrice} {
+		if symbol.TickSize.IsPositive() && !price.Mod(symbol.TickSize).IsZero() {
+			return fmt.Errorf("prices must be multiples of the tick size %s", symbol.TickSize)
+		}
+	}
+	return nil
+}
+
+func (h *HandleRequests) handlePlaceOrder(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, MaxBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, err.Error())
+		return
+	}
+	var orderReq OrderRequest
+	if err = json.Unmarshal(body, &orderReq); err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid order body")
+		return
+	}
+	if err = h.validateOrder(&orderReq); err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, err.Error())
+		return
+	}
+	request := wsclient.OrderRequest{
+		ClientOrderID: orderReq.ClientOrderID,
+		Symbol:        orderReq.Symbol,
+		Side:          orderReq.Side,
+		Type:          orderReq.Type,
+		TimeInForce:   orderReq.TimeInForce,
+		Quantity:      orderReq.Quantity,
+		Price:         orderReq.Price,
+		StopPrice:     orderReq.StopPrice,
+		PostOnly:      orderReq.PostOnly,
+	}
+	if orderReq.ExpireTime != nil {
+		request.ExpireTime = *orderReq.ExpireTime
+	}
+	order, err := h.HitWrapper.PlaceOrder(req.Context(), request)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	responseJSON, err := json.Marshal(order)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusCreated, responseJSON)
+}
+
+type OrdersResponse struct {
+	Orders []wsclient.Order `json:"orders"`
+}
+
+// writeOrders responds with the orders, or with the error of the exchange call.
+func writeOrders(w http.ResponseWriter, orders []wsclient.Order, err error) {
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	if orders == nil {
+		orders = []wsclient.Order{}
+	}
+	responseJSON, err := json.Marshal(&OrdersResponse{Orders: orders})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+func (h *HandleRequests) handleActiveOrders(w http.ResponseWriter, req *http.Request) {
+	symbol := req.URL.Query().Get("symbol")
+	if symbol != "" && !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+		writeError(w, http.StatusBadRequest, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	orders, err := h.HitWrapper.GetActiveOrders(req.Context(), symbol)
+	writeOrders(w, orders, err)
+}
+
+const (
+	defaultAccountHistoryLimit = 100
+	maxAccountHistoryLimit     = 1000
+)
+
+// parseHistoryFilter reads the symbol, from, till, limit and offset
+// parameters of the account history endpoints, returning the message of the
+// first invalid one.
+func (h *HandleRequests) parseHistoryFilter(req *http.Request) (wsclient.HistoryFilter, string) {
+	query := req.URL.Query()
+	filter := wsclient.HistoryFilter{Symbol: query.Get("symbol")}
+	if filter.Symbol != "" && !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), filter.Symbol) {
+		return filter, "Not a valid Symbol"
+	}
+	var ok bool
+	if filter.From, ok = parseTimeParam(query.Get("from"), time.Time{}); !ok {
+		return filter, "Invalid from"
+	}
+	if filter.Till, ok = parseTimeParam(query.Get("till"), time.Time{}); !ok {
+		return filter, "Invalid till"
+	}
+	if filter.Limit, ok = parseLimitParam(query.Get("limit"), defaultAccountHistoryLimit, maxAccountHistoryLimit); !ok {
+		return filter, "Invalid limit"
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return filter, "Invalid offset"
+		}
+		filter.Offset = offset
+	}
+	return filter, ""
+}
+
+func (h *HandleRequests) handleOrderHistory(w http.ResponseWriter, req *http.Request) {
+	filter, invalid := h.parseHistoryFilter(req)
+	if invalid != "" {
+		writeError(w, http.StatusBadRequest, CodeValidation, invalid)
+		return
+	}
+	orders, err := h.HitWrapper.GetOrderHistory(req.Context(), filter)
+	writeOrders(w, orders, err)
+}
+
+// MyTrade is an executed trade of the account. Liquidity is "taker" or
+// "maker".
+type MyTrade struct {
+	wsclient.AccountTrade
+	Liquidity string `json:"liquidity"`
+}
+
+type MyTradesResponse struct {
+	Trades []MyTrade `json:"trades"`
+}
+
+func (h *HandleRequests) handleMyTrades(w http.ResponseWriter, req *http.Request) {
+	filter, invalid := h.parseHistoryFilter(req)
+	if invalid != "" {
+		writeError(w, http.StatusBadRequest, CodeValidation, invalid)
+		return
+	}
+	trades, err := h.HitWrapper.GetTradeHistory(req.Context(), filter)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	myTrades := make([]MyTrade, 0, len(trades))
+	for _, trade := range trades {
+		liquidity := "maker"
+		if trade.Taker {
+			liquidity = "taker"
+		}
+		myTrades = append(myTrades, MyTrade{AccountTrade: trade, Liquidity: liquidity})
+	}
+	responseJSON, err := json.Marshal(&MyTradesResponse{Trades: myTrades})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+func (h *HandleRequests) handleCancelOrder(w http.ResponseWriter, req *http.Request) {
+	order, err := h.HitWrapper.CancelOrder(req.Context(), mux.Vars(req)["clientOrderId"])
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	responseJSON, err := json.Marshal(order)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+func (h *HandleRequests) handleCancelAllOrders(w http.ResponseWriter, req *http.Request) {
+	symbol := req.URL.Query().Get("symbol")
+	if symbol != "" && !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+		writeError(w, http.StatusBadRequest, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	orders, err := h.HitWrapper.CancelAllOrders(req.Context(), symbol)
+	writeOrders(w, orders, err)
+}
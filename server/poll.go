@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultPollTimeout = 30 * time.Second
+	maxPollTimeout     = 2 * time.Minute
+)
+
+// handleCurrencyPoll returns the ticker of a symbol once its timestamp is
+// after ?since=, holding the request until the next update otherwise. It
+// answers 204 when no update came within the timeout, clients then poll
+// again with the same since.
+func (h *HandleRequests) handleCurrencyPoll(w http.ResponseWriter, req *http.Request) {
+	symbol := mux.Vars(req)["symbol"]
+	if !h.knownSymbol(symbol) {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	query := req.URL.Query()
+	since, ok := parseTimeParam(query.Get("since"), time.Time{})
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid since")
+		return
+	}
+	timeout, ok := parseWindowParam(query.Get("timeout"), defaultPollTimeout, maxPollTimeout)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid timeout, expected a duration up to "+maxPollTimeout.String())
+		return
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		// waiting before reading the ticker doesn't miss an update in between
+		updated := h.Updates.Wait(symbol)
+		ticker, err := h.HitWrapper.GetMarketSummary(req.Context(), symbol)
+		if err != nil {
+			writeUpstreamError(w, err)
+			return
+		}
+		if ticker != nil && ticker.Timestamp.After(since) {
+			responseJSON, err := json.Marshal(ticker)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+				return
+			}
+			h.flagStale(w)
+			writeResponse(w, http.StatusOK, responseJSON)
+			return
+		}
+		select {
+		case <-updated:
+		case <-timer.C:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
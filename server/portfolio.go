@@ -0,0 +1,215 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/crypto-api-server/portfolio"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+type PortfolioResponse struct {
+	Holdings []portfolio.Holding `json:"holdings"`
+}
+
+type HoldingRequest struct {
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// PortfolioAsset is the valuation of a holding. Assets without a price have
+// an error and count for nothing in the totals.
+type PortfolioAsset struct {
+	Currency         string          `json:"currency"`
+	Amount           decimal.Decimal `json:"amount"`
+	Price            decimal.Decimal `json:"price"`
+	Value            decimal.Decimal `json:"value"`
+	Change24h        decimal.Decimal `json:"change24h"`
+	ChangePercent24h decimal.Decimal `json:"changePercent24h"`
+	// Weight is the percentage of the portfolio value.
+	Weight decimal.Decimal `json:"weight"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type PortfolioValueResponse struct {
+	Quote            string           `json:"quote"`
+	Value            decimal.Decimal  `json:"value"`
+	Change24h        decimal.Decimal  `json:"change24h"`
+	ChangePercent24h decimal.Decimal  `json:"changePercent24h"`
+	Assets           []PortfolioAsset `json:"assets"`
+}
+
+// routePrices prices a unit along the route at the last prices and at the
+// open prices of 24 hours ago.
+func (h *HandleRequests) routePrices(ctx context.Context, route []conversionLeg) (decimal.Decimal, decimal.Decimal, error) {
+	now, dayAgo := decimal.NewFromInt(1), decimal.NewFromInt(1)
+	for _, step := range route {
+		ticker, err := h.HitWrapper.GetMarketSummary(ctx, step.symbol.Id)
+		if err != nil {
+			return decimal.Zero, decimal.Zero, err
+		}
+		if ticker == nil || ticker.Last.IsZero() {
+			return decimal.Zero, decimal.Zero, fmt.Errorf("no price for %s", step.symbol.Id)
+		}
+		open := ticker.Open
+		if open.IsZero() {
+			open = ticker.Last
+		}
+		if step.sell {
+			now, dayAgo = now.Mul(ticker.Last), dayAgo.Mul(open)
+		} else {
+			now, dayAgo = now.Div(ticker.Last), dayAgo.Div(open)
+		}
+	}
+	return now, dayAgo, nil
+}
+
+// unitPrices returns the price of a unit of currency in quote now and 24
+// hours ago. Fiat currencies without pairs are converted through USD with
+// the reference rates, which are taken as unchanged over the day.
+func (h *HandleRequests) unitPrices(ctx context.Context, currency string, quote string) (decimal.Decimal, decimal.Decimal, error) {
+	if currency == quote {
+		return decimal.NewFromInt(1), decimal.NewFromInt(1), nil
+	}
+	var lastErr error
+	// admin only, every symbol can be traded
+	for _, route := range h.findRoutes(nil, currency, quote) {
+		now, dayAgo, err := h.routePrices(ctx, route)
+		if err == nil {
+			return now, dayAgo, nil
+		}
+		lastErr = err
+	}
+	if h.FX.Supports(currency) && h.FX.Supports(quote) {
+		rate, err := h.FX.Rate(currency, quote)
+		if err != nil {
+			return decimal.Zero, decimal.Zero, err
+		}
+		return decimal.NewFromFloat(rate), decimal.NewFromFloat(rate), nil
+	}
+	if quote != "USD" && h.FX.Supports(quote) {
+		now, dayAgo, err := h.unitPrices(ctx, currency, "USD")
+		if err != nil {
+			return decimal.Zero, decimal.Zero, err
+		}
+		rate, err := h.FX.Rate("USD", quote)
+		if err != nil {
+			return decimal.Zero, decimal.Zero, err
+		}
+		return now.Mul(decimal.NewFromFloat(rate)), dayAgo.Mul(decimal.NewFromFloat(rate)), nil
+	}
+	if lastErr != nil {
+		return decimal.Zero, decimal.Zero, lastErr
+	}
+	return decimal.Zero, decimal.Zero, fmt.Errorf("no %s price for %s", quote, currency)
+}
+
+// percentChange returns the change from before to after in percent, rounded
+// to 2 decimals.
+func percentChange(before decimal.Decimal, after decimal.Decimal) decimal.Decimal {
+	if before.IsZero() {
+		return decimal.Zero
+	}
+	return after.Sub(before).Div(before).Mul(decimal.NewFromInt(100)).Round(2)
+}
+
+func (h *HandleRequests) handlePortfolio(w http.ResponseWriter, req *http.Request) {
+	responseJSON, err := json.Marshal(&PortfolioResponse{Holdings: h.Portfolio.Holdings()})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+func (h *HandleRequests) handleSetHolding(w http.ResponseWriter, req *http.Request) {
+	currency := strings.ToUpper(mux.Vars(req)["currency"])
+	if len(h.HitWrapper.Currencies()) > 0 {
+		if _, ok := h.HitWrapper.Currency(currency); !ok && !h.FX.Supports(currency) {
+			writeError(w, http.StatusBadRequest, CodeUnknownCurrency, "Not a valid currency: "+currency)
+			return
+		}
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, MaxBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, err.Error())
+		return
+	}
+	var holdingReq HoldingRequest
+	if err = json.Unmarshal(body, &holdingReq); err != nil || holdingReq.Amount.IsNegative() {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid holding body")
+		return
+	}
+	if err = h.Portfolio.Set(currency, holdingReq.Amount); err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	responseJSON, err := json.Marshal(&portfolio.Holding{Currency: currency, Amount: holdingReq.Amount})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+func (h *HandleRequests) handleDeleteHolding(w http.ResponseWriter, req *http.Request) {
+	removed, err := h.Portfolio.Remove(strings.ToUpper(mux.Vars(req)["currency"]))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Holding not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HandleRequests) handlePortfolioValue(w http.ResponseWriter, req *http.Request) {
+	quote := strings.ToUpper(req.URL.Query().Get("quote"))
+	if quote == "" {
+		quote = "USD"
+	}
+	if _, ok := h.HitWrapper.Currency(quote); !ok && !h.FX.Supports(quote) {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Unsupported quote currency")
+		return
+	}
+
+	response := PortfolioValueResponse{Quote: quote, Assets: []PortfolioAsset{}}
+	dayAgoValue := decimal.Zero
+	for _, holding := range h.Portfolio.Holdings() {
+		asset := PortfolioAsset{Currency: holding.Currency, Amount: holding.Amount}
+		now, dayAgo, err := h.unitPrices(req.Context(), holding.Currency, quote)
+		if err != nil {
+			asset.Error = err.Error()
+			response.Assets = append(response.Assets, asset)
+			continue
+		}
+		asset.Price = now
+		asset.Value = holding.Amount.Mul(now)
+		asset.Change24h = asset.Value.Sub(holding.Amount.Mul(dayAgo))
+		asset.ChangePercent24h = percentChange(dayAgo, now)
+		response.Value = response.Value.Add(asset.Value)
+		dayAgoValue = dayAgoValue.Add(holding.Amount.Mul(dayAgo))
+		response.Assets = append(response.Assets, asset)
+	}
+	response.Change24h = response.Value.Sub(dayAgoValue)
+	response.ChangePercent24h = percentChange(dayAgoValue, response.Value)
+	if response.Value.IsPositive() {
+		for i := range response.Assets {
+			response.Assets[i].Weight = response.Assets[i].Value.Div(response.Value).Mul(decimal.NewFromInt(100)).Round(2)
+		}
+	}
+
+	responseJSON, err := json.Marshal(&response)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	h.flagStale(w)
+	writeResponse(w, http.StatusOK, responseJSON)
+}
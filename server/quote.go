@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/crypto-api-server/tenants"
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+// quoteParam returns the ?quote= fiat currency, "" when prices are kept in
+// the quote currency of each symbol.
+func (h *HandleRequests) quoteParam(req *http.Request) (string, bool) {
+	quote := strings.ToUpper(req.URL.Query().Get("quote"))
+	if quote == "" {
+		return "", true
+	}
+	return quote, h.FX.Supports(quote)
+}
+
+// quoteRate returns the units of the fiat quote per unit of the quote
+// currency of symbol. Crypto quote currencies are first converted to USD or
+// EUR through the exchange pairs the tenant of ctx can query, then to quote
+// with the reference rates.
+func (h *HandleRequests) quoteRate(ctx context.Context, symbol string, quote string) (decimal.Decimal, error) {
+	market, ok := h.HitWrapper.Symbol(symbol)
+	if !ok {
+		return decimal.Zero, fmt.Errorf("unknown symbol %s", symbol)
+	}
+	from := market.QuoteCurrency
+	if from == quote {
+		return decimal.NewFromInt(1), nil
+	}
+	if h.FX.Supports(from) {
+		rate, err := h.FX.Rate(from, quote)
+		return decimal.NewFromFloat(rate), err
+	}
+	for _, fiat := range []string{"USD", "EUR"} {
+		for _, route := range h.findRoutes(tenants.FromContext(ctx), from, fiat) {
+			_, price, err := h.priceRoute(ctx, route, decimal.NewFromInt(1))
+			if err != nil {
+				continue
+			}
+			rate, err := h.FX.Rate(fiat, quote)
+			if err != nil {
+				return decimal.Zero, err
+			}
+			return price.Mul(decimal.NewFromFloat(rate)), nil
+		}
+	}
+	return decimal.Zero, fmt.Errorf("no %s rate for %s", quote, from)
+}
+
+// convertTickers returns copies of tickers with prices and quote volume in
+// quote. Tickers without a rate are left out.
+func (h *HandleRequests) convertTickers(ctx context.Context, tickers []*wsclient.Ticker, quote string) ([]*wsclient.Ticker, error) {
+	rates := make(map[string]decimal.Decimal)
+	converted := make([]*wsclient.Ticker, 0, len(tickers))
+	var lastErr error
+	for _, ticker := range tickers {
+		market := h.HitWrapper.Symbols()[ticker.Symbol].QuoteCurrency
+		rate, ok := rates[market]
+		if !ok {
+			var err error
+			if rate, err = h.quoteRate(ctx, ticker.Symbol, quote); err != nil {
+				lastErr = err
+				continue
+			}
+			rates[market] = rate
+		}
+		copied := *ticker
+		copied.Ask = copied.Ask.Mul(rate)
+		copied.Bid = copied.Bid.Mul(rate)
+		copied.Last = copied.Last.Mul(rate)
+		copied.Open = copied.Open.Mul(rate)
+		copied.Low = copied.Low.Mul(rate)
+		copied.High = copied.High.Mul(rate)
+		copied.VolumeQuote = copied.VolumeQuote.Mul(rate)
+		copied.ChangeAbs24h = copied.ChangeAbs24h.Mul(rate)
+		for _, price := range []**decimal.Decimal{&copied.SessionHigh, &copied.SessionLow, &copied.AllTimeHigh, &copied.AllTimeLow} {
+			if *price != nil {
+				converted := (*price).Mul(rate)
+				*price = &converted
+			}
+		}
+		if copied.Indicators != nil {
+			copied.Indicators = make(map[string]decimal.Decimal, len(ticker.Indicators))
+			for name, value := range ticker.Indicators {
+				copied.Indicators[name] = value.Mul(rate)
+			}
+		}
+		converted = append(converted, &copied)
+	}
+	if len(converted) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return converted, nil
+}
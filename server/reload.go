@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/crypto-api-server/audit"
+	"github.com/crypto-api-server/config"
+	"github.com/crypto-api-server/wsclient"
+)
+
+// ReloadResponse lists the changes applied by a configuration reload.
+type ReloadResponse struct {
+	SymbolsAdded   []string `json:"symbolsAdded"`
+	SymbolsRemoved []string `json:"symbolsRemoved"`
+	RulesAdded     []string `json:"rulesAdded"`
+	RulesRemoved   []string `json:"rulesRemoved"`
+}
+
+// applyConfig applies the settings that can change at runtime: the tenants,
+// the feed symbols, the upstream rate limits and the alert rules of the
+// configuration. Rules added with the admin API are left alone.
+func (h *HandleRequests) applyConfig(cfg *config.Config) (*ReloadResponse, error) {
+	h.reloadMutex.Lock()
+	defer h.reloadMutex.Unlock()
+	result := &ReloadResponse{
+		SymbolsAdded:   []string{},
+		SymbolsRemoved: []string{},
+		RulesAdded:     []string{},
+		RulesRemoved:   []string{},
+	}
+	h.applyTenants(cfg.Tenants)
+	added, removed, err := h.HitWrapper.UpdateFeedSymbols(cfg.Symbols)
+	result.SymbolsAdded = append(result.SymbolsAdded, added...)
+	result.SymbolsRemoved = append(result.SymbolsRemoved, removed...)
+	for _, symbol := range removed {
+		if h.Recent != nil {
+			h.Recent.Remove(symbol)
+		}
+		h.Candles.Remove(symbol)
+		h.VWAP.Remove(symbol)
+		h.Spread.Remove(symbol)
+		h.Averages.Remove(symbol)
+		h.Extremes.Remove(symbol)
+	}
+	if err != nil {
+		return result, err
+	}
+	limits := map[string]float64{
+		wsclient.LimitMarketData: cfg.RateLimits.MarketData,
+		wsclient.LimitTrading:    cfg.RateLimits.Trading,
+		wsclient.LimitOther:      cfg.RateLimits.Other,
+	}
+	for class, rate := range limits {
+		if err := h.HitWrapper.SetRateLimit(class, rate); err != nil {
+			return result, err
+		}
+	}
+	if h.configRules == nil {
+		h.configRules = make(map[string]string)
+	}
+	wanted := make(map[string]bool, len(cfg.AlertRules))
+	for _, expr := range cfg.AlertRules {
+		wanted[expr] = true
+		if _, ok := h.configRules[expr]; ok {
+			continue
+		}
+		rule, err := h.Alerts.AddRule(expr)
+		if err != nil {
+			return result, err
+		}
+		h.configRules[expr] = rule.ID
+		result.RulesAdded = append(result.RulesAdded, expr)
+	}
+	for expr, id := range h.configRules {
+		if !wanted[expr] {
+			h.Alerts.RemoveRule(id)
+			delete(h.configRules, expr)
+			result.RulesRemoved = append(result.RulesRemoved, expr)
+		}
+	}
+	return result, nil
+}
+
+// loadConfig reads the configuration again from the file, environment and
+// flags the configuration of the server was loaded from.
+func (h *HandleRequests) loadConfig() (*config.Config, error) {
+	cfg, err := h.cfg.Reload()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// reloadConfig reads the configuration again and applies it.
+func (h *HandleRequests) reloadConfig() (*ReloadResponse, error) {
+	cfg, err := h.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return h.applyConfig(cfg)
+}
+
+// Reload reads the configuration again from its sources and applies it, see
+// applyConfig and config.Config.Reload. The reload is recorded in the audit log as done by
+// actor, such as SIGHUP.
+func (s *Server) Reload(actor string) (*ReloadResponse, error) {
+	result, err := s.h.reloadConfig()
+	entry := audit.Entry{Actor: actor, Action: "adminReload", Params: make(map[string]interface{})}
+	if result != nil {
+		entry.Params["result"] = result
+	}
+	if err != nil {
+		entry.Params["error"] = err.Error()
+	}
+	s.h.recordAudit(entry)
+	if err != nil {
+		return result, err
+	}
+	log.Printf("config reloaded: symbols +%v -%v, alert rules +%v -%v",
+		result.SymbolsAdded, result.SymbolsRemoved, result.RulesAdded, result.RulesRemoved)
+	return result, nil
+}
+
+func (h *HandleRequests) handleReload(w http.ResponseWriter, req *http.Request) {
+	cfg, err := h.loadConfig()
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, CodeValidation, err.Error())
+		return
+	}
+	// the changes applied before a failure are kept
+	result, err := h.applyConfig(cfg)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	responseJSON, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/crypto-api-server/requestid"
+)
+
+// requestIDWriter keeps the status of a response.
+type requestIDWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *requestIDWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *requestIDWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush lets streaming handlers flush through the writer.
+func (w *requestIDWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// withRequestID gives every request an ID: the X-Request-ID header of the
+// client when it is valid, a random one otherwise. The ID is set in the
+// request context, echoed in the response header and the error bodies, and
+// prefixes the log lines of the request. Requests are logged at the debug
+// level, and at any level when they fail with a server error.
+func (h *HandleRequests) withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		w.Header().Set(requestid.Header, id)
+		writer := &requestIDWriter{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(writer, req.WithContext(requestid.NewContext(req.Context(), id)))
+		if h.logRequests || writer.status >= http.StatusInternalServerError {
+			log.Printf("[%s] %s %s %d %s", id, req.Method, req.URL.Path, writer.status, time.Since(start))
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/crypto-api-server/config"
+	"github.com/crypto-api-server/storage"
+)
+
+// startRetention applies the retention policy of history to store every
+// retention interval, when set, until ctx is done.
+func startRetention(ctx context.Context, store storage.Store, history config.History) error {
+	if history.Retention == "" {
+		return nil
+	}
+	policy, err := storage.ParseRetentionPolicy(history.Retention)
+	if err != nil {
+		return fmt.Errorf("invalid history.retention: %v", err)
+	}
+	if _, ok := store.(storage.RetentionStore); !ok && policy.Tickers > 0 {
+		return fmt.Errorf("history.retention: the history backend can't prune ticker updates")
+	}
+	go runRetention(ctx, store, policy, history.RetentionInterval)
+	return nil
+}
+
+// runRetention applies policy at startup and then every interval until ctx
+// is done.
+func runRetention(ctx context.Context, store storage.Store, policy storage.RetentionPolicy, interval time.Duration) {
+	apply := func() {
+		started := time.Now()
+		result, err := storage.ApplyRetention(store, policy, started)
+		if err != nil {
+			log.Printf("retention: %v", err)
+		}
+		if result.Downsampled > 0 || result.Pruned > 0 {
+			log.Printf("retention: %d candles downsampled, %d rows pruned in %s",
+				result.Downsampled, result.Pruned, time.Since(started).Round(time.Millisecond))
+		}
+	}
+	apply()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			apply()
+		}
+	}
+}
@@ -0,0 +1,719 @@
+// Package server is the crypto API server: it streams the HitBTC tickers into
+// its caches and serves them, with the analytics, history and trading
+// endpoints built on them, over HTTP. It can be embedded with New and Run.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/crypto-api-server/alerts"
+	"github.com/crypto-api-server/audit"
+	"github.com/crypto-api-server/candles"
+	"github.com/crypto-api-server/config"
+	"github.com/crypto-api-server/fxrates"
+	"github.com/crypto-api-server/indicators"
+	"github.com/crypto-api-server/inmemorycache"
+	"github.com/crypto-api-server/papertrading"
+	"github.com/crypto-api-server/portfolio"
+	"github.com/crypto-api-server/publisher"
+	"github.com/crypto-api-server/scheduler"
+	"github.com/crypto-api-server/storage"
+	"github.com/crypto-api-server/symbolsearch"
+	"github.com/crypto-api-server/telegram"
+	"github.com/crypto-api-server/tenants"
+	"github.com/crypto-api-server/tracing"
+	"github.com/crypto-api-server/webhooks"
+	"github.com/crypto-api-server/wrappers"
+	"github.com/crypto-api-server/wsclient"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+)
+
+// MaxBodyBytes bounds the request bodies, see guardRequests.
+const MaxBodyBytes = int64(65536)
+
+type HandleRequests struct {
+	HitWrapper *wrappers.Wrappers
+	Webhooks   *webhooks.Dispatcher
+	Alerts     *alerts.Engine
+	History    storage.Store
+	Recent     *inmemorycache.TickerHistory
+	Candles    *candles.Builder
+	VWAP       *indicators.VWAP
+	Spread     *indicators.Spread
+	Averages   *indicators.MovingAverages
+	Extremes   *indicators.Extremes
+	Updates    *inmemorycache.Notifier
+	FX         *fxrates.Rates
+	Portfolio  *portfolio.Portfolio
+	Jobs       *scheduler.Scheduler
+	Tenants    *tenants.Registry
+	Audit      *audit.Log
+	router     *mux.Router
+	// symbolIndex searches the symbols, rebuilt when their metadata is
+	// loaded.
+	symbolIndex *symbolsearch.Index
+	indexMutex  sync.RWMutex
+	// logRequests logs every request, not only the failed ones.
+	logRequests bool
+	// flushTraces exports the pending spans before exiting, nil without
+	// tracing.
+	flushTraces func(context.Context) error
+	// configRules maps the alert rules of the configuration to their IDs,
+	// reloadMutex serializes the reloads.
+	configRules map[string]string
+	reloadMutex sync.Mutex
+	// cfg is the configuration the server was created with, reloaded from
+	// its sources by handleReload.
+	cfg *config.Config
+	// adminToken is the bearer token of the admin routes, which are
+	// disabled when it is empty.
+	adminToken string
+	// closers are the publishers and the history recorder, closed by
+	// Server.Close.
+	closers []io.Closer
+}
+
+// newRouter registers the public API routes and the admin routes, as asked.
+// Every route is named so it can be documented in routeDocs and picked up by
+// /openapi.json.
+func (h *HandleRequests) newRouter(public, admin bool) *mux.Router {
+	myRouter := mux.NewRouter().StrictSlash(true)
+	myRouter.NotFoundHandler = http.HandlerFunc(handleNotFound)
+	myRouter.MethodNotAllowedHandler = http.HandlerFunc(handleMethodNotAllowed)
+	// spans are named after the route templates
+	myRouter.Use(otelmux.Middleware(tracing.ServiceName))
+	myRouter.Use(h.authenticateTenant)
+	myRouter.Use(h.validateParams)
+	if public {
+		h.addPublicRoutes(myRouter)
+	}
+	if admin {
+		h.addAdminRoutes(myRouter)
+	}
+	return myRouter
+}
+
+// addPublicRoutes registers the market data routes.
+func (h *HandleRequests) addPublicRoutes(myRouter *mux.Router) {
+	myRouter.HandleFunc("/currency/all", h.handleAllCurrency).Methods("GET").Name("currencyAll")
+	myRouter.HandleFunc("/currency/{symbol}", h.handleCurrencyBySymbol).Methods("GET").Name("currencyBySymbol")
+	myRouter.HandleFunc("/currency/{symbol}/history", h.handleRecentHistory).Methods("GET").Name("currencyHistory")
+	myRouter.HandleFunc("/currency/{symbol}/poll", h.handleCurrencyPoll).Methods("GET").Name("currencyPoll")
+	myRouter.HandleFunc("/symbols/search", h.handleSymbolSearch).Methods("GET").Name("symbolSearch")
+	myRouter.HandleFunc("/symbols/{symbol}", h.handleSymbol).Methods("GET").Name("symbol")
+	myRouter.HandleFunc("/currencies/{currency}", h.handleCurrencyInfo).Methods("GET").Name("currency")
+	myRouter.HandleFunc("/history/{symbol}", h.handleHistory).Methods("GET").Name("history")
+	myRouter.HandleFunc("/export/{symbol}.parquet", h.handleExportParquet).Methods("GET").Name("exportParquet")
+	myRouter.HandleFunc("/candles/live/{symbol}", h.handleLiveCandles).Methods("GET").Name("candlesLive")
+	myRouter.HandleFunc("/candles/{symbol}.csv", h.handleCandlesCSV).Methods("GET").Name("candlesCSV")
+	myRouter.HandleFunc("/vwap/{symbol}", h.handleVWAP).Methods("GET").Name("vwap")
+	myRouter.HandleFunc("/spread/{symbol}", h.handleSpread).Methods("GET").Name("spread")
+	myRouter.HandleFunc("/extremes/{symbol}", h.handleExtremes).Methods("GET").Name("extremes")
+	myRouter.HandleFunc("/volatility/{symbol}", h.handleVolatility).Methods("GET").Name("volatility")
+	myRouter.HandleFunc("/correlation", h.handleCorrelation).Methods("GET").Name("correlation")
+	myRouter.HandleFunc("/depth/{symbol}", h.handleDepth).Methods("GET").Name("depth")
+	myRouter.HandleFunc("/changes", h.handleChanges).Methods("GET").Name("changes")
+	myRouter.HandleFunc("/movers", h.handleMovers).Methods("GET").Name("movers")
+	myRouter.HandleFunc("/stats", h.handleStats).Methods("GET").Name("stats")
+	myRouter.HandleFunc("/convert", h.handleConvert).Methods("GET").Name("convert")
+	myRouter.HandleFunc("/graphql", h.handleGraphQL).Methods("GET", "POST").Name("graphql")
+	myRouter.HandleFunc("/openapi.json", h.handleOpenAPI).Methods("GET").Name("openapi")
+}
+
+// addAdminRoutes registers the routes requiring the admin token.
+func (h *HandleRequests) addAdminRoutes(myRouter *mux.Router) {
+	myRouter.HandleFunc("/orders", h.requireAdmin(h.handlePlaceOrder)).Methods("POST").Name("orderCreate")
+	myRouter.HandleFunc("/orders", h.requireAdmin(h.handleActiveOrders)).Methods("GET").Name("orders")
+	myRouter.HandleFunc("/orders", h.requireAdmin(h.handleCancelAllOrders)).Methods("DELETE").Name("orderCancelAll")
+	myRouter.HandleFunc("/orders/history", h.requireAdmin(h.handleOrderHistory)).Methods("GET").Name("orderHistory")
+	myRouter.HandleFunc("/orders/{clientOrderId}", h.requireAdmin(h.handleCancelOrder)).Methods("DELETE").Name("orderCancel")
+	myRouter.HandleFunc("/trades/mine", h.requireAdmin(h.handleMyTrades)).Methods("GET").Name("myTrades")
+	myRouter.HandleFunc("/fees/{symbol}", h.requireAdmin(h.handleFees)).Methods("GET").Name("fees")
+	myRouter.HandleFunc("/balance/trading", h.requireAdmin(h.handleTradingBalance)).Methods("GET").Name("balanceTrading")
+	myRouter.HandleFunc("/balance/account", h.requireAdmin(h.handleAccountBalance)).Methods("GET").Name("balanceAccount")
+	myRouter.HandleFunc("/deposit/{currency}/address", h.requireAdmin(h.handleDepositAddress)).Methods("GET").Name("depositAddress")
+	myRouter.HandleFunc("/deposit/{currency}/address", h.requireAdmin(h.handleDepositAddress)).Methods("POST").Name("depositAddressNew")
+	myRouter.HandleFunc("/transfer", h.requireAdmin(h.handleTransfer)).Methods("POST").Name("transfer")
+	myRouter.HandleFunc("/withdraw", h.requireAdmin(h.handleWithdraw)).Methods("POST").Name("withdraw")
+	myRouter.HandleFunc("/withdraw/{id}/commit", h.requireAdmin(h.handleCommitWithdraw)).Methods("POST").Name("withdrawCommit")
+	myRouter.HandleFunc("/withdraw/{id}", h.requireAdmin(h.handleRollbackWithdraw)).Methods("DELETE").Name("withdrawRollback")
+	myRouter.HandleFunc("/portfolio", h.requireAdmin(h.handlePortfolio)).Methods("GET").Name("portfolio")
+	myRouter.HandleFunc("/portfolio/value", h.requireAdmin(h.handlePortfolioValue)).Methods("GET").Name("portfolioValue")
+	myRouter.HandleFunc("/portfolio/{currency}", h.requireAdmin(h.handleSetHolding)).Methods("PUT").Name("portfolioSet")
+	myRouter.HandleFunc("/portfolio/{currency}", h.requireAdmin(h.handleDeleteHolding)).Methods("DELETE").Name("portfolioDelete")
+	myRouter.HandleFunc("/admin/reload", h.requireAdmin(h.handleReload)).Methods("POST").Name("adminReload")
+	myRouter.HandleFunc("/admin/cache", h.requireAdmin(h.handleCacheInfo)).Methods("GET").Name("adminCache")
+	myRouter.HandleFunc("/admin/cache/flush", h.requireAdmin(h.handleCacheFlush)).Methods("POST").Name("adminCacheFlush")
+	myRouter.HandleFunc("/admin/webhooks", h.requireAdmin(h.handleListWebhooks)).Methods("GET").Name("adminWebhooks")
+	myRouter.HandleFunc("/admin/webhooks", h.requireAdmin(h.handleCreateWebhook)).Methods("POST").Name("adminWebhookCreate")
+	myRouter.HandleFunc("/admin/webhooks/{id}", h.requireAdmin(h.handleDeleteWebhook)).Methods("DELETE").Name("adminWebhookDelete")
+	myRouter.HandleFunc("/admin/audit", h.requireAdmin(h.handleAudit)).Methods("GET").Name("adminAudit")
+	myRouter.HandleFunc("/admin/usage", h.requireAdmin(h.handleUsage)).Methods("GET").Name("adminUsage")
+	myRouter.HandleFunc("/admin/jobs", h.requireAdmin(h.handleJobs)).Methods("GET").Name("adminJobs")
+	myRouter.HandleFunc("/admin/jobs/{name}/run", h.requireAdmin(h.handleRunJob)).Methods("POST").Name("adminJobRun")
+	myRouter.HandleFunc("/admin/alerts", h.requireAdmin(h.handleListAlertRules)).Methods("GET").Name("adminAlerts")
+	myRouter.HandleFunc("/admin/alerts", h.requireAdmin(h.handleCreateAlertRule)).Methods("POST").Name("adminAlertCreate")
+	myRouter.HandleFunc("/admin/alerts/{id}", h.requireAdmin(h.handleDeleteAlertRule)).Methods("DELETE").Name("adminAlertDelete")
+}
+
+// Server is the crypto API server: the upstream connections, the caches and
+// background tasks fed by them and the HTTP API.
+type Server struct {
+	cfg *config.Config
+	h   *HandleRequests
+	// stop cancels the context of the background tasks, closeOnce guards
+	// Close.
+	stop      context.CancelFunc
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// newUpstream creates the exchange wrapper of cfg, recording the REST calls
+// when configured. The feed recorder is returned to be attached once the
+// wrapper is set up.
+func newUpstream(cfg *config.Config) (*wrappers.Wrappers, *wsclient.Recorder, error) {
+	upstreamOptions := clientOptions(cfg.Upstream)
+	var feedRecorder *wsclient.Recorder
+	if cfg.Record != "" {
+		var restRecorder *wsclient.Recorder
+		var err error
+		if feedRecorder, restRecorder, err = openRecorders(cfg.Record); err != nil {
+			return nil, nil, err
+		}
+		upstreamOptions = append(upstreamOptions, wsclient.WithRecorder(restRecorder))
+	}
+	hitWrapper, err := newWrapper(cfg, upstreamOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hitWrapper, feedRecorder, nil
+}
+
+// Backfill stores the HitBTC candles asked by the -backfill arguments of
+// cfg, see backfillRequest.
+func Backfill(cfg *config.Config) error {
+	hitWrapper, _, err := newUpstream(cfg)
+	if err != nil {
+		return err
+	}
+	h := &HandleRequests{HitWrapper: hitWrapper}
+	h.configureUpstream(cfg.Upstream)
+	return h.backfill(cfg)
+}
+
+// New connects to the exchange and starts the feeds, caches and background
+// tasks of cfg, which is validated. The API is served by Run, or by Handler
+// for tests and programs serving it themselves, which stop the server with
+// Close. Failing optional features are logged and left out.
+func New(cfg *config.Config) (*Server, error) {
+	hitWrapper, feedRecorder, err := newUpstream(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if feedRecorder != nil {
+		hitWrapper.SetFeedRecorder(feedRecorder)
+	}
+	h := &HandleRequests{
+		HitWrapper: hitWrapper,
+		Webhooks:   webhooks.NewDispatcher(1024),
+		Alerts:     alerts.NewEngine(alerts.LogNotifier{}),
+		Candles:    candles.NewBuilder(500),
+		VWAP:       indicators.NewVWAP(24 * time.Hour),
+		Spread:     indicators.NewSpread(24 * time.Hour),
+		Averages:   indicators.NewMovingAverages(maxAveragePeriod),
+		Extremes:   indicators.NewExtremes(),
+		Updates:    inmemorycache.NewNotifier(),
+		FX:         fxrates.NewRates(cfg.FXRatesURL),
+		Portfolio:  portfolio.New(),
+		Jobs:       scheduler.New(),
+		Tenants:    tenants.NewRegistry(),
+		Audit:      audit.New(auditLogSize),
+		cfg:        cfg,
+		adminToken: cfg.AdminToken,
+	}
+	if cfg.AuditLogFile != "" {
+		if h.Audit, err = audit.Open(cfg.AuditLogFile, auditLogSize); err != nil {
+			hitWrapper.CloseFeed()
+			return nil, err
+		}
+	}
+	if cfg.PortfolioFile != "" {
+		if h.Portfolio, err = portfolio.Open(cfg.PortfolioFile); err != nil {
+			hitWrapper.CloseFeed()
+			return nil, err
+		}
+	}
+	if cfg.Tracing.Endpoint != "" {
+		if h.flushTraces, err = tracing.Setup(context.Background(), cfg.Tracing.Endpoint); err != nil {
+			log.Printf("tracing: %v", err)
+		}
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	h.HitWrapper.SetCacheTTL(cfg.CacheTTL)
+	h.HitWrapper.SetDebug(cfg.LogLevel == "debug")
+	h.logRequests = cfg.LogLevel == "debug"
+	h.registerWebhooks(cfg.Webhooks)
+	h.Webhooks.Start(ctx, 4)
+	h.HitWrapper.AddTickerListener(h.Webhooks.Notify)
+	if cfg.AlertWebhookURL != "" {
+		h.Alerts.AddNotifier(alerts.NewWebhookNotifier(cfg.AlertWebhookURL))
+	}
+	if _, err := h.applyConfig(cfg); err != nil {
+		log.Printf("config: %v", err)
+	}
+	h.HitWrapper.AddTickerListener(h.Alerts.Evaluate)
+	h.HitWrapper.AddTickerListener(h.Candles.Update)
+	h.HitWrapper.AddTickerListener(h.VWAP.Update)
+	h.HitWrapper.AddTickerListener(h.Spread.Update)
+	h.HitWrapper.AddTickerListener(h.Averages.Update)
+	h.HitWrapper.AddTickerListener(h.Extremes.Update)
+	h.HitWrapper.AddTickerListener(h.Updates.Notify)
+	if cfg.PaperTrading.Enabled {
+		// validated with the configuration
+		balances, _ := cfg.PaperBalances()
+		paper := papertrading.NewEngine(h.HitWrapper.Symbol, balances)
+		h.HitWrapper.SetTradingClient(paper)
+		h.HitWrapper.AddTickerListener(paper.Update)
+	}
+	h.Recent = inmemorycache.NewTickerHistory(cfg.History.RecentSize)
+	h.HitWrapper.AddTickerListener(h.Recent.Add)
+	monitor := newFeedMonitor(2 * time.Minute)
+	h.HitWrapper.AddTickerListener(monitor.Observe)
+	monitor.OnIncident(func(message string) { log.Print(message) })
+	if cfg.Telegram.BotToken != "" {
+		bot := telegram.NewBot(cfg.Telegram.BotToken, cfg.Telegram.ChatID, h.lookupTicker, h.HitWrapper.SupportedSymbols)
+		h.Alerts.AddNotifier(bot)
+		monitor.OnIncident(bot.NotifyIncident)
+		go bot.Run(ctx)
+	}
+	go monitor.Run(ctx, 30*time.Second)
+	go h.FX.Run(ctx, 6*time.Hour)
+	h.configureUpstream(cfg.Upstream)
+	if err := h.startPublishers(cfg); err != nil {
+		log.Printf("publishers: %v", err)
+	}
+	if err := h.startHistory(ctx, cfg.History); err != nil {
+		log.Printf("history: %v", err)
+	}
+	if err := h.startParquetExport(ctx, cfg.History); err != nil {
+		log.Printf("history export: %v", err)
+	}
+	archiver, err := h.newArchiver(cfg.Archive)
+	if err != nil {
+		log.Printf("archive: %v", err)
+	}
+	if err := h.HitWrapper.CacheAllSymbols(ctx); err != nil {
+		log.Printf("symbols: %v", err)
+	}
+	if err := h.HitWrapper.CacheFullName(ctx); err != nil {
+		log.Printf("currencies: %v", err)
+	}
+	h.rebuildSymbolIndex()
+	if err := h.subscribeMarketFeeds(ctx, cfg.Feed); err != nil {
+		log.Printf("feed: %v", err)
+	}
+	if err := h.startJobs(ctx, cfg.Jobs, cfg.Feed, archiver); err != nil {
+		log.Printf("jobs: %v", err)
+	}
+	h.router = h.newRouter(true, cfg.Server.AdminListen == "")
+	return &Server{cfg: cfg, h: h, stop: stop}, nil
+}
+
+// Close stops the background tasks and the feed, and closes the publishers
+// and the history store, once the buffered updates are written. The
+// handlers must not be served anymore.
+func (s *Server) Close() error {
+	s.closeOnce.Do(func() {
+		s.stop()
+		s.h.HitWrapper.CloseFeed()
+		for _, closer := range s.h.closers {
+			if err := closer.Close(); err != nil && s.closeErr == nil {
+				s.closeErr = err
+			}
+		}
+		if s.h.flushTraces != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			s.h.flushTraces(ctx)
+		}
+	})
+	return s.closeErr
+}
+
+// Handler returns the handler of the public API, with the admin routes
+// unless server.adminListen is set.
+func (s *Server) Handler() http.Handler {
+	return s.h.withRequestID(s.h.guardRequests(s.h.router))
+}
+
+type Response struct {
+	Currencies []*wsclient.Ticker `json:"currencies"`
+}
+
+func (h *HandleRequests) handleAllCurrency(w http.ResponseWriter, req *http.Request) {
+	format, ok := responseFormat(req)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Unsupported format")
+		return
+	}
+	quote, ok := h.quoteParam(req)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Unsupported quote currency")
+		return
+	}
+	fields, ok := tickerFieldsParam(req, format)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid fields",
+			ErrorDetail{Field: "fields", Message: "only JSON, CSV and NDJSON responses can select fields"})
+		return
+	}
+	averages, ok := movingAveragesParam(req, format)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid indicators",
+			ErrorDetail{Field: "indicators", Message: "only JSON responses can include indicators"})
+		return
+	}
+	filter := parseMarketFilter(req)
+	groupBy := req.URL.Query().Get("group_by")
+	if groupBy != "" && format != formatJSON {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid group_by",
+			ErrorDetail{Field: "group_by", Message: "only JSON responses can be grouped"})
+		return
+	}
+	if format == formatNDJSON {
+		h.streamTickersNDJSON(w, req, quote, fields, filter)
+		return
+	}
+	currencies, err := h.GetAllCurrencies()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	if len(currencies) == 0 {
+		writeError(w, http.StatusNotFound, CodeNotFound, "No data Found")
+		return
+	}
+	currencies = h.addIndicators(h.filterMarkets(filter, currencies), averages)
+	h.recordAccess(currencies)
+	h.addExtremes(currencies)
+	if quote != "" {
+		if currencies, err = h.convertTickers(req.Context(), currencies, quote); err != nil {
+			writeError(w, http.StatusBadGateway, CodeUpstreamUnavailable, err.Error())
+			return
+		}
+	}
+
+	var body []byte
+	if groupBy != "" {
+		body, err = encodeGroups(h.groupByQuote(currencies), fields)
+	} else {
+		body, err = encodeTickers(format, currencies, fields, false)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	h.flagStale(w)
+	writeFormattedResponse(w, http.StatusOK, format, body)
+}
+
+func (h *HandleRequests) handleCurrencyBySymbol(w http.ResponseWriter, req *http.Request) {
+	format, ok := responseFormat(req)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Unsupported format")
+		return
+	}
+	quote, ok := h.quoteParam(req)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Unsupported quote currency")
+		return
+	}
+	fields, ok := tickerFieldsParam(req, format)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid fields",
+			ErrorDetail{Field: "fields", Message: "only JSON, CSV and NDJSON responses can select fields"})
+		return
+	}
+	averages, ok := movingAveragesParam(req, format)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid indicators",
+			ErrorDetail{Field: "indicators", Message: "only JSON responses can include indicators"})
+		return
+	}
+	vars := mux.Vars(req)
+	key := vars["symbol"]
+	var body []byte
+	if h.knownSymbol(key) {
+		currency, err := h.HitWrapper.GetMarketSummary(req.Context(), key)
+		// an open circuit breaker is a 503 like the other unavailabilities,
+		// and a stale_data error when the expired ticker is cached
+		if err != nil {
+			writeUpstreamError(w, err)
+			return
+		}
+		if currency == nil {
+			writeError(w, http.StatusNotFound, CodeNotFound, "No data Found")
+			return
+		}
+		currencies := h.addIndicators([]*wsclient.Ticker{currency}, averages)
+		h.addExtremes(currencies)
+		if quote != "" {
+			if currencies, err = h.convertTickers(req.Context(), currencies, quote); err != nil {
+				writeError(w, http.StatusBadGateway, CodeUpstreamUnavailable, err.Error())
+				return
+			}
+		}
+		body, err = encodeTickers(format, currencies, fields, true)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+			return
+		}
+	} else {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+
+	h.flagStale(w)
+	writeFormattedResponse(w, http.StatusOK, format, body)
+}
+
+// startPublishers registers the message broker publishers of cfg.
+func (h *HandleRequests) startPublishers(cfg *config.Config) error {
+	if len(cfg.Kafka.Brokers) > 0 {
+		kafka := publisher.NewKafkaPublisher(cfg.Kafka.Brokers, cfg.Kafka.Topic)
+		h.addPublisher("kafka", kafka, 1024)
+	}
+	if cfg.NATS.URL != "" {
+		nats, err := publisher.NewNATSPublisher(cfg.NATS.URL, cfg.NATS.SubjectPrefix)
+		if err != nil {
+			return err
+		}
+		h.addPublisher("nats", nats, 1024)
+	}
+	if cfg.MQTT.Broker != "" {
+		mqtt, err := publisher.NewMQTTPublisher(cfg.MQTT.Broker, cfg.MQTT.TopicPrefix, byte(cfg.MQTT.QoS), cfg.MQTT.Retain)
+		if err != nil {
+			return err
+		}
+		h.addPublisher("mqtt", mqtt, 1024)
+	}
+	if cfg.Redis.URL != "" {
+		redis, err := publisher.NewRedisPublisher(cfg.Redis.URL, cfg.Redis.ChannelPrefix)
+		if err != nil {
+			return err
+		}
+		h.addPublisher("redis", redis, 1024)
+	}
+	if cfg.Influx.URL != "" {
+		influx, err := publisher.NewInfluxPublisher(publisher.InfluxConfig{
+			URL:         cfg.Influx.URL,
+			Token:       cfg.Influx.Token,
+			Org:         cfg.Influx.Org,
+			Bucket:      cfg.Influx.Bucket,
+			Database:    cfg.Influx.Database,
+			Measurement: cfg.Influx.Measurement,
+		})
+		if err != nil {
+			return err
+		}
+		h.addPublisher("influx", influx, 4096)
+	}
+	return nil
+}
+
+// addPublisher queues the ticker updates to p, the queue is closed with
+// the server.
+func (h *HandleRequests) addPublisher(name string, p publisher.Publisher, size int) {
+	queue := publisher.NewQueue(name, p, size)
+	h.HitWrapper.AddTickerListener(queue.Notify)
+	h.closers = append(h.closers, queue)
+}
+
+// openHistory opens the history store of history, nil when there is none.
+func openHistory(history config.History) (storage.Store, error) {
+	backend := history.Backend
+	if backend == "" && history.SQLitePath != "" {
+		backend = "sqlite"
+	}
+	switch backend {
+	case "":
+		return nil, nil
+	case "sqlite":
+		return storage.NewSQLiteStore(history.SQLitePath)
+	case "postgres":
+		return storage.NewPostgresStore(history.PostgresDSN, history.Timescale)
+	default:
+		return nil, fmt.Errorf("unknown history.backend %q", backend)
+	}
+}
+
+// startHistory opens the configured history store and records the feed into
+// it, pruning it as configured until ctx is done.
+func (h *HandleRequests) startHistory(ctx context.Context, history config.History) error {
+	store, err := openHistory(history)
+	if store == nil || err != nil {
+		return err
+	}
+	h.History = store
+	if extremesStore, ok := store.(storage.ExtremesStore); ok {
+		h.Extremes.SetLoader(loadExtremes(extremesStore))
+	}
+	recorder := storage.NewRecorder(store, history.SampleInterval, time.Second, 500)
+	h.HitWrapper.AddTickerListener(recorder.Record)
+	// closes the store too
+	h.closers = append(h.closers, recorder)
+	if candleStore, ok := store.(storage.CandleStore); ok {
+		h.Candles.OnClose(func(candle storage.Candle) {
+			go func() {
+				if err := candleStore.AppendCandles([]storage.Candle{candle}); err != nil {
+					log.Printf("history: candle %s %s: %v", candle.Symbol, candle.Period, err)
+				}
+			}()
+		})
+	}
+	return startRetention(ctx, store, history)
+}
+
+// knownSymbol tells whether symbol is listed, or was delisted, which
+// GetMarketSummary reports with a DelistedError.
+func (h *HandleRequests) knownSymbol(symbol string) bool {
+	if _, delisted := h.HitWrapper.Delisted(symbol); delisted {
+		return true
+	}
+	return h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol)
+}
+
+// lookupTicker returns the ticker of a valid symbol.
+func (h *HandleRequests) lookupTicker(ctx context.Context, symbol string) (*wsclient.Ticker, error) {
+	if !h.knownSymbol(symbol) {
+		return nil, errors.New("Not a valid Symbol")
+	}
+	ticker, err := h.HitWrapper.GetMarketSummary(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if ticker == nil {
+		return nil, errors.New("No data Found")
+	}
+	return ticker, nil
+}
+
+// newWrapper creates the wrapper of the configured exchange API version, or
+// of the replayed feed.
+func newWrapper(cfg *config.Config, options []wsclient.Option) (*wrappers.Wrappers, error) {
+	exchange := cfg.Exchange
+	if cfg.Replay.File != "" {
+		return wrappers.NewReplayWrapper(cfg.Replay.File, cfg.Replay.Speed, exchange.APIKey, exchange.APISecret, options...)
+	}
+	if exchange.APIVersion == 3 {
+		return wrappers.NewHitBtcV3Wrapper(exchange.APIKey, exchange.APISecret, options...), nil
+	}
+	return wrappers.NewHitBtcV2Wrapper(exchange.APIKey, exchange.APISecret, options...), nil
+}
+
+// openRecorders creates the timestamped files of the recorded feed and REST
+// responses in dir.
+func openRecorders(dir string) (feed *wsclient.Recorder, rest *wsclient.Recorder, err error) {
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, err
+	}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	feedFile, err := os.Create(filepath.Join(dir, "feed-"+stamp+".ndjson"))
+	if err != nil {
+		return nil, nil, err
+	}
+	restFile, err := os.Create(filepath.Join(dir, "rest-"+stamp+".ndjson"))
+	if err != nil {
+		feedFile.Close()
+		return nil, nil, err
+	}
+	return wsclient.NewRecorder(feedFile), wsclient.NewRecorder(restFile), nil
+}
+
+// clientOptions configures the HTTP client of the HitBTC REST calls.
+func clientOptions(upstream config.Upstream) []wsclient.Option {
+	options := []wsclient.Option{wsclient.WithTimeout(upstream.Timeout)}
+	if upstream.ProxyURL != "" {
+		// validated with the configuration
+		proxyURL, _ := url.Parse(upstream.ProxyURL)
+		options = append(options, wsclient.WithTransport(wsclient.TransportConfig{ProxyURL: proxyURL}))
+	}
+	return options
+}
+
+// configureUpstream sets the retry policy and the circuit breaker of the
+// HitBTC REST calls.
+func (h *HandleRequests) configureUpstream(upstream config.Upstream) {
+	policy := wsclient.DefaultRetryPolicy
+	policy.MaxRetries = upstream.MaxRetries
+	policy.RetryUnsafe = upstream.RetryUnsafe
+	h.HitWrapper.SetRetryPolicy(policy)
+	h.HitWrapper.SetCircuitBreaker(upstream.BreakerThreshold, upstream.BreakerCooldown)
+}
+
+// flagStale marks responses served from the cache while the upstream circuit
+// breaker is open, the cached data may be stale.
+func (h *HandleRequests) flagStale(w http.ResponseWriter) {
+	if !h.HitWrapper.UpstreamAvailable() {
+		w.Header().Set("X-Data-Stale", "true")
+	}
+}
+
+// subscribeMarketFeeds connects to the feed, unsubscribing the idle symbols
+// until ctx is done when configured.
+func (h *HandleRequests) subscribeMarketFeeds(ctx context.Context, feed config.Feed) error {
+	// validated with the configuration
+	overflow, _ := wsclient.ParseOverflowPolicy(feed.OverflowPolicy)
+	h.HitWrapper.SetFeedBuffer(feed.BufferSize, overflow)
+	h.HitWrapper.SetFeedWorkers(feed.Workers)
+	h.HitWrapper.SetSubscribeConcurrency(feed.SubscribeConcurrency)
+	h.HitWrapper.SetLazySubscription(feed.Lazy)
+	if feed.IdleTimeout > 0 {
+		go h.HitWrapper.RunIdleUnsubscribe(ctx, feed.IdleTimeout)
+	}
+	return h.HitWrapper.FeedConnect()
+}
+
+// isSymbolValid checks if a string is present in a slice
+// func isSymbolValid(s []string, str string) bool {
+// 	for _, v := range s {
+// 		if v == str {
+// 			return true
+// 		}
+// 	}
+// 	return false
+// }
+
+// recordAccess keeps the symbols of the tickers served to a client from
+// being unsubscribed as idle.
+func (h *HandleRequests) recordAccess(tickers []*wsclient.Ticker) {
+	symbols := make([]string, len(tickers))
+	for i, ticker := range tickers {
+		symbols[i] = ticker.Symbol
+	}
+	h.HitWrapper.RecordAccess(symbols...)
+}
+
+func (h *HandleRequests) GetAllCurrencies() ([]*wsclient.Ticker, error) {
+	data, err := h.HitWrapper.GetCurrenciesFromCache()
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+func writeResponse(w http.ResponseWriter, code int, response []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,445 @@
+package server
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crypto-api-server/config"
+	"github.com/crypto-api-server/indicators"
+	"github.com/crypto-api-server/webhooks"
+	"github.com/crypto-api-server/wrappers"
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+// The tests serve the API of servers replaying a recorded feed. The HitBTC
+// REST calls go through a proxy refusing them, so that they fail at once
+// and everything is served from the feed.
+
+// writeFeed records two rounds of ticker updates of each symbol to a replay
+// file, 200ms apart. Like on the live feed, the updates replayed before the
+// symbols are subscribed are dropped, the second round is kept.
+func writeFeed(t *testing.T, lasts map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "feed.ndjson")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	start := time.Now().UTC()
+	for round := 0; round < 2; round++ {
+		now := start.Add(time.Duration(round) * 200 * time.Millisecond)
+		for symbol, last := range lasts {
+			params, err := json.Marshal(wsclient.WSNotificationTickerResponse{
+				Ask: last, Bid: last, Last: last, Open: last, Low: last, High: last,
+				Volume: "1", VolumeQuote: last, Timestamp: now.Format(time.RFC3339Nano), Symbol: symbol,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := encoder.Encode(wsclient.FeedRecord{Time: now, Method: "ticker", Params: params}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return path
+}
+
+// newTestServer starts a server replaying a feed of BTCUSD and ETHBTC with
+// the admin token adminToken, and waits for the feed.
+func newTestServer(t *testing.T, adminToken string) (*Server, *httptest.Server) {
+	t.Helper()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "offline", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(proxy.Close)
+
+	cfg := config.Default()
+	cfg.Symbols = []string{"BTCUSD", "ETHBTC"}
+	cfg.AdminToken = adminToken
+	cfg.Replay.File = writeFeed(t, map[string]string{"BTCUSD": "65000", "ETHBTC": "0.05"})
+	cfg.Replay.Speed = 1
+	cfg.Upstream.ProxyURL = proxy.URL
+	cfg.Upstream.MaxRetries = 0
+	cfg.Upstream.BreakerThreshold = 0
+	cfg.FXRatesURL = proxy.URL
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		if err := srv.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		currencies, _ := srv.h.GetAllCurrencies()
+		if len(currencies) == 2 {
+			return srv, ts
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the replayed feed delivered %d tickers, want 2", len(currencies))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// get requests path from ts with the bearer token, when set.
+func get(t *testing.T, ts *httptest.Server, path string, token string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestCurrencyBySymbol(t *testing.T) {
+	_, ts := newTestServer(t, "")
+
+	resp := get(t, ts, "/currency/BTCUSD", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /currency/BTCUSD: status %d, want 200", resp.StatusCode)
+	}
+	var ticker wsclient.Ticker
+	if err := json.NewDecoder(resp.Body).Decode(&ticker); err != nil {
+		t.Fatal(err)
+	}
+	if ticker.Symbol != "BTCUSD" || ticker.Last.String() != "65000" {
+		t.Errorf("GET /currency/BTCUSD: %s at %s, want BTCUSD at 65000", ticker.Symbol, ticker.Last)
+	}
+
+	if resp := get(t, ts, "/currency/XYZUSD", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /currency/XYZUSD: status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestAllCurrencies(t *testing.T) {
+	_, ts := newTestServer(t, "")
+
+	resp := get(t, ts, "/currency/all", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /currency/all: status %d, want 200", resp.StatusCode)
+	}
+	var response Response
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Currencies) != 2 {
+		t.Errorf("GET /currency/all: %d tickers, want 2", len(response.Currencies))
+	}
+}
+
+// TestCurrencyCSV checks that the CSV columns are the JSON fields.
+func TestCurrencyCSV(t *testing.T) {
+	_, ts := newTestServer(t, "")
+
+	resp := get(t, ts, "/currency/BTCUSD?format=csv", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /currency/BTCUSD?format=csv: status %d, want 200", resp.StatusCode)
+	}
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || len(records[0]) != len(tickerFields) {
+		t.Fatalf("GET /currency/BTCUSD?format=csv: %v, want a header and a row of %d columns", records, len(tickerFields))
+	}
+	row := make(map[string]string)
+	for i, column := range records[0] {
+		row[column] = records[1][i]
+	}
+	want := map[string]string{"symbol": "BTCUSD", "last": "65000", "source": wsclient.SourceWebsocket, "delisted": ""}
+	for column, value := range want {
+		if row[column] != value {
+			t.Errorf("GET /currency/BTCUSD?format=csv: %s %q, want %q", column, row[column], value)
+		}
+	}
+	for _, column := range []string{"cachedAt", "ageMs"} {
+		if row[column] == "" {
+			t.Errorf("GET /currency/BTCUSD?format=csv: no %s", column)
+		}
+	}
+}
+
+// TestMoversEmptyCache checks that an empty cache has no movers, rather
+// than failing.
+func TestMoversEmptyCache(t *testing.T) {
+	srv, ts := newTestServer(t, "")
+	srv.h.HitWrapper.FlushCache("")
+
+	resp := get(t, ts, "/movers", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /movers: status %d, want 200", resp.StatusCode)
+	}
+	var response map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	for _, list := range []string{"gainers", "losers"} {
+		if string(response[list]) != "[]" {
+			t.Errorf("GET /movers: %s %s, want []", list, response[list])
+		}
+	}
+}
+
+// TestAdminTokens checks that servers in the same process keep their own
+// admin token.
+func TestAdminTokens(t *testing.T) {
+	_, first := newTestServer(t, "first-token")
+	_, second := newTestServer(t, "second-token")
+	_, disabled := newTestServer(t, "")
+
+	tests := []struct {
+		name  string
+		ts    *httptest.Server
+		token string
+		want  int
+	}{
+		{"own token", first, "first-token", http.StatusOK},
+		{"other server token", first, "second-token", http.StatusUnauthorized},
+		{"no token", first, "", http.StatusUnauthorized},
+		{"second own token", second, "second-token", http.StatusOK},
+		{"second other token", second, "first-token", http.StatusUnauthorized},
+		{"disabled", disabled, "first-token", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if resp := get(t, test.ts, "/admin/jobs", test.token); resp.StatusCode != test.want {
+				t.Errorf("GET /admin/jobs: status %d, want %d", resp.StatusCode, test.want)
+			}
+		})
+	}
+}
+
+// TestReloadWithoutSource checks that a configuration built in code isn't
+// reloaded from the command line of the process.
+func TestReloadWithoutSource(t *testing.T) {
+	srv, ts := newTestServer(t, "token")
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/admin/reload", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("POST /admin/reload: status %d, want 422", resp.StatusCode)
+	}
+	if _, err := srv.Reload("test"); err == nil {
+		t.Error("Reload: no error for a configuration built in code")
+	}
+}
+
+// TestDebugVars checks that each server serves its own stats.
+// TestReloadRemovesSymbol checks that the state kept of a symbol removed
+// from the configuration is dropped.
+func TestReloadRemovesSymbol(t *testing.T) {
+	srv, _ := newTestServer(t, "")
+	h := srv.h
+	now := time.Now()
+	listeners := []func(*wsclient.Ticker){h.Recent.Add, h.Candles.Update, h.VWAP.Update, h.Spread.Update, h.Averages.Update, h.Extremes.Update}
+	for i, volume := range []string{"10", "12"} {
+		for _, symbol := range []string{"BTCUSD", "ETHBTC"} {
+			ticker := &wsclient.Ticker{
+				Symbol: symbol, Ask: decimal.NewFromInt(101), Bid: decimal.NewFromInt(99), Last: decimal.NewFromInt(100),
+				Volume: decimal.RequireFromString(volume), Timestamp: now.Add(time.Duration(i) * time.Second),
+			}
+			for _, listener := range listeners {
+				listener(ticker)
+			}
+		}
+	}
+	kept := func(symbol string) map[string]bool {
+		_, vwap := h.VWAP.Compute(symbol, time.Hour, now.Add(time.Minute))
+		_, spread := h.Spread.Compute(symbol, time.Hour, now.Add(time.Minute))
+		_, extremes := h.Extremes.Get(symbol)
+		return map[string]bool{
+			"recent":   len(h.Recent.Last(symbol, 1)) > 0,
+			"candles":  len(h.Candles.Candles(symbol, "M1", 1)) > 0,
+			"vwap":     vwap,
+			"spread":   spread,
+			"averages": len(h.Averages.Compute(symbol, []indicators.MovingAverage{{Name: "sma1", Kind: "sma", Period: 1}})) > 0,
+			"extremes": extremes,
+		}
+	}
+	for name, ok := range kept("ETHBTC") {
+		if !ok {
+			t.Fatalf("%s: nothing kept of ETHBTC before the reload", name)
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Symbols = []string{"BTCUSD"}
+	result, err := h.applyConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.SymbolsRemoved) != 1 || result.SymbolsRemoved[0] != "ETHBTC" {
+		t.Fatalf("removed %v, want [ETHBTC]", result.SymbolsRemoved)
+	}
+	for name, ok := range kept("ETHBTC") {
+		if ok {
+			t.Errorf("%s: ETHBTC kept after its removal", name)
+		}
+	}
+	for name, ok := range kept("BTCUSD") {
+		if !ok {
+			t.Errorf("%s: BTCUSD dropped with ETHBTC", name)
+		}
+	}
+}
+
+func TestDebugVars(t *testing.T) {
+	srv, _ := newTestServer(t, "")
+	debug := httptest.NewServer(srv.h.debugHandler())
+	defer debug.Close()
+
+	resp := get(t, debug, "/debug/vars", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /debug/vars: status %d, want 200", resp.StatusCode)
+	}
+	var vars map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&vars); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"stats", "memstats", "cmdline"} {
+		if _, ok := vars[name]; !ok {
+			t.Errorf("GET /debug/vars: no %q variable", name)
+		}
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	srv, _ := newTestServer(t, "")
+	if err := srv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the cleanup closes it again
+}
+
+// TestWriteUpstreamErrorCircuitOpen checks that an open circuit breaker is
+// recognized when wrapped, and that an expired cached ticker is reported as
+// stale rather than missing.
+func TestWriteUpstreamErrorCircuitOpen(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{"open", wsclient.ErrCircuitOpen, CodeUpstreamUnavailable},
+		{"wrapped", fmt.Errorf("getting the ticker: %w", wsclient.ErrCircuitOpen), CodeUpstreamUnavailable},
+		{"stale", &wrappers.StaleDataError{Symbol: "BTCUSD", CachedAt: time.Now(), Err: wsclient.ErrCircuitOpen}, CodeStaleData},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			writeUpstreamError(recorder, test.err)
+			var response ErrorResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if recorder.Code != http.StatusServiceUnavailable || response.Error.Code != test.code {
+				t.Errorf("status %d, code %q, want 503 and %q", recorder.Code, response.Error.Code, test.code)
+			}
+		})
+	}
+}
+
+// TestConfigWebhooks checks that the webhooks of the configuration are
+// filtered by symbol and threshold, and signed, like the ones of the admin API.
+func TestConfigWebhooks(t *testing.T) {
+	type delivery struct {
+		path      string
+		signature string
+		payload   webhooks.Payload
+		body      []byte
+		timestamp string
+	}
+	deliveries := make(chan delivery, 8)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		d := delivery{path: req.URL.Path, signature: req.Header.Get("X-Webhook-Signature"), body: body, timestamp: req.Header.Get("X-Webhook-Timestamp")}
+		if err := json.Unmarshal(body, &d.payload); err != nil {
+			t.Error(err)
+		}
+		deliveries <- d
+	}))
+	defer receiver.Close()
+
+	above := 70000.0
+	h := &HandleRequests{Webhooks: webhooks.NewDispatcher(8)}
+	h.registerWebhooks([]config.Webhook{
+		{URL: receiver.URL + "/signed", Symbols: []string{"ETHBTC"}, Secret: "s3cret"},
+		{URL: receiver.URL + "/threshold", Symbols: []string{"BTCUSD"}, Above: &above},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h.Webhooks.Start(ctx, 1)
+
+	ticker := func(symbol string, last string) *wsclient.Ticker {
+		return &wsclient.Ticker{Symbol: symbol, Last: decimal.RequireFromString(last)}
+	}
+	receive := func() delivery {
+		t.Helper()
+		select {
+		case d := <-deliveries:
+			return d
+		case <-time.After(5 * time.Second):
+			t.Fatal("no delivery")
+		}
+		return delivery{}
+	}
+
+	// below the threshold, and not a symbol of the signed webhook
+	h.Webhooks.Notify(ticker("BTCUSD", "65000"))
+	h.Webhooks.Notify(ticker("ETHBTC", "0.05"))
+	d := receive()
+	if d.path != "/signed" || d.payload.Event != webhooks.EventTickerUpdate || d.payload.Ticker.Symbol != "ETHBTC" {
+		t.Errorf("%s delivered to %s, want the ETHBTC update to /signed", d.payload.Event, d.path)
+	}
+	if want := "sha256=" + webhooks.Sign("s3cret", d.timestamp, d.body); d.signature != want {
+		t.Errorf("signature %q, want %q", d.signature, want)
+	}
+
+	h.Webhooks.Notify(ticker("BTCUSD", "71000"))
+	d = receive()
+	if d.path != "/threshold" || d.payload.Event != webhooks.EventThresholdCrossed || d.signature != "" {
+		t.Errorf("%s delivered to %s signed %q, want an unsigned crossing to /threshold", d.payload.Event, d.path, d.signature)
+	}
+	select {
+	case d := <-deliveries:
+		t.Errorf("unexpected %s delivery to %s", d.payload.Event, d.path)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/crypto-api-server/indicators"
+	"github.com/gorilla/mux"
+)
+
+type SpreadResponse struct {
+	Symbol string `json:"symbol"`
+	Window string `json:"window"`
+	indicators.SpreadResult
+}
+
+func (h *HandleRequests) handleSpread(w http.ResponseWriter, req *http.Request) {
+	symbol := mux.Vars(req)["symbol"]
+	if !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	window, ok := parseWindowParam(req.URL.Query().Get("window"), time.Hour, h.Spread.MaxWindow())
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid window, expected a duration up to "+h.Spread.MaxWindow().String())
+		return
+	}
+	result, ok := h.Spread.Compute(symbol, window, time.Now())
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeNotFound, "No quote received for symbol")
+		return
+	}
+	responseJSON, err := json.Marshal(&SpreadResponse{Symbol: symbol, Window: window.String(), SpreadResult: result})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+type StatsResponse struct {
+	ActiveMarkets int `json:"activeMarkets"`
+	// QuoteVolume sums the 24h quote volume per quote currency, volumes in
+	// different currencies can't be added up
+	QuoteVolume          map[string]decimal.Decimal `json:"quoteVolume"`
+	AverageSpreadPercent float64                    `json:"averageSpreadPercent"`
+	NewestUpdate         *time.Time                 `json:"newestUpdate"`
+	OldestUpdate         *time.Time                 `json:"oldestUpdate"`
+	MaxAgeSeconds        float64                    `json:"maxAgeSeconds"`
+	// DroppedUpdates counts the feed updates dropped by TICKER_OVERFLOW_POLICY
+	DroppedUpdates uint64 `json:"droppedUpdates"`
+	// UpstreamRateLimit counts the HitBTC REST requests per rate limit class
+	UpstreamRateLimit map[string]wsclient.RateLimitStats `json:"upstreamRateLimit"`
+	// UpstreamCircuit is the state of the circuit breaker: closed, open or half-open
+	UpstreamCircuit string `json:"upstreamCircuit"`
+}
+
+// computeStats aggregates the cached tickers. A market is active when it has
+// a last price.
+func (h *HandleRequests) computeStats(tickers []*wsclient.Ticker, now time.Time) *StatsResponse {
+	stats := &StatsResponse{QuoteVolume: make(map[string]decimal.Decimal)}
+	var spreadSum float64
+	var spreadCount int
+	for _, t := range tickers {
+		if t.Last.IsZero() {
+			continue
+		}
+		stats.ActiveMarkets++
+		quote := h.HitWrapper.Symbols()[t.Symbol].QuoteCurrency
+		stats.QuoteVolume[quote] = stats.QuoteVolume[quote].Add(t.VolumeQuote)
+		if t.Ask.IsPositive() && t.Bid.IsPositive() {
+			ask, bid := t.Ask.InexactFloat64(), t.Bid.InexactFloat64()
+			mid := (ask + bid) / 2
+			spreadSum += (ask - bid) / mid * 100
+			spreadCount++
+		}
+		if t.Timestamp.IsZero() {
+			continue
+		}
+		timestamp := t.Timestamp
+		if stats.NewestUpdate == nil || timestamp.After(*stats.NewestUpdate) {
+			stats.NewestUpdate = &timestamp
+		}
+		if stats.OldestUpdate == nil || timestamp.Before(*stats.OldestUpdate) {
+			stats.OldestUpdate = &timestamp
+		}
+	}
+	if spreadCount > 0 {
+		stats.AverageSpreadPercent = spreadSum / float64(spreadCount)
+	}
+	if stats.OldestUpdate != nil {
+		stats.MaxAgeSeconds = now.Sub(*stats.OldestUpdate).Seconds()
+	}
+	for _, dropped := range h.HitWrapper.FeedDrops() {
+		stats.DroppedUpdates += dropped
+	}
+	stats.UpstreamRateLimit = h.HitWrapper.RateLimitStats()
+	stats.UpstreamCircuit = h.HitWrapper.CircuitState()
+	return stats
+}
+
+func (h *HandleRequests) handleStats(w http.ResponseWriter, req *http.Request) {
+	// an empty cache is reported as zero active markets
+	currencies, _ := h.GetAllCurrencies()
+	currencies = scopeTickers(req, currencies)
+	h.recordAccess(currencies)
+	responseJSON, err := json.Marshal(h.computeStats(currencies, time.Now()))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/crypto-api-server/symbolsearch"
+	"github.com/crypto-api-server/tenants"
+	"github.com/crypto-api-server/wsclient"
+	"github.com/gorilla/mux"
+)
+
+// SymbolResponse is the metadata of a symbol with the full names of its
+// currencies. Streamed tells whether its ticker comes from the websocket
+// feed rather than from REST calls.
+type SymbolResponse struct {
+	wsclient.Symbol
+	BaseFullName  string `json:"baseFullName,omitempty"`
+	QuoteFullName string `json:"quoteFullName,omitempty"`
+	Streamed      bool   `json:"streamed"`
+}
+
+func (h *HandleRequests) handleSymbol(w http.ResponseWriter, req *http.Request) {
+	symbol := mux.Vars(req)["symbol"]
+	info, ok := h.HitWrapper.Symbol(symbol)
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	responseJSON, err := json.Marshal(&SymbolResponse{
+		Symbol:        info,
+		BaseFullName:  h.HitWrapper.CurrencyFullName(info.BaseCurrency),
+		QuoteFullName: h.HitWrapper.CurrencyFullName(info.QuoteCurrency),
+		Streamed:      h.HitWrapper.Contains(h.HitWrapper.SupportedSymbols(), symbol),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+func (h *HandleRequests) handleCurrencyInfo(w http.ResponseWriter, req *http.Request) {
+	currency := strings.ToUpper(mux.Vars(req)["currency"])
+	info, ok := h.HitWrapper.Currency(currency)
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeUnknownCurrency, "Not a valid currency")
+		return
+	}
+	responseJSON, err := json.Marshal(&info)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SymbolSearchResponse lists the symbols matching a query, best first.
+type SymbolSearchResponse struct {
+	Query   string               `json:"query"`
+	Results []symbolsearch.Match `json:"results"`
+}
+
+// rebuildSymbolIndex indexes the symbols with the full names of their
+// currencies.
+func (h *HandleRequests) rebuildSymbolIndex() {
+	ids := h.HitWrapper.AllSymbols()
+	symbols := make([]symbolsearch.Symbol, 0, len(ids))
+	for _, id := range ids {
+		info, _ := h.HitWrapper.Symbol(id)
+		symbols = append(symbols, symbolsearch.Symbol{
+			ID:            id,
+			BaseCurrency:  info.BaseCurrency,
+			QuoteCurrency: info.QuoteCurrency,
+			BaseName:      h.HitWrapper.CurrencyFullName(info.BaseCurrency),
+			QuoteName:     h.HitWrapper.CurrencyFullName(info.QuoteCurrency),
+		})
+	}
+	index := symbolsearch.New(symbols)
+	h.indexMutex.Lock()
+	h.symbolIndex = index
+	h.indexMutex.Unlock()
+}
+
+func (h *HandleRequests) handleSymbolSearch(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	q := query.Get("q")
+	limit, ok := parseLimitParam(query.Get("limit"), defaultSearchLimit, maxSearchLimit)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid limit")
+		return
+	}
+	h.indexMutex.RLock()
+	index := h.symbolIndex
+	h.indexMutex.RUnlock()
+	if index == nil {
+		index = symbolsearch.New(nil)
+	}
+	results := index.Search(q, limit)
+	if tenant := tenants.FromContext(req.Context()); tenant.Scoped() {
+		// the allowed symbols are searched among all the matches
+		results = make([]symbolsearch.Match, 0, limit)
+		for _, match := range index.Search(q, len(h.HitWrapper.AllSymbols())) {
+			if tenant.Allows(match.Symbol) && len(results) < limit {
+				results = append(results, match)
+			}
+		}
+	}
+	responseJSON, err := json.Marshal(&SymbolSearchResponse{Query: q, Results: results})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
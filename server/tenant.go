@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crypto-api-server/config"
+	"github.com/crypto-api-server/tenants"
+	"github.com/crypto-api-server/wsclient"
+	"github.com/gorilla/mux"
+)
+
+// apiKeyHeader is the header of the tenant API keys, which are also
+// accepted as bearer tokens.
+const apiKeyHeader = "X-API-Key"
+
+// applyTenants replaces the tenants with those of the configuration.
+func (h *HandleRequests) applyTenants(configured []config.Tenant) {
+	keys := make(map[string]*tenants.Tenant)
+	for _, c := range configured {
+		tenant := tenants.New(c.Name, c.Symbols, c.RateLimit, c.Burst)
+		for _, key := range c.APIKeys {
+			keys[key] = tenant
+		}
+	}
+	h.Tenants.Set(keys)
+}
+
+// authenticateTenant identifies the tenant of the requests by their API key
+// and enforces its rate limit and the symbol of the path. The API is open
+// when no tenant is configured, requests with the admin token and the API
+// description are let through.
+func (h *HandleRequests) authenticateTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !h.Tenants.Enabled() || h.isAdmin(req) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		if route := mux.CurrentRoute(req); route != nil && route.GetName() == "openapi" {
+			next.ServeHTTP(w, req)
+			return
+		}
+		key := apiKey(req)
+		tenant := h.Tenants.Lookup(key)
+		if tenant == nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Missing or unknown API key")
+			return
+		}
+		writer := &usageWriter{ResponseWriter: w}
+		defer func() {
+			h.Tenants.Record(key, tenant, endpointName(req), writer.status, writer.bytes)
+		}()
+		if ok, retryAfter := tenant.Take(time.Now()); !ok {
+			writer.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writeError(writer, http.StatusTooManyRequests, CodeRateLimited, "Rate limit of "+tenant.Name+" exceeded")
+			return
+		}
+		if symbol, ok := mux.Vars(req)["symbol"]; ok && !tenant.Allows(symbol) {
+			writeError(writer, http.StatusForbidden, CodeForbidden, "Symbol not allowed: "+symbol)
+			return
+		}
+		next.ServeHTTP(writer, req.WithContext(tenants.NewContext(req.Context(), tenant)))
+	})
+}
+
+// usageWriter keeps the status and the body size of a response.
+type usageWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *usageWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *usageWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush lets streaming handlers flush through the writer.
+func (w *usageWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// endpointName names the endpoint of req after its method and route
+// template, such as "GET /currency/{symbol}".
+func endpointName(req *http.Request) string {
+	path := req.URL.Path
+	if route := mux.CurrentRoute(req); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			path = template
+		}
+	}
+	return req.Method + " " + path
+}
+
+const (
+	defaultUsageEndpoints = 5
+	maxUsageEndpoints     = 50
+)
+
+// UsageResponse is the usage of the API keys since Since.
+type UsageResponse struct {
+	Since time.Time       `json:"since"`
+	Keys  []tenants.Usage `json:"keys"`
+}
+
+func (h *HandleRequests) handleUsage(w http.ResponseWriter, req *http.Request) {
+	top, ok := parseLimitParam(req.URL.Query().Get("top"), defaultUsageEndpoints, maxUsageEndpoints)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid top")
+		return
+	}
+	var response UsageResponse
+	response.Since, response.Keys = h.Tenants.Usage(top)
+	responseJSON, err := json.Marshal(&response)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+// apiKey returns the API key of req, from the X-API-Key header or the
+// bearer token.
+func apiKey(req *http.Request) string {
+	if key := req.Header.Get(apiKeyHeader); key != "" {
+		return key
+	}
+	authorization := req.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return ""
+	}
+	return strings.TrimPrefix(authorization, "Bearer ")
+}
+
+// scopeTickers returns the tickers of the symbols the tenant of req is
+// allowed.
+func scopeTickers(req *http.Request, tickers []*wsclient.Ticker) []*wsclient.Ticker {
+	tenant := tenants.FromContext(req.Context())
+	if !tenant.Scoped() {
+		return tickers
+	}
+	kept := make([]*wsclient.Ticker, 0, len(tickers))
+	for _, ticker := range tickers {
+		if tenant.Allows(ticker.Symbol) {
+			kept = append(kept, ticker)
+		}
+	}
+	return kept
+}
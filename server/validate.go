@@ -0,0 +1,138 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crypto-api-server/indicators"
+	"github.com/crypto-api-server/openapi"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	symbolPattern   = regexp.MustCompile(`^[A-Za-z0-9_]{2,32}$`)
+	currencyPattern = regexp.MustCompile(`^[A-Za-z0-9]{1,16}$`)
+)
+
+// paramFormat checks the values of a parameter format.
+type paramFormat struct {
+	valid   func(value string) bool
+	message string
+}
+
+// paramFormats are the formats of openapi.Param checked by validateParams.
+var paramFormats = map[string]paramFormat{
+	"symbol":   {symbolPattern.MatchString, "must be 2 to 32 letters, digits or underscores"},
+	"currency": {currencyPattern.MatchString, "must be 1 to 16 letters or digits"},
+	"duration": {
+		func(value string) bool {
+			d, err := time.ParseDuration(value)
+			return err == nil && d > 0
+		},
+		"must be a positive duration such as 15m or 1h",
+	},
+	"ticker-fields": {
+		func(value string) bool {
+			_, ok := parseFieldsParam(value)
+			return ok
+		},
+		"must be a comma separated list of ticker fields: " + strings.Join(tickerFields, ", "),
+	},
+	"indicators": {
+		func(value string) bool {
+			_, err := indicators.ParseMovingAverages(value, maxAveragePeriod)
+			return err == nil
+		},
+		fmt.Sprintf("must be a comma separated list of moving averages such as sma20,ema50, of 1 to %d minutes", maxAveragePeriod),
+	},
+	"timestamp": {
+		func(value string) bool {
+			_, ok := parseTimeParam(value, time.Time{})
+			return ok
+		},
+		"must be an RFC 3339 time or Unix milliseconds",
+	},
+}
+
+// pathFormats are the formats of the path variables, by name.
+var pathFormats = map[string]string{
+	"symbol":   "symbol",
+	"currency": "currency",
+}
+
+// validateParam returns the problem with the value of p, or "" when it is
+// valid.
+func validateParam(p openapi.Param, value string) string {
+	switch p.Type {
+	case "integer":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "must be an integer"
+		}
+		if n < p.Minimum {
+			return "must be at least " + strconv.Itoa(p.Minimum)
+		}
+	case "number":
+		if _, err := decimal.NewFromString(value); err != nil {
+			return "must be a number"
+		}
+	}
+	if format, ok := paramFormats[p.Format]; ok && !format.valid(value) {
+		return format.message
+	}
+	if len(p.Enum) > 0 {
+		for _, allowed := range p.Enum {
+			if strings.EqualFold(value, allowed) {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(p.Enum, ", ")
+	}
+	return ""
+}
+
+// validateParams checks the path variables and the documented query
+// parameters of the matched route, so that malformed requests are rejected
+// with every invalid field before the handler calls the exchange.
+func (h *HandleRequests) validateParams(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var details []ErrorDetail
+		vars := mux.Vars(req)
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if format, ok := paramFormats[pathFormats[name]]; ok && !format.valid(vars[name]) {
+				details = append(details, ErrorDetail{Field: name, Message: format.message})
+			}
+		}
+		if route := mux.CurrentRoute(req); route != nil {
+			query := req.URL.Query()
+			for _, p := range routeDocs[route.GetName()].QueryParams {
+				value := query.Get(p.Name)
+				if value == "" {
+					if p.Required {
+						details = append(details, ErrorDetail{Field: p.Name, Message: "is required"})
+					}
+					continue
+				}
+				if problem := validateParam(p, value); problem != "" {
+					details = append(details, ErrorDetail{Field: p.Name, Message: problem})
+				}
+			}
+		}
+		if len(details) > 0 {
+			writeError(w, http.StatusBadRequest, CodeValidation, "Invalid parameters", details...)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
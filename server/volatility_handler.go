@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/crypto-api-server/indicators"
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxAnalyticsWindow = 30 * 24 * time.Hour
+	// maxAnalyticsSamples bounds the stored updates read per symbol.
+	maxAnalyticsSamples = 100000
+)
+
+type VolatilityResponse struct {
+	Symbol   string `json:"symbol"`
+	Window   string `json:"window"`
+	Interval string `json:"interval"`
+	indicators.VolatilityResult
+}
+
+// storedPrices returns the last prices stored for symbol over the window
+// ending now, oldest first.
+func (h *HandleRequests) storedPrices(symbol string, window time.Duration) ([]indicators.PricePoint, error) {
+	now := time.Now()
+	history, err := h.History.History(symbol, now.Add(-window), now, maxAnalyticsSamples)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]indicators.PricePoint, 0, len(history))
+	for _, ticker := range history {
+		points = append(points, indicators.PricePoint{Price: ticker.Last.InexactFloat64(), Timestamp: ticker.Timestamp})
+	}
+	return points, nil
+}
+
+// parseAnalyticsParams parses the ?window= and ?interval= of the return
+// based analytics, the window must span at least two intervals.
+func parseAnalyticsParams(req *http.Request, defaultWindow time.Duration) (window time.Duration, interval time.Duration, ok bool) {
+	query := req.URL.Query()
+	if window, ok = parseWindowParam(query.Get("window"), defaultWindow, maxAnalyticsWindow); !ok {
+		return 0, 0, false
+	}
+	if interval, ok = parseWindowParam(query.Get("interval"), time.Minute, window/2); !ok {
+		return 0, 0, false
+	}
+	return window, interval, true
+}
+
+func (h *HandleRequests) handleVolatility(w http.ResponseWriter, req *http.Request) {
+	if h.History == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "History storage is not enabled")
+		return
+	}
+	symbol := mux.Vars(req)["symbol"]
+	if !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	window, interval, ok := parseAnalyticsParams(req, time.Hour)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid window or interval, expected a window up to "+maxAnalyticsWindow.String()+" of at least two intervals")
+		return
+	}
+	points, err := h.storedPrices(symbol, window)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	result, ok := indicators.RealizedVolatility(points, interval)
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeNotFound, "Not enough stored prices in window")
+		return
+	}
+	responseJSON, err := json.Marshal(&VolatilityResponse{Symbol: symbol, Window: window.String(), Interval: interval.String(), VolatilityResult: result})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
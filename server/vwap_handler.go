@@ -0,0 +1,52 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/crypto-api-server/indicators"
+	"github.com/gorilla/mux"
+)
+
+type VWAPResponse struct {
+	Symbol string `json:"symbol"`
+	Window string `json:"window"`
+	indicators.VWAPResult
+}
+
+// parseWindowParam parses a ?window= duration such as 15m or 1h.
+func parseWindowParam(value string, defaultWindow time.Duration, maxWindow time.Duration) (time.Duration, bool) {
+	if value == "" {
+		return defaultWindow, true
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil || window <= 0 || window > maxWindow {
+		return 0, false
+	}
+	return window, true
+}
+
+func (h *HandleRequests) handleVWAP(w http.ResponseWriter, req *http.Request) {
+	symbol := mux.Vars(req)["symbol"]
+	if !h.HitWrapper.Contains(h.HitWrapper.AllSymbols(), symbol) {
+		writeError(w, http.StatusNotFound, CodeUnknownSymbol, "Not a valid Symbol")
+		return
+	}
+	window, ok := parseWindowParam(req.URL.Query().Get("window"), time.Hour, h.VWAP.MaxWindow())
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid window, expected a duration up to "+h.VWAP.MaxWindow().String())
+		return
+	}
+	result, ok := h.VWAP.Compute(symbol, window, time.Now())
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeNotFound, "No traded volume in window")
+		return
+	}
+	responseJSON, err := json.Marshal(&VWAPResponse{Symbol: symbol, Window: window.String(), VWAPResult: result})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, responseJSON)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+type WithdrawRequest struct {
+	Currency  string          `json:"currency"`
+	Amount    decimal.Decimal `json:"amount"`
+	Address   string          `json:"address"`
+	PaymentID string          `json:"paymentId,omitempty"`
+}
+
+// WithdrawResponse is a withdrawal, pending until committed.
+type WithdrawResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"` // pending, committed or rolledBack
+}
+
+func (h *HandleRequests) handleWithdraw(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, MaxBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, err.Error())
+		return
+	}
+	var withdrawReq WithdrawRequest
+	if err = json.Unmarshal(body, &withdrawReq); err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidation, "Invalid withdraw body")
+		return
+	}
+	info, ok := h.HitWrapper.Currency(withdrawReq.Currency)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeUnknownCurrency, "Not a valid Currency")
+		return
+	}
+	if !info.PayoutEnabled {
+		writeError(w, http.StatusConflict, CodeCurrencyDisabled, "Withdrawals of "+withdrawReq.Currency+" are disabled")
+		return
+	}
+	if !withdrawReq.Amount.IsPositive() || withdrawReq.Address == "" {
+		writeError(w, http.StatusBadRequest, CodeValidation, "amount and address are required")
+		return
+	}
+	if info.PayoutIsPaymentId && withdrawReq.PaymentID == "" {
+		writeError(w, http.StatusBadRequest, CodeValidation, "paymentId is required for "+withdrawReq.Currency)
+		return
+	}
+	withdraw, err := h.HitWrapper.Withdraw(req.Context(), wsclient.WithdrawRequest{
+		Currency:  withdrawReq.Currency,
+		Amount:    withdrawReq.Amount,
+		Address:   withdrawReq.Address,
+		PaymentID: withdrawReq.PaymentID,
+	})
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	responseJSON, err := json.Marshal(&WithdrawResponse{ID: withdraw.ID, Status: "pending"})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusCreated, responseJSON)
+}
+
+func (h *HandleRequests) handleCommitWithdraw(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+	if err := h.HitWrapper.CommitWithdraw(req.Context(), id); err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	responseJSON, _ := json.Marshal(&WithdrawResponse{ID: id, Status: "committed"})
+	writeResponse(w, http.StatusOK, responseJSON)
+}
+
+func (h *HandleRequests) handleRollbackWithdraw(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+	if err := h.HitWrapper.RollbackWithdraw(req.Context(), id); err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	responseJSON, _ := json.Marshal(&WithdrawResponse{ID: id, Status: "rolledBack"})
+	writeResponse(w, http.StatusOK, responseJSON)
+}
@@ -0,0 +1,301 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/lib/pq"
+)
+
+// postgresMigrations are applied in order, each once, tracked in schema_migrations.
+var postgresMigrations = []string{
+	`CREATE TABLE tickers (
+		symbol       TEXT             NOT NULL,
+		ts           TIMESTAMPTZ      NOT NULL,
+		ask          DOUBLE PRECISION NOT NULL,
+		bid          DOUBLE PRECISION NOT NULL,
+		last         DOUBLE PRECISION NOT NULL,
+		open         DOUBLE PRECISION NOT NULL,
+		low          DOUBLE PRECISION NOT NULL,
+		high         DOUBLE PRECISION NOT NULL,
+		volume       DOUBLE PRECISION NOT NULL,
+		volume_quote DOUBLE PRECISION NOT NULL
+	);
+	CREATE INDEX tickers_symbol_ts ON tickers (symbol, ts DESC);`,
+	`CREATE TABLE candles (
+		symbol       TEXT             NOT NULL,
+		period       TEXT             NOT NULL,
+		ts           TIMESTAMPTZ      NOT NULL,
+		open         DOUBLE PRECISION NOT NULL,
+		high         DOUBLE PRECISION NOT NULL,
+		low          DOUBLE PRECISION NOT NULL,
+		close        DOUBLE PRECISION NOT NULL,
+		volume       DOUBLE PRECISION NOT NULL,
+		volume_quote DOUBLE PRECISION NOT NULL,
+		PRIMARY KEY (symbol, period, ts)
+	);`,
+	`ALTER TABLE tickers
+		ALTER COLUMN ask TYPE NUMERIC,
+		ALTER COLUMN bid TYPE NUMERIC,
+		ALTER COLUMN last TYPE NUMERIC,
+		ALTER COLUMN open TYPE NUMERIC,
+		ALTER COLUMN low TYPE NUMERIC,
+		ALTER COLUMN high TYPE NUMERIC,
+		ALTER COLUMN volume TYPE NUMERIC,
+		ALTER COLUMN volume_quote TYPE NUMERIC;`,
+}
+
+// timescaleMigrations turn the tables into hypertables when TimescaleDB is enabled.
+var timescaleMigrations = []string{
+	`CREATE EXTENSION IF NOT EXISTS timescaledb`,
+	`SELECT create_hypertable('tickers', 'ts', if_not_exists => TRUE, migrate_data => TRUE)`,
+	`SELECT create_hypertable('candles', 'ts', if_not_exists => TRUE, migrate_data => TRUE)`,
+}
+
+// PostgresStore keeps ticker and candle history in PostgreSQL, optionally
+// as TimescaleDB hypertables.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore connects to dsn and applies the pending migrations.
+func NewPostgresStore(dsn string, timescale bool) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err = migrate(db, postgresMigrations); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if timescale {
+		for _, stmt := range timescaleMigrations {
+			if _, err = db.Exec(stmt); err != nil {
+				db.Close()
+				return nil, fmt.Errorf("timescale: %v", err)
+			}
+		}
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// migrate applies the migrations newer than the recorded schema version.
+func migrate(db *sql.DB, migrations []string) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at TIMESTAMPTZ NOT NULL DEFAULT now())`); err != nil {
+		return err
+	}
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return err
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %v", i+1, err)
+		}
+		if _, err = tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, i+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Append implements Store. The batch is written with COPY.
+func (s *PostgresStore) Append(tickers []*wsclient.Ticker) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(pq.CopyIn("tickers", "symbol", "ts", "ask", "bid", "last", "open", "low", "high", "volume", "volume_quote"))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, t := range tickers {
+		if _, err = stmt.Exec(t.Symbol, t.Timestamp, t.Ask, t.Bid, t.Last, t.Open, t.Low, t.High, t.Volume, t.VolumeQuote); err != nil {
+			stmt.Close()
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err = stmt.Exec(); err != nil {
+		stmt.Close()
+		tx.Rollback()
+		return err
+	}
+	if err = stmt.Close(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// History implements Store.
+func (s *PostgresStore) History(symbol string, from, till time.Time, limit int) ([]*wsclient.Ticker, error) {
+	rows, err := s.db.Query(`SELECT symbol, ts, ask, bid, last, open, low, high, volume, volume_quote
+		FROM tickers WHERE symbol = $1 AND ts >= $2 AND ts <= $3 ORDER BY ts DESC LIMIT $4`,
+		symbol, from, till, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tickers := make([]*wsclient.Ticker, 0)
+	for rows.Next() {
+		var t wsclient.Ticker
+		err = rows.Scan(&t.Symbol, &t.Timestamp, &t.Ask, &t.Bid, &t.Last, &t.Open, &t.Low, &t.High, &t.Volume, &t.VolumeQuote)
+		if err != nil {
+			return nil, err
+		}
+		t.ID = t.Symbol
+		t.Timestamp = t.Timestamp.UTC()
+		tickers = append(tickers, &t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	reverseTickers(tickers)
+	return tickers, nil
+}
+
+// AppendCandles implements CandleStore.
+func (s *PostgresStore) AppendCandles(candles []Candle) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO candles (symbol, period, ts, open, high, low, close, volume, volume_quote)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (symbol, period, ts) DO UPDATE SET open = EXCLUDED.open, high = EXCLUDED.high,
+			low = EXCLUDED.low, close = EXCLUDED.close, volume = EXCLUDED.volume, volume_quote = EXCLUDED.volume_quote`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, c := range candles {
+		_, err = stmt.Exec(c.Symbol, c.Period, c.OpenTime, c.Open, c.High, c.Low, c.Close, c.Volume, c.VolumeQuote)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Extremes implements ExtremesStore.
+func (s *PostgresStore) Extremes(symbol string) (high PricePoint, low PricePoint, ok bool, err error) {
+	for _, q := range []struct {
+		order string
+		point *PricePoint
+	}{{"DESC", &high}, {"ASC", &low}} {
+		err = s.db.QueryRow(`SELECT last, ts FROM tickers WHERE symbol = $1 ORDER BY last `+q.order+`, ts LIMIT 1`, symbol).
+			Scan(&q.point.Price, &q.point.Timestamp)
+		if err == sql.ErrNoRows {
+			return PricePoint{}, PricePoint{}, false, nil
+		}
+		if err != nil {
+			return PricePoint{}, PricePoint{}, false, err
+		}
+		q.point.Timestamp = q.point.Timestamp.UTC()
+	}
+	return high, low, true, nil
+}
+
+// Candles implements CandleStore.
+func (s *PostgresStore) Candles(symbol string, period string, from, till time.Time, limit int) ([]Candle, error) {
+	rows, err := s.db.Query(`SELECT symbol, period, ts, open, high, low, close, volume, volume_quote
+		FROM candles WHERE symbol = $1 AND period = $2 AND ts >= $3 AND ts <= $4 ORDER BY ts DESC LIMIT $5`,
+		symbol, period, from, till, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	candles := make([]Candle, 0)
+	for rows.Next() {
+		var c Candle
+		err = rows.Scan(&c.Symbol, &c.Period, &c.OpenTime, &c.Open, &c.High, &c.Low, &c.Close, &c.Volume, &c.VolumeQuote)
+		if err != nil {
+			return nil, err
+		}
+		c.OpenTime = c.OpenTime.UTC()
+		candles = append(candles, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(candles)-1; i < j; i, j = i+1, j-1 {
+		candles[i], candles[j] = candles[j], candles[i]
+	}
+	return candles, nil
+}
+
+// epochBucket is the SQL start of the bucket of ts, $1 seconds long.
+const epochBucket = `to_timestamp(floor(extract(epoch FROM ts)::double precision / $1::double precision) * $1::double precision)`
+
+// PruneTickers implements RetentionStore.
+func (s *PostgresStore) PruneTickers(cutoff time.Time) (int64, error) {
+	result, err := s.db.Exec(`DELETE FROM tickers WHERE ts < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DownsampleTickers implements DownsampleStore.
+func (s *PostgresStore) DownsampleTickers(period string, duration time.Duration, cutoff time.Time) (int64, error) {
+	result, err := s.db.Exec(`INSERT INTO candles (symbol, period, ts, open, high, low, close, volume, volume_quote)
+		SELECT symbol, $2::text, bucket, (array_agg(last ORDER BY ts))[1], max(last), min(last), (array_agg(last ORDER BY ts DESC))[1],
+			GREATEST((array_agg(volume ORDER BY ts DESC))[1] - (array_agg(volume ORDER BY ts))[1], 0),
+			GREATEST((array_agg(volume_quote ORDER BY ts DESC))[1] - (array_agg(volume_quote ORDER BY ts))[1], 0)
+		FROM (SELECT *, `+epochBucket+` AS bucket FROM tickers WHERE ts < $3) AS t
+		GROUP BY symbol, bucket
+		ON CONFLICT (symbol, period, ts) DO NOTHING`,
+		duration.Seconds(), period, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DownsampleCandles implements DownsampleStore.
+func (s *PostgresStore) DownsampleCandles(period string, target string, duration time.Duration, cutoff time.Time) (int64, error) {
+	result, err := s.db.Exec(`INSERT INTO candles (symbol, period, ts, open, high, low, close, volume, volume_quote)
+		SELECT symbol, $2::text, bucket, (array_agg(open ORDER BY ts))[1], max(high), min(low), (array_agg(close ORDER BY ts DESC))[1],
+			sum(volume), sum(volume_quote)
+		FROM (SELECT *, `+epochBucket+` AS bucket FROM candles WHERE period = $3 AND ts < $4) AS c
+		GROUP BY symbol, bucket
+		ON CONFLICT (symbol, period, ts) DO NOTHING`,
+		duration.Seconds(), target, period, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PruneCandles implements DownsampleStore.
+func (s *PostgresStore) PruneCandles(period string, cutoff time.Time) (int64, error) {
+	result, err := s.db.Exec(`DELETE FROM candles WHERE period = $1 AND ts < $2`, period, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Close implements Store.
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
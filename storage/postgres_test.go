@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newPostgresStore connects to the database of POSTGRES_TEST_DSN, emptied,
+// and skips the test when it isn't set.
+func newPostgresStore(t *testing.T) *PostgresStore {
+	t.Helper()
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+	store, err := NewPostgresStore(dsn, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if _, err = store.db.Exec(`TRUNCATE tickers, candles`); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestPostgresMigrate(t *testing.T) {
+	store := newPostgresStore(t)
+	// the migrations already applied are skipped
+	if err := migrate(store.db, postgresMigrations); err != nil {
+		t.Fatal(err)
+	}
+	var version, count int
+	if err := store.db.QueryRow(`SELECT MAX(version), COUNT(*) FROM schema_migrations`).Scan(&version, &count); err != nil {
+		t.Fatal(err)
+	}
+	if version != len(postgresMigrations) || count != len(postgresMigrations) {
+		t.Errorf("version %d of %d migrations recorded, want %d", version, count, len(postgresMigrations))
+	}
+}
+
+func TestPostgresHistory(t *testing.T) {
+	testHistory(t, newPostgresStore(t))
+}
+
+func TestPostgresCandles(t *testing.T) {
+	store := newPostgresStore(t)
+	candle := func(minutes int, close float64) Candle {
+		return Candle{Symbol: "BTCUSD", Period: "M1", OpenTime: base.Add(time.Duration(minutes) * time.Minute),
+			Open: 100, High: 110, Low: 90, Close: close, Volume: 1, VolumeQuote: 100}
+	}
+	if err := store.AppendCandles([]Candle{candle(1, 101), candle(0, 100), candle(2, 102)}); err != nil {
+		t.Fatal(err)
+	}
+	// replaces the candle of the same open time
+	if err := store.AppendCandles([]Candle{candle(1, 105)}); err != nil {
+		t.Fatal(err)
+	}
+	candles, err := store.Candles("BTCUSD", "M1", base, base.Add(2*time.Minute), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Candle{candle(1, 105), candle(2, 102)}; !reflect.DeepEqual(candles, want) {
+		t.Errorf("candles %+v, want %+v", candles, want)
+	}
+	if candles, err = store.Candles("BTCUSD", "H1", base, base.Add(time.Hour), 10); err != nil || len(candles) != 0 {
+		t.Errorf("got %v, %v for another period", candles, err)
+	}
+}
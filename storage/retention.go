@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// RetentionStore is implemented by backends that can prune the ticker history.
+type RetentionStore interface {
+	// PruneTickers deletes the ticker updates before cutoff.
+	PruneTickers(cutoff time.Time) (int64, error)
+}
+
+// DownsampleStore is implemented by backends that keep candles and can
+// aggregate the finer history into them. Existing candles are kept.
+type DownsampleStore interface {
+	// DownsampleTickers aggregates the ticker updates before cutoff into
+	// candles of period, lasting duration. Volumes are the change of the
+	// rolling 24h volume and are approximate.
+	DownsampleTickers(period string, duration time.Duration, cutoff time.Time) (int64, error)
+	// DownsampleCandles aggregates the candles of period opened before
+	// cutoff into candles of target, lasting duration.
+	DownsampleCandles(period string, target string, duration time.Duration, cutoff time.Time) (int64, error)
+	// PruneCandles deletes the candles of period opened before cutoff.
+	PruneCandles(period string, cutoff time.Time) (int64, error)
+}
+
+// CandleRetention is how long the candles of a period are kept.
+type CandleRetention struct {
+	Period   string
+	Duration time.Duration
+	// Keep is zero for candles kept forever
+	Keep time.Duration
+}
+
+// RetentionPolicy is how long the history is kept. Before history is
+// pruned it is downsampled into the next longer candle period of the
+// policy, so that charts keep a coarser history.
+type RetentionPolicy struct {
+	// Tickers is zero when the ticker updates are kept forever
+	Tickers time.Duration
+	// Candles are sorted by period, shortest first
+	Candles []CandleRetention
+}
+
+// RetentionResult counts the rows written and deleted by ApplyRetention.
+type RetentionResult struct {
+	Downsampled int64
+	Pruned      int64
+}
+
+// ParseRetentionPolicy parses comma separated kind:duration pairs where the
+// kind is "tickers" or a candle period such as M1 or H1, and the duration is
+// like 7d, 12h or "forever", e.g. "tickers:7d,M1:90d,H1:forever".
+func ParseRetentionPolicy(value string) (RetentionPolicy, error) {
+	var policy RetentionPolicy
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 {
+			return policy, fmt.Errorf("invalid retention %q, expected kind:duration", item)
+		}
+		kind := strings.TrimSpace(parts[0])
+		keep, err := parseRetentionDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return policy, fmt.Errorf("invalid retention %q: %v", item, err)
+		}
+		if kind == "tickers" {
+			policy.Tickers = keep
+			continue
+		}
+		duration, ok := wsclient.CandlePeriods[kind]
+		// months don't have a fixed duration to aggregate into
+		if !ok || kind == "1M" {
+			return policy, fmt.Errorf("invalid retention %q: unknown candle period %s", item, kind)
+		}
+		policy.Candles = append(policy.Candles, CandleRetention{Period: kind, Duration: duration, Keep: keep})
+	}
+	sort.Slice(policy.Candles, func(i, j int) bool { return policy.Candles[i].Duration < policy.Candles[j].Duration })
+	return policy, nil
+}
+
+// parseRetentionDuration accepts "forever", days such as 7d, and Go durations.
+func parseRetentionDuration(value string) (time.Duration, error) {
+	if value == "forever" {
+		return 0, nil
+	}
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("invalid number of days %q", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return duration, nil
+}
+
+// ApplyRetention downsamples and prunes the history of store that is older
+// than policy allows at now. Candles are only aggregated into whole longer
+// candles, the history of the one in progress at the cutoff is kept until
+// the next run. Backends that can't downsample are only pruned.
+func ApplyRetention(store Store, policy RetentionPolicy, now time.Time) (RetentionResult, error) {
+	var result RetentionResult
+	if policy.Tickers > 0 {
+		// the ticker updates first, so that their candles cascade into the
+		// longer periods in the same run
+		if err := applyTickerRetention(store, policy, now, &result); err != nil {
+			return result, err
+		}
+	}
+	downsampler, canDownsample := store.(DownsampleStore)
+	for i, candles := range policy.Candles {
+		if candles.Keep == 0 || !canDownsample {
+			continue
+		}
+		cutoff := now.Add(-candles.Keep)
+		if i+1 < len(policy.Candles) {
+			target := policy.Candles[i+1]
+			cutoff = truncateEpoch(cutoff, target.Duration)
+			written, err := downsampler.DownsampleCandles(candles.Period, target.Period, target.Duration, cutoff)
+			if err != nil {
+				return result, fmt.Errorf("downsampling %s candles: %v", candles.Period, err)
+			}
+			result.Downsampled += written
+		}
+		deleted, err := downsampler.PruneCandles(candles.Period, cutoff)
+		if err != nil {
+			return result, fmt.Errorf("pruning %s candles: %v", candles.Period, err)
+		}
+		result.Pruned += deleted
+	}
+	return result, nil
+}
+
+func applyTickerRetention(store Store, policy RetentionPolicy, now time.Time, result *RetentionResult) error {
+	pruner, ok := store.(RetentionStore)
+	if !ok {
+		return fmt.Errorf("the history backend can't prune ticker updates")
+	}
+	cutoff := now.Add(-policy.Tickers)
+	if downsampler, ok := store.(DownsampleStore); ok {
+		target := CandleRetention{Period: "M1", Duration: time.Minute}
+		if len(policy.Candles) > 0 {
+			target = policy.Candles[0]
+		}
+		cutoff = truncateEpoch(cutoff, target.Duration)
+		written, err := downsampler.DownsampleTickers(target.Period, target.Duration, cutoff)
+		if err != nil {
+			return fmt.Errorf("downsampling ticker updates: %v", err)
+		}
+		result.Downsampled += written
+	}
+	deleted, err := pruner.PruneTickers(cutoff)
+	if err != nil {
+		return fmt.Errorf("pruning ticker updates: %v", err)
+	}
+	result.Pruned += deleted
+	return nil
+}
+
+// truncateEpoch rounds t down to a multiple of d since the Unix epoch, as
+// the backends bucket the candles.
+func truncateEpoch(t time.Time, d time.Duration) time.Time {
+	seconds := int64(d / time.Second)
+	return time.Unix(t.Unix()/seconds*seconds, 0).UTC()
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+func TestParseRetentionPolicy(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		value string
+		want  RetentionPolicy
+		err   string
+	}{
+		{"", RetentionPolicy{}, ""},
+		{
+			" H1:forever, tickers:7d,M1:90d ,",
+			RetentionPolicy{Tickers: 7 * day, Candles: []CandleRetention{
+				{Period: "M1", Duration: time.Minute, Keep: 90 * day},
+				{Period: "H1", Duration: time.Hour},
+			}},
+			"",
+		},
+		{"tickers:12h", RetentionPolicy{Tickers: 12 * time.Hour}, ""},
+		{"tickers", RetentionPolicy{}, "expected kind:duration"},
+		{"tickers:0d", RetentionPolicy{}, `invalid number of days "0d"`},
+		{"tickers:-1h", RetentionPolicy{}, `invalid duration "-1h"`},
+		{"tickers:soon", RetentionPolicy{}, `invalid duration "soon"`},
+		{"M2:7d", RetentionPolicy{}, "unknown candle period M2"},
+		{"1M:forever", RetentionPolicy{}, "unknown candle period 1M"},
+	}
+	for _, test := range tests {
+		policy, err := ParseRetentionPolicy(test.value)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("ParseRetentionPolicy(%q): got %v, want an error containing %q", test.value, err, test.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(policy, test.want) {
+			t.Errorf("ParseRetentionPolicy(%q) = %+v, %v, want %+v", test.value, policy, err, test.want)
+		}
+	}
+}
+
+// retentionStore records the retention calls of ApplyRetention.
+type retentionStore struct {
+	Store
+	calls []string
+}
+
+func (s *retentionStore) record(format string, args ...interface{}) {
+	s.calls = append(s.calls, fmt.Sprintf(format, args...))
+}
+
+func (s *retentionStore) PruneTickers(cutoff time.Time) (int64, error) {
+	s.record("PruneTickers %s", cutoff.Format(time.RFC3339))
+	return 5, nil
+}
+
+func (s *retentionStore) DownsampleTickers(period string, duration time.Duration, cutoff time.Time) (int64, error) {
+	s.record("DownsampleTickers %s %v %s", period, duration, cutoff.Format(time.RFC3339))
+	return 3, nil
+}
+
+func (s *retentionStore) DownsampleCandles(period string, target string, duration time.Duration, cutoff time.Time) (int64, error) {
+	s.record("DownsampleCandles %s %s %v %s", period, target, duration, cutoff.Format(time.RFC3339))
+	return 7, nil
+}
+
+func (s *retentionStore) PruneCandles(period string, cutoff time.Time) (int64, error) {
+	s.record("PruneCandles %s %s", period, cutoff.Format(time.RFC3339))
+	return 11, nil
+}
+
+func TestApplyRetention(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 34, 56, 0, time.UTC)
+	tests := []struct {
+		policy string
+		want   []string
+		result RetentionResult
+	}{
+		{
+			// the cutoffs are rounded down to whole candles of the target
+			// period, and candles kept forever aren't pruned
+			"tickers:7d,M1:90d,H1:forever",
+			[]string{
+				"DownsampleTickers M1 1m0s 2024-03-03T12:34:00Z",
+				"PruneTickers 2024-03-03T12:34:00Z",
+				"DownsampleCandles M1 H1 1h0m0s 2023-12-11T12:00:00Z",
+				"PruneCandles M1 2023-12-11T12:00:00Z",
+			},
+			RetentionResult{Downsampled: 10, Pruned: 16},
+		},
+		{
+			"tickers:1h,H1:30d",
+			[]string{
+				"DownsampleTickers H1 1h0m0s 2024-03-10T11:00:00Z",
+				"PruneTickers 2024-03-10T11:00:00Z",
+				"PruneCandles H1 2024-02-09T12:34:56Z",
+			},
+			RetentionResult{Downsampled: 3, Pruned: 16},
+		},
+		{
+			"tickers:1h",
+			[]string{
+				"DownsampleTickers M1 1m0s 2024-03-10T11:34:00Z",
+				"PruneTickers 2024-03-10T11:34:00Z",
+			},
+			RetentionResult{Downsampled: 3, Pruned: 5},
+		},
+		{"M1:forever", nil, RetentionResult{}},
+	}
+	for _, test := range tests {
+		policy, err := ParseRetentionPolicy(test.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store := &retentionStore{}
+		result, err := ApplyRetention(store, policy, now)
+		if err != nil {
+			t.Fatalf("%s: %v", test.policy, err)
+		}
+		if !reflect.DeepEqual(store.calls, test.want) {
+			t.Errorf("%s: calls\n%s\nwant\n%s", test.policy, strings.Join(store.calls, "\n"), strings.Join(test.want, "\n"))
+		}
+		if result != test.result {
+			t.Errorf("%s: result %+v, want %+v", test.policy, result, test.result)
+		}
+	}
+}
+
+// historyStore is a Store that can't prune.
+type historyStore struct {
+	Store
+}
+
+func TestApplyRetentionWithoutPruning(t *testing.T) {
+	_, err := ApplyRetention(historyStore{}, RetentionPolicy{Tickers: time.Hour}, time.Now())
+	if err == nil {
+		t.Error("no error pruning the ticker updates of a store that can't")
+	}
+	// the candles are only kept by the stores that downsample
+	if _, err = ApplyRetention(historyStore{}, RetentionPolicy{Candles: []CandleRetention{{Period: "M1", Duration: time.Minute, Keep: time.Hour}}}, time.Now()); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestApplyRetentionSQLite checks that SQLite, which doesn't keep candles,
+// prunes the updates at the exact cutoff.
+func TestApplyRetentionSQLite(t *testing.T) {
+	store := newSQLiteStore(t)
+	err := store.Append([]*wsclient.Ticker{tickerAt("BTCUSD", 0, "100"), tickerAt("BTCUSD", 1, "101"), tickerAt("BTCUSD", 2, "102")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := base.Add(time.Hour + 90*time.Second)
+	result, err := ApplyRetention(store, RetentionPolicy{Tickers: time.Hour}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (RetentionResult{Pruned: 2}) {
+		t.Errorf("result %+v, want 2 pruned", result)
+	}
+	tickers, err := store.History("BTCUSD", base, now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prices := lastPrices(tickers); !reflect.DeepEqual(prices, []string{"102"}) {
+		t.Errorf("prices %v, want [102]", prices)
+	}
+}
+
+func TestApplyRetentionPostgres(t *testing.T) {
+	store := newPostgresStore(t)
+	update := func(seconds int, last string, volume int64) *wsclient.Ticker {
+		ticker := tickerAt("BTCUSD", 0, last)
+		ticker.Timestamp = base.Add(time.Duration(seconds) * time.Second)
+		ticker.Volume = decimal.NewFromInt(volume)
+		return ticker
+	}
+	err := store.Append([]*wsclient.Ticker{
+		update(0, "100", 10), update(20, "104", 11), update(40, "98", 14),
+		update(70, "101", 15),
+		update(130, "102", 16),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the cutoff at base+2m30s is rounded down to base+2m
+	now := base.Add(time.Hour + 150*time.Second)
+	policy := RetentionPolicy{Tickers: time.Hour, Candles: []CandleRetention{{Period: "M1", Duration: time.Minute}}}
+	result, err := ApplyRetention(store, policy, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (RetentionResult{Downsampled: 2, Pruned: 4}) {
+		t.Errorf("result %+v, want 2 downsampled and 4 pruned", result)
+	}
+	candles, err := store.Candles("BTCUSD", "M1", base, now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Candle{
+		{Symbol: "BTCUSD", Period: "M1", OpenTime: base, Open: 100, High: 104, Low: 98, Close: 98, Volume: 4},
+		{Symbol: "BTCUSD", Period: "M1", OpenTime: base.Add(time.Minute), Open: 101, High: 101, Low: 101, Close: 101},
+	}
+	if !reflect.DeepEqual(candles, want) {
+		t.Errorf("candles %+v, want %+v", candles, want)
+	}
+	tickers, err := store.History("BTCUSD", base, now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prices := lastPrices(tickers); !reflect.DeepEqual(prices, []string{"102"}) {
+		t.Errorf("prices %v after pruning, want [102]", prices)
+	}
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	// registers the pure Go "sqlite" database/sql driver
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS tickers (
+	symbol       TEXT    NOT NULL,
+	ts           INTEGER NOT NULL,
+	ask          REAL    NOT NULL,
+	bid          REAL    NOT NULL,
+	last         REAL    NOT NULL,
+	open         REAL    NOT NULL,
+	low          REAL    NOT NULL,
+	high         REAL    NOT NULL,
+	volume       REAL    NOT NULL,
+	volume_quote REAL    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS tickers_symbol_ts ON tickers (symbol, ts);
+`
+
+// SQLiteStore keeps the ticker history in an embedded SQLite database.
+// Timestamps are stored as Unix milliseconds. Prices are stored as REAL, so
+// the history is exact only to float64 precision.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (or creates) the database file at path.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite serialises writers, a single connection avoids SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	if _, err = db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Append implements Store.
+func (s *SQLiteStore) Append(tickers []*wsclient.Ticker) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO tickers (symbol, ts, ask, bid, last, open, low, high, volume, volume_quote)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, t := range tickers {
+		_, err = stmt.Exec(t.Symbol, unixMillis(t.Timestamp), t.Ask, t.Bid, t.Last, t.Open, t.Low, t.High, t.Volume, t.VolumeQuote)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// History implements Store.
+func (s *SQLiteStore) History(symbol string, from, till time.Time, limit int) ([]*wsclient.Ticker, error) {
+	rows, err := s.db.Query(`SELECT symbol, ts, ask, bid, last, open, low, high, volume, volume_quote
+		FROM tickers WHERE symbol = ? AND ts >= ? AND ts <= ? ORDER BY ts DESC LIMIT ?`,
+		symbol, unixMillis(from), unixMillis(till), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanTickers(rows)
+}
+
+// Extremes implements ExtremesStore.
+func (s *SQLiteStore) Extremes(symbol string) (high PricePoint, low PricePoint, ok bool, err error) {
+	for _, q := range []struct {
+		order string
+		point *PricePoint
+	}{{"DESC", &high}, {"ASC", &low}} {
+		var ts int64
+		err = s.db.QueryRow(`SELECT last, ts FROM tickers WHERE symbol = ? ORDER BY last `+q.order+`, ts LIMIT 1`, symbol).
+			Scan(&q.point.Price, &ts)
+		if err == sql.ErrNoRows {
+			return PricePoint{}, PricePoint{}, false, nil
+		}
+		if err != nil {
+			return PricePoint{}, PricePoint{}, false, err
+		}
+		q.point.Timestamp = time.Unix(0, ts*int64(time.Millisecond)).UTC()
+	}
+	return high, low, true, nil
+}
+
+// PruneTickers implements RetentionStore.
+func (s *SQLiteStore) PruneTickers(cutoff time.Time) (int64, error) {
+	result, err := s.db.Exec(`DELETE FROM tickers WHERE ts < ?`, unixMillis(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Close implements Store.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// scanTickers reads rows selected newest first and returns them oldest first.
+func scanTickers(rows *sql.Rows) ([]*wsclient.Ticker, error) {
+	tickers := make([]*wsclient.Ticker, 0)
+	for rows.Next() {
+		var t wsclient.Ticker
+		var ts int64
+		err := rows.Scan(&t.Symbol, &ts, &t.Ask, &t.Bid, &t.Last, &t.Open, &t.Low, &t.High, &t.Volume, &t.VolumeQuote)
+		if err != nil {
+			return nil, err
+		}
+		t.ID = t.Symbol
+		t.Timestamp = time.Unix(0, ts*int64(time.Millisecond)).UTC()
+		tickers = append(tickers, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	reverseTickers(tickers)
+	return tickers, nil
+}
+
+func reverseTickers(tickers []*wsclient.Ticker) {
+	for i, j := 0, len(tickers)-1; i < j; i, j = i+1, j-1 {
+		tickers[i], tickers[j] = tickers[j], tickers[i]
+	}
+}
+
+func unixMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
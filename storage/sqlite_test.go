@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+// base is the time of the first ticker update of the tests.
+var base = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newSQLiteStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// tickerAt is an update of symbol at base plus minutes, trading at last.
+func tickerAt(symbol string, minutes int, last string) *wsclient.Ticker {
+	price := decimal.RequireFromString(last)
+	return &wsclient.Ticker{
+		Symbol:      symbol,
+		Timestamp:   base.Add(time.Duration(minutes) * time.Minute),
+		Ask:         price.Add(decimal.NewFromInt(1)),
+		Bid:         price.Sub(decimal.NewFromInt(1)),
+		Last:        price,
+		Open:        decimal.NewFromInt(100),
+		Low:         decimal.NewFromInt(90),
+		High:        decimal.NewFromInt(110),
+		Volume:      decimal.RequireFromString("12.5"),
+		VolumeQuote: decimal.NewFromInt(1250),
+	}
+}
+
+// lastPrices are the last prices of tickers, in order.
+func lastPrices(tickers []*wsclient.Ticker) []string {
+	prices := make([]string, len(tickers))
+	for i, ticker := range tickers {
+		prices[i] = ticker.Last.String()
+	}
+	return prices
+}
+
+// testHistory checks the inserts and range queries of a Store.
+func testHistory(t *testing.T, store Store) {
+	t.Helper()
+	// appended out of order, over two batches
+	err := store.Append([]*wsclient.Ticker{
+		tickerAt("BTCUSD", 2, "102"),
+		tickerAt("BTCUSD", 0, "100"),
+		tickerAt("ETHBTC", 1, "0.05"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Append([]*wsclient.Ticker{tickerAt("BTCUSD", 1, "101.5"), tickerAt("BTCUSD", 3, "103")}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		symbol   string
+		from     int
+		till     int
+		limit    int
+		want     []string
+		wantFrom int
+	}{
+		{"all", "BTCUSD", 0, 3, 10, []string{"100", "101.5", "102", "103"}, 0},
+		{"bounds included", "BTCUSD", 1, 2, 10, []string{"101.5", "102"}, 1},
+		{"most recent kept", "BTCUSD", 0, 3, 2, []string{"102", "103"}, 2},
+		{"other symbol", "ETHBTC", 0, 3, 10, []string{"0.05"}, 1},
+		{"empty range", "BTCUSD", 4, 10, 10, []string{}, 0},
+		{"unknown symbol", "XYZUSD", 0, 3, 10, []string{}, 0},
+	}
+	for _, test := range tests {
+		tickers, err := store.History(test.symbol, base.Add(time.Duration(test.from)*time.Minute), base.Add(time.Duration(test.till)*time.Minute), test.limit)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got := lastPrices(tickers); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: prices %v, want %v", test.name, got, test.want)
+			continue
+		}
+		if len(tickers) == 0 {
+			continue
+		}
+		first := tickers[0]
+		if !first.Timestamp.Equal(base.Add(time.Duration(test.wantFrom)*time.Minute)) || first.Symbol != test.symbol || first.ID != test.symbol {
+			t.Errorf("%s: first update of %s (%s) at %v", test.name, first.Symbol, first.ID, first.Timestamp)
+		}
+	}
+
+	tickers, err := store.History("BTCUSD", base, base, 1)
+	if err != nil || len(tickers) != 1 {
+		t.Fatalf("got %v, %v, want the first update", tickers, err)
+	}
+	want := tickerAt("BTCUSD", 0, "100")
+	got := tickers[0]
+	if !got.Ask.Equal(want.Ask) || !got.Bid.Equal(want.Bid) || !got.Open.Equal(want.Open) || !got.Low.Equal(want.Low) ||
+		!got.High.Equal(want.High) || !got.Volume.Equal(want.Volume) || !got.VolumeQuote.Equal(want.VolumeQuote) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestSQLiteHistory(t *testing.T) {
+	testHistory(t, newSQLiteStore(t))
+}
+
+func TestSQLiteExtremes(t *testing.T) {
+	store := newSQLiteStore(t)
+	if _, _, ok, err := store.Extremes("BTCUSD"); ok || err != nil {
+		t.Fatalf("got %v, %v for an empty history", ok, err)
+	}
+	err := store.Append([]*wsclient.Ticker{
+		tickerAt("BTCUSD", 0, "100"),
+		tickerAt("BTCUSD", 1, "120"),
+		tickerAt("BTCUSD", 2, "80"),
+		tickerAt("BTCUSD", 3, "120"),
+		tickerAt("ETHBTC", 4, "1000"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	high, low, ok, err := store.Extremes("BTCUSD")
+	if err != nil || !ok {
+		t.Fatalf("got %v, %v", ok, err)
+	}
+	// the first time the high was traded
+	if high.Price != 120 || !high.Timestamp.Equal(base.Add(time.Minute)) {
+		t.Errorf("high %v at %v, want 120 at %v", high.Price, high.Timestamp, base.Add(time.Minute))
+	}
+	if low.Price != 80 || !low.Timestamp.Equal(base.Add(2*time.Minute)) {
+		t.Errorf("low %v at %v, want 80 at %v", low.Price, low.Timestamp, base.Add(2*time.Minute))
+	}
+}
+
+func TestSQLitePruneTickers(t *testing.T) {
+	store := newSQLiteStore(t)
+	err := store.Append([]*wsclient.Ticker{
+		tickerAt("BTCUSD", 0, "100"),
+		tickerAt("BTCUSD", 1, "101"),
+		tickerAt("ETHBTC", 1, "0.05"),
+		tickerAt("BTCUSD", 2, "102"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := store.PruneTickers(base.Add(2 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 3 {
+		t.Errorf("deleted %d, want 3", deleted)
+	}
+	tickers, err := store.History("BTCUSD", base, base.Add(time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prices := lastPrices(tickers); len(prices) != 1 || prices[0] != "102" {
+		t.Errorf("prices %v after pruning, want [102]", prices)
+	}
+}
@@ -0,0 +1,148 @@
+// Package storage persists ticker updates so historical queries can be
+// served without external services.
+package storage
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// Store is a ticker history backend.
+type Store interface {
+	// Append stores a batch of ticker updates.
+	Append(tickers []*wsclient.Ticker) error
+	// History returns the updates of symbol in [from, till], oldest first,
+	// keeping the most recent ones when there are more than limit.
+	History(symbol string, from, till time.Time, limit int) ([]*wsclient.Ticker, error)
+	Close() error
+}
+
+// Recorder buffers ticker updates from the feed and appends them to a Store
+// in batches. With a sample interval at most one update per symbol and
+// interval is kept.
+type Recorder struct {
+	store          Store
+	sampleInterval time.Duration
+	batchSize      int
+
+	mutex     *sync.Mutex
+	pending   []*wsclient.Ticker
+	lastSaved map[string]time.Time
+	flush     chan struct{}
+	done      chan struct{}
+}
+
+// NewRecorder starts a Recorder flushing to store every flushInterval or
+// when batchSize updates are pending.
+func NewRecorder(store Store, sampleInterval time.Duration, flushInterval time.Duration, batchSize int) *Recorder {
+	r := &Recorder{
+		store:          store,
+		sampleInterval: sampleInterval,
+		batchSize:      batchSize,
+		mutex:          &sync.Mutex{},
+		lastSaved:      make(map[string]time.Time),
+		flush:          make(chan struct{}, 1),
+		done:           make(chan struct{}),
+	}
+	go r.run(flushInterval)
+	return r
+}
+
+// Record is registered as a ticker listener.
+func (r *Recorder) Record(ticker *wsclient.Ticker) {
+	at := ticker.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	r.mutex.Lock()
+	if last, ok := r.lastSaved[ticker.Symbol]; ok && at.Sub(last) < r.sampleInterval {
+		r.mutex.Unlock()
+		return
+	}
+	r.lastSaved[ticker.Symbol] = at
+	stored := *ticker
+	stored.Timestamp = at
+	r.pending = append(r.pending, &stored)
+	full := len(r.pending) >= r.batchSize
+	r.mutex.Unlock()
+	if full {
+		select {
+		case r.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (r *Recorder) run(flushInterval time.Duration) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.flush:
+		case <-r.done:
+			r.write()
+			return
+		}
+		r.write()
+	}
+}
+
+func (r *Recorder) write() {
+	r.mutex.Lock()
+	batch := r.pending
+	r.pending = nil
+	r.mutex.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+	if err := r.store.Append(batch); err != nil {
+		log.Printf("history: dropping %d updates: %v", len(batch), err)
+	}
+}
+
+// Close writes the pending updates and closes the store.
+func (r *Recorder) Close() error {
+	close(r.done)
+	r.write()
+	return r.store.Close()
+}
+
+// Candle is an OHLCV bar of a symbol for a period starting at OpenTime.
+type Candle struct {
+	Symbol      string    `json:"symbol"`
+	Period      string    `json:"period"`
+	OpenTime    time.Time `json:"timestamp"`
+	Open        float64   `json:"open,string"`
+	High        float64   `json:"max,string"`
+	Low         float64   `json:"min,string"`
+	Close       float64   `json:"close,string"`
+	Volume      float64   `json:"volume,string"`
+	VolumeQuote float64   `json:"volumeQuote,string"`
+}
+
+// CandleStore is implemented by backends that also keep candles.
+type CandleStore interface {
+	// AppendCandles inserts candles, replacing existing ones for the same
+	// symbol, period and open time.
+	AppendCandles(candles []Candle) error
+	// Candles returns the candles of symbol and period in [from, till], oldest first.
+	Candles(symbol string, period string, from, till time.Time, limit int) ([]Candle, error)
+}
+
+// PricePoint is a last price and the time it was traded.
+type PricePoint struct {
+	Price     float64   `json:"price,string"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ExtremesStore is implemented by backends that can find the extreme prices
+// of the history.
+type ExtremesStore interface {
+	// Extremes returns the highest and lowest last prices stored for symbol,
+	// and false when none is.
+	Extremes(symbol string) (high PricePoint, low PricePoint, ok bool, err error)
+}
@@ -0,0 +1,171 @@
+// Package symbolsearch finds symbols by their id or the names of their
+// currencies, ranking exact and prefix matches before fuzzy ones.
+package symbolsearch
+
+import (
+	"sort"
+	"strings"
+)
+
+// Scores of the kinds of matches, the best match of a symbol counts.
+const (
+	scoreExact         = 100
+	scoreBase          = 90
+	scorePrefix        = 80
+	scoreNamePrefix    = 70
+	scoreQuote         = 50
+	scoreSubstring     = 40
+	scoreNameSubstring = 30
+	scoreFuzzy         = 20
+)
+
+// Symbol is a symbol to index, with its currencies and their full names.
+type Symbol struct {
+	ID            string
+	BaseCurrency  string
+	QuoteCurrency string
+	BaseName      string
+	QuoteName     string
+}
+
+// Match is a symbol matching a query. Higher scores are better matches.
+type Match struct {
+	Symbol        string `json:"symbol"`
+	BaseCurrency  string `json:"baseCurrency,omitempty"`
+	QuoteCurrency string `json:"quoteCurrency,omitempty"`
+	FullName      string `json:"fullName,omitempty"`
+	Score         int    `json:"score"`
+}
+
+type entry struct {
+	symbol Symbol
+	// lower case copies of the fields, matched against the query
+	id, base, quote, baseName, quoteName string
+}
+
+// Index searches a fixed set of symbols. It is safe for concurrent use, build
+// a new one when the symbols change.
+type Index struct {
+	entries []entry
+}
+
+// New indexes symbols.
+func New(symbols []Symbol) *Index {
+	entries := make([]entry, 0, len(symbols))
+	for _, symbol := range symbols {
+		entries = append(entries, entry{
+			symbol:    symbol,
+			id:        strings.ToLower(symbol.ID),
+			base:      strings.ToLower(symbol.BaseCurrency),
+			quote:     strings.ToLower(symbol.QuoteCurrency),
+			baseName:  strings.ToLower(symbol.BaseName),
+			quoteName: strings.ToLower(symbol.QuoteName),
+		})
+	}
+	return &Index{entries: entries}
+}
+
+// Search returns at most limit symbols matching query, best first. Symbols
+// of equal score are sorted by id, shorter ids first.
+func (ix *Index) Search(query string, limit int) []Match {
+	query = strings.ToLower(strings.TrimSpace(query))
+	matches := make([]Match, 0)
+	if query == "" {
+		return matches
+	}
+	for _, e := range ix.entries {
+		score := e.score(query)
+		if score == 0 {
+			continue
+		}
+		matches = append(matches, Match{
+			Symbol:        e.symbol.ID,
+			BaseCurrency:  e.symbol.BaseCurrency,
+			QuoteCurrency: e.symbol.QuoteCurrency,
+			FullName:      e.symbol.BaseName,
+			Score:         score,
+		})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if len(matches[i].Symbol) != len(matches[j].Symbol) {
+			return len(matches[i].Symbol) < len(matches[j].Symbol)
+		}
+		return matches[i].Symbol < matches[j].Symbol
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// score returns the score of the best match of query, 0 when none.
+func (e entry) score(query string) int {
+	switch {
+	case e.id == query:
+		return scoreExact
+	case e.base == query || e.baseName == query:
+		return scoreBase
+	case strings.HasPrefix(e.id, query):
+		return scorePrefix
+	case e.baseName != "" && strings.HasPrefix(e.baseName, query):
+		return scoreNamePrefix
+	case e.quote == query || e.quoteName == query:
+		return scoreQuote
+	case strings.Contains(e.id, query):
+		return scoreSubstring
+	case strings.Contains(e.baseName, query) || strings.Contains(e.quoteName, query):
+		return scoreNameSubstring
+	}
+	// typos: one edit per four characters of the query is tolerated
+	maxDistance := len(query) / 4
+	if maxDistance == 0 {
+		return 0
+	}
+	best := maxDistance + 1
+	for _, candidate := range []string{e.id, e.base, e.baseName} {
+		if candidate == "" {
+			continue
+		}
+		if d := distance(query, candidate); d < best {
+			best = d
+		}
+	}
+	if best > maxDistance {
+		return 0
+	}
+	return scoreFuzzy - best
+}
+
+// distance is the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
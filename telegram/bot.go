@@ -0,0 +1,200 @@
+// Package telegram delivers alerts and feed incidents to a Telegram chat and
+// answers price commands sent to the bot.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/crypto-api-server/alerts"
+	"github.com/crypto-api-server/wsclient"
+)
+
+const apiBase = "https://api.telegram.org"
+
+// PriceLookup returns the current ticker of a symbol.
+type PriceLookup func(ctx context.Context, symbol string) (*wsclient.Ticker, error)
+
+// Bot is a Telegram bot bound to a single chat. Commands from other chats
+// are ignored.
+type Bot struct {
+	token      string
+	chatID     int64
+	httpClient *http.Client
+	lookup     PriceLookup
+	symbols    func() []string
+}
+
+// NewBot creates a bot posting to chatID. lookup and symbols serve the
+// /price and /symbols commands.
+func NewBot(token string, chatID int64, lookup PriceLookup, symbols func() []string) *Bot {
+	return &Bot{
+		token:      token,
+		chatID:     chatID,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+		lookup:     lookup,
+		symbols:    symbols,
+	}
+}
+
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+}
+
+type update struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Text string `json:"text"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+}
+
+// call invokes a Bot API method with a JSON body and decodes the result.
+func (b *Bot) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/bot%s/%s", apiBase, b.token, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		var resp *http.Response
+		if resp, err = b.httpClient.Do(req); err == nil {
+			return decodeResponse(method, resp, result)
+		}
+	}
+	// the token is part of the URL, don't let it leak into logs
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("telegram %s: %v", method, urlErr.Err)
+	}
+	return err
+}
+
+// decodeResponse decodes the result of the Bot API method from resp.
+func decodeResponse(method string, resp *http.Response, result interface{}) error {
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var apiResp apiResponse
+	if err = json.Unmarshal(data, &apiResp); err != nil {
+		return fmt.Errorf("telegram %s: %s", method, resp.Status)
+	}
+	if !apiResp.OK {
+		return fmt.Errorf("telegram %s: %s", method, apiResp.Description)
+	}
+	if result != nil {
+		return json.Unmarshal(apiResp.Result, result)
+	}
+	return nil
+}
+
+// Send posts a text message to the bot's chat.
+func (b *Bot) Send(text string) error {
+	return b.sendTo(b.chatID, text)
+}
+
+func (b *Bot) sendTo(chatID int64, text string) error {
+	return b.call(context.Background(), "sendMessage", map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	}, nil)
+}
+
+// Name implements alerts.Notifier.
+func (b *Bot) Name() string { return "telegram" }
+
+// Notify implements alerts.Notifier.
+func (b *Bot) Notify(alert alerts.Alert) error {
+	return b.Send("🔔 " + alert.Message)
+}
+
+// NotifyIncident reports a feed health incident.
+func (b *Bot) NotifyIncident(message string) {
+	if err := b.Send("⚠️ " + message); err != nil {
+		log.Print(err)
+	}
+}
+
+// Run long-polls for updates and answers commands until ctx is done.
+func (b *Bot) Run(ctx context.Context) {
+	var offset int64
+	for ctx.Err() == nil {
+		var updates []update
+		err := b.call(ctx, "getUpdates", map[string]interface{}{
+			"offset":          offset,
+			"timeout":         30,
+			"allowed_updates": []string{"message"},
+		}, &updates)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Print(err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message == nil || u.Message.Chat.ID != b.chatID {
+				continue
+			}
+			if reply := b.handleCommand(ctx, u.Message.Text); reply != "" {
+				if err = b.sendTo(u.Message.Chat.ID, reply); err != nil {
+					log.Print(err)
+				}
+			}
+		}
+	}
+}
+
+func (b *Bot) handleCommand(ctx context.Context, text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return ""
+	}
+	// commands may be addressed as /price@MyBot in group chats
+	command := strings.SplitN(fields[0], "@", 2)[0]
+	switch command {
+	case "/price":
+		if len(fields) < 2 {
+			return "Usage: /price SYMBOL"
+		}
+		var lines []string
+		for _, symbol := range fields[1:] {
+			ticker, err := b.lookup(ctx, strings.ToUpper(symbol))
+			if err != nil {
+				lines = append(lines, fmt.Sprintf("%s: %v", strings.ToUpper(symbol), err))
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s last %s bid %s ask %s",
+				ticker.Symbol, ticker.Last, ticker.Bid, ticker.Ask))
+		}
+		return strings.Join(lines, "\n")
+	case "/symbols":
+		return strings.Join(b.symbols(), ", ")
+	case "/start", "/help":
+		return "/price SYMBOL... - current prices\n/symbols - streamed symbols"
+	}
+	return "Unknown command, try /help"
+}
@@ -0,0 +1,149 @@
+// Package tenants identifies the clients of the API by their keys and
+// enforces the symbols and the request rate allowed to their tenant.
+package tenants
+
+import (
+	"context"
+	"crypto/sha256"
+	"math"
+	"sync"
+	"time"
+)
+
+// Tenant is a client of the API. A nil Tenant, the one of the requests of a
+// deployment without tenants, is allowed everything.
+type Tenant struct {
+	Name string
+	// symbols are the allowed symbols, nil allows all of them
+	symbols map[string]bool
+	limiter *limiter
+}
+
+// New returns a tenant allowed symbols, all of them when empty, at rate
+// requests per second with bursts of burst. A zero rate is unlimited, a zero
+// burst follows the rate.
+func New(name string, symbols []string, rate float64, burst int) *Tenant {
+	t := &Tenant{Name: name}
+	if len(symbols) > 0 {
+		t.symbols = make(map[string]bool, len(symbols))
+		for _, symbol := range symbols {
+			t.symbols[symbol] = true
+		}
+	}
+	if rate > 0 {
+		if burst <= 0 {
+			burst = int(math.Max(1, math.Floor(rate)))
+		}
+		t.limiter = &limiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+	}
+	return t
+}
+
+// Allows tells whether the tenant can query symbol.
+func (t *Tenant) Allows(symbol string) bool {
+	return t == nil || t.symbols == nil || t.symbols[symbol]
+}
+
+// Scoped tells whether the tenant is restricted to some symbols.
+func (t *Tenant) Scoped() bool {
+	return t != nil && t.symbols != nil
+}
+
+// Take spends a request of the rate limit at now. When the tenant is over
+// its limit it returns false and how long to wait for the next request.
+func (t *Tenant) Take(now time.Time) (bool, time.Duration) {
+	if t == nil || t.limiter == nil {
+		return true, 0
+	}
+	return t.limiter.take(now)
+}
+
+// limiter is a token bucket filled with rate tokens per second up to burst.
+// Unlike the upstream limiter, requests arriving on an empty bucket are
+// rejected rather than queued.
+type limiter struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (l *limiter) take(now time.Time) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	if l.tokens < 1 {
+		return false, time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	}
+	l.tokens--
+	return true, 0
+}
+
+// Registry maps the API keys to their tenant and counts their usage.
+type Registry struct {
+	mutex sync.RWMutex
+	// keys are hashed so that lookups don't leak their timing
+	keys  map[[sha256.Size]byte]*Tenant
+	usage *usageTracker
+}
+
+// NewRegistry returns a registry without tenants.
+func NewRegistry() *Registry {
+	return &Registry{keys: make(map[[sha256.Size]byte]*Tenant), usage: newUsageTracker()}
+}
+
+// Set replaces the tenants with those of keys, which maps every API key to
+// its tenant. The rate limits start over.
+func (r *Registry) Set(keys map[string]*Tenant) {
+	hashed := make(map[[sha256.Size]byte]*Tenant, len(keys))
+	for key, tenant := range keys {
+		hashed[sha256.Sum256([]byte(key))] = tenant
+	}
+	r.mutex.Lock()
+	r.keys = hashed
+	r.mutex.Unlock()
+}
+
+// Enabled tells whether any tenant is registered, the API is open otherwise.
+func (r *Registry) Enabled() bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return len(r.keys) > 0
+}
+
+// Lookup returns the tenant of key, nil when the key is unknown.
+func (r *Registry) Lookup(key string) *Tenant {
+	if key == "" {
+		return nil
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.keys[sha256.Sum256([]byte(key))]
+}
+
+// Record counts a request made with key, a key of tenant, to endpoint and
+// answered with status and bytes of body.
+func (r *Registry) Record(key string, tenant *Tenant, endpoint string, status int, bytes int64) {
+	r.usage.record(sha256.Sum256([]byte(key)), tenant.Name, endpoint, status, bytes, time.Now())
+}
+
+// Usage returns the time the counting started and the usage of every key
+// that made requests since, most requests first, with up to top endpoints.
+func (r *Registry) Usage(top int) (time.Time, []Usage) {
+	return r.usage.snapshot(top)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying tenant.
+func NewContext(ctx context.Context, tenant *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenant)
+}
+
+// FromContext returns the tenant of ctx, nil when there is none.
+func FromContext(ctx context.Context) *Tenant {
+	tenant, _ := ctx.Value(contextKey{}).(*Tenant)
+	return tenant
+}
@@ -0,0 +1,112 @@
+package tenants
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EndpointUsage counts the requests of an API key to an endpoint.
+type EndpointUsage struct {
+	Endpoint    string `json:"endpoint"`
+	Requests    uint64 `json:"requests"`
+	BytesServed uint64 `json:"bytesServed"`
+}
+
+// Usage counts the requests of an API key.
+type Usage struct {
+	Tenant string `json:"tenant"`
+	// KeyID is the start of the SHA-256 of the key, which identifies it
+	// without disclosing it
+	KeyID       string `json:"keyId"`
+	Requests    uint64 `json:"requests"`
+	BytesServed uint64 `json:"bytesServed"`
+	// Errors counts the responses with a 4xx or 5xx status, RateLimited
+	// those rejected by the rate limit of the tenant
+	Errors       uint64          `json:"errors"`
+	RateLimited  uint64          `json:"rateLimited"`
+	LastRequest  time.Time       `json:"lastRequest"`
+	TopEndpoints []EndpointUsage `json:"topEndpoints"`
+}
+
+type keyUsage struct {
+	usage     Usage
+	endpoints map[string]*EndpointUsage
+}
+
+// usageTracker counts the requests by API key hash. The counters outlive
+// the reloads of the tenants.
+type usageTracker struct {
+	mutex sync.Mutex
+	since time.Time
+	keys  map[[sha256.Size]byte]*keyUsage
+}
+
+func newUsageTracker() *usageTracker {
+	return &usageTracker{since: time.Now(), keys: make(map[[sha256.Size]byte]*keyUsage)}
+}
+
+func (u *usageTracker) record(hash [sha256.Size]byte, tenant string, endpoint string, status int, bytes int64, now time.Time) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	k, ok := u.keys[hash]
+	if !ok {
+		k = &keyUsage{
+			usage:     Usage{KeyID: hex.EncodeToString(hash[:4])},
+			endpoints: make(map[string]*EndpointUsage),
+		}
+		u.keys[hash] = k
+	}
+	k.usage.Tenant = tenant
+	k.usage.Requests++
+	k.usage.BytesServed += uint64(bytes)
+	k.usage.LastRequest = now
+	if status >= 400 {
+		k.usage.Errors++
+	}
+	if status == 429 {
+		k.usage.RateLimited++
+	}
+	e, ok := k.endpoints[endpoint]
+	if !ok {
+		e = &EndpointUsage{Endpoint: endpoint}
+		k.endpoints[endpoint] = e
+	}
+	e.Requests++
+	e.BytesServed += uint64(bytes)
+}
+
+// snapshot returns the usage of every key, most requests first, with their
+// top endpoints.
+func (u *usageTracker) snapshot(top int) (time.Time, []Usage) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	usages := make([]Usage, 0, len(u.keys))
+	for _, k := range u.keys {
+		usage := k.usage
+		usage.TopEndpoints = make([]EndpointUsage, 0, len(k.endpoints))
+		for _, e := range k.endpoints {
+			usage.TopEndpoints = append(usage.TopEndpoints, *e)
+		}
+		sort.Slice(usage.TopEndpoints, func(i, j int) bool {
+			a, b := usage.TopEndpoints[i], usage.TopEndpoints[j]
+			if a.Requests != b.Requests {
+				return a.Requests > b.Requests
+			}
+			return a.Endpoint < b.Endpoint
+		})
+		if len(usage.TopEndpoints) > top {
+			usage.TopEndpoints = usage.TopEndpoints[:top]
+		}
+		usages = append(usages, usage)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Requests != usages[j].Requests {
+			return usages[i].Requests > usages[j].Requests
+		}
+		return usages[i].KeyID < usages[j].KeyID
+	})
+	return u.since, usages
+}
@@ -0,0 +1,268 @@
+// Package webhooks pushes ticker updates to user registered URLs.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+const (
+	EventTickerUpdate     = "ticker.update"
+	EventThresholdCrossed = "ticker.threshold_crossed"
+)
+
+// Webhook is a registered delivery target. Without thresholds every update of
+// the subscribed symbols is delivered, otherwise only crossings of Above/Below.
+type Webhook struct {
+	ID      string   `json:"id"`
+	URL     string   `json:"url"`
+	Symbols []string `json:"symbols,omitempty"`
+	Secret  string   `json:"secret,omitempty"`
+	Above   *float64 `json:"above,omitempty"`
+	Below   *float64 `json:"below,omitempty"`
+
+	// lastPrice holds the previous last price per symbol for crossing detection
+	lastPrice map[string]float64
+}
+
+// Payload is the JSON body POSTed to the webhook URL.
+type Payload struct {
+	Event     string           `json:"event"`
+	WebhookID string           `json:"webhookId"`
+	Ticker    *wsclient.Ticker `json:"ticker"`
+	SentAt    time.Time        `json:"sentAt"`
+}
+
+type delivery struct {
+	hook    Webhook
+	payload Payload
+}
+
+// Dispatcher matches ticker updates against the registered webhooks and
+// delivers them from a pool of workers, retrying failed deliveries with
+// exponential backoff.
+type Dispatcher struct {
+	mutex       *sync.Mutex
+	hooks       map[string]*Webhook
+	queue       chan delivery
+	httpClient  *http.Client
+	MaxRetries  int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// NewDispatcher creates a Dispatcher with a delivery queue of queueSize.
+func NewDispatcher(queueSize int) *Dispatcher {
+	return &Dispatcher{
+		mutex:       &sync.Mutex{},
+		hooks:       make(map[string]*Webhook),
+		queue:       make(chan delivery, queueSize),
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		MaxRetries:  5,
+		BaseBackoff: 500 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+	}
+}
+
+// Start launches the delivery workers, which stop when ctx is done. The
+// deliveries in progress are abandoned then.
+func (d *Dispatcher) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-d.queue:
+					d.deliver(ctx, job)
+				}
+			}
+		}()
+	}
+}
+
+// Register validates and adds a webhook, assigning it an ID.
+func (d *Dispatcher) Register(hook Webhook) (*Webhook, error) {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("webhook url must be an absolute http(s) URL")
+	}
+	if hook.Above != nil && hook.Below != nil && *hook.Below > *hook.Above {
+		return nil, errors.New("below threshold must not be greater than above threshold")
+	}
+	id := make([]byte, 8)
+	if _, err = rand.Read(id); err != nil {
+		return nil, err
+	}
+	hook.ID = hex.EncodeToString(id)
+	hook.lastPrice = make(map[string]float64)
+
+	d.mutex.Lock()
+	d.hooks[hook.ID] = &hook
+	d.mutex.Unlock()
+	ret := hook
+	return &ret, nil
+}
+
+// Unregister removes a webhook, returning false if it does not exist.
+func (d *Dispatcher) Unregister(id string) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if _, ok := d.hooks[id]; !ok {
+		return false
+	}
+	delete(d.hooks, id)
+	return true
+}
+
+// List returns the registered webhooks ordered by ID. Secrets are omitted.
+func (d *Dispatcher) List() []Webhook {
+	d.mutex.Lock()
+	hooks := make([]Webhook, 0, len(d.hooks))
+	for _, hook := range d.hooks {
+		h := *hook
+		h.Secret = ""
+		hooks = append(hooks, h)
+	}
+	d.mutex.Unlock()
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].ID < hooks[j].ID })
+	return hooks
+}
+
+// Notify queues deliveries for every webhook matching the ticker. It never
+// blocks: when the queue is full the delivery is dropped and logged.
+func (d *Dispatcher) Notify(ticker *wsclient.Ticker) {
+	d.mutex.Lock()
+	var jobs []delivery
+	for _, hook := range d.hooks {
+		if len(hook.Symbols) > 0 && !contains(hook.Symbols, ticker.Symbol) {
+			continue
+		}
+		event := hook.event(ticker)
+		if event == "" {
+			continue
+		}
+		jobs = append(jobs, delivery{
+			hook:    *hook,
+			payload: Payload{Event: event, WebhookID: hook.ID, Ticker: ticker, SentAt: time.Now().UTC()},
+		})
+	}
+	d.mutex.Unlock()
+
+	for _, job := range jobs {
+		select {
+		case d.queue <- job:
+		default:
+			log.Printf("webhook %s: delivery queue full, dropping %s update", job.hook.ID, ticker.Symbol)
+		}
+	}
+}
+
+// event returns the event to deliver for the ticker, or "" if none. Must be
+// called with the dispatcher mutex held.
+func (hook *Webhook) event(ticker *wsclient.Ticker) string {
+	if hook.Above == nil && hook.Below == nil {
+		return EventTickerUpdate
+	}
+	last := ticker.Last.InexactFloat64()
+	previous, seen := hook.lastPrice[ticker.Symbol]
+	hook.lastPrice[ticker.Symbol] = last
+	if !seen {
+		return ""
+	}
+	if hook.Above != nil && previous <= *hook.Above && last > *hook.Above {
+		return EventThresholdCrossed
+	}
+	if hook.Below != nil && previous >= *hook.Below && last < *hook.Below {
+		return EventThresholdCrossed
+	}
+	return ""
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, job delivery) {
+	body, err := json.Marshal(job.payload)
+	if err != nil {
+		log.Printf("webhook %s: %v", job.hook.ID, err)
+		return
+	}
+	backoff := d.BaseBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := d.post(ctx, job.hook, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= d.MaxRetries {
+			log.Printf("webhook %s: giving up after %d attempts: %v", job.hook.ID, attempt+1, err)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > d.MaxBackoff {
+			backoff = d.MaxBackoff
+		}
+	}
+}
+
+// post sends one delivery attempt and reports whether a failure is retryable.
+func (d *Dispatcher) post(ctx context.Context, hook Webhook, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", hook.ID)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	if hook.Secret != "" {
+		req.Header.Set("X-Webhook-Signature", "sha256="+Sign(hook.Secret, timestamp, body))
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// Sign returns the hex HMAC-SHA256 of "timestamp.body" keyed with secret.
+// Receivers recompute it to authenticate deliveries.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func contains(s []string, str string) bool {
+	for _, v := range s {
+		if v == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package wrappers
+
+import (
+	"context"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+// The wrapper reaches the exchange through the interfaces below, implemented
+// by wsclient.HitBtc and wsclient.WSClient, so it can be tested with fakes.
+
+// MarketDataClient reads the public market data of the REST API.
+type MarketDataClient interface {
+	GetTicker(ctx context.Context, market string) (wsclient.Ticker, error)
+	GetSymbols(ctx context.Context) ([]wsclient.Symbol, error)
+	GetCurrencies(ctx context.Context) ([]wsclient.Currency, error)
+	GetOrderBook(ctx context.Context, market string, limit int) (wsclient.OrderBook, error)
+	GetCandles(ctx context.Context, market string, period string, from, till time.Time, limit int) ([]wsclient.WSCandle, error)
+}
+
+// TradingClient calls the account and trading endpoints of the REST API.
+type TradingClient interface {
+	PlaceOrder(ctx context.Context, request wsclient.OrderRequest) (wsclient.Order, error)
+	GetActiveOrders(ctx context.Context, symbol string) ([]wsclient.Order, error)
+	CancelOrder(ctx context.Context, clientOrderID string) (wsclient.Order, error)
+	CancelAllOrders(ctx context.Context, symbol string) ([]wsclient.Order, error)
+	GetOrderHistory(ctx context.Context, filter wsclient.HistoryFilter) ([]wsclient.Order, error)
+	GetTradeHistory(ctx context.Context, filter wsclient.HistoryFilter) ([]wsclient.AccountTrade, error)
+	GetTradingFee(ctx context.Context, symbol string) (wsclient.TradingFee, error)
+	GetTradingBalance(ctx context.Context) ([]wsclient.Balance, error)
+	GetAccountBalance(ctx context.Context) ([]wsclient.Balance, error)
+	GetDepositAddress(ctx context.Context, currency string) (wsclient.DepositAddress, error)
+	NewDepositAddress(ctx context.Context, currency string) (wsclient.DepositAddress, error)
+	Transfer(ctx context.Context, currency string, amount decimal.Decimal, transferType string) (wsclient.Transfer, error)
+	Withdraw(ctx context.Context, request wsclient.WithdrawRequest) (wsclient.Withdraw, error)
+	CommitWithdraw(ctx context.Context, id string) error
+	RollbackWithdraw(ctx context.Context, id string) error
+}
+
+// UpstreamClient controls how the REST calls are made.
+type UpstreamClient interface {
+	SetDebug(enable bool)
+	SetRetryPolicy(policy wsclient.RetryPolicy)
+	SetCircuitBreaker(threshold int, cooldown time.Duration)
+	CircuitState() string
+	SetRateLimit(class string, rate float64) error
+	RateLimitStats() map[string]wsclient.RateLimitStats
+}
+
+// RESTClient is the REST API of the exchange.
+type RESTClient interface {
+	MarketDataClient
+	TradingClient
+	UpstreamClient
+}
+
+// FeedClient is the websocket ticker feed of the exchange.
+type FeedClient interface {
+	TickerStream() <-chan wsclient.WSNotificationTickerResponse
+	SubscribeTickerStream(symbol string) error
+	UnsubscribeTicker(symbol string) error
+}
+
+// bufferedFeed is a FeedClient buffering its notifications.
+type bufferedFeed interface {
+	SetTickerBuffer(size int, overflow wsclient.OverflowPolicy)
+	TickerDrops() map[string]uint64
+}
+
+// recordedFeed is a FeedClient able to record its notifications.
+type recordedFeed interface {
+	SetRecorder(recorder *wsclient.Recorder)
+}
+
+// replayedFeed is a FeedClient replaying a recorded feed.
+type replayedFeed interface {
+	ReplaySymbols() []string
+}
+
+// closableFeed is a FeedClient holding connections to close.
+type closableFeed interface {
+	Close()
+}
+
+var (
+	_ RESTClient   = (*wsclient.HitBtc)(nil)
+	_ FeedClient   = (*wsclient.WSClient)(nil)
+	_ bufferedFeed = (*wsclient.WSClient)(nil)
+	_ recordedFeed = (*wsclient.WSClient)(nil)
+	_ replayedFeed = (*wsclient.WSClient)(nil)
+	_ closableFeed = (*wsclient.WSClient)(nil)
+)
@@ -0,0 +1,171 @@
+package wrappers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// fakeExchange serves the REST market data and the ticker feed of a wrapper
+// from memory. The trading calls aren't faked, calling them panics.
+type fakeExchange struct {
+	TradingClient
+
+	mutex      sync.Mutex
+	tickers    map[string]wsclient.Ticker
+	symbols    []wsclient.Symbol
+	currencies []wsclient.Currency
+	// err fails the REST calls when set
+	err error
+	// restCalls counts the REST calls by method
+	restCalls  map[string]int
+	subscribed map[string]bool
+	stream     chan wsclient.WSNotificationTickerResponse
+}
+
+var (
+	_ RESTClient   = (*fakeExchange)(nil)
+	_ FeedClient   = (*fakeExchange)(nil)
+	_ closableFeed = (*fakeExchange)(nil)
+)
+
+func newFakeExchange(symbols []wsclient.Symbol, currencies []wsclient.Currency) *fakeExchange {
+	return &fakeExchange{
+		tickers:    make(map[string]wsclient.Ticker),
+		symbols:    symbols,
+		currencies: currencies,
+		restCalls:  make(map[string]int),
+		subscribed: make(map[string]bool),
+		stream:     make(chan wsclient.WSNotificationTickerResponse, 16),
+	}
+}
+
+// setTicker sets the ticker returned by GetTicker for its symbol.
+func (f *fakeExchange) setTicker(ticker wsclient.Ticker) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.tickers[ticker.Symbol] = ticker
+}
+
+// setSymbols replaces the symbols returned by GetSymbols.
+func (f *fakeExchange) setSymbols(symbols []wsclient.Symbol) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.symbols = symbols
+}
+
+// setCurrencies replaces the currencies returned by GetCurrencies.
+func (f *fakeExchange) setCurrencies(currencies []wsclient.Currency) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.currencies = currencies
+}
+
+// fail makes the REST calls return err, or succeed again when nil.
+func (f *fakeExchange) fail(err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.err = err
+}
+
+// calls returns the number of calls of the REST method.
+func (f *fakeExchange) calls(method string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.restCalls[method]
+}
+
+// isSubscribed tells whether the feed of symbol is subscribed.
+func (f *fakeExchange) isSubscribed(symbol string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.subscribed[symbol]
+}
+
+// call counts a call of the REST method and returns the error to fail it
+// with.
+func (f *fakeExchange) call(method string) error {
+	f.restCalls[method]++
+	return f.err
+}
+
+func (f *fakeExchange) GetTicker(ctx context.Context, market string) (wsclient.Ticker, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.call("GetTicker"); err != nil {
+		return wsclient.Ticker{}, err
+	}
+	ticker, ok := f.tickers[market]
+	if !ok {
+		return wsclient.Ticker{}, fmt.Errorf("unknown symbol %s", market)
+	}
+	return ticker, nil
+}
+
+func (f *fakeExchange) GetSymbols(ctx context.Context) ([]wsclient.Symbol, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.call("GetSymbols"); err != nil {
+		return nil, err
+	}
+	return append([]wsclient.Symbol(nil), f.symbols...), nil
+}
+
+func (f *fakeExchange) GetCurrencies(ctx context.Context) ([]wsclient.Currency, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.call("GetCurrencies"); err != nil {
+		return nil, err
+	}
+	return append([]wsclient.Currency(nil), f.currencies...), nil
+}
+
+func (f *fakeExchange) GetOrderBook(ctx context.Context, market string, limit int) (wsclient.OrderBook, error) {
+	return wsclient.OrderBook{}, errors.New("order books aren't faked")
+}
+
+func (f *fakeExchange) GetCandles(ctx context.Context, market string, period string, from, till time.Time, limit int) ([]wsclient.WSCandle, error) {
+	return nil, errors.New("candles aren't faked")
+}
+
+func (f *fakeExchange) SetDebug(enable bool)                                    {}
+func (f *fakeExchange) SetRetryPolicy(policy wsclient.RetryPolicy)              {}
+func (f *fakeExchange) SetCircuitBreaker(threshold int, cooldown time.Duration) {}
+func (f *fakeExchange) CircuitState() string                                    { return "closed" }
+func (f *fakeExchange) SetRateLimit(class string, rate float64) error           { return nil }
+
+func (f *fakeExchange) RateLimitStats() map[string]wsclient.RateLimitStats {
+	return nil
+}
+
+func (f *fakeExchange) TickerStream() <-chan wsclient.WSNotificationTickerResponse {
+	return f.stream
+}
+
+func (f *fakeExchange) SubscribeTickerStream(symbol string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.subscribed[symbol] = true
+	return nil
+}
+
+func (f *fakeExchange) UnsubscribeTicker(symbol string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.subscribed, symbol)
+	return nil
+}
+
+// Close closes the stream, which stops the feed workers.
+func (f *fakeExchange) Close() {
+	close(f.stream)
+}
+
+// push sends a ticker notification on the feed.
+func (f *fakeExchange) push(notification wsclient.WSNotificationTickerResponse) {
+	f.stream <- notification
+}
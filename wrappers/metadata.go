@@ -0,0 +1,178 @@
+package wrappers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+)
+
+// metadata is the symbols and currencies of the exchange. allSymbols lists
+// the symbols, which are only known by their ID when replaying offline.
+type metadata struct {
+	allSymbols []string
+	symbols    map[string]wsclient.Symbol
+	currencies map[string]wsclient.Currency
+}
+
+// AllSymbols returns the symbols of the exchange, the slice must not be
+// modified.
+func (wrapper *Wrappers) AllSymbols() []string {
+	wrapper.metadataMutex.RLock()
+	defer wrapper.metadataMutex.RUnlock()
+	return wrapper.metadata.allSymbols
+}
+
+// Symbols returns the metadata of the symbols by ID, the map must not be
+// modified.
+func (wrapper *Wrappers) Symbols() map[string]wsclient.Symbol {
+	wrapper.metadataMutex.RLock()
+	defer wrapper.metadataMutex.RUnlock()
+	return wrapper.metadata.symbols
+}
+
+// Symbol returns the metadata of symbol.
+func (wrapper *Wrappers) Symbol(symbol string) (wsclient.Symbol, bool) {
+	info, ok := wrapper.Symbols()[symbol]
+	return info, ok
+}
+
+// Currencies returns the metadata of the currencies by ID, the map must not
+// be modified.
+func (wrapper *Wrappers) Currencies() map[string]wsclient.Currency {
+	wrapper.metadataMutex.RLock()
+	defer wrapper.metadataMutex.RUnlock()
+	return wrapper.metadata.currencies
+}
+
+// Currency returns the metadata of currency.
+func (wrapper *Wrappers) Currency(currency string) (wsclient.Currency, bool) {
+	info, ok := wrapper.Currencies()[currency]
+	return info, ok
+}
+
+// CurrencyFullName returns the full name of currency, empty when unknown.
+func (wrapper *Wrappers) CurrencyFullName(currency string) string {
+	return wrapper.Currencies()[currency].FullName
+}
+
+// DelistedError is returned for a symbol delisted by the exchange.
+type DelistedError struct {
+	Symbol string
+	Since  time.Time
+}
+
+func (e *DelistedError) Error() string {
+	return fmt.Sprintf("%s was delisted", e.Symbol)
+}
+
+// RefreshMetadata caches the symbols and currencies of the exchange again.
+// It returns the symbols listed since they were last cached, none when they
+// weren't cached yet, and the symbols delisted since, either gone from the
+// symbols or trading a delisted currency, both sorted. The delisted symbols
+// are unsubscribed from the feed and their cached tickers flagged.
+func (wrapper *Wrappers) RefreshMetadata(ctx context.Context) (listed []string, delisted []string, err error) {
+	previousSymbols := wrapper.AllSymbols()
+	previous := make(map[string]bool, len(previousSymbols))
+	for _, symbol := range previousSymbols {
+		previous[symbol] = true
+	}
+	if err = wrapper.CacheAllSymbols(ctx); err != nil {
+		return nil, nil, err
+	}
+	if err = wrapper.CacheFullName(ctx); err != nil {
+		return nil, nil, err
+	}
+	if len(previous) == 0 {
+		return nil, nil, nil
+	}
+	currentSymbols := wrapper.AllSymbols()
+	current := make(map[string]bool, len(currentSymbols))
+	for _, symbol := range currentSymbols {
+		current[symbol] = true
+		if !previous[symbol] {
+			listed = append(listed, symbol)
+		}
+	}
+	// a set, as a symbol can be both gone and trading a delisted currency
+	gone := make(map[string]bool)
+	for symbol := range previous {
+		if !current[symbol] {
+			gone[symbol] = true
+		}
+	}
+	// gone since an earlier refresh
+	wrapper.symbolsMutex.RLock()
+	for symbol := range wrapper.delisted {
+		if !current[symbol] {
+			gone[symbol] = true
+		}
+	}
+	wrapper.symbolsMutex.RUnlock()
+	currencies := wrapper.Currencies()
+	for symbol, info := range wrapper.Symbols() {
+		if currencies[info.BaseCurrency].Delisted || currencies[info.QuoteCurrency].Delisted {
+			gone[symbol] = true
+		}
+	}
+	for symbol := range gone {
+		delisted = append(delisted, symbol)
+	}
+	sort.Strings(listed)
+	sort.Strings(delisted)
+	return listed, wrapper.delist(delisted), nil
+}
+
+// Delisted tells whether symbol was delisted, and since when.
+func (wrapper *Wrappers) Delisted(symbol string) (time.Time, bool) {
+	wrapper.symbolsMutex.RLock()
+	defer wrapper.symbolsMutex.RUnlock()
+	since, ok := wrapper.delisted[symbol]
+	return since, ok
+}
+
+// delist records the delisting of symbols, dropping them from the feed, and
+// returns the ones not delisted before. A delisted symbol listed again is
+// served as usual, but no longer streamed.
+func (wrapper *Wrappers) delist(symbols []string) []string {
+	wrapper.updateMutex.Lock()
+	defer wrapper.updateMutex.Unlock()
+	wrapper.symbolsMutex.RLock()
+	previous := wrapper.delisted
+	wrapper.symbolsMutex.RUnlock()
+
+	now := time.Now()
+	delisted := make(map[string]time.Time, len(symbols))
+	var added []string
+	for _, symbol := range symbols {
+		if since, ok := previous[symbol]; ok {
+			delisted[symbol] = since
+			continue
+		}
+		delisted[symbol] = now
+		added = append(added, symbol)
+	}
+
+	wrapper.symbolsMutex.Lock()
+	wrapper.delisted = delisted
+	feedSymbols := make([]string, 0, len(wrapper.feedSymbols))
+	for _, symbol := range wrapper.feedSymbols {
+		if _, ok := delisted[symbol]; !ok {
+			feedSymbols = append(feedSymbols, symbol)
+		}
+	}
+	wrapper.feedSymbols = feedSymbols
+	wrapper.symbolsMutex.Unlock()
+
+	for _, symbol := range added {
+		if wrapper.isSubscribed(symbol) {
+			// the feed of a delisted symbol is gone anyway
+			_ = wrapper.ws.UnsubscribeTicker(symbol)
+			wrapper.markSubscribed([]string{symbol}, false)
+		}
+		wrapper.summaries.MarkDelisted(symbol)
+	}
+	return added
+}
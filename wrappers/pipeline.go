@@ -0,0 +1,104 @@
+package wrappers
+
+import (
+	"hash/fnv"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+// The websocket feed is processed by a pool of workers, each symbol being
+// dispatched to the same worker so that its updates keep their order. Each
+// update goes through parseTicker, enrichTicker and storeTicker, which are
+// also used by the REST path.
+
+// parseTicker converts a websocket ticker notification to a Ticker.
+func parseTicker(notification wsclient.WSNotificationTickerResponse) *wsclient.Ticker {
+	last, _ := decimal.NewFromString(notification.Last)
+	ask, _ := decimal.NewFromString(notification.Ask)
+	bid, _ := decimal.NewFromString(notification.Bid)
+	open, _ := decimal.NewFromString(notification.Open)
+	low, _ := decimal.NewFromString(notification.Low)
+	high, _ := decimal.NewFromString(notification.High)
+	volume, _ := decimal.NewFromString(notification.Volume)
+	volumeQuote, _ := decimal.NewFromString(notification.VolumeQuote)
+	timestamp, _ := time.Parse(time.RFC3339Nano, notification.Timestamp)
+	return &wsclient.Ticker{
+		Last:        last,
+		Ask:         ask,
+		Bid:         bid,
+		Open:        open,
+		Low:         low,
+		High:        high,
+		Volume:      volume,
+		VolumeQuote: volumeQuote,
+		Symbol:      notification.Symbol,
+		Timestamp:   timestamp,
+		ID:          notification.Symbol,
+		Source:      wsclient.SourceWebsocket,
+	}
+}
+
+// enrichTicker fills the fee currency and its full name from the cached
+// metadata, and computes the 24h change.
+func (wrapper *Wrappers) enrichTicker(ticker *wsclient.Ticker) {
+	info, _ := wrapper.Symbol(ticker.Symbol)
+	ticker.FeeCurrency = info.FeeCurrency
+	ticker.FullName = wrapper.CurrencyFullName(info.FeeCurrency)
+	setChange24h(ticker)
+}
+
+var hundred = decimal.NewFromInt(100)
+
+// setChange24h computes the change since the open price of the 24h window,
+// the percentage stays zero without an open price.
+func setChange24h(ticker *wsclient.Ticker) {
+	ticker.ChangeAbs24h = ticker.Last.Sub(ticker.Open)
+	ticker.ChangePercent24h = decimal.Zero
+	if !ticker.Open.IsZero() {
+		ticker.ChangePercent24h = ticker.ChangeAbs24h.Mul(hundred).DivRound(ticker.Open, 2)
+	}
+}
+
+// storeTicker caches the ticker of a supported symbol and notifies the
+// listeners. A ticker older than the cached one, such as a REST response
+// overtaken by the feed, is ignored.
+func (wrapper *Wrappers) storeTicker(ticker *wsclient.Ticker) {
+	if !wrapper.isFeedSymbol(ticker.Symbol) {
+		return
+	}
+	if !wrapper.summaries.SetIfNewer(ticker.Symbol, ticker) {
+		return
+	}
+	for _, listener := range wrapper.tickerListeners() {
+		listener(ticker)
+	}
+}
+
+// dispatchFeed sends the stream updates to workers workers, always the same
+// for a symbol, until the stream is closed.
+func (wrapper *Wrappers) dispatchFeed(stream <-chan wsclient.WSNotificationTickerResponse, workers int) {
+	queues := make([]chan wsclient.WSNotificationTickerResponse, workers)
+	for i := range queues {
+		queues[i] = make(chan wsclient.WSNotificationTickerResponse, 64)
+		go wrapper.feedWorker(queues[i])
+	}
+	for notification := range stream {
+		hash := fnv.New32a()
+		hash.Write([]byte(notification.Symbol))
+		queues[hash.Sum32()%uint32(workers)] <- notification
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+}
+
+// feedWorker processes the updates of its queue until it is closed.
+func (wrapper *Wrappers) feedWorker(queue <-chan wsclient.WSNotificationTickerResponse) {
+	for notification := range queue {
+		ticker := parseTicker(notification)
+		wrapper.enrichTicker(ticker)
+		wrapper.storeTicker(ticker)
+	}
+}
@@ -0,0 +1,224 @@
+package wrappers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSubscribeConcurrency is the number of feed subscription requests in
+// flight at once.
+const defaultSubscribeConcurrency = 16
+
+// SubscribeError lists the symbols whose feed subscription failed, the other
+// symbols were subscribed.
+type SubscribeError struct {
+	Failed map[string]error
+}
+
+func (e *SubscribeError) Error() string {
+	symbols := make([]string, 0, len(e.Failed))
+	for symbol := range e.Failed {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	const shown = 5
+	failures := make([]string, 0, shown)
+	for i, symbol := range symbols {
+		if i == shown {
+			failures = append(failures, fmt.Sprintf("and %d more", len(symbols)-shown))
+			break
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", symbol, e.Failed[symbol]))
+	}
+	return fmt.Sprintf("subscribing %d symbols failed: %s", len(symbols), strings.Join(failures, "; "))
+}
+
+// SetSubscribeConcurrency sets the number of feed subscription requests in
+// flight at once.
+func (wrapper *Wrappers) SetSubscribeConcurrency(concurrency int) {
+	if concurrency > 0 {
+		wrapper.subscribeConcurrency = concurrency
+	}
+}
+
+// subscribeAll subscribes the ticker feed of the symbols, pipelining the
+// requests on the connection. It returns the subscribed symbols in their
+// order, and a *SubscribeError when some failed.
+func (wrapper *Wrappers) subscribeAll(symbols []string) ([]string, error) {
+	errs := make([]error, len(symbols))
+	slots := make(chan struct{}, wrapper.subscribeConcurrency)
+	var wg sync.WaitGroup
+	for i, symbol := range symbols {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, symbol string) {
+			defer wg.Done()
+			errs[i] = wrapper.ws.SubscribeTickerStream(symbol)
+			<-slots
+		}(i, symbol)
+	}
+	wg.Wait()
+
+	subscribed := make([]string, 0, len(symbols))
+	failed := make(map[string]error)
+	for i, symbol := range symbols {
+		if errs[i] != nil {
+			failed[symbol] = errs[i]
+			continue
+		}
+		subscribed = append(subscribed, symbol)
+	}
+	if len(failed) > 0 {
+		return subscribed, &SubscribeError{Failed: failed}
+	}
+	return subscribed, nil
+}
+
+// SetLazySubscription defers the subscription of the feed symbols to their
+// first request, which is answered from the REST API meanwhile. It must be
+// called before FeedConnect.
+func (wrapper *Wrappers) SetLazySubscription(lazy bool) {
+	wrapper.lazy = lazy
+}
+
+// SubscribedSymbols returns the feed symbols currently subscribed, sorted.
+func (wrapper *Wrappers) SubscribedSymbols() []string {
+	wrapper.symbolsMutex.RLock()
+	defer wrapper.symbolsMutex.RUnlock()
+	symbols := make([]string, 0, len(wrapper.subscribed))
+	for symbol := range wrapper.subscribed {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+func (wrapper *Wrappers) isSubscribed(symbol string) bool {
+	wrapper.symbolsMutex.RLock()
+	defer wrapper.symbolsMutex.RUnlock()
+	return wrapper.subscribed[symbol]
+}
+
+func (wrapper *Wrappers) markSubscribed(symbols []string, subscribed bool) {
+	wrapper.symbolsMutex.Lock()
+	defer wrapper.symbolsMutex.Unlock()
+	now := time.Now()
+	for _, symbol := range symbols {
+		if subscribed {
+			wrapper.subscribed[symbol] = true
+			// a symbol subscribed up front is idle from now on
+			if _, ok := wrapper.lastAccess[symbol]; !ok {
+				wrapper.lastAccess[symbol] = now
+			}
+		} else {
+			delete(wrapper.subscribed, symbol)
+			delete(wrapper.lastAccess, symbol)
+		}
+	}
+}
+
+// subscribeOnDemand records the access to a requested feed symbol, and
+// subscribes its feed in the background when it isn't yet, because
+// subscriptions are lazy, its subscription failed or it was idle. The request
+// itself is served from the REST API.
+func (wrapper *Wrappers) subscribeOnDemand(symbol string) {
+	wrapper.symbolsMutex.Lock()
+	if !wrapper.feedOn || !wrapper.Contains(wrapper.feedSymbols, symbol) {
+		wrapper.symbolsMutex.Unlock()
+		return
+	}
+	wrapper.lastAccess[symbol] = time.Now()
+	if wrapper.subscribed[symbol] || wrapper.pending[symbol] {
+		wrapper.symbolsMutex.Unlock()
+		return
+	}
+	wrapper.pending[symbol] = true
+	wrapper.symbolsMutex.Unlock()
+
+	go func() {
+		defer func() {
+			wrapper.symbolsMutex.Lock()
+			delete(wrapper.pending, symbol)
+			wrapper.symbolsMutex.Unlock()
+		}()
+		// serialized with UpdateFeedSymbols, which may have removed the
+		// symbol meanwhile
+		wrapper.updateMutex.Lock()
+		defer wrapper.updateMutex.Unlock()
+		if !wrapper.isFeedSymbol(symbol) || wrapper.isSubscribed(symbol) {
+			return
+		}
+		if err := wrapper.ws.SubscribeTickerStream(symbol); err != nil {
+			log.Printf("subscribing %s on demand: %v", symbol, err)
+			return
+		}
+		wrapper.markSubscribed([]string{symbol}, true)
+	}()
+}
+
+// RecordAccess records a request of the subscribed feed symbols, such as
+// the listings serving them from the cache, so that they aren't unsubscribed
+// as idle.
+func (wrapper *Wrappers) RecordAccess(symbols ...string) {
+	wrapper.symbolsMutex.Lock()
+	defer wrapper.symbolsMutex.Unlock()
+	now := time.Now()
+	for _, symbol := range symbols {
+		if wrapper.subscribed[symbol] {
+			wrapper.lastAccess[symbol] = now
+		}
+	}
+}
+
+// UnsubscribeIdle unsubscribes the feed symbols not requested within window,
+// by GetMarketSummary or RecordAccess, and drops their tickers from the cache. They stay feed symbols, their next
+// request subscribes them again. It returns the unsubscribed symbols.
+func (wrapper *Wrappers) UnsubscribeIdle(window time.Duration) ([]string, error) {
+	wrapper.updateMutex.Lock()
+	defer wrapper.updateMutex.Unlock()
+	cutoff := time.Now().Add(-window)
+	var idle []string
+	wrapper.symbolsMutex.RLock()
+	for symbol := range wrapper.subscribed {
+		if wrapper.lastAccess[symbol].Before(cutoff) {
+			idle = append(idle, symbol)
+		}
+	}
+	wrapper.symbolsMutex.RUnlock()
+	sort.Strings(idle)
+
+	for i, symbol := range idle {
+		if err := wrapper.ws.UnsubscribeTicker(symbol); err != nil {
+			return idle[:i], err
+		}
+		wrapper.markSubscribed([]string{symbol}, false)
+		wrapper.summaries.Delete(symbol)
+	}
+	return idle, nil
+}
+
+// RunIdleUnsubscribe unsubscribes the feed symbols idle for window, checking
+// every half window, until ctx is done.
+func (wrapper *Wrappers) RunIdleUnsubscribe(ctx context.Context, window time.Duration) {
+	ticker := time.NewTicker(window / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		idle, err := wrapper.UnsubscribeIdle(window)
+		if err != nil {
+			log.Printf("unsubscribing idle symbols: %v", err)
+		}
+		if len(idle) > 0 {
+			log.Printf("unsubscribed %d idle symbols: %s", len(idle), strings.Join(idle, ", "))
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package wrappers
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer starts the spans of the wrapper methods, between the handler span
+// and the spans of the upstream calls.
+var tracer = otel.Tracer("github.com/crypto-api-server/wrappers")
+
+// endSpan records err, if any, on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
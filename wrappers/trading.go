@@ -0,0 +1,118 @@
+package wrappers
+
+import (
+	"context"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+// SetTradingClient sends the account and trading calls to client instead of
+// the REST API, e.g. to a paper trading engine.
+func (wrapper *Wrappers) SetTradingClient(client TradingClient) {
+	wrapper.trading = client
+}
+
+// PlaceOrder places an order with the account of the API key.
+func (wrapper *Wrappers) PlaceOrder(ctx context.Context, request wsclient.OrderRequest) (*wsclient.Order, error) {
+	order, err := wrapper.trading.PlaceOrder(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CancelOrder cancels an order by client order id.
+func (wrapper *Wrappers) CancelOrder(ctx context.Context, clientOrderID string) (*wsclient.Order, error) {
+	order, err := wrapper.trading.CancelOrder(ctx, clientOrderID)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CancelAllOrders cancels the active orders of symbol, or all of them when
+// symbol is empty.
+func (wrapper *Wrappers) CancelAllOrders(ctx context.Context, symbol string) ([]wsclient.Order, error) {
+	return wrapper.trading.CancelAllOrders(ctx, symbol)
+}
+
+// GetActiveOrders returns the active orders of symbol, or all of them when
+// symbol is empty.
+func (wrapper *Wrappers) GetActiveOrders(ctx context.Context, symbol string) ([]wsclient.Order, error) {
+	return wrapper.trading.GetActiveOrders(ctx, symbol)
+}
+
+// GetOrderHistory returns the closed orders matching filter.
+func (wrapper *Wrappers) GetOrderHistory(ctx context.Context, filter wsclient.HistoryFilter) ([]wsclient.Order, error) {
+	return wrapper.trading.GetOrderHistory(ctx, filter)
+}
+
+// GetTradeHistory returns the executed trades of the account matching filter.
+func (wrapper *Wrappers) GetTradeHistory(ctx context.Context, filter wsclient.HistoryFilter) ([]wsclient.AccountTrade, error) {
+	return wrapper.trading.GetTradeHistory(ctx, filter)
+}
+
+// GetTradingBalance returns the balances of the trading account.
+func (wrapper *Wrappers) GetTradingBalance(ctx context.Context) ([]wsclient.Balance, error) {
+	return wrapper.trading.GetTradingBalance(ctx)
+}
+
+// GetAccountBalance returns the balances of the main account.
+func (wrapper *Wrappers) GetAccountBalance(ctx context.Context) ([]wsclient.Balance, error) {
+	return wrapper.trading.GetAccountBalance(ctx)
+}
+
+// GetDepositAddress returns the deposit address of currency, generating a new
+// one when renew is set.
+func (wrapper *Wrappers) GetDepositAddress(ctx context.Context, currency string, renew bool) (*wsclient.DepositAddress, error) {
+	var address wsclient.DepositAddress
+	var err error
+	if renew {
+		address, err = wrapper.trading.NewDepositAddress(ctx, currency)
+	} else {
+		address, err = wrapper.trading.GetDepositAddress(ctx, currency)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// Withdraw creates a crypto withdrawal, which must then be committed or
+// rolled back.
+func (wrapper *Wrappers) Withdraw(ctx context.Context, request wsclient.WithdrawRequest) (*wsclient.Withdraw, error) {
+	withdraw, err := wrapper.trading.Withdraw(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return &withdraw, nil
+}
+
+// CommitWithdraw confirms a withdrawal.
+func (wrapper *Wrappers) CommitWithdraw(ctx context.Context, id string) error {
+	return wrapper.trading.CommitWithdraw(ctx, id)
+}
+
+// RollbackWithdraw cancels a withdrawal.
+func (wrapper *Wrappers) RollbackWithdraw(ctx context.Context, id string) error {
+	return wrapper.trading.RollbackWithdraw(ctx, id)
+}
+
+// Transfer moves funds between the main and trading accounts.
+func (wrapper *Wrappers) Transfer(ctx context.Context, currency string, amount decimal.Decimal, transferType string) (*wsclient.Transfer, error) {
+	transfer, err := wrapper.trading.Transfer(ctx, currency, amount, transferType)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// GetTradingFee returns the commission rates of the account for symbol.
+func (wrapper *Wrappers) GetTradingFee(ctx context.Context, symbol string) (*wsclient.TradingFee, error) {
+	fee, err := wrapper.trading.GetTradingFee(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &fee, nil
+}
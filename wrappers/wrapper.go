@@ -1,40 +1,135 @@
 package wrappers
 
 import (
-	"os"
-	"os/signal"
-	"strconv"
-	"syscall"
+	"context"
+	"errors"
+	"sync"
+	"time"
 
 	"github.com/crypto-api-server/inmemorycache"
 	"github.com/crypto-api-server/wsclient"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var SymbolsFeeCurrency = make(map[string]string, 0)
-var CurrencyFullName = make(map[string]string, 0)
+// StaleDataError is returned for a cached ticker older than the cache TTL
+// which can't be refreshed.
+type StaleDataError struct {
+	Symbol   string
+	CachedAt time.Time
+	Err      error
+}
+
+func (e *StaleDataError) Error() string {
+	return "the cached ticker of " + e.Symbol + " is stale and can't be refreshed: " + e.Err.Error()
+}
+
+func (e *StaleDataError) Unwrap() error {
+	return e.Err
+}
+
+// TickerListener is notified of every ticker stored in the summary cache.
+// Listeners run on the feed worker of the symbol, or on the request storing
+// a REST ticker, so they are called concurrently for different symbols and
+// must not block.
+type TickerListener func(ticker *wsclient.Ticker)
 
 type Wrappers struct {
-	api         *wsclient.HitBtc
-	ws          *wsclient.WSClient
+	api         RESTClient
+	trading     TradingClient
+	ws          FeedClient
 	websocketOn bool
 	summaries   *inmemorycache.CurrencyCache
-	AllSymbols  []string
+	// listeners is replaced, never modified, under listenersMutex, the
+	// feed reads it while they are still being added.
+	listeners      []TickerListener
+	listenersMutex sync.RWMutex
+	feedWorkers    int
+	// subscribeConcurrency bounds the feed subscriptions in flight.
+	subscribeConcurrency int
+	// feedSymbols is replaced, never modified, under symbolsMutex. The
+	// subscribed ones are in subscribed, those being subscribed on demand
+	// in pending. updateMutex serializes the subscription changes.
+	feedSymbols  []string
+	subscribed   map[string]bool
+	pending      map[string]bool
+	lastAccess   map[string]time.Time
+	feedOn       bool
+	lazy         bool
+	symbolsMutex sync.RWMutex
+	updateMutex  sync.Mutex
+	cacheTTL     time.Duration
+	// delisted holds the time the symbols were found delisted, it is
+	// replaced, never modified, under symbolsMutex.
+	delisted map[string]time.Time
+	// metadata is replaced, never modified, under metadataMutex when it is
+	// cached again.
+	metadata      metadata
+	metadataMutex sync.RWMutex
 }
 
-// NewHitBtcV2Wrapper creates a generic wrapper of the HitBtc API v2.0.
-func NewHitBtcV2Wrapper(publicKey string, secretKey string) *Wrappers {
+// NewHitBtcV2Wrapper creates a generic wrapper of the HitBtc API v2.0, the
+// options configure the HTTP client of the REST calls.
+func NewHitBtcV2Wrapper(publicKey string, secretKey string, options ...wsclient.Option) *Wrappers {
 	ws, _ := wsclient.NewWSClient()
+	return newWrappers(wsclient.New(publicKey, secretKey, options...), ws)
+}
+
+// NewHitBtcV3Wrapper creates a generic wrapper of the HitBtc API v3, market
+// data, account and trading calls and the feed included.
+func NewHitBtcV3Wrapper(publicKey string, secretKey string, options ...wsclient.Option) *Wrappers {
+	ws, _ := wsclient.NewWSClientV3()
+	return newWrappers(wsclient.NewV3(publicKey, secretKey, options...), ws)
+}
+
+// NewReplayWrapper creates a wrapper streaming the feed recorded in path
+// instead of the HitBTC feed, at speed times the recorded pace. REST calls
+// still go to the HitBtc API v2.0.
+func NewReplayWrapper(path string, speed float64, publicKey string, secretKey string, options ...wsclient.Option) (*Wrappers, error) {
+	ws, err := wsclient.NewReplayClient(path, speed)
+	if err != nil {
+		return nil, err
+	}
+	return newWrappers(wsclient.New(publicKey, secretKey, options...), ws), nil
+}
+
+func newWrappers(api *wsclient.HitBtc, ws *wsclient.WSClient) *Wrappers {
+	// a nil *WSClient would make a non-nil FeedClient
+	if ws == nil {
+		return New(api, nil)
+	}
+	return New(api, ws)
+}
+
+// New creates a wrapper of the api REST client and the ws ticker feed, which
+// may be nil when the feed isn't used.
+func New(api RESTClient, ws FeedClient) *Wrappers {
 	return &Wrappers{
-		api:         wsclient.New(publicKey, secretKey),
+		api:         api,
+		trading:     api,
 		ws:          ws,
 		websocketOn: false,
+		feedWorkers: 4,
+		feedSymbols: append([]string(nil), supportedSymbols...),
+		subscribed:  make(map[string]bool),
+		pending:     make(map[string]bool),
+		lastAccess:  make(map[string]time.Time),
 		summaries:   inmemorycache.NewCurrencyCache(),
+		metadata: metadata{
+			symbols:    make(map[string]wsclient.Symbol),
+			currencies: make(map[string]wsclient.Currency),
+		},
+		// pipelined on the connection, subscribing hundreds of symbols one
+		// by one takes minutes
+		subscribeConcurrency: defaultSubscribeConcurrency,
 	}
 }
 
 // GetTicker gets the updated ticker for a market.
-func (wrapper *Wrappers) GetTicker(symbol string) (*wsclient.Ticker, error) {
-	hitbtcTicker, err := wrapper.api.GetTicker(symbol)
+func (wrapper *Wrappers) GetTicker(ctx context.Context, symbol string) (_ *wsclient.Ticker, err error) {
+	ctx, span := tracer.Start(ctx, "Wrappers.GetTicker", trace.WithAttributes(attribute.String("symbol", symbol)))
+	defer func() { endSpan(span, err) }()
+	hitbtcTicker, err := wrapper.api.GetTicker(ctx, symbol)
 	if err != nil {
 		return nil, err
 	}
@@ -50,170 +145,384 @@ func (wrapper *Wrappers) GetTicker(symbol string) (*wsclient.Ticker, error) {
 		VolumeQuote: hitbtcTicker.VolumeQuote,
 		Symbol:      hitbtcTicker.Symbol,
 		Timestamp:   hitbtcTicker.Timestamp,
+		Source:      wsclient.SourceREST,
 	}, nil
 }
 
-// GetMarketSummary gets the current market summary.
-func (wrapper *Wrappers) GetMarketSummary(symbol string) (*wsclient.Ticker, error) {
-	ret, exists := wrapper.summaries.Get(symbol)
-	if !exists {
-		hitbtcTicker, err := wrapper.GetTicker(symbol)
+// GetOrderBook gets the first limit price levels of each side of the order
+// book of a market. Order books aren't cached.
+func (wrapper *Wrappers) GetOrderBook(ctx context.Context, symbol string, limit int) (_ *wsclient.OrderBook, err error) {
+	ctx, span := tracer.Start(ctx, "Wrappers.GetOrderBook", trace.WithAttributes(attribute.String("symbol", symbol)))
+	defer func() { endSpan(span, err) }()
+	book, err := wrapper.api.GetOrderBook(ctx, symbol, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
+
+// GetCandles gets at most limit candles of a market and period opened in
+// [from, till], oldest first. Candles aren't cached.
+func (wrapper *Wrappers) GetCandles(ctx context.Context, symbol string, period string, from, till time.Time, limit int) (_ []wsclient.WSCandle, err error) {
+	ctx, span := tracer.Start(ctx, "Wrappers.GetCandles", trace.WithAttributes(attribute.String("symbol", symbol), attribute.String("period", period)))
+	defer func() { endSpan(span, err) }()
+	return wrapper.api.GetCandles(ctx, symbol, period, from, till, limit)
+}
+
+// GetMarketSummary gets the current market summary. Cached tickers are
+// returned with the time they were cached and their age, the ones fetched
+// from the REST API have an age of zero.
+func (wrapper *Wrappers) GetMarketSummary(ctx context.Context, symbol string) (_ *wsclient.Ticker, err error) {
+	ctx, span := tracer.Start(ctx, "Wrappers.GetMarketSummary", trace.WithAttributes(attribute.String("symbol", symbol)))
+	defer func() { endSpan(span, err) }()
+	if since, ok := wrapper.Delisted(symbol); ok {
+		return nil, &DelistedError{Symbol: symbol, Since: since}
+	}
+	wrapper.subscribeOnDemand(symbol)
+	entry, exists := wrapper.summaries.Lookup(symbol)
+	expired := exists && wrapper.cacheTTL > 0 && time.Since(entry.CachedAt) > wrapper.cacheTTL
+	span.SetAttributes(attribute.Bool("cache.hit", exists && !expired))
+	if !exists || expired {
+		hitbtcTicker, err := wrapper.GetTicker(ctx, symbol)
 		if err != nil {
+			if expired {
+				return nil, &StaleDataError{Symbol: symbol, CachedAt: entry.CachedAt, Err: err}
+			}
 			return nil, err
 		}
-		feeCurrency := ""
-		_, ok := SymbolsFeeCurrency[hitbtcTicker.Symbol]
-		if ok {
-			feeCurrency = SymbolsFeeCurrency[hitbtcTicker.Symbol]
+		hitbtcTicker.ID = hitbtcTicker.Symbol
+		wrapper.enrichTicker(hitbtcTicker)
+		wrapper.storeTicker(hitbtcTicker)
+		// the stored ticker is shared with the cache
+		fetched := *hitbtcTicker
+		var age int64
+		fetched.AgeMs = &age
+		return &fetched, nil
+	}
+
+	return withProvenance(entry, time.Now()), nil
+}
+
+// withProvenance sets the cache time and age of the ticker of a cache entry.
+func withProvenance(entry inmemorycache.Entry, now time.Time) *wsclient.Ticker {
+	ticker := entry.Ticker
+	cachedAt := entry.CachedAt
+	age := now.Sub(cachedAt).Milliseconds()
+	ticker.CachedAt = &cachedAt
+	ticker.AgeMs = &age
+	return ticker
+}
+
+// SetFeedBuffer sets the buffering of the websocket ticker channels. It must
+// be called before FeedConnect.
+func (wrapper *Wrappers) SetFeedBuffer(size int, overflow wsclient.OverflowPolicy) {
+	if feed, ok := wrapper.ws.(bufferedFeed); ok {
+		feed.SetTickerBuffer(size, overflow)
+	}
+}
+
+// UpdateFeedSymbols sets the symbols streamed from the websocket feed. Once
+// the feed is connected the added symbols are subscribed, unless
+// subscriptions are lazy, the removed ones are unsubscribed and dropped from
+// the cache, and the other subscriptions are left alone. Added symbols
+// failing to subscribe are subscribed again on their next request.
+func (wrapper *Wrappers) UpdateFeedSymbols(symbols []string) (added []string, removed []string, err error) {
+	wrapper.updateMutex.Lock()
+	defer wrapper.updateMutex.Unlock()
+	current := wrapper.SupportedSymbols()
+	for _, symbol := range symbols {
+		if !wrapper.Contains(current, symbol) && !wrapper.Contains(added, symbol) {
+			added = append(added, symbol)
 		}
-		fullName := ""
-		_, ok = CurrencyFullName[feeCurrency]
-		if ok {
-			fullName = CurrencyFullName[feeCurrency]
+	}
+	for _, symbol := range current {
+		if !wrapper.Contains(symbols, symbol) {
+			removed = append(removed, symbol)
 		}
-		ret = &wsclient.Ticker{
-			Last:        hitbtcTicker.Last,
-			Ask:         hitbtcTicker.Ask,
-			Bid:         hitbtcTicker.Bid,
-			Open:        hitbtcTicker.Open,
-			Low:         hitbtcTicker.Low,
-			High:        hitbtcTicker.High,
-			Volume:      hitbtcTicker.Volume,
-			VolumeQuote: hitbtcTicker.VolumeQuote,
-			Symbol:      hitbtcTicker.Symbol,
-			Timestamp:   hitbtcTicker.Timestamp,
-			FeeCurrency: feeCurrency,
-			FullName:    fullName,
-			ID:          hitbtcTicker.Symbol,
+	}
+	if !wrapper.websocketOn {
+		wrapper.setFeedSymbols(append([]string(nil), symbols...))
+		return added, removed, nil
+	}
+	current = append(current, added...)
+	wrapper.setFeedSymbols(current)
+	if !wrapper.lazy {
+		subscribed, err := wrapper.subscribeAll(added)
+		wrapper.markSubscribed(subscribed, true)
+		if err != nil {
+			return added, nil, err
 		}
-		if wrapper.Contains(supportedSymbols, hitbtcTicker.Symbol) {
-			wrapper.summaries.Set(symbol, ret)
+	}
+	for i, symbol := range removed {
+		if wrapper.isSubscribed(symbol) {
+			if err = wrapper.ws.UnsubscribeTicker(symbol); err != nil {
+				return added, removed[:i], err
+			}
+			wrapper.markSubscribed([]string{symbol}, false)
 		}
-		return ret, nil
-	}
-
-	return ret, nil
-}
-
-// subscribeFeeds subscribes to the Market Summary Feed service.
-func (wrapper *Wrappers) subscribeFeeds(symbol string, closeChan chan bool, c chan os.Signal) error {
-	handleTicker := func(wrapper *Wrappers, currencyChannel <-chan wsclient.WSNotificationTickerResponse, m string) {
-		for {
-			select {
-			case <-closeChan:
-
-				wrapper.Close(symbol)
-				return
-			default:
-				hitbtcSummary, stillOpen := <-currencyChannel
-				if !stillOpen {
-					return
-				}
-				last, _ := strconv.ParseFloat(hitbtcSummary.Last, 64)
-				ask, _ := strconv.ParseFloat(hitbtcSummary.Ask, 64)
-				bid, _ := strconv.ParseFloat(hitbtcSummary.Bid, 64)
-				open, _ := strconv.ParseFloat(hitbtcSummary.Open, 64)
-				low, _ := strconv.ParseFloat(hitbtcSummary.Low, 64)
-				high, _ := strconv.ParseFloat(hitbtcSummary.High, 64)
-				volume, _ := strconv.ParseFloat(hitbtcSummary.Volume, 64)
-				volumeQuota, _ := strconv.ParseFloat(hitbtcSummary.VolumeQuote, 64)
-				feeCurrency := ""
-				_, ok := SymbolsFeeCurrency[hitbtcSummary.Symbol]
-				if ok {
-					feeCurrency = SymbolsFeeCurrency[hitbtcSummary.Symbol]
-				}
-				fullName := ""
-				_, ok = CurrencyFullName[feeCurrency]
-				if ok {
-					fullName = CurrencyFullName[feeCurrency]
-				}
-				sum := &wsclient.Ticker{
-					Last:        last,
-					Ask:         ask,
-					Bid:         bid,
-					Open:        open,
-					Low:         low,
-					High:        high,
-					Volume:      volume,
-					VolumeQuote: volumeQuota,
-					Symbol:      hitbtcSummary.Symbol,
-					FeeCurrency: feeCurrency,
-					FullName:    fullName,
-					ID:          hitbtcSummary.Symbol,
-				}
-				if wrapper.Contains(supportedSymbols, hitbtcSummary.Symbol) {
-					wrapper.summaries.Set(symbol, sum)
-				}
-
+		kept := make([]string, 0, len(current))
+		for _, s := range current {
+			if s != symbol {
+				kept = append(kept, s)
 			}
 		}
+		current = kept
+		wrapper.setFeedSymbols(current)
+		wrapper.summaries.Delete(symbol)
 	}
-	summaryChannel, err := wrapper.ws.SubscribeTicker(symbol)
-	if err != nil {
-		return err
+	return added, removed, nil
+}
+
+func (wrapper *Wrappers) isFeedSymbol(symbol string) bool {
+	wrapper.symbolsMutex.RLock()
+	defer wrapper.symbolsMutex.RUnlock()
+	return wrapper.Contains(wrapper.feedSymbols, symbol)
+}
+
+func (wrapper *Wrappers) setFeedSymbols(symbols []string) {
+	wrapper.symbolsMutex.Lock()
+	wrapper.feedSymbols = symbols
+	wrapper.symbolsMutex.Unlock()
+}
+
+// SetCacheTTL sets the time since a ticker was cached after which it is
+// fetched again from the REST API, whatever the exchange timestamp of the
+// ticker, zero keeps the cached tickers until the feed updates them.
+func (wrapper *Wrappers) SetCacheTTL(ttl time.Duration) {
+	wrapper.cacheTTL = ttl
+}
+
+// SetDebug enables the dumps of the upstream REST requests and responses.
+func (wrapper *Wrappers) SetDebug(enable bool) {
+	wrapper.api.SetDebug(enable)
+}
+
+// SetFeedRecorder records the notifications of the websocket feed, see
+// wsclient.WSClient.SetRecorder.
+func (wrapper *Wrappers) SetFeedRecorder(recorder *wsclient.Recorder) {
+	if feed, ok := wrapper.ws.(recordedFeed); ok {
+		feed.SetRecorder(recorder)
 	}
+}
 
-	go handleTicker(wrapper, summaryChannel, symbol)
-	return nil
+// SetFeedWorkers sets the number of workers processing the websocket feed,
+// the updates of a symbol are processed by one of them. It must be called
+// before FeedConnect.
+func (wrapper *Wrappers) SetFeedWorkers(workers int) {
+	if workers > 0 {
+		wrapper.feedWorkers = workers
+	}
+}
+
+// FeedDrops returns the ticker updates dropped per symbol because the feed
+// consumer was behind.
+func (wrapper *Wrappers) FeedDrops() map[string]uint64 {
+	feed, ok := wrapper.ws.(bufferedFeed)
+	if !ok {
+		return map[string]uint64{}
+	}
+	return feed.TickerDrops()
+}
+
+// SetRetryPolicy sets the retries of transient upstream REST errors.
+func (wrapper *Wrappers) SetRetryPolicy(policy wsclient.RetryPolicy) {
+	wrapper.api.SetRetryPolicy(policy)
+}
+
+// SetCircuitBreaker sets the consecutive upstream failures opening the circuit
+// breaker and how long it stays open.
+func (wrapper *Wrappers) SetCircuitBreaker(threshold int, cooldown time.Duration) {
+	wrapper.api.SetCircuitBreaker(threshold, cooldown)
+}
+
+// UpstreamAvailable tells whether the HitBTC REST API is called, it isn't
+// while the circuit breaker is open.
+func (wrapper *Wrappers) UpstreamAvailable() bool {
+	return wrapper.api.CircuitState() != wsclient.CircuitOpen
+}
+
+// CircuitState returns the state of the upstream circuit breaker.
+func (wrapper *Wrappers) CircuitState() string {
+	return wrapper.api.CircuitState()
+}
+
+// SetRateLimit sets the upstream REST requests per second allowed for a
+// request class.
+func (wrapper *Wrappers) SetRateLimit(class string, rate float64) error {
+	return wrapper.api.SetRateLimit(class, rate)
+}
+
+// RateLimitStats returns the upstream REST requests counted by the client-side
+// rate limiter per request class.
+func (wrapper *Wrappers) RateLimitStats() map[string]wsclient.RateLimitStats {
+	return wrapper.api.RateLimitStats()
+}
+
+// AddTickerListener registers a listener for ticker updates, it misses the
+// updates stored before.
+func (wrapper *Wrappers) AddTickerListener(listener TickerListener) {
+	wrapper.listenersMutex.Lock()
+	defer wrapper.listenersMutex.Unlock()
+	listeners := make([]TickerListener, len(wrapper.listeners), len(wrapper.listeners)+1)
+	copy(listeners, wrapper.listeners)
+	wrapper.listeners = append(listeners, listener)
+}
+
+// tickerListeners returns the registered listeners.
+func (wrapper *Wrappers) tickerListeners() []TickerListener {
+	wrapper.listenersMutex.RLock()
+	defer wrapper.listenersMutex.RUnlock()
+	return wrapper.listeners
 }
 
-// FeedConnect connects to the feed of the exchange.
+// FeedConnect connects to the feed of the exchange and subscribes the feed
+// symbols concurrently, or none of them when subscriptions are lazy. Symbols
+// failing to subscribe are reported in a *SubscribeError and subscribed
+// again on their next request.
 func (wrapper *Wrappers) FeedConnect() error {
+	if wrapper.ws == nil {
+		return errors.New("the ticker feed isn't connected")
+	}
+	wrapper.updateMutex.Lock()
+	defer wrapper.updateMutex.Unlock()
 	wrapper.websocketOn = true
-	closeChan := make(chan bool)
-	ch := make(chan os.Signal)
-	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-ch
-		os.Exit(0)
-	}()
-	for _, m := range supportedSymbols {
-		err := wrapper.subscribeFeeds(m, closeChan, ch)
-		if err != nil {
-			return err
-		}
+	go wrapper.dispatchFeed(wrapper.ws.TickerStream(), wrapper.feedWorkers)
+	wrapper.symbolsMutex.Lock()
+	wrapper.feedOn = true
+	wrapper.symbolsMutex.Unlock()
+	if wrapper.lazy {
+		return nil
 	}
+	subscribed, err := wrapper.subscribeAll(wrapper.SupportedSymbols())
+	wrapper.markSubscribed(subscribed, true)
+	return err
+}
 
-	return nil
+// CloseFeed closes the connections of the feed, which stops the feed
+// workers. The feed can't be connected again.
+func (wrapper *Wrappers) CloseFeed() {
+	wrapper.updateMutex.Lock()
+	defer wrapper.updateMutex.Unlock()
+	wrapper.websocketOn = false
+	if feed, ok := wrapper.ws.(closableFeed); ok {
+		feed.Close()
+	}
 }
 
 func (wrapper *Wrappers) Close(m string) {
 	wrapper.ws.UnsubscribeTicker(m)
 }
 
-func (wrapper *Wrappers) CacheAllSymbols() error {
-	symbolsrecords, err := wrapper.api.GetSymbols()
+func (wrapper *Wrappers) CacheAllSymbols(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "Wrappers.CacheAllSymbols")
+	defer func() { endSpan(span, err) }()
+	symbolsrecords, err := wrapper.api.GetSymbols(ctx)
 	if err != nil {
+		// replaying offline, the recorded symbols are served without metadata
+		if feed, ok := wrapper.ws.(replayedFeed); ok && len(feed.ReplaySymbols()) > 0 {
+			wrapper.metadataMutex.Lock()
+			wrapper.metadata.allSymbols = feed.ReplaySymbols()
+			wrapper.metadataMutex.Unlock()
+		}
 		return err
 	}
 	var symbols []string
+	infos := make(map[string]wsclient.Symbol, len(symbolsrecords))
 	for _, sym := range symbolsrecords {
-		SymbolsFeeCurrency[sym.Id] = sym.FeeCurrency
+		infos[sym.Id] = sym
 		symbols = append(symbols, sym.Id)
 	}
-	wrapper.AllSymbols = symbols
+	wrapper.metadataMutex.Lock()
+	wrapper.metadata.symbols = infos
+	wrapper.metadata.allSymbols = symbols
+	wrapper.metadataMutex.Unlock()
 	return nil
 }
 
+// GetCurrenciesFromCache returns a point-in-time copy of the cached tickers,
+// sorted by symbol, with the time they were cached and their age.
 func (wrapper *Wrappers) GetCurrenciesFromCache() ([]*wsclient.Ticker, error) {
-	allRecords, err := wrapper.summaries.GetAll()
-	if err != nil {
-		return nil, err
+	entries := wrapper.summaries.Entries()
+	if len(entries) == 0 {
+		return nil, errors.New("no data present")
+	}
+	now := time.Now()
+	allRecords := make([]*wsclient.Ticker, 0, len(entries))
+	for _, entry := range entries {
+		allRecords = append(allRecords, withProvenance(entry, now))
 	}
 	return allRecords, nil
 }
 
-func (wrapper *Wrappers) CacheFullName() error {
-	currencyRecords, err := wrapper.api.GetCurrencies()
+// RangeCurrencies calls fn with every cached ticker, sorted by symbol, until
+// fn returns false. The tickers are copied from the cache one at a time.
+func (wrapper *Wrappers) RangeCurrencies(fn func(*wsclient.Ticker) bool) {
+	wrapper.summaries.Range(func(entry inmemorycache.Entry) bool {
+		return fn(withProvenance(entry, time.Now()))
+	})
+}
+
+// Changes returns at most limit tickers updated after the cache sequence
+// number since, oldest update first, the cursor to pass as since to get the
+// next changes, and whether more changes are left.
+func (wrapper *Wrappers) Changes(since uint64, limit int) ([]*wsclient.Ticker, uint64, bool) {
+	entries, last := wrapper.summaries.Changes(since)
+	more := len(entries) > limit
+	if more {
+		entries = entries[:limit]
+		last = entries[limit-1].Seq
+	}
+	now := time.Now()
+	tickers := make([]*wsclient.Ticker, 0, len(entries))
+	for _, entry := range entries {
+		tickers = append(tickers, withProvenance(entry, now))
+	}
+	return tickers, last, more
+}
+
+// CacheEntries returns a copy of the cache entries, sorted by symbol.
+func (wrapper *Wrappers) CacheEntries() []inmemorycache.Entry {
+	return wrapper.summaries.Entries()
+}
+
+// FlushCache removes the ticker of symbol from the cache, or every ticker
+// when symbol is empty, and returns the number of entries removed. The feed
+// symbols are cached again on their next update, the others on their next
+// request.
+func (wrapper *Wrappers) FlushCache(symbol string) int {
+	if symbol == "" {
+		return wrapper.summaries.Flush()
+	}
+	if wrapper.summaries.Delete(symbol) {
+		return 1
+	}
+	return 0
+}
+
+func (wrapper *Wrappers) CacheFullName(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "Wrappers.CacheFullName")
+	defer func() { endSpan(span, err) }()
+	currencyRecords, err := wrapper.api.GetCurrencies(ctx)
 	if err != nil {
 		return err
 	}
+	currencies := make(map[string]wsclient.Currency, len(currencyRecords))
 	for _, currency := range currencyRecords {
-		CurrencyFullName[currency.Id] = currency.FullName
+		currencies[currency.Id] = currency
 	}
+	wrapper.metadataMutex.Lock()
+	wrapper.metadata.currencies = currencies
+	wrapper.metadataMutex.Unlock()
 	return nil
 }
 
+// SupportedSymbols returns the symbols streamed from the websocket feed.
+func (wrapper *Wrappers) SupportedSymbols() []string {
+	wrapper.symbolsMutex.RLock()
+	defer wrapper.symbolsMutex.RUnlock()
+	return append([]string(nil), wrapper.feedSymbols...)
+}
+
 // Contains checks if a string is present in a slice
 func (wrapper *Wrappers) Contains(s []string, str string) bool {
 	for _, v := range s {
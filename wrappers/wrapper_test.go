@@ -0,0 +1,227 @@
+package wrappers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/crypto-api-server/wsclient"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	testSymbols = []wsclient.Symbol{
+		{Id: "BTCUSD", BaseCurrency: "BTC", QuoteCurrency: "USD", FeeCurrency: "USD"},
+		{Id: "ETHBTC", BaseCurrency: "ETH", QuoteCurrency: "BTC", FeeCurrency: "BTC"},
+	}
+	testCurrencies = []wsclient.Currency{
+		{Id: "BTC", FullName: "Bitcoin"},
+		{Id: "ETH", FullName: "Ethereum"},
+		{Id: "USD", FullName: "US Dollar"},
+	}
+)
+
+// newTestWrapper returns a wrapper of a fake exchange, with its metadata
+// cached.
+func newTestWrapper(t *testing.T) (*Wrappers, *fakeExchange) {
+	t.Helper()
+	exchange := newFakeExchange(testSymbols, testCurrencies)
+	wrapper := New(exchange, exchange)
+	ctx := context.Background()
+	if err := wrapper.CacheAllSymbols(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := wrapper.CacheFullName(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return wrapper, exchange
+}
+
+func TestGetMarketSummary(t *testing.T) {
+	wrapper, exchange := newTestWrapper(t)
+	exchange.setTicker(wsclient.Ticker{
+		Symbol:    "BTCUSD",
+		Last:      decimal.RequireFromString("50000.5"),
+		Open:      decimal.RequireFromString("40000"),
+		Timestamp: time.Now(),
+	})
+
+	ticker, err := wrapper.GetMarketSummary(context.Background(), "BTCUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticker.Source != wsclient.SourceREST || ticker.AgeMs == nil || *ticker.AgeMs != 0 {
+		t.Errorf("fetched ticker: source %q, age %v, want %q with an age of 0", ticker.Source, ticker.AgeMs, wsclient.SourceREST)
+	}
+	if ticker.ID != "BTCUSD" || ticker.FeeCurrency != "USD" || ticker.FullName != "US Dollar" {
+		t.Errorf("fetched ticker: id %q, fee currency %q, full name %q", ticker.ID, ticker.FeeCurrency, ticker.FullName)
+	}
+	if !ticker.ChangePercent24h.Equal(decimal.RequireFromString("25")) {
+		t.Errorf("24h change %s%%, want 25%%", ticker.ChangePercent24h)
+	}
+
+	cached, err := wrapper.GetMarketSummary(context.Background(), "BTCUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := exchange.calls("GetTicker"); calls != 1 {
+		t.Errorf("%d REST calls, want the second summary served from the cache", calls)
+	}
+	if cached.CachedAt == nil || !cached.Last.Equal(ticker.Last) {
+		t.Errorf("cached ticker: cached at %v, last %s, want the fetched ticker", cached.CachedAt, cached.Last)
+	}
+}
+
+// TestGetMarketSummaryQuietMarket checks that the cache time, not the
+// exchange timestamp, expires a ticker: on a quiet market the timestamp
+// stays old.
+func TestGetMarketSummaryQuietMarket(t *testing.T) {
+	wrapper, exchange := newTestWrapper(t)
+	wrapper.SetCacheTTL(time.Minute)
+	exchange.setTicker(wsclient.Ticker{
+		Symbol:    "BTCUSD",
+		Last:      decimal.RequireFromString("50000"),
+		Timestamp: time.Now().Add(-2 * time.Minute),
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := wrapper.GetMarketSummary(context.Background(), "BTCUSD"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := exchange.calls("GetTicker"); calls != 1 {
+		t.Errorf("%d REST calls, want the second summary served from the cache", calls)
+	}
+}
+
+func TestGetMarketSummaryStale(t *testing.T) {
+	wrapper, exchange := newTestWrapper(t)
+	wrapper.SetCacheTTL(20 * time.Millisecond)
+	exchange.setTicker(wsclient.Ticker{
+		Symbol:    "BTCUSD",
+		Last:      decimal.RequireFromString("50000"),
+		Timestamp: time.Now(),
+	})
+	if _, err := wrapper.GetMarketSummary(context.Background(), "BTCUSD"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(40 * time.Millisecond)
+
+	unavailable := errors.New("upstream unavailable")
+	exchange.fail(unavailable)
+	_, err := wrapper.GetMarketSummary(context.Background(), "BTCUSD")
+	var stale *StaleDataError
+	if !errors.As(err, &stale) {
+		t.Fatalf("got %v, want a *StaleDataError", err)
+	}
+	if stale.Symbol != "BTCUSD" || stale.CachedAt.IsZero() || !errors.Is(err, unavailable) {
+		t.Errorf("got %#v, want the cache time of BTCUSD and the REST error", stale)
+	}
+	if calls := exchange.calls("GetTicker"); calls != 2 {
+		t.Errorf("%d REST calls, want the expired ticker fetched again", calls)
+	}
+}
+
+func TestDelisting(t *testing.T) {
+	wrapper, exchange := newTestWrapper(t)
+	updates := make(chan *wsclient.Ticker, 1)
+	wrapper.AddTickerListener(func(ticker *wsclient.Ticker) { updates <- ticker })
+	if err := wrapper.FeedConnect(); err != nil {
+		t.Fatal(err)
+	}
+	defer wrapper.CloseFeed()
+	if !exchange.isSubscribed("ETHBTC") {
+		t.Fatal("ETHBTC isn't subscribed")
+	}
+	exchange.push(wsclient.WSNotificationTickerResponse{
+		Symbol:    "ETHBTC",
+		Last:      "0.05",
+		Open:      "0.04",
+		Timestamp: time.Now().Format(time.RFC3339Nano),
+	})
+	select {
+	case <-updates:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the feed update wasn't stored")
+	}
+
+	exchange.setSymbols(testSymbols[:1])
+	_, delisted, err := wrapper.RefreshMetadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delisted) != 1 || delisted[0] != "ETHBTC" {
+		t.Fatalf("delisted %v, want [ETHBTC]", delisted)
+	}
+	if exchange.isSubscribed("ETHBTC") {
+		t.Error("the delisted symbol is still subscribed")
+	}
+	if wrapper.isFeedSymbol("ETHBTC") {
+		t.Error("the delisted symbol is still a feed symbol")
+	}
+	_, err = wrapper.GetMarketSummary(context.Background(), "ETHBTC")
+	var delistedErr *DelistedError
+	if !errors.As(err, &delistedErr) || delistedErr.Symbol != "ETHBTC" {
+		t.Errorf("got %v, want a *DelistedError for ETHBTC", err)
+	}
+	entry, ok := wrapper.summaries.Lookup("ETHBTC")
+	if !ok || !entry.Ticker.Delisted {
+		t.Error("the cached ticker of the delisted symbol isn't flagged")
+	}
+}
+
+// TestDelistingCurrency checks that a symbol trading a delisted currency is
+// delisted once, also when it's later gone from the symbols.
+func TestDelistingCurrency(t *testing.T) {
+	wrapper, exchange := newTestWrapper(t)
+	currencies := append([]wsclient.Currency(nil), testCurrencies...)
+	currencies[1].Delisted = true
+	exchange.setCurrencies(currencies)
+	_, delisted, err := wrapper.RefreshMetadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delisted) != 1 || delisted[0] != "ETHBTC" {
+		t.Fatalf("delisted %v, want [ETHBTC]", delisted)
+	}
+	since, ok := wrapper.Delisted("ETHBTC")
+	if !ok {
+		t.Fatal("ETHBTC isn't delisted")
+	}
+
+	exchange.setSymbols(testSymbols[:1])
+	for i := 0; i < 2; i++ {
+		if _, delisted, err = wrapper.RefreshMetadata(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(delisted) != 0 {
+			t.Errorf("refresh %d: delisted %v again", i+1, delisted)
+		}
+	}
+	if again, ok := wrapper.Delisted("ETHBTC"); !ok || !again.Equal(since) {
+		t.Errorf("delisted since %v, %v, want %v", again, ok, since)
+	}
+}
+
+// TestRecordAccess checks that the symbols read by the listings aren't
+// unsubscribed as idle.
+func TestRecordAccess(t *testing.T) {
+	wrapper, exchange := newTestWrapper(t)
+	if err := wrapper.FeedConnect(); err != nil {
+		t.Fatal(err)
+	}
+	defer wrapper.CloseFeed()
+
+	time.Sleep(50 * time.Millisecond)
+	wrapper.RecordAccess("BTCUSD", "XYZUSD")
+	idle, err := wrapper.UnsubscribeIdle(25 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idle) != 1 || idle[0] != "ETHBTC" {
+		t.Fatalf("unsubscribed %v, want [ETHBTC]", idle)
+	}
+	if !exchange.isSubscribed("BTCUSD") || exchange.isSubscribed("ETHBTC") {
+		t.Error("the accessed symbol was unsubscribed or the idle one kept")
+	}
+}
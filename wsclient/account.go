@@ -0,0 +1,156 @@
+package wsclient
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/shopspring/decimal"
+)
+
+// Balance is the balance of a currency.
+type Balance struct {
+	Currency  string          `json:"currency"`
+	Available decimal.Decimal `json:"available"`
+	Reserved  decimal.Decimal `json:"reserved"` // held by active orders
+}
+
+// GetTradingBalance returns the balances of the trading account.
+func (b *HitBtc) GetTradingBalance(ctx context.Context) (balances []Balance, err error) {
+	resource := "trading/balance"
+	if b.v3() {
+		resource = "spot/balance"
+	}
+	err = b.call(ctx, "GET", resource, nil, true, &balances)
+	return
+}
+
+// GetAccountBalance returns the balances of the main account, which must be
+// transferred to the trading account before trading.
+func (b *HitBtc) GetAccountBalance(ctx context.Context) (balances []Balance, err error) {
+	resource := "account/balance"
+	if b.v3() {
+		resource = "wallet/balance"
+	}
+	err = b.call(ctx, "GET", resource, nil, true, &balances)
+	return
+}
+
+// DepositAddress is a crypto address to deposit a currency to the main account.
+type DepositAddress struct {
+	Address   string `json:"address"`
+	PaymentID string `json:"paymentId,omitempty"` // memo or tag required by some currencies
+}
+
+// GetDepositAddress returns the current deposit address of currency.
+func (b *HitBtc) GetDepositAddress(ctx context.Context, currency string) (address DepositAddress, err error) {
+	if b.v3() {
+		return b.getDepositAddressV3(ctx, currency)
+	}
+	err = b.call(ctx, "GET", "account/crypto/address/"+url.PathEscape(currency), nil, true, &address)
+	return
+}
+
+// NewDepositAddress generates a new deposit address for currency.
+func (b *HitBtc) NewDepositAddress(ctx context.Context, currency string) (address DepositAddress, err error) {
+	if b.v3() {
+		return b.newDepositAddressV3(ctx, currency)
+	}
+	err = b.call(ctx, "POST", "account/crypto/address/"+url.PathEscape(currency), nil, true, &address)
+	return
+}
+
+// WithdrawRequest describes a crypto withdrawal from the main account.
+type WithdrawRequest struct {
+	Currency   string
+	Amount     decimal.Decimal
+	Address    string
+	PaymentID  string // memo or tag required by some currencies
+	AutoCommit bool   // without it the withdrawal waits for CommitWithdraw
+}
+
+// Withdraw is a created withdrawal.
+type Withdraw struct {
+	ID string `json:"id"`
+}
+
+// Withdraw requests a crypto withdrawal.
+func (b *HitBtc) Withdraw(ctx context.Context, request WithdrawRequest) (withdraw Withdraw, err error) {
+	if b.v3() {
+		return b.withdrawV3(ctx, request)
+	}
+	payload := map[string]string{
+		"currency":   request.Currency,
+		"amount":     request.Amount.String(),
+		"address":    request.Address,
+		"autoCommit": strconv.FormatBool(request.AutoCommit),
+	}
+	if request.PaymentID != "" {
+		payload["paymentId"] = request.PaymentID
+	}
+	err = b.call(ctx, "POST", "account/crypto/withdraw", payload, true, &withdraw)
+	return
+}
+
+type withdrawResult struct {
+	Result bool `json:"result"`
+}
+
+// withdrawResource returns the path of the withdrawal id.
+func (b *HitBtc) withdrawResource(id string) string {
+	if b.v3() {
+		return "wallet/crypto/withdraw/" + url.PathEscape(id)
+	}
+	return "account/crypto/withdraw/" + url.PathEscape(id)
+}
+
+// CommitWithdraw confirms a withdrawal created without AutoCommit.
+func (b *HitBtc) CommitWithdraw(ctx context.Context, id string) error {
+	var result withdrawResult
+	if err := b.call(ctx, "PUT", b.withdrawResource(id), nil, true, &result); err != nil {
+		return err
+	}
+	if !result.Result {
+		return errors.New("Withdraw commit not successful")
+	}
+	return nil
+}
+
+// RollbackWithdraw cancels a withdrawal created without AutoCommit.
+func (b *HitBtc) RollbackWithdraw(ctx context.Context, id string) error {
+	var result withdrawResult
+	if err := b.call(ctx, "DELETE", b.withdrawResource(id), nil, true, &result); err != nil {
+		return err
+	}
+	if !result.Result {
+		return errors.New("Withdraw rollback not successful")
+	}
+	return nil
+}
+
+// Transfer types of Transfer.
+const (
+	TransferBankToExchange = "bankToExchange"
+	TransferExchangeToBank = "exchangeToBank"
+)
+
+// Transfer is a transfer between the main and trading accounts.
+type Transfer struct {
+	ID string `json:"id"`
+}
+
+// Transfer moves amount of currency between the main (bank) and trading
+// (exchange) accounts.
+func (b *HitBtc) Transfer(ctx context.Context, currency string, amount decimal.Decimal, transferType string) (transfer Transfer, err error) {
+	if b.v3() {
+		return b.transferV3(ctx, currency, amount, transferType)
+	}
+	payload := map[string]string{
+		"currency": currency,
+		"amount":   amount.String(),
+		"type":     transferType,
+	}
+	err = b.call(ctx, "POST", "account/transfer", payload, true, &transfer)
+	return
+}
@@ -0,0 +1,82 @@
+package wsclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the API while the circuit
+// breaker is open. It matches ErrUpstreamUnavailable.
+var ErrCircuitOpen error = &unavailableError{errors.New("HitBTC API unavailable, circuit breaker open")}
+
+// States of the circuit breaker.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// circuitBreaker opens after threshold consecutive transient failures and
+// rejects the requests for cooldown. It then lets a single probe request
+// through, closing again when it succeeds.
+type circuitBreaker struct {
+	mutex     sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	state     string
+	openedAt  time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, state: CircuitClosed}
+}
+
+// allow tells whether a request may be done now.
+func (cb *circuitBreaker) allow(now time.Time) bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	switch cb.state {
+	case CircuitOpen:
+		if now.Sub(cb.openedAt) < cb.cooldown {
+			return false
+		}
+		cb.state = CircuitHalfOpen
+		return true
+	case CircuitHalfOpen:
+		// the probe request is in flight
+		return false
+	}
+	return true
+}
+
+// done records the outcome of an allowed request.
+func (cb *circuitBreaker) done(err error, now time.Time) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// the caller gave up, the outcome says nothing about the API
+		if cb.state == CircuitHalfOpen {
+			cb.state = CircuitOpen
+		}
+		return
+	}
+	if err == nil || !retryable(err) {
+		cb.failures = 0
+		cb.state = CircuitClosed
+		return
+	}
+	cb.failures++
+	if cb.state == CircuitHalfOpen || (cb.threshold > 0 && cb.failures >= cb.threshold) {
+		cb.state = CircuitOpen
+		cb.openedAt = now
+	}
+}
+
+func (cb *circuitBreaker) current() string {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	return cb.state
+}
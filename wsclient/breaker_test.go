@@ -0,0 +1,78 @@
+package wsclient
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestCircuitBreaker walks the breaker through its states on a fake clock:
+// closed, open after the threshold, half-open after the cooldown, open again
+// when the probe fails, and closed when it succeeds.
+func TestCircuitBreaker(t *testing.T) {
+	transient := &StatusError{StatusCode: http.StatusServiceUnavailable}
+	cb := newCircuitBreaker(3, 30*time.Second)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tick := func(d time.Duration) { now = now.Add(d) }
+
+	steps := []struct {
+		name  string
+		run   func()
+		state string
+		allow bool
+	}{
+		{"new", func() {}, CircuitClosed, true},
+		{"two failures", func() { cb.done(transient, now); cb.done(transient, now) }, CircuitClosed, true},
+		{"success resets the failures", func() { cb.done(nil, now); cb.done(transient, now); cb.done(transient, now) }, CircuitClosed, true},
+		{"rejected request resets the failures", func() {
+			cb.done(&StatusError{StatusCode: http.StatusBadRequest}, now)
+			cb.done(transient, now)
+			cb.done(transient, now)
+		}, CircuitClosed, true},
+		{"threshold reached", func() { cb.done(transient, now) }, CircuitOpen, false},
+		{"within the cooldown", func() { tick(29 * time.Second) }, CircuitOpen, false},
+		// allow lets the probe through and half-opens the circuit
+		{"cooldown over", func() { tick(time.Second) }, CircuitOpen, true},
+		{"probe in flight", func() {}, CircuitHalfOpen, false},
+		{"probe failed", func() { cb.done(transient, now) }, CircuitOpen, false},
+		{"cooldown restarted", func() { tick(29 * time.Second) }, CircuitOpen, false},
+		{"second cooldown over", func() { tick(time.Second) }, CircuitOpen, true},
+		{"probe canceled", func() { cb.done(context.Canceled, now) }, CircuitOpen, true},
+		{"probe succeeded", func() { cb.done(nil, now) }, CircuitClosed, true},
+	}
+	for _, step := range steps {
+		step.run()
+		if state := cb.current(); state != step.state {
+			t.Fatalf("%s: state %s, want %s", step.name, state, step.state)
+		}
+		if allowed := cb.allow(now); allowed != step.allow {
+			t.Fatalf("%s: allow %v, want %v", step.name, allowed, step.allow)
+		}
+	}
+}
+
+// TestCircuitBreakerCanceled checks that the requests given up by their
+// caller don't count as failures.
+func TestCircuitBreakerCanceled(t *testing.T) {
+	cb := newCircuitBreaker(1, time.Minute)
+	now := time.Now()
+	cb.done(context.Canceled, now)
+	cb.done(context.DeadlineExceeded, now)
+	if state := cb.current(); state != CircuitClosed {
+		t.Errorf("state %s after canceled requests, want %s", state, CircuitClosed)
+	}
+}
+
+// TestCircuitBreakerDisabled checks that a threshold of 0 never opens the
+// circuit.
+func TestCircuitBreakerDisabled(t *testing.T) {
+	cb := newCircuitBreaker(0, time.Minute)
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		cb.done(errTimeout, now)
+	}
+	if !cb.allow(now) || cb.current() != CircuitClosed {
+		t.Errorf("state %s after failures with the breaker disabled", cb.current())
+	}
+}
@@ -0,0 +1,154 @@
+package wsclient
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+)
+
+// CandlePeriods are the durations of the candle periods of HitBTC, a month
+// counting as 30 days.
+var CandlePeriods = map[string]time.Duration{
+	"M1":  time.Minute,
+	"M3":  3 * time.Minute,
+	"M5":  5 * time.Minute,
+	"M15": 15 * time.Minute,
+	"M30": 30 * time.Minute,
+	"H1":  time.Hour,
+	"H4":  4 * time.Hour,
+	"D1":  24 * time.Hour,
+	"D7":  7 * 24 * time.Hour,
+	"1M":  30 * 24 * time.Hour,
+}
+
+// MaxCandlesLimit is the most candles returned by a GetCandles call.
+const MaxCandlesLimit = 1000
+
+// WSCandle is an OHLCV candle of a market.
+type WSCandle struct {
+	Timestamp   time.Time       `json:"timestamp"`
+	Open        decimal.Decimal `json:"open"`
+	Close       decimal.Decimal `json:"close"`
+	Min         decimal.Decimal `json:"min"`
+	Max         decimal.Decimal `json:"max"`
+	Volume      decimal.Decimal `json:"volume"`      // in base currency
+	VolumeQuote decimal.Decimal `json:"volumeQuote"` // in quote currency
+}
+
+// WSNotificationCandlesResponse is the snapshotCandles and updateCandles
+// notification type on websocket. The first notification after subscribing is
+// a snapshot of the recent candles, oldest first; updates carry the candle in
+// progress.
+type WSNotificationCandlesResponse struct {
+	Data     []WSCandle `json:"data"`
+	Symbol   string     `json:"symbol"`
+	Period   string     `json:"period"`
+	Snapshot bool       `json:"-"`
+}
+
+// WSSubscribeCandlesRequest is request type on websocket candles subscription.
+type WSSubscribeCandlesRequest struct {
+	Symbol string `json:"symbol,required"`
+	Period string `json:"period,required"` // M1, M3, M5, M15, M30, H1, H4, D1, D7 or 1M
+	Limit  int    `json:"limit,omitempty"` // candles in the snapshot, 100 by default
+}
+
+func candlesKey(symbol string, period string) string {
+	return symbol + "/" + period
+}
+
+// SubscribeCandles subscribes to the candles of the specified market and period.
+func (c *WSClient) SubscribeCandles(symbol string, period string) (<-chan WSNotificationCandlesResponse, error) {
+	err := c.subscriptionOp("subscribeCandles", WSSubscribeCandlesRequest{Symbol: symbol, Period: period})
+	if err != nil {
+		return nil, errors.Annotate(err, "Hitbtc SubscribeCandles")
+	}
+
+	notifications := &c.updates.notifications
+	notifications.mutex.Lock()
+	defer notifications.mutex.Unlock()
+	key := candlesKey(symbol, period)
+	if notifications.CandlesFeed[key] == nil {
+		notifications.CandlesFeed[key] = make(chan WSNotificationCandlesResponse, notifications.bufferSize)
+	}
+
+	return notifications.CandlesFeed[key], nil
+}
+
+// UnsubscribeCandles unsubscribes from the candles of the specified market and period.
+//
+// This closes also the connected channel of updates.
+func (c *WSClient) UnsubscribeCandles(symbol string, period string) error {
+	err := c.subscriptionOp("unsubscribeCandles", WSSubscribeCandlesRequest{Symbol: symbol, Period: period})
+	if err != nil {
+		return errors.Annotate(err, "Hitbtc UnsubscribeCandles")
+	}
+
+	key := candlesKey(symbol, period)
+	c.updates.notifications.mutex.Lock()
+	if channel, ok := c.updates.notifications.CandlesFeed[key]; ok {
+		close(channel)
+		delete(c.updates.notifications.CandlesFeed, key)
+	}
+	c.updates.notifications.mutex.Unlock()
+
+	return nil
+}
+
+// deliverCandles sends msg to the candles channel of its symbol and period.
+// Candles are not dropped, the sender waits for a full channel.
+func (n *notificationChannels) deliverCandles(msg WSNotificationCandlesResponse) {
+	n.closing.RLock()
+	defer n.closing.RUnlock()
+	n.mutex.Lock()
+	channel := n.CandlesFeed[candlesKey(msg.Symbol, msg.Period)]
+	n.mutex.Unlock()
+	if channel != nil {
+		select {
+		case channel <- msg:
+		case <-n.done:
+		}
+	}
+}
+
+// candleV3 is the v3 REST candle, which names the quote volume differently.
+type candleV3 struct {
+	WSCandle
+	VolumeQuote decimal.Decimal `json:"volume_quote"`
+}
+
+// GetCandles returns at most limit candles of a market and period opened in
+// [from, till], oldest first, in the model of the feed.
+func (b *HitBtc) GetCandles(ctx context.Context, market string, period string, from, till time.Time, limit int) ([]WSCandle, error) {
+	symbol := strings.ToUpper(market)
+	payload := map[string]string{
+		"period": period,
+		"sort":   "ASC",
+		"from":   from.UTC().Format(time.RFC3339),
+		"till":   till.UTC().Format(time.RFC3339),
+		"limit":  strconv.Itoa(limit),
+	}
+	resource := "public/candles/" + url.PathEscape(symbol)
+	if b.v3() {
+		var response []candleV3
+		if err := b.call(ctx, "GET", resource, payload, false, &response); err != nil {
+			return nil, err
+		}
+		candles := make([]WSCandle, len(response))
+		for i, candle := range response {
+			candles[i] = candle.WSCandle
+			candles[i].VolumeQuote = candle.VolumeQuote
+		}
+		return candles, nil
+	}
+	var candles []WSCandle
+	if err := b.call(ctx, "GET", resource, payload, false, &candles); err != nil {
+		return nil, err
+	}
+	return candles, nil
+}
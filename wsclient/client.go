@@ -1,6 +1,7 @@
 package wsclient
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -10,24 +11,57 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/crypto-api-server/requestid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// StatusError is returned for upstream responses with an unexpected HTTP status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration // zero when the response had no Retry-After header
+}
+
+func (e *StatusError) Error() string {
+	return e.Status
+}
+
 type client struct {
 	apiKey      string
 	apiSecret   string
 	httpClient  *http.Client
 	httpTimeout time.Duration
 	debug       bool
+	limiter     *rateLimiter
+	retry       RetryPolicy
+	breaker     *circuitBreaker
+	base        string
+	recorder    *Recorder
 }
 
 // NewClient return a new HitBtc HTTP client
-func NewClient(apiKey, apiSecret string) (c *client) {
-	return &client{apiKey, apiSecret, &http.Client{}, 30 * time.Second, false}
+func NewClient(apiKey, apiSecret string, options ...Option) (c *client) {
+	c = &client{apiKey, apiSecret, &http.Client{}, 30 * time.Second, false, newRateLimiter(), DefaultRetryPolicy, newCircuitBreaker(5, 30*time.Second), API_BASE, nil}
+	for _, option := range options {
+		option(c)
+	}
+	return c
 }
 
 // NewClient returns a new HitBtc HTTP client with custom timeout
 func NewClientWithCustomTimeout(apiKey, apiSecret string, timeout time.Duration) (c *client) {
-	return &client{apiKey, apiSecret, &http.Client{}, timeout, false}
+	return &client{apiKey, apiSecret, &http.Client{}, timeout, false, newRateLimiter(), DefaultRetryPolicy, newCircuitBreaker(5, 30*time.Second), API_BASE, nil}
+}
+
+// logPrefix returns the request ID of ctx, if any, formatted for the log
+// lines.
+func logPrefix(ctx context.Context) string {
+	if id := requestid.FromContext(ctx); id != "" {
+		return "[" + id + "] "
+	}
+	return ""
 }
 
 func (c client) dumpRequest(r *http.Request) {
@@ -35,11 +69,16 @@ func (c client) dumpRequest(r *http.Request) {
 		log.Print("dumpReq ok: <nil>")
 		return
 	}
+	// the credentials must not end up in the logs
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		r.Header.Set("Authorization", "[redacted]")
+		defer r.Header.Set("Authorization", authorization)
+	}
 	dump, err := httputil.DumpRequest(r, true)
 	if err != nil {
-		log.Print("dumpReq err:", err)
+		log.Print(logPrefix(r.Context()), "dumpReq err:", err)
 	} else {
-		log.Print("dumpReq ok:", string(dump))
+		log.Print(logPrefix(r.Context()), "dumpReq ok:", string(dump))
 	}
 }
 
@@ -48,11 +87,15 @@ func (c client) dumpResponse(r *http.Response) {
 		log.Print("dumpResponse ok: <nil>")
 		return
 	}
+	prefix := ""
+	if r.Request != nil {
+		prefix = logPrefix(r.Request.Context())
+	}
 	dump, err := httputil.DumpResponse(r, true)
 	if err != nil {
-		log.Print("dumpResponse err:", err)
+		log.Print(prefix, "dumpResponse err:", err)
 	} else {
-		log.Print("dumpResponse ok:", string(dump))
+		log.Print(prefix, "dumpResponse ok:", string(dump))
 	}
 }
 
@@ -79,19 +122,65 @@ func (c *client) doTimeoutRequest(timer *time.Timer, req *http.Request) (*http.R
 	case r := <-done:
 		return r.resp, r.err
 	case <-timer.C:
-		return nil, errors.New("timeout on reading data from HitBtc API")
+		return nil, errTimeout
 	}
 }
 
-// do prepare and process HTTP request to HitBtc API
-func (c *client) do(method string, resource string, payload map[string]string, authNeeded bool) (response []byte, err error) {
-	connectTimer := time.NewTimer(c.httpTimeout)
+// do prepare and process HTTP request to HitBtc API, retrying transient
+// errors according to the retry policy. Requests fail fast with
+// ErrCircuitOpen while the circuit breaker is open, and are abandoned when
+// ctx is done. Each call is traced in a span covering its retries.
+func (c *client) do(ctx context.Context, method string, resource string, payload map[string]string, authNeeded bool) (response []byte, err error) {
+	ctx, span := tracer.Start(ctx, "hitbtc "+method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("http.method", method),
+		attribute.String("hitbtc.resource", resource),
+	))
+	attempts := 0
+	defer func() {
+		span.SetAttributes(attribute.Int("hitbtc.attempts", attempts))
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			span.SetAttributes(attribute.Int("http.status_code", statusErr.StatusCode))
+		}
+		endSpan(span, err)
+	}()
+	if !c.breaker.allow(time.Now()) {
+		return nil, ErrCircuitOpen
+	}
+	defer func() { c.breaker.done(err, time.Now()) }()
+	for attempt := 0; ; attempt++ {
+		attempts = attempt + 1
+		response, err = c.doOnce(ctx, method, resource, payload, authNeeded)
+		if err == nil || attempt >= c.retry.MaxRetries || !retryable(err) {
+			return
+		}
+		if method != "GET" && !c.retry.RetryUnsafe {
+			return
+		}
+		delay, ok := c.retry.delay(attempt, err)
+		if !ok {
+			return
+		}
+		if c.debug {
+			log.Printf("%sretrying %s %s in %s: %v", logPrefix(ctx), method, resource, delay, err)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return response, ctx.Err()
+		}
+	}
+}
+
+func (c *client) doOnce(ctx context.Context, method string, resource string, payload map[string]string, authNeeded bool) (response []byte, err error) {
 
 	var rawurl string
 	if strings.HasPrefix(resource, "http") {
 		rawurl = resource
 	} else {
-		rawurl = fmt.Sprintf("%s/%s", API_BASE, resource)
+		rawurl = fmt.Sprintf("%s/%s", c.base, resource)
 	}
 	var formData string
 	if method == "GET" {
@@ -114,24 +203,45 @@ func (c *client) do(method string, resource string, payload map[string]string, a
 		}
 		formData = formValues.Encode()
 	}
-	req, err := http.NewRequest(method, rawurl, strings.NewReader(formData))
+	req, err := http.NewRequestWithContext(ctx, method, rawurl, strings.NewReader(formData))
 	if err != nil {
 		return
 	}
 
 	req.Header.Add("Accept", "application/json")
+	if method != "GET" && formData != "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
 
-	// Auth
+	if authNeeded && (len(c.apiKey) == 0 || len(c.apiSecret) == 0) {
+		err = errors.New("you need to set api key and api secret to call this method")
+		return
+	}
+
+	// bursts of requests queue here rather than getting the API key banned
+	if err = c.limiter.wait(ctx, resource); err != nil {
+		return
+	}
+
+	// Auth, after the wait so that the v3 signature is fresh
 	if authNeeded {
-		if len(c.apiKey) == 0 || len(c.apiSecret) == 0 {
-			err = errors.New("you need to set api key and api secret to call this method")
-			return
+		if c.base == API_V3_BASE {
+			body := ""
+			if method != "GET" {
+				body = formData
+			}
+			req.Header.Set("Authorization", c.signV3(method, req.URL, body, time.Now()))
+		} else {
+			req.SetBasicAuth(c.apiKey, c.apiSecret)
 		}
-		req.SetBasicAuth(c.apiKey, c.apiSecret)
 	}
 
+	connectTimer := time.NewTimer(c.httpTimeout)
 	resp, err := c.doTimeoutRequest(connectTimer, req)
 	if err != nil {
+		if ctx.Err() == nil && !errors.Is(err, ErrUpstreamUnavailable) {
+			err = &unavailableError{err}
+		}
 		return
 	}
 
@@ -140,8 +250,15 @@ func (c *client) do(method string, resource string, payload map[string]string, a
 	if err != nil {
 		return response, err
 	}
-	if resp.StatusCode != 200 && resp.StatusCode != 401 {
-		err = errors.New(resp.Status)
+	if c.recorder != nil {
+		c.record(method, rawurl, formData, resp.StatusCode, response)
+	}
+	if resp.StatusCode != 200 {
+		err = &StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 	return response, err
 }
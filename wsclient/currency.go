@@ -1,14 +1,19 @@
 package wsclient
 
-// Currency represents currency data.
+import "github.com/shopspring/decimal"
+
+// Currency represents currency data. PayoutFee is the withdrawal fee, of the
+// default network for v3.
 type Currency struct {
-	Id                 string `json:"id"`
-	FullName           string `json:"fullName"`
-	Crypto             bool   `json:"crypto"`
-	PayinEnabled       bool   `json:"payinEnabled"`
-	PayinPaymentId     bool   `json:"payinPaymentId"`
-	PayinConfirmations uint   `json:"payinConfirmations"`
-	PayoutEnabled      bool   `json:"payoutEnabled"`
-	PayoutIsPaymentId  bool   `json:"payoutIsPaymentId"`
-	TransferEnabled    bool   `json:"transferEnabled"`
+	Id                 string          `json:"id"`
+	FullName           string          `json:"fullName"`
+	Crypto             bool            `json:"crypto"`
+	PayinEnabled       bool            `json:"payinEnabled"`
+	PayinPaymentId     bool            `json:"payinPaymentId"`
+	PayinConfirmations uint            `json:"payinConfirmations"`
+	PayoutEnabled      bool            `json:"payoutEnabled"`
+	PayoutIsPaymentId  bool            `json:"payoutIsPaymentId"`
+	TransferEnabled    bool            `json:"transferEnabled"`
+	Delisted           bool            `json:"delisted"`
+	PayoutFee          decimal.Decimal `json:"payoutFee"`
 }
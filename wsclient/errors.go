@@ -0,0 +1,78 @@
+package wsclient
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kinds of upstream errors. The errors returned by the client match them
+// with errors.Is, whether they come from an API error, an HTTP status or the
+// transport.
+var (
+	ErrSymbolNotFound      = errors.New("symbol not found")
+	ErrRateLimited         = errors.New("rate limited by HitBTC")
+	ErrAuth                = errors.New("HitBTC authentication failed")
+	ErrUpstreamUnavailable = errors.New("HitBTC API unavailable")
+)
+
+// HitBTC API error codes, see https://api.hitbtc.com/#error-codes
+const (
+	codeRateLimited        = 429
+	codeInternal           = 500
+	codeServiceUnavailable = 503
+	codeGatewayTimeout     = 504
+	codeAuthRequired       = 1001
+	codeAuthMethod         = 1004
+	codeSymbolNotFound     = 2001
+)
+
+// Is matches the API error against the error kinds by code, falling back to
+// the HTTP status of the response.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrSymbolNotFound:
+		return e.Code == codeSymbolNotFound
+	case ErrRateLimited:
+		return e.Code == codeRateLimited || e.StatusCode == http.StatusTooManyRequests
+	case ErrAuth:
+		return (e.Code >= codeAuthRequired && e.Code <= codeAuthMethod) || e.StatusCode == http.StatusUnauthorized
+	case ErrUpstreamUnavailable:
+		switch e.Code {
+		case codeInternal, codeServiceUnavailable, codeGatewayTimeout:
+			return true
+		}
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// Is matches the status of the response against the error kinds.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrAuth:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrUpstreamUnavailable:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// unavailableError marks transport failures, timeouts and the open circuit
+// breaker as ErrUpstreamUnavailable, keeping the underlying error.
+type unavailableError struct {
+	err error
+}
+
+func (e *unavailableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *unavailableError) Unwrap() error {
+	return e.err
+}
+
+func (e *unavailableError) Is(target error) bool {
+	return target == ErrUpstreamUnavailable
+}
@@ -2,9 +2,9 @@
 package wsclient
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
+	"net/http"
 	"strings"
 	"time"
 )
@@ -14,8 +14,8 @@ const (
 )
 
 // New returns an instantiated HitBTC struct
-func New(apiKey, apiSecret string) *HitBtc {
-	client := NewClient(apiKey, apiSecret)
+func New(apiKey, apiSecret string, options ...Option) *HitBtc {
+	client := NewClient(apiKey, apiSecret, options...)
 	return &HitBtc{client}
 }
 
@@ -25,27 +25,39 @@ func NewWithCustomTimeout(apiKey, apiSecret string, timeout time.Duration) *HitB
 	return &HitBtc{client}
 }
 
-// handleErr gets JSON response from livecoin API en deal with error
-func handleErr(r interface{}) error {
-	switch v := r.(type) {
-	case map[string]interface{}:
-		error := r.(map[string]interface{})["error"]
-		if error != nil {
-			switch v := error.(type) {
-			case map[string]interface{}:
-				errorMessage := error.(map[string]interface{})["message"]
-				return errors.New(errorMessage.(string))
-			default:
-				return fmt.Errorf("i don't know about type %T ", v)
-			}
-		}
-	case []interface{}:
-		return nil
-	default:
-		return fmt.Errorf("i don't know about type %T ", v)
+// APIError is an error reported by the HitBTC API.
+type APIError struct {
+	StatusCode  int    `json:"-"`
+	Code        int    `json:"code"`
+	Message     string `json:"message"`
+	Description string `json:"description"`
+}
+
+func (e *APIError) Error() string {
+	if e.Description != "" {
+		return e.Message + ": " + e.Description
 	}
+	return e.Message
+}
 
-	return nil
+// call does the request and decodes the response into out, returning the
+// API error of the response body when there is one.
+func (b *HitBtc) call(ctx context.Context, method string, resource string, payload map[string]string, authNeeded bool, out interface{}) error {
+	r, err := b.client.do(ctx, method, resource, payload, authNeeded)
+	var apiResponse struct {
+		Error *APIError `json:"error"`
+	}
+	if len(r) > 0 && json.Unmarshal(r, &apiResponse) == nil && apiResponse.Error != nil {
+		apiResponse.Error.StatusCode = http.StatusOK
+		if statusErr, ok := err.(*StatusError); ok {
+			apiResponse.Error.StatusCode = statusErr.StatusCode
+		}
+		return apiResponse.Error
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(r, out)
 }
 
 // HitBtc represent a HitBTC client
@@ -58,70 +70,68 @@ func (b *HitBtc) SetDebug(enable bool) {
 	b.client.debug = enable
 }
 
+// SetRetryPolicy sets the retries of transient upstream errors.
+func (b *HitBtc) SetRetryPolicy(policy RetryPolicy) {
+	b.client.retry = policy
+}
+
+// SetCircuitBreaker makes the client fail fast with ErrCircuitOpen for
+// cooldown after threshold consecutive transient errors. A zero threshold
+// disables the breaker.
+func (b *HitBtc) SetCircuitBreaker(threshold int, cooldown time.Duration) {
+	b.client.breaker = newCircuitBreaker(threshold, cooldown)
+}
+
+// CircuitState returns the state of the circuit breaker: CircuitClosed,
+// CircuitOpen or CircuitHalfOpen.
+func (b *HitBtc) CircuitState() string {
+	return b.client.breaker.current()
+}
+
+// SetRateLimit sets the requests per second allowed for a request class:
+// LimitMarketData, LimitTrading or LimitOther.
+func (b *HitBtc) SetRateLimit(class string, rate float64) error {
+	return b.client.limiter.setRate(class, rate)
+}
+
+// RateLimitStats returns the requests counted by the client-side rate limiter
+// per request class.
+func (b *HitBtc) RateLimitStats() map[string]RateLimitStats {
+	return b.client.limiter.stats()
+}
+
 // GetCurrencies is used to get all supported currencies at HitBtc along with other meta data.
-func (b *HitBtc) GetCurrencies() (currencies []Currency, err error) {
-	r, err := b.client.do("GET", "public/currency", nil, false)
-	if err != nil {
-		return
+func (b *HitBtc) GetCurrencies(ctx context.Context) (currencies []Currency, err error) {
+	if b.v3() {
+		return b.getCurrenciesV3(ctx)
 	}
-	var response interface{}
-	if err = json.Unmarshal(r, &response); err != nil {
-		return
-	}
-	if err = handleErr(response); err != nil {
-		return
-	}
-	err = json.Unmarshal(r, &currencies)
+	err = b.call(ctx, "GET", "public/currency", nil, false, &currencies)
 	return
 }
 
 // GetSymbols is used to get the open and available trading markets at HitBtc along with other meta data.
-func (b *HitBtc) GetSymbols() (symbols []Symbol, err error) {
-	r, err := b.client.do("GET", "public/symbol", nil, false)
-	if err != nil {
-		return
-	}
-	var response interface{}
-	if err = json.Unmarshal(r, &response); err != nil {
-		return
+func (b *HitBtc) GetSymbols(ctx context.Context) (symbols []Symbol, err error) {
+	if b.v3() {
+		return b.getSymbolsV3(ctx)
 	}
-	if err = handleErr(response); err != nil {
-		return
-	}
-	err = json.Unmarshal(r, &symbols)
+	err = b.call(ctx, "GET", "public/symbol", nil, false, &symbols)
 	return
 }
 
 // GetTicker is used to get the current ticker values for a market.
-func (b *HitBtc) GetTicker(market string) (ticker Ticker, err error) {
-	r, err := b.client.do("GET", "public/ticker/"+strings.ToUpper(market), nil, false)
-	if err != nil {
-		return
-	}
-	var response interface{}
-	if err = json.Unmarshal(r, &response); err != nil {
-		return
-	}
-	if err = handleErr(response); err != nil {
-		return
+func (b *HitBtc) GetTicker(ctx context.Context, market string) (ticker Ticker, err error) {
+	if b.v3() {
+		return b.getTickerV3(ctx, market)
 	}
-	err = json.Unmarshal(r, &ticker)
+	err = b.call(ctx, "GET", "public/ticker/"+strings.ToUpper(market), nil, false, &ticker)
 	return
 }
 
 // GetAllTicker is used to get the current ticker values for all markets.
-func (b *HitBtc) GetAllTicker() (tickers Tickers, err error) {
-	r, err := b.client.do("GET", "public/ticker", nil, false)
-	if err != nil {
-		return
-	}
-	var response interface{}
-	if err = json.Unmarshal(r, &response); err != nil {
-		return
-	}
-	if err = handleErr(response); err != nil {
-		return
+func (b *HitBtc) GetAllTicker(ctx context.Context) (tickers Tickers, err error) {
+	if b.v3() {
+		return b.getAllTickerV3(ctx)
 	}
-	err = json.Unmarshal(r, &tickers)
+	err = b.call(ctx, "GET", "public/ticker", nil, false, &tickers)
 	return
 }
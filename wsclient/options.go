@@ -0,0 +1,71 @@
+package wsclient
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Option configures the HTTP client of New.
+type Option func(c *client)
+
+// WithHTTPClient makes the REST calls go through httpClient, for tests or
+// custom transports.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout sets the timeout of the REST calls, 30 seconds by default.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *client) {
+		c.httpTimeout = timeout
+	}
+}
+
+// TransportConfig configures the transport of the REST calls. Zero fields
+// keep the defaults of http.DefaultTransport.
+type TransportConfig struct {
+	// ProxyURL overrides the HTTPS_PROXY environment variable
+	ProxyURL          *url.URL
+	TLSConfig         *tls.Config
+	DialTimeout       time.Duration
+	KeepAlive         time.Duration
+	DisableKeepAlives bool
+	IdleConnTimeout   time.Duration
+}
+
+// WithTransport makes the REST calls go through a transport built from config.
+func WithTransport(config TransportConfig) Option {
+	return func(c *client) {
+		c.httpClient = &http.Client{Transport: config.transport()}
+	}
+}
+
+func (config TransportConfig) transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.ProxyURL != nil {
+		transport.Proxy = http.ProxyURL(config.ProxyURL)
+	}
+	if config.TLSConfig != nil {
+		transport.TLSClientConfig = config.TLSConfig
+	}
+	if config.DialTimeout > 0 || config.KeepAlive != 0 {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		if config.DialTimeout > 0 {
+			dialer.Timeout = config.DialTimeout
+		}
+		if config.KeepAlive != 0 {
+			dialer.KeepAlive = config.KeepAlive
+		}
+		transport.DialContext = dialer.DialContext
+	}
+	transport.DisableKeepAlives = config.DisableKeepAlives
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+	return transport
+}
@@ -0,0 +1,66 @@
+package wsclient
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// OrderBookLevel is the quantity offered at a price.
+type OrderBookLevel struct {
+	Price decimal.Decimal `json:"price"`
+	Size  decimal.Decimal `json:"size"`
+}
+
+// OrderBook is a snapshot of the order book of a market, asks by increasing
+// price and bids by decreasing price.
+type OrderBook struct {
+	Symbol    string           `json:"symbol"`
+	Ask       []OrderBookLevel `json:"ask"`
+	Bid       []OrderBookLevel `json:"bid"`
+	Timestamp time.Time        `json:"timestamp"`
+}
+
+// orderBookV3 is the v3 order book, levels are [price, size] pairs.
+type orderBookV3 struct {
+	Ask       [][2]decimal.Decimal `json:"ask"`
+	Bid       [][2]decimal.Decimal `json:"bid"`
+	Timestamp time.Time            `json:"timestamp"`
+}
+
+func levelsV3(levels [][2]decimal.Decimal) []OrderBookLevel {
+	result := make([]OrderBookLevel, len(levels))
+	for i, level := range levels {
+		result[i] = OrderBookLevel{Price: level[0], Size: level[1]}
+	}
+	return result
+}
+
+// GetOrderBook returns the first limit price levels of each side of the order
+// book of a market, 0 for the full book.
+func (b *HitBtc) GetOrderBook(ctx context.Context, market string, limit int) (OrderBook, error) {
+	symbol := strings.ToUpper(market)
+	resource := "public/orderbook/" + url.PathEscape(symbol)
+	if b.v3() {
+		var response orderBookV3
+		if err := b.call(ctx, "GET", resource, map[string]string{"depth": strconv.Itoa(limit)}, false, &response); err != nil {
+			return OrderBook{}, err
+		}
+		return OrderBook{
+			Symbol:    symbol,
+			Ask:       levelsV3(response.Ask),
+			Bid:       levelsV3(response.Bid),
+			Timestamp: response.Timestamp,
+		}, nil
+	}
+	var book OrderBook
+	if err := b.call(ctx, "GET", resource, map[string]string{"limit": strconv.Itoa(limit)}, false, &book); err != nil {
+		return OrderBook{}, err
+	}
+	book.Symbol = symbol
+	return book, nil
+}
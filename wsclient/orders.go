@@ -0,0 +1,198 @@
+package wsclient
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Order is an order of the account.
+type Order struct {
+	ID            int64            `json:"id"`
+	ClientOrderID string           `json:"clientOrderId"`
+	Symbol        string           `json:"symbol"`
+	Side          string           `json:"side"`
+	Status        string           `json:"status"`
+	Type          string           `json:"type"`
+	TimeInForce   string           `json:"timeInForce"`
+	Quantity      decimal.Decimal  `json:"quantity"`
+	Price         decimal.Decimal  `json:"price"`
+	CumQuantity   decimal.Decimal  `json:"cumQuantity"`
+	StopPrice     *decimal.Decimal `json:"stopPrice,omitempty"`
+	ExpireTime    *time.Time       `json:"expireTime,omitempty"`
+	PostOnly      bool             `json:"postOnly"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	UpdatedAt     time.Time        `json:"updatedAt"`
+}
+
+// OrderRequest describes a new order. Price is required for limit orders and
+// StopPrice for stop orders.
+type OrderRequest struct {
+	ClientOrderID string
+	Symbol        string
+	Side          string // buy or sell
+	Type          string // limit, market, stopLimit or stopMarket
+	TimeInForce   string // GTC, IOC, FOK, Day or GTD
+	Quantity      decimal.Decimal
+	Price         decimal.Decimal
+	StopPrice     decimal.Decimal
+	ExpireTime    time.Time // for GTD
+	PostOnly      bool
+}
+
+// payload returns the v2 parameters of the order.
+func (request OrderRequest) payload() map[string]string {
+	payload := map[string]string{
+		"symbol":   request.Symbol,
+		"side":     request.Side,
+		"quantity": request.Quantity.String(),
+	}
+	if request.ClientOrderID != "" {
+		payload["clientOrderId"] = request.ClientOrderID
+	}
+	if request.Type != "" {
+		payload["type"] = request.Type
+	}
+	if request.TimeInForce != "" {
+		payload["timeInForce"] = request.TimeInForce
+	}
+	if !request.Price.IsZero() {
+		payload["price"] = request.Price.String()
+	}
+	if !request.StopPrice.IsZero() {
+		payload["stopPrice"] = request.StopPrice.String()
+	}
+	if !request.ExpireTime.IsZero() {
+		payload["expireTime"] = request.ExpireTime.UTC().Format(time.RFC3339)
+	}
+	if request.PostOnly {
+		payload["postOnly"] = "true"
+	}
+	return payload
+}
+
+// PlaceOrder places a new order.
+func (b *HitBtc) PlaceOrder(ctx context.Context, request OrderRequest) (order Order, err error) {
+	if b.v3() {
+		return b.placeOrderV3(ctx, request.payload())
+	}
+	err = b.call(ctx, "POST", "order", request.payload(), true, &order)
+	return
+}
+
+// CancelOrder cancels the order with the client order id.
+func (b *HitBtc) CancelOrder(ctx context.Context, clientOrderID string) (order Order, err error) {
+	if b.v3() {
+		return b.cancelOrderV3(ctx, clientOrderID)
+	}
+	err = b.call(ctx, "DELETE", "order/"+url.PathEscape(clientOrderID), nil, true, &order)
+	return
+}
+
+// CancelAllOrders cancels the active orders of symbol, or of every symbol
+// when symbol is empty.
+func (b *HitBtc) CancelAllOrders(ctx context.Context, symbol string) (orders []Order, err error) {
+	payload := map[string]string{}
+	if symbol != "" {
+		payload["symbol"] = symbol
+	}
+	if b.v3() {
+		return b.listOrdersV3(ctx, "DELETE", "spot/order", payload)
+	}
+	err = b.call(ctx, "DELETE", "order", payload, true, &orders)
+	return
+}
+
+// GetActiveOrders returns the active orders of symbol, or of every symbol
+// when symbol is empty.
+func (b *HitBtc) GetActiveOrders(ctx context.Context, symbol string) (orders []Order, err error) {
+	payload := map[string]string{}
+	if symbol != "" {
+		payload["symbol"] = symbol
+	}
+	if b.v3() {
+		return b.listOrdersV3(ctx, "GET", "spot/order", payload)
+	}
+	err = b.call(ctx, "GET", "order", payload, true, &orders)
+	return
+}
+
+// HistoryFilter selects account history records. Zero fields are not sent.
+type HistoryFilter struct {
+	Symbol string
+	From   time.Time
+	Till   time.Time
+	Limit  int // 100 by default, at most 1000
+	Offset int
+}
+
+func (f HistoryFilter) payload() map[string]string {
+	payload := map[string]string{}
+	if f.Symbol != "" {
+		payload["symbol"] = f.Symbol
+	}
+	if !f.From.IsZero() {
+		payload["from"] = f.From.UTC().Format(time.RFC3339Nano)
+	}
+	if !f.Till.IsZero() {
+		payload["till"] = f.Till.UTC().Format(time.RFC3339Nano)
+	}
+	if f.Limit > 0 {
+		payload["limit"] = strconv.Itoa(f.Limit)
+	}
+	if f.Offset > 0 {
+		payload["offset"] = strconv.Itoa(f.Offset)
+	}
+	return payload
+}
+
+// GetOrderHistory returns the closed orders of the account, newest first.
+func (b *HitBtc) GetOrderHistory(ctx context.Context, filter HistoryFilter) (orders []Order, err error) {
+	if b.v3() {
+		return b.listOrdersV3(ctx, "GET", "spot/history/order", filter.payload())
+	}
+	err = b.call(ctx, "GET", "history/order", filter.payload(), true, &orders)
+	return
+}
+
+// AccountTrade is an execution of an order of the account. Taker is false
+// when the order provided liquidity.
+type AccountTrade struct {
+	ID            int64           `json:"id"`
+	OrderID       int64           `json:"orderId"`
+	ClientOrderID string          `json:"clientOrderId"`
+	Symbol        string          `json:"symbol"`
+	Side          string          `json:"side"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	Price         decimal.Decimal `json:"price"`
+	Fee           decimal.Decimal `json:"fee"`
+	Taker         bool            `json:"taker"`
+	Timestamp     time.Time       `json:"timestamp"`
+}
+
+// GetTradeHistory returns the executed trades of the account, newest first.
+func (b *HitBtc) GetTradeHistory(ctx context.Context, filter HistoryFilter) (trades []AccountTrade, err error) {
+	if b.v3() {
+		return b.getTradeHistoryV3(ctx, filter)
+	}
+	err = b.call(ctx, "GET", "history/trades", filter.payload(), true, &trades)
+	return
+}
+
+// TradingFee is the commission rates of the account for a symbol.
+type TradingFee struct {
+	TakeLiquidityRate    decimal.Decimal `json:"takeLiquidityRate"`
+	ProvideLiquidityRate decimal.Decimal `json:"provideLiquidityRate"`
+}
+
+// GetTradingFee returns the commission rates of the account for symbol.
+func (b *HitBtc) GetTradingFee(ctx context.Context, symbol string) (fee TradingFee, err error) {
+	if b.v3() {
+		return b.getTradingFeeV3(ctx, symbol)
+	}
+	err = b.call(ctx, "GET", "trading/fee/"+url.PathEscape(symbol), nil, true, &fee)
+	return
+}
@@ -0,0 +1,160 @@
+package wsclient
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request classes of the HitBTC rate limits.
+const (
+	LimitMarketData = "marketData" // public endpoints, 100 requests per second per IP
+	LimitTrading    = "trading"    // order placement and cancellation, 300 per second per user
+	LimitOther      = "other"      // account and history endpoints, 10 per second per user
+)
+
+// RateLimitStats counts the requests that went through a limiter.
+type RateLimitStats struct {
+	Requests uint64 `json:"requests"`
+	// Delayed counts the requests that waited for a token
+	Delayed     uint64  `json:"delayed"`
+	WaitSeconds float64 `json:"waitSeconds"`
+}
+
+// tokenBucket is a token bucket filled with rate tokens per second up to
+// burst. Requests arriving on an empty bucket reserve a future token and
+// sleep until it's due, so they queue in arrival order.
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	stats  RateLimitStats
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// reserve takes a token and returns how long to wait before using it.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	b.stats.Requests++
+	if b.tokens >= 0 {
+		return 0
+	}
+	wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.stats.Delayed++
+	b.stats.WaitSeconds += wait.Seconds()
+	return wait
+}
+
+// release gives back a reserved token that won't be used.
+func (b *tokenBucket) release() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// wait blocks until a token is available or ctx is done, in which case the
+// reserved token is given back for the next requests.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	wait := b.reserve(time.Now())
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.release()
+		return ctx.Err()
+	}
+}
+
+// setRate changes the rate, the burst follows it.
+func (b *tokenBucket) setRate(rate float64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.rate = rate
+	b.burst = math.Max(1, math.Floor(rate))
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+func (b *tokenBucket) snapshot() RateLimitStats {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.stats
+}
+
+// rateLimiter holds a bucket per request class.
+type rateLimiter struct {
+	buckets map[string]*tokenBucket
+}
+
+// newRateLimiter returns a limiter with the documented HitBTC limits.
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: map[string]*tokenBucket{
+		LimitMarketData: newTokenBucket(100, 100),
+		LimitTrading:    newTokenBucket(300, 300),
+		LimitOther:      newTokenBucket(10, 10),
+	}}
+}
+
+// limitClass returns the request class of resource, a v2 or v3 path.
+func limitClass(resource string) string {
+	resource = strings.TrimPrefix(resource, API_BASE+"/")
+	resource = strings.TrimPrefix(resource, API_V3_BASE+"/")
+	// the v3 spot orders are limited like the v2 orders
+	resource = strings.TrimPrefix(resource, "spot/")
+	switch {
+	case strings.HasPrefix(resource, "public/"):
+		return LimitMarketData
+	case resource == "order" || strings.HasPrefix(resource, "order/"):
+		return LimitTrading
+	default:
+		return LimitOther
+	}
+}
+
+func (l *rateLimiter) wait(ctx context.Context, resource string) error {
+	return l.buckets[limitClass(resource)].wait(ctx)
+}
+
+func (l *rateLimiter) setRate(class string, rate float64) error {
+	bucket, ok := l.buckets[class]
+	if !ok {
+		return fmt.Errorf("unknown rate limit class %q", class)
+	}
+	if rate <= 0 {
+		return fmt.Errorf("invalid %s rate limit %v", class, rate)
+	}
+	bucket.setRate(rate)
+	return nil
+}
+
+func (l *rateLimiter) stats() map[string]RateLimitStats {
+	stats := make(map[string]RateLimitStats, len(l.buckets))
+	for class, bucket := range l.buckets {
+		stats[class] = bucket.snapshot()
+	}
+	return stats
+}
@@ -0,0 +1,132 @@
+package wsclient
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	b := newTokenBucket(10, 3)
+	now := b.last
+	for i := 0; i < 3; i++ {
+		if wait := b.reserve(now); wait != 0 {
+			t.Fatalf("request %d of the burst waits %v", i+1, wait)
+		}
+	}
+	// each request past the burst waits for a token more, 100ms apart
+	for i, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond} {
+		if wait := b.reserve(now); wait != want {
+			t.Errorf("request %d past the burst waits %v, want %v", i+1, wait, want)
+		}
+	}
+	stats := b.snapshot()
+	if stats.Requests != 5 || stats.Delayed != 2 || stats.WaitSeconds < 0.299 || stats.WaitSeconds > 0.301 {
+		t.Errorf("stats %+v, want 5 requests, 2 delayed for 0.3s", stats)
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	b := newTokenBucket(10, 3)
+	now := b.last
+	for i := 0; i < 4; i++ {
+		b.reserve(now)
+	}
+	// 250ms refill 2.5 tokens, one of them taken by the reserve
+	now = now.Add(250 * time.Millisecond)
+	if wait := b.reserve(now); wait != 0 {
+		t.Errorf("request after the refill waits %v", wait)
+	}
+	if b.tokens < 0.49 || b.tokens > 0.51 {
+		t.Errorf("%v tokens left, want 0.5", b.tokens)
+	}
+
+	// the bucket doesn't fill beyond the burst
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if wait := b.reserve(now); wait != 0 {
+			t.Fatalf("request %d after an hour waits %v", i+1, wait)
+		}
+	}
+	if wait := b.reserve(now); wait != 100*time.Millisecond {
+		t.Errorf("request past the refilled burst waits %v, want 100ms", wait)
+	}
+}
+
+// TestTokenBucketWaitCanceled checks that a canceled wait gives its token
+// back, so the next request doesn't wait for it.
+func TestTokenBucketWaitCanceled(t *testing.T) {
+	b := newTokenBucket(1, 1)
+	if err := b.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := b.wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("the canceled wait returned after %v", elapsed)
+	}
+	// without the release the next request would wait close to 2s
+	if wait := b.reserve(time.Now()); wait > 1100*time.Millisecond {
+		t.Errorf("the next request waits %v, want at most the 1s of its own token", wait)
+	}
+}
+
+func TestTokenBucketSetRate(t *testing.T) {
+	b := newTokenBucket(100, 100)
+	b.setRate(2.5)
+	if b.rate != 2.5 || b.burst != 2 || b.tokens != 2 {
+		t.Errorf("rate %v, burst %v, %v tokens, want 2.5, 2 and 2", b.rate, b.burst, b.tokens)
+	}
+	b.setRate(0.5)
+	if b.burst != 1 || b.tokens != 1 {
+		t.Errorf("burst %v, %v tokens, want at least a token of burst", b.burst, b.tokens)
+	}
+	now := b.last
+	b.reserve(now)
+	if wait := b.reserve(now); wait != 2*time.Second {
+		t.Errorf("request past the burst waits %v, want 2s at 0.5 per second", wait)
+	}
+}
+
+func TestRateLimiterSetRate(t *testing.T) {
+	l := newRateLimiter()
+	if err := l.setRate(LimitOther, 5); err != nil {
+		t.Fatal(err)
+	}
+	if bucket := l.buckets[LimitOther]; bucket.rate != 5 || bucket.burst != 5 {
+		t.Errorf("rate %v, burst %v, want 5 and 5", bucket.rate, bucket.burst)
+	}
+	if err := l.setRate("unknown", 5); err == nil {
+		t.Error("no error for an unknown class")
+	}
+	for _, rate := range []float64{0, -1} {
+		if err := l.setRate(LimitTrading, rate); err == nil {
+			t.Errorf("no error for a rate of %v", rate)
+		}
+	}
+}
+
+func TestLimitClass(t *testing.T) {
+	tests := []struct {
+		resource string
+		want     string
+	}{
+		{API_BASE + "/public/ticker/BTCUSD", LimitMarketData},
+		{API_V3_BASE + "/public/ticker", LimitMarketData},
+		{API_BASE + "/order", LimitTrading},
+		{API_BASE + "/order/abc", LimitTrading},
+		{API_V3_BASE + "/spot/order/abc", LimitTrading},
+		{API_BASE + "/orders", LimitOther},
+		{API_BASE + "/trading/balance", LimitOther},
+		{API_V3_BASE + "/spot/history/order", LimitOther},
+	}
+	for _, test := range tests {
+		if got := limitClass(test.resource); got != test.want {
+			t.Errorf("limitClass(%q) = %q, want %q", test.resource, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package wsclient
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// RESTRecord is a line of a recorded REST file: a call of the REST API and
+// its response.
+type RESTRecord struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	URL    string    `json:"url"`
+	// Form is the form data of POST, PUT and DELETE calls.
+	Form   string `json:"form,omitempty"`
+	Status int    `json:"status"`
+	// Body is the response, as a JSON string when it isn't JSON.
+	Body json.RawMessage `json:"body"`
+}
+
+// Recorder writes records as JSON lines to a writer. It is safe for
+// concurrent use.
+type Recorder struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	failed  bool
+}
+
+// NewRecorder creates a Recorder writing to w. The REST client records with
+// WithRecorder and the feed with WSClient.SetRecorder.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{encoder: json.NewEncoder(w)}
+}
+
+// Record writes a line, only the first failure is logged.
+func (r *Recorder) Record(record interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.encoder.Encode(record); err != nil && !r.failed {
+		r.failed = true
+		log.Print("recorder: ", err)
+	}
+}
+
+// WithRecorder records the REST calls and their responses with recorder.
+func WithRecorder(recorder *Recorder) Option {
+	return func(c *client) {
+		c.recorder = recorder
+	}
+}
+
+func (c *client) record(method string, rawurl string, form string, status int, body []byte) {
+	record := RESTRecord{Time: time.Now().UTC(), Method: method, URL: rawurl, Status: status, Body: body}
+	if method != "GET" {
+		record.Form = form
+	}
+	if !json.Valid(body) {
+		record.Body, _ = json.Marshal(string(body))
+	}
+	c.recorder.Record(record)
+}
+
+// SetRecorder records the notifications of the feed with recorder, as
+// FeedRecord lines that can be replayed with NewReplayClient.
+func (c *WSClient) SetRecorder(recorder *Recorder) {
+	c.updates.notifications.mutex.Lock()
+	c.updates.notifications.recorder = recorder
+	c.updates.notifications.mutex.Unlock()
+}
+
+func (n *notificationChannels) record(method string, params json.RawMessage) {
+	n.mutex.Lock()
+	recorder := n.recorder
+	n.mutex.Unlock()
+	if recorder != nil {
+		recorder.Record(FeedRecord{Time: time.Now().UTC(), Method: method, Params: params})
+	}
+}
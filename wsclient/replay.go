@@ -0,0 +1,163 @@
+package wsclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	jsonrpc2 "github.com/sourcegraph/jsonrpc2"
+)
+
+var errReplayUnsupported = errors.New("not supported when replaying a recorded feed")
+
+// maxRecordSize bounds a line of a recorded feed.
+const maxRecordSize = 1 << 20
+
+// FeedRecord is a line of a recorded feed file: a notification of the v2
+// feed and the time it was received.
+type FeedRecord struct {
+	Time   time.Time       `json:"time"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// replayFeed replays the notifications of a recorded feed file.
+type replayFeed struct {
+	path    string
+	speed   float64
+	symbols []string
+	start   sync.Once
+	done    chan struct{}
+	stop    sync.Once
+}
+
+// NewReplayClient creates a WSClient replaying the notifications recorded in
+// path instead of connecting to HitBTC. speed scales the recorded pace: 1
+// replays in real time, 10 ten times faster and 0 without waiting. The
+// replay starts with the first subscription.
+func NewReplayClient(path string, speed float64) (*WSClient, error) {
+	if speed < 0 {
+		return nil, errors.Errorf("invalid replay speed %v", speed)
+	}
+	symbols, err := recordedSymbols(path)
+	if err != nil {
+		return nil, err
+	}
+	feed := &replayFeed{path: path, speed: speed, symbols: symbols, done: make(chan struct{})}
+	return &WSClient{replay: feed, updates: newResponseChannels()}, nil
+}
+
+// ReplaySymbols returns the symbols of the tickers of the replayed feed, and
+// nil when the client isn't replaying a feed.
+func (c *WSClient) ReplaySymbols() []string {
+	if c.replay == nil {
+		return nil
+	}
+	return append([]string(nil), c.replay.symbols...)
+}
+
+// recordedSymbols checks every record of the file and returns the symbols of
+// the recorded tickers, sorted.
+func recordedSymbols(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record FeedRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, errors.Annotatef(err, "%s:%d", path, line)
+		}
+		if record.Method != "ticker" {
+			continue
+		}
+		var ticker WSNotificationTickerResponse
+		if err := json.Unmarshal(record.Params, &ticker); err != nil {
+			return nil, errors.Annotatef(err, "%s:%d", path, line)
+		}
+		seen[ticker.Symbol] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	symbols := make([]string, 0, len(seen))
+	for symbol := range seen {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols, nil
+}
+
+// subscriptionOp starts the replay, notifications of the symbols that aren't
+// subscribed are dropped like on the live feed.
+func (f *replayFeed) subscriptionOp(op string, handler *responseChannels) error {
+	switch op {
+	case "subscribeTicker", "subscribeTrades", "subscribeCandles":
+		f.start.Do(func() { go f.run(handler) })
+		return nil
+	case "unsubscribeTicker", "unsubscribeTrades", "unsubscribeCandles":
+		return nil
+	}
+	return errReplayUnsupported
+}
+
+// run delivers the records at the recorded pace until the end of the file
+// or close.
+func (f *replayFeed) run(handler *responseChannels) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		log.Print("replay: ", err)
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	var first time.Time
+	started := time.Now()
+	count := 0
+	for scanner.Scan() {
+		var record FeedRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if first.IsZero() {
+			first = record.Time
+		}
+		if f.speed > 0 {
+			due := started.Add(time.Duration(float64(record.Time.Sub(first)) / f.speed))
+			select {
+			case <-time.After(time.Until(due)):
+			case <-f.done:
+				return
+			}
+		}
+		select {
+		case <-f.done:
+			return
+		default:
+		}
+		params := record.Params
+		handler.Handle(context.Background(), nil, &jsonrpc2.Request{Method: record.Method, Params: (*json.RawMessage)(&params)})
+		count++
+	}
+	log.Printf("replay: %d notifications of %s replayed", count, f.path)
+}
+
+func (f *replayFeed) close() {
+	f.stop.Do(func() { close(f.done) })
+}
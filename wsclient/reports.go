@@ -0,0 +1,116 @@
+package wsclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+)
+
+// WSLoginRequest is the login request type on websocket, signed with HS256
+// so the secret key is not sent.
+type WSLoginRequest struct {
+	Algo      string `json:"algo"`
+	PKey      string `json:"pKey"`
+	Nonce     string `json:"nonce"`
+	Signature string `json:"signature"`
+}
+
+// Login authenticates the connection with the API key, which is required
+// before SubscribeReports.
+func (c *WSClient) Login(apiKey string, apiSecret string) error {
+	if len(apiKey) == 0 || len(apiSecret) == 0 {
+		return errors.New("you need to set api key and api secret to login")
+	}
+	if c.v3 != nil {
+		return c.loginV3(apiKey, apiSecret)
+	}
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return errors.Annotate(err, "Hitbtc Login")
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+	mac := hmac.New(sha256.New, []byte(apiSecret))
+	mac.Write([]byte(nonce))
+	request := WSLoginRequest{
+		Algo:      "HS256",
+		PKey:      apiKey,
+		Nonce:     nonce,
+		Signature: hex.EncodeToString(mac.Sum(nil)),
+	}
+	if c.replay != nil {
+		return errReplayUnsupported
+	}
+	var success bool
+	if err := c.conn.Call(context.Background(), "login", request, &success); err != nil {
+		return errors.Annotate(err, "Hitbtc Login")
+	}
+	if !success {
+		return errors.New("Login not successful")
+	}
+	return nil
+}
+
+// WSReport is an execution report of an order of the account.
+type WSReport struct {
+	ID            string          `json:"id"`
+	ClientOrderID string          `json:"clientOrderId"`
+	Symbol        string          `json:"symbol"`
+	Side          string          `json:"side"`
+	Status        string          `json:"status"` // new, suspended, partiallyFilled, filled, canceled or expired
+	Type          string          `json:"type"`
+	TimeInForce   string          `json:"timeInForce"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	Price         decimal.Decimal `json:"price"`
+	CumQuantity   decimal.Decimal `json:"cumQuantity"`
+	PostOnly      bool            `json:"postOnly"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	UpdatedAt     time.Time       `json:"updatedAt"`
+	ReportType    string          `json:"reportType"` // status, new, canceled, expired, suspended, trade or replaced
+	TradeQuantity decimal.Decimal `json:"tradeQuantity"`
+	TradePrice    decimal.Decimal `json:"tradePrice"`
+	TradeID       int64           `json:"tradeId"`
+	TradeFee      decimal.Decimal `json:"tradeFee"`
+}
+
+// SubscribeReports subscribes to the execution reports of the logged in
+// account. The active orders are first delivered as reports of type status.
+func (c *WSClient) SubscribeReports() (<-chan WSReport, error) {
+	err := c.subscriptionOp("subscribeReports", struct{}{})
+	if err != nil {
+		return nil, errors.Annotate(err, "Hitbtc SubscribeReports")
+	}
+
+	notifications := &c.updates.notifications
+	notifications.mutex.Lock()
+	defer notifications.mutex.Unlock()
+	if notifications.ReportsFeed == nil {
+		notifications.ReportsFeed = make(chan WSReport, notifications.bufferSize)
+	}
+	return notifications.ReportsFeed, nil
+}
+
+// deliverReports sends the reports in order. Reports are not dropped, the
+// sender waits for a full channel.
+func (n *notificationChannels) deliverReports(reports []WSReport) {
+	n.closing.RLock()
+	defer n.closing.RUnlock()
+	n.mutex.Lock()
+	channel := n.ReportsFeed
+	n.mutex.Unlock()
+	if channel == nil {
+		return
+	}
+	for _, report := range reports {
+		select {
+		case channel <- report:
+		case <-n.done:
+			return
+		}
+	}
+}
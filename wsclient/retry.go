@@ -0,0 +1,75 @@
+package wsclient
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var errTimeout error = &unavailableError{errors.New("timeout on reading data from HitBtc API")}
+
+// RetryPolicy configures the retries of transient upstream errors: network
+// errors, timeouts, 429 and 5xx responses.
+type RetryPolicy struct {
+	MaxRetries int
+	// BaseDelay doubles on every retry up to MaxDelay. A Retry-After header
+	// longer than MaxDelay stops the retries.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// RetryUnsafe also retries the requests that are not GET, an order
+	// could then be placed twice.
+	RetryUnsafe bool
+}
+
+// DefaultRetryPolicy retries GET requests three times.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  200 * time.Millisecond,
+	MaxDelay:   5 * time.Second,
+}
+
+// retryable tells whether err is worth retrying. Cancelled requests aren't.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	var urlErr *url.Error
+	return err == errTimeout || errors.As(err, &urlErr)
+}
+
+// delay returns how long to wait before the retry following attempt, and
+// false when the retry must not be done.
+func (p RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter, statusErr.RetryAfter <= p.MaxDelay
+	}
+	backoff := p.BaseDelay << uint(attempt)
+	if backoff <= 0 || backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	// jitter spreads the retries of concurrent requests
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)), true
+}
+
+// parseRetryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,106 @@
+package wsclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"too many requests", &StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"server error", &StatusError{StatusCode: http.StatusBadGateway}, true},
+		{"wrapped server error", fmt.Errorf("ticker: %w", &StatusError{StatusCode: http.StatusServiceUnavailable}), true},
+		{"client error", &StatusError{StatusCode: http.StatusBadRequest}, false},
+		{"timeout", errTimeout, true},
+		{"network", &url.Error{Op: "Get", URL: API_BASE, Err: errors.New("connection refused")}, true},
+		{"canceled", &url.Error{Op: "Get", URL: API_BASE, Err: context.Canceled}, false},
+		{"deadline", fmt.Errorf("ticker: %w", context.DeadlineExceeded), false},
+		{"other", errors.New("invalid JSON"), false},
+	}
+	for _, test := range tests {
+		if got := retryable(test.err); got != test.want {
+			t.Errorf("%s: retryable(%v) = %v, want %v", test.name, test.err, got, test.want)
+		}
+	}
+}
+
+func TestRetryDelayBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	err := &StatusError{StatusCode: http.StatusBadGateway}
+	tests := []struct {
+		attempt int
+		backoff time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		// capped at MaxDelay
+		{4, time.Second},
+		{20, time.Second},
+		// the shift overflows
+		{64, time.Second},
+	}
+	for _, test := range tests {
+		// the jitter draws the delay in [backoff/2, backoff]
+		for i := 0; i < 20; i++ {
+			delay, retry := policy.delay(test.attempt, err)
+			if !retry || delay < test.backoff/2 || delay > test.backoff {
+				t.Fatalf("attempt %d: delay %v, %v, want a retry within [%v, %v]", test.attempt, delay, retry, test.backoff/2, test.backoff)
+			}
+		}
+	}
+}
+
+func TestRetryDelayRetryAfter(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}
+	tests := []struct {
+		retryAfter time.Duration
+		retry      bool
+	}{
+		{time.Second, true},
+		{2 * time.Second, true},
+		// longer than MaxDelay, the retries stop
+		{3 * time.Second, false},
+	}
+	for _, test := range tests {
+		err := fmt.Errorf("ticker: %w", &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: test.retryAfter})
+		delay, retry := policy.delay(0, err)
+		if delay != test.retryAfter || retry != test.retry {
+			t.Errorf("Retry-After %v: delay %v, %v, want %v, %v", test.retryAfter, delay, retry, test.retryAfter, test.retry)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"0", 0},
+		{"-5", 0},
+		{"soon", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		// the other HTTP date formats
+		{now.Add(time.Minute).Format(time.RFC850), time.Minute},
+		{now.Add(time.Minute).Format(time.ANSIC), time.Minute},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{now.Format(http.TimeFormat), 0},
+	}
+	for _, test := range tests {
+		if got := parseRetryAfter(test.header, now); got != test.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", test.header, got, test.want)
+		}
+	}
+}
@@ -1,13 +1,15 @@
 package wsclient
 
+import "github.com/shopspring/decimal"
+
 // Symbol represents data of a Currency Pair on a market.
 type Symbol struct {
-	Id                   string  `json:"id"`
-	BaseCurrency         string  `json:"baseCurrency"`
-	QuoteCurrency        string  `json:"quoteCurrency"`
-	QuantityIncrement    float64 `json:"quantityIncrement,string"`
-	TickSize             float64 `json:"tickSize,string"`
-	TakeLiquidityRate    float64 `json:"takeLiquidityRate,string"`
-	ProvideLiquidityRate float64 `json:"provideLiquidityRate,string"`
-	FeeCurrency          string  `json:"feeCurrency"`
+	Id                   string          `json:"id"`
+	BaseCurrency         string          `json:"baseCurrency"`
+	QuoteCurrency        string          `json:"quoteCurrency"`
+	QuantityIncrement    decimal.Decimal `json:"quantityIncrement"`
+	TickSize             decimal.Decimal `json:"tickSize"`
+	TakeLiquidityRate    decimal.Decimal `json:"takeLiquidityRate"`
+	ProvideLiquidityRate decimal.Decimal `json:"provideLiquidityRate"`
+	FeeCurrency          string          `json:"feeCurrency"`
 }
@@ -3,25 +3,77 @@ package wsclient
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 type Tickers []Ticker
 
-//Ticker represents a Ticker from hitbtc API.
+// Sources of a ticker.
+const (
+	SourceWebsocket = "websocket"
+	SourceREST      = "rest"
+)
+
+// Ticker represents a Ticker from hitbtc API. Prices and volumes are decimals
+// so the exact values sent by the exchange are kept and served as strings.
 type Ticker struct {
-	ID          string    `json:"id"`
-	FullName    string    `json:"fullname"`
-	Ask         float64   `json:"ask,string"`
-	Bid         float64   `json:"bid,string"`
-	Last        float64   `json:"last,string"`
-	Open        float64   `json:"open,string"`
-	Low         float64   `json:"low,string,omitempty"`
-	High        float64   `json:"high,string,omitempty"`
-	Volume      float64   `json:"volume,string"`
-	VolumeQuote float64   `json:"volumeQuote,string"`
-	Timestamp   time.Time `json:"timestamp"`
-	Symbol      string    `json:"symbol"`
-	FeeCurrency string    `json:"feecurrency"`
+	ID       string          `json:"id"`
+	FullName string          `json:"fullname"`
+	Ask      decimal.Decimal `json:"ask"`
+	Bid      decimal.Decimal `json:"bid"`
+	Last     decimal.Decimal `json:"last"`
+	Open     decimal.Decimal `json:"open"`
+	// Low and High are omitted when zero, as the exchange leaves them empty
+	// for the symbols not traded in the last 24h.
+	Low         decimal.Decimal `json:"low,omitempty"`
+	High        decimal.Decimal `json:"high,omitempty"`
+	Volume      decimal.Decimal `json:"volume"`
+	VolumeQuote decimal.Decimal `json:"volumeQuote"`
+	Timestamp   time.Time       `json:"timestamp"`
+	Symbol      string          `json:"symbol"`
+	FeeCurrency string          `json:"feecurrency"`
+	// ChangeAbs24h is Last minus Open, ChangePercent24h the same in percent
+	// of Open, rounded to two decimals.
+	ChangeAbs24h     decimal.Decimal `json:"changeAbs24h"`
+	ChangePercent24h decimal.Decimal `json:"changePercent24h"`
+	// Source is SourceWebsocket or SourceREST. CachedAt and AgeMs are set on
+	// the tickers served from the cache, AgeMs is the time since CachedAt.
+	Source   string     `json:"source,omitempty"`
+	CachedAt *time.Time `json:"cachedAt,omitempty"`
+	AgeMs    *int64     `json:"ageMs,omitempty"`
+	// Delisted is set on the last ticker cached of a delisted symbol, whose
+	// price no longer changes.
+	Delisted bool `json:"delisted,omitempty"`
+	// Indicators are the moving averages requested with ?indicators=, by
+	// name such as sma20.
+	Indicators map[string]decimal.Decimal `json:"indicators,omitempty"`
+	// SessionHigh and SessionLow are the extremes of Last since the server
+	// started, AllTimeHigh and AllTimeLow those of the stored history.
+	SessionHigh *decimal.Decimal `json:"sessionHigh,omitempty"`
+	SessionLow  *decimal.Decimal `json:"sessionLow,omitempty"`
+	AllTimeHigh *decimal.Decimal `json:"allTimeHigh,omitempty"`
+	AllTimeLow  *decimal.Decimal `json:"allTimeLow,omitempty"`
+}
+
+// MarshalJSON omits Low and High when zero, which omitempty doesn't do for
+// decimals.
+func (t Ticker) MarshalJSON() ([]byte, error) {
+	type Alias Ticker
+	aux := struct {
+		Alias
+		Low  *decimal.Decimal `json:"low,omitempty"`
+		High *decimal.Decimal `json:"high,omitempty"`
+	}{
+		Alias: Alias(t),
+	}
+	if !t.Low.IsZero() {
+		aux.Low = &t.Low
+	}
+	if !t.High.IsZero() {
+		aux.High = &t.High
+	}
+	return json.Marshal(aux)
 }
 
 func (t *Ticker) UnmarshalJSON(data []byte) error {
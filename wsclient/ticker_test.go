@@ -0,0 +1,57 @@
+package wsclient
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestTickerJSON(t *testing.T) {
+	ticker := Ticker{
+		Symbol:    "BTCUSD",
+		Last:      decimal.RequireFromString("65000.10"),
+		Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 250e6, time.UTC),
+	}
+	tests := []struct {
+		name      string
+		low, high string
+		want      []string
+		omitted   []string
+	}{
+		{"empty range", "0", "0", []string{`"last":"65000.1"`, `"open":"0"`}, []string{`"low"`, `"high"`}},
+		{"range", "64000", "66000.5", []string{`"low":"64000"`, `"high":"66000.5"`}, nil},
+		{"low only", "64000", "0", []string{`"low":"64000"`}, []string{`"high"`}},
+	}
+	for _, test := range tests {
+		ticker.Low, ticker.High = decimal.RequireFromString(test.low), decimal.RequireFromString(test.high)
+		// both the values and the pointers use MarshalJSON
+		for _, value := range []interface{}{ticker, &ticker} {
+			data, err := json.Marshal(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("%s: %s, want %s", test.name, data, want)
+				}
+			}
+			for _, omitted := range test.omitted {
+				if strings.Contains(string(data), omitted) {
+					t.Errorf("%s: %s, want %s omitted", test.name, data, omitted)
+				}
+			}
+		}
+
+		data, _ := json.Marshal(ticker)
+		var decoded Ticker
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Low.Equal(ticker.Low) || !decoded.High.Equal(ticker.High) || !decoded.Timestamp.Equal(ticker.Timestamp) {
+			t.Errorf("%s: decoded %+v, want %+v", test.name, decoded, ticker)
+		}
+	}
+}
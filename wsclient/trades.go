@@ -0,0 +1,84 @@
+package wsclient
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+)
+
+// WSTrade is a public trade of a market.
+type WSTrade struct {
+	ID        int64           `json:"id"`
+	Price     decimal.Decimal `json:"price"`
+	Quantity  decimal.Decimal `json:"quantity"`
+	Side      string          `json:"side"` // taker side, buy or sell
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// WSNotificationTradesResponse is the snapshotTrades and updateTrades
+// notification type on websocket. The first notification after subscribing is
+// a snapshot of the recent trades, oldest first.
+type WSNotificationTradesResponse struct {
+	Data     []WSTrade `json:"data"`
+	Symbol   string    `json:"symbol"`
+	Snapshot bool      `json:"-"`
+}
+
+// WSSubscribeTradesRequest is request type on websocket trades subscription.
+type WSSubscribeTradesRequest struct {
+	Symbol string `json:"symbol,required"`
+	Limit  int    `json:"limit,omitempty"` // trades in the snapshot, 100 by default
+}
+
+// SubscribeTrades subscribes to the trades of the specified market.
+func (c *WSClient) SubscribeTrades(symbol string) (<-chan WSNotificationTradesResponse, error) {
+	err := c.subscriptionOp("subscribeTrades", WSSubscribeTradesRequest{Symbol: symbol})
+	if err != nil {
+		return nil, errors.Annotate(err, "Hitbtc SubscribeTrades")
+	}
+
+	notifications := &c.updates.notifications
+	notifications.mutex.Lock()
+	defer notifications.mutex.Unlock()
+	if notifications.TradesFeed[symbol] == nil {
+		notifications.TradesFeed[symbol] = make(chan WSNotificationTradesResponse, notifications.bufferSize)
+	}
+
+	return notifications.TradesFeed[symbol], nil
+}
+
+// UnsubscribeTrades unsubscribes from the trades of the specified market.
+//
+// This closes also the connected channel of updates.
+func (c *WSClient) UnsubscribeTrades(symbol string) error {
+	err := c.subscriptionOp("unsubscribeTrades", WSSubscriptionRequest{Symbol: symbol})
+	if err != nil {
+		return errors.Annotate(err, "Hitbtc UnsubscribeTrades")
+	}
+
+	c.updates.notifications.mutex.Lock()
+	if channel, ok := c.updates.notifications.TradesFeed[symbol]; ok {
+		close(channel)
+		delete(c.updates.notifications.TradesFeed, symbol)
+	}
+	c.updates.notifications.mutex.Unlock()
+
+	return nil
+}
+
+// deliverTrades sends msg to the trades channel of its symbol. Trades are not
+// dropped, the sender waits for a full channel.
+func (n *notificationChannels) deliverTrades(msg WSNotificationTradesResponse) {
+	n.closing.RLock()
+	defer n.closing.RUnlock()
+	n.mutex.Lock()
+	channel := n.TradesFeed[msg.Symbol]
+	n.mutex.Unlock()
+	if channel != nil {
+		select {
+		case channel <- msg:
+		case <-n.done:
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package wsclient
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// The account and trading calls of the API v3 take snake case parameters
+// and return snake case models, which are mapped to the v2 ones. The v2
+// trading account is the v3 spot account and the v2 main account the v3
+// wallet.
+
+// orderParamsV3 renames the v2 order parameters which differ in v3.
+var orderParamsV3 = map[string]string{
+	"clientOrderId": "client_order_id",
+	"timeInForce":   "time_in_force",
+	"stopPrice":     "stop_price",
+	"expireTime":    "expire_time",
+	"postOnly":      "post_only",
+}
+
+// renameParams returns payload with the keys renamed by names.
+func renameParams(payload map[string]string, names map[string]string) map[string]string {
+	renamed := make(map[string]string, len(payload))
+	for key, value := range payload {
+		if name, ok := names[key]; ok {
+			key = name
+		}
+		renamed[key] = value
+	}
+	return renamed
+}
+
+type orderV3 struct {
+	ID            int64            `json:"id"`
+	ClientOrderID string           `json:"client_order_id"`
+	Symbol        string           `json:"symbol"`
+	Side          string           `json:"side"`
+	Status        string           `json:"status"`
+	Type          string           `json:"type"`
+	TimeInForce   string           `json:"time_in_force"`
+	Quantity      decimal.Decimal  `json:"quantity"`
+	Price         decimal.Decimal  `json:"price"`
+	CumQuantity   decimal.Decimal  `json:"quantity_cumulative"`
+	StopPrice     *decimal.Decimal `json:"stop_price"`
+	ExpireTime    *time.Time       `json:"expire_time"`
+	PostOnly      bool             `json:"post_only"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+func (o orderV3) order() Order {
+	return Order{
+		ID:            o.ID,
+		ClientOrderID: o.ClientOrderID,
+		Symbol:        o.Symbol,
+		Side:          o.Side,
+		Status:        o.Status,
+		Type:          o.Type,
+		TimeInForce:   o.TimeInForce,
+		Quantity:      o.Quantity,
+		Price:         o.Price,
+		CumQuantity:   o.CumQuantity,
+		StopPrice:     o.StopPrice,
+		ExpireTime:    o.ExpireTime,
+		PostOnly:      o.PostOnly,
+		CreatedAt:     o.CreatedAt,
+		UpdatedAt:     o.UpdatedAt,
+	}
+}
+
+func ordersV3(response []orderV3) []Order {
+	orders := make([]Order, len(response))
+	for i, order := range response {
+		orders[i] = order.order()
+	}
+	return orders
+}
+
+func (b *HitBtc) placeOrderV3(ctx context.Context, payload map[string]string) (Order, error) {
+	var response orderV3
+	if err := b.call(ctx, "POST", "spot/order", renameParams(payload, orderParamsV3), true, &response); err != nil {
+		return Order{}, err
+	}
+	return response.order(), nil
+}
+
+func (b *HitBtc) cancelOrderV3(ctx context.Context, clientOrderID string) (Order, error) {
+	var response orderV3
+	if err := b.call(ctx, "DELETE", "spot/order/"+url.PathEscape(clientOrderID), nil, true, &response); err != nil {
+		return Order{}, err
+	}
+	return response.order(), nil
+}
+
+// listOrdersV3 returns the orders of a GET or DELETE of resource.
+func (b *HitBtc) listOrdersV3(ctx context.Context, method string, resource string, payload map[string]string) ([]Order, error) {
+	var response []orderV3
+	if err := b.call(ctx, method, resource, payload, true, &response); err != nil {
+		return nil, err
+	}
+	return ordersV3(response), nil
+}
+
+type accountTradeV3 struct {
+	ID            int64           `json:"id"`
+	OrderID       int64           `json:"order_id"`
+	ClientOrderID string          `json:"client_order_id"`
+	Symbol        string          `json:"symbol"`
+	Side          string          `json:"side"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	Price         decimal.Decimal `json:"price"`
+	Fee           decimal.Decimal `json:"fee"`
+	Taker         bool            `json:"taker"`
+	Timestamp     time.Time       `json:"timestamp"`
+}
+
+func (b *HitBtc) getTradeHistoryV3(ctx context.Context, filter HistoryFilter) ([]AccountTrade, error) {
+	var response []accountTradeV3
+	if err := b.call(ctx, "GET", "spot/history/trade", filter.payload(), true, &response); err != nil {
+		return nil, err
+	}
+	trades := make([]AccountTrade, len(response))
+	for i, trade := range response {
+		trades[i] = AccountTrade(trade)
+	}
+	return trades, nil
+}
+
+type tradingFeeV3 struct {
+	TakeRate decimal.Decimal `json:"take_rate"`
+	MakeRate decimal.Decimal `json:"make_rate"`
+}
+
+func (b *HitBtc) getTradingFeeV3(ctx context.Context, symbol string) (TradingFee, error) {
+	var response tradingFeeV3
+	if err := b.call(ctx, "GET", "spot/fee/"+url.PathEscape(symbol), nil, true, &response); err != nil {
+		return TradingFee{}, err
+	}
+	return TradingFee{TakeLiquidityRate: response.TakeRate, ProvideLiquidityRate: response.MakeRate}, nil
+}
+
+type depositAddressV3 struct {
+	Address   string `json:"address"`
+	PaymentID string `json:"payment_id"`
+}
+
+func (b *HitBtc) getDepositAddressV3(ctx context.Context, currency string) (DepositAddress, error) {
+	var response []depositAddressV3
+	if err := b.call(ctx, "GET", "wallet/crypto/address", map[string]string{"currency": currency}, true, &response); err != nil {
+		return DepositAddress{}, err
+	}
+	if len(response) == 0 {
+		return DepositAddress{}, errors.New("no deposit address for " + currency)
+	}
+	return DepositAddress(response[0]), nil
+}
+
+func (b *HitBtc) newDepositAddressV3(ctx context.Context, currency string) (DepositAddress, error) {
+	var response depositAddressV3
+	if err := b.call(ctx, "POST", "wallet/crypto/address", map[string]string{"currency": currency}, true, &response); err != nil {
+		return DepositAddress{}, err
+	}
+	return DepositAddress(response), nil
+}
+
+func (b *HitBtc) withdrawV3(ctx context.Context, request WithdrawRequest) (Withdraw, error) {
+	payload := map[string]string{
+		"currency":    request.Currency,
+		"amount":      request.Amount.String(),
+		"address":     request.Address,
+		"auto_commit": strconv.FormatBool(request.AutoCommit),
+	}
+	if request.PaymentID != "" {
+		payload["payment_id"] = request.PaymentID
+	}
+	var withdraw Withdraw
+	err := b.call(ctx, "POST", "wallet/crypto/withdraw", payload, true, &withdraw)
+	return withdraw, err
+}
+
+// transferAccountsV3 maps the v2 transfer types to the v3 source and
+// destination accounts.
+var transferAccountsV3 = map[string][2]string{
+	TransferBankToExchange: {"wallet", "spot"},
+	TransferExchangeToBank: {"spot", "wallet"},
+}
+
+func (b *HitBtc) transferV3(ctx context.Context, currency string, amount decimal.Decimal, transferType string) (Transfer, error) {
+	accounts, ok := transferAccountsV3[transferType]
+	if !ok {
+		return Transfer{}, errors.New("unknown transfer type " + transferType)
+	}
+	payload := map[string]string{
+		"currency":    currency,
+		"amount":      amount.String(),
+		"source":      accounts[0],
+		"destination": accounts[1],
+	}
+	// the IDs of the transfers, a single one between these accounts
+	var response []string
+	if err := b.call(ctx, "POST", "wallet/transfer", payload, true, &response); err != nil {
+		return Transfer{}, err
+	}
+	if len(response) == 0 {
+		return Transfer{}, errors.New("Transfer not successful")
+	}
+	return Transfer{ID: response[0]}, nil
+}
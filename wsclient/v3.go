@@ -0,0 +1,186 @@
+package wsclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	API_V3_BASE = "https://api.hitbtc.com/api/3" // HitBtc API v3 endpoint
+)
+
+// NewV3 returns a HitBTC client of the API v3. The v3 responses are mapped
+// to the v2 models, and the authenticated requests are signed with HS256.
+func NewV3(apiKey, apiSecret string, options ...Option) *HitBtc {
+	client := NewClient(apiKey, apiSecret, options...)
+	client.base = API_V3_BASE
+	return &HitBtc{client}
+}
+
+// v3 reports whether the client reads the API v3.
+func (b *HitBtc) v3() bool {
+	return b.client.base == API_V3_BASE
+}
+
+// signV3 returns the HS256 Authorization header of a request: the
+// HMAC-SHA256, keyed by the API secret, of the method, the path and query,
+// the body and the timestamp. Unlike basic auth the secret isn't sent.
+func (c *client) signV3(method string, u *url.URL, body string, now time.Time) string {
+	timestamp := strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10)
+	message := method + u.EscapedPath()
+	if u.RawQuery != "" {
+		message += "?" + u.RawQuery
+	}
+	mac := hmac.New(sha256.New, []byte(c.apiSecret))
+	mac.Write([]byte(message + body + timestamp))
+	credentials := c.apiKey + ":" + hex.EncodeToString(mac.Sum(nil)) + ":" + timestamp
+	return "HS256 " + base64.StdEncoding.EncodeToString([]byte(credentials))
+}
+
+type currencyV3 struct {
+	FullName        string `json:"full_name"`
+	Crypto          bool   `json:"crypto"`
+	PayinEnabled    bool   `json:"payin_enabled"`
+	PayoutEnabled   bool   `json:"payout_enabled"`
+	TransferEnabled bool   `json:"transfer_enabled"`
+	Delisted        bool   `json:"delisted"`
+	Networks        []struct {
+		Default            bool            `json:"default"`
+		PayinPaymentID     bool            `json:"payin_payment_id"`
+		PayoutIsPaymentID  bool            `json:"payout_is_payment_id"`
+		PayinConfirmations uint            `json:"payin_confirmations"`
+		PayoutFee          decimal.Decimal `json:"payout_fee"`
+	} `json:"networks"`
+}
+
+// currency maps the v3 currency to the v2 model, the payment id settings and
+// the payout fee are the ones of the default network.
+func (c currencyV3) currency(id string) Currency {
+	currency := Currency{
+		Id:              id,
+		FullName:        c.FullName,
+		Crypto:          c.Crypto,
+		PayinEnabled:    c.PayinEnabled,
+		PayoutEnabled:   c.PayoutEnabled,
+		TransferEnabled: c.TransferEnabled,
+		Delisted:        c.Delisted,
+	}
+	for _, network := range c.Networks {
+		if network.Default {
+			currency.PayinPaymentId = network.PayinPaymentID
+			currency.PayoutIsPaymentId = network.PayoutIsPaymentID
+			currency.PayinConfirmations = network.PayinConfirmations
+			currency.PayoutFee = network.PayoutFee
+		}
+	}
+	return currency
+}
+
+type symbolV3 struct {
+	Type              string          `json:"type"`
+	BaseCurrency      string          `json:"base_currency"`
+	QuoteCurrency     string          `json:"quote_currency"`
+	QuantityIncrement decimal.Decimal `json:"quantity_increment"`
+	TickSize          decimal.Decimal `json:"tick_size"`
+	TakeRate          decimal.Decimal `json:"take_rate"`
+	MakeRate          decimal.Decimal `json:"make_rate"`
+	FeeCurrency       string          `json:"fee_currency"`
+}
+
+type tickerV3 struct {
+	Ask         decimal.Decimal `json:"ask"`
+	Bid         decimal.Decimal `json:"bid"`
+	Last        decimal.Decimal `json:"last"`
+	Open        decimal.Decimal `json:"open"`
+	Low         decimal.Decimal `json:"low"`
+	High        decimal.Decimal `json:"high"`
+	Volume      decimal.Decimal `json:"volume"`
+	VolumeQuote decimal.Decimal `json:"volume_quote"`
+	Timestamp   time.Time       `json:"timestamp"`
+}
+
+func (t tickerV3) ticker(symbol string) Ticker {
+	return Ticker{
+		Ask:         t.Ask,
+		Bid:         t.Bid,
+		Last:        t.Last,
+		Open:        t.Open,
+		Low:         t.Low,
+		High:        t.High,
+		Volume:      t.Volume,
+		VolumeQuote: t.VolumeQuote,
+		Timestamp:   t.Timestamp,
+		Symbol:      symbol,
+	}
+}
+
+func (b *HitBtc) getCurrenciesV3(ctx context.Context) ([]Currency, error) {
+	var response map[string]currencyV3
+	if err := b.call(ctx, "GET", "public/currency", nil, false, &response); err != nil {
+		return nil, err
+	}
+	currencies := make([]Currency, 0, len(response))
+	for id, currency := range response {
+		currencies = append(currencies, currency.currency(id))
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Id < currencies[j].Id })
+	return currencies, nil
+}
+
+// getSymbolsV3 returns the spot symbols, v3 also lists futures.
+func (b *HitBtc) getSymbolsV3(ctx context.Context) ([]Symbol, error) {
+	var response map[string]symbolV3
+	if err := b.call(ctx, "GET", "public/symbol", nil, false, &response); err != nil {
+		return nil, err
+	}
+	symbols := make([]Symbol, 0, len(response))
+	for id, symbol := range response {
+		if symbol.Type != "" && symbol.Type != "spot" {
+			continue
+		}
+		symbols = append(symbols, Symbol{
+			Id:                   id,
+			BaseCurrency:         symbol.BaseCurrency,
+			QuoteCurrency:        symbol.QuoteCurrency,
+			QuantityIncrement:    symbol.QuantityIncrement,
+			TickSize:             symbol.TickSize,
+			TakeLiquidityRate:    symbol.TakeRate,
+			ProvideLiquidityRate: symbol.MakeRate,
+			FeeCurrency:          symbol.FeeCurrency,
+		})
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].Id < symbols[j].Id })
+	return symbols, nil
+}
+
+func (b *HitBtc) getTickerV3(ctx context.Context, market string) (Ticker, error) {
+	symbol := strings.ToUpper(market)
+	var response tickerV3
+	if err := b.call(ctx, "GET", "public/ticker/"+url.PathEscape(symbol), nil, false, &response); err != nil {
+		return Ticker{}, err
+	}
+	return response.ticker(symbol), nil
+}
+
+func (b *HitBtc) getAllTickerV3(ctx context.Context) (Tickers, error) {
+	var response map[string]tickerV3
+	if err := b.call(ctx, "GET", "public/ticker", nil, false, &response); err != nil {
+		return nil, err
+	}
+	tickers := make(Tickers, 0, len(response))
+	for symbol, ticker := range response {
+		tickers = append(tickers, ticker.ticker(symbol))
+	}
+	sort.Slice(tickers, func(i, j int) bool { return tickers[i].Symbol < tickers[j].Symbol })
+	return tickers, nil
+}
@@ -3,15 +3,40 @@ package wsclient
 import (
 	"context"
 	"encoding/json"
+	"sync"
 
 	"github.com/gorilla/websocket"
 	"github.com/juju/errors"
 	jsonrpc2 "github.com/sourcegraph/jsonrpc2"
 	jsonrpc2ws "github.com/sourcegraph/jsonrpc2/websocket"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const wsAPIURL string = "wss://api.hitbtc.com/api/2/ws"
 
+// OverflowPolicy decides what happens to a ticker notification when the
+// channel of its symbol is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits for the consumer, stalling the notifications of
+	// every symbol.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest discards the oldest buffered notification.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowDropNewest discards the incoming notification.
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+)
+
+// ParseOverflowPolicy parses an OverflowPolicy name.
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(name); policy {
+	case OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+		return policy, nil
+	}
+	return "", errors.Errorf("unknown overflow policy %q", name)
+}
+
 // responseChannels handles all incoming data from the hitbtc connection.
 type responseChannels struct {
 	notifications notificationChannels
@@ -20,14 +45,32 @@ type responseChannels struct {
 }
 
 // notificationChannels contains all the notifications from hitbtc for subscribed feeds.
+// Symbols subscribed with SubscribeTickerStream share TickerStream instead of
+// having their own channel in TickerFeed. The deliveries hold closing for
+// reading, so that Close doesn't close a channel they send to, and give up
+// waiting for a full channel when done is closed.
 type notificationChannels struct {
-	TickerFeed map[string]chan WSNotificationTickerResponse
+	closing      sync.RWMutex
+	done         chan struct{}
+	closeOnce    sync.Once
+	mutex        *sync.Mutex
+	bufferSize   int
+	overflow     OverflowPolicy
+	TickerFeed   map[string]chan WSNotificationTickerResponse
+	TickerStream chan WSNotificationTickerResponse
+	TradesFeed   map[string]chan WSNotificationTradesResponse
+	CandlesFeed  map[string]chan WSNotificationCandlesResponse
+	ReportsFeed  chan WSReport
+	streamed     map[string]bool
+	dropped      map[string]uint64
+	recorder     *Recorder
 }
 
 // Handle handles all incoming connections and fills the channels properly.
 func (h *responseChannels) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
 	if req.Params != nil {
 		message := *req.Params
+		h.notifications.record(req.Method, message)
 		switch req.Method {
 		case "ticker":
 			var msg WSNotificationTickerResponse
@@ -35,16 +78,121 @@ func (h *responseChannels) Handle(ctx context.Context, conn *jsonrpc2.Conn, req
 			if err != nil {
 				h.ErrorFeed <- err
 			} else {
-				h.notifications.TickerFeed[msg.Symbol] <- msg
+				h.notifications.deliverTicker(msg)
 			}
+		case "snapshotTrades", "updateTrades":
+			var msg WSNotificationTradesResponse
+			err := json.Unmarshal(message, &msg)
+			if err != nil {
+				h.ErrorFeed <- err
+			} else {
+				msg.Snapshot = req.Method == "snapshotTrades"
+				h.notifications.deliverTrades(msg)
+			}
+		case "snapshotCandles", "updateCandles":
+			var msg WSNotificationCandlesResponse
+			err := json.Unmarshal(message, &msg)
+			if err != nil {
+				h.ErrorFeed <- err
+			} else {
+				msg.Snapshot = req.Method == "snapshotCandles"
+				h.notifications.deliverCandles(msg)
+			}
+		case "activeOrders":
+			var msg []WSReport
+			err := json.Unmarshal(message, &msg)
+			if err != nil {
+				h.ErrorFeed <- err
+			} else {
+				h.notifications.deliverReports(msg)
+			}
+		case "report":
+			var msg WSReport
+			err := json.Unmarshal(message, &msg)
+			if err != nil {
+				h.ErrorFeed <- err
+			} else {
+				h.notifications.deliverReports([]WSReport{msg})
+			}
+		}
+	}
+}
+
+// deliverTicker sends msg to the channel of its symbol, applying the
+// overflow policy when the channel is full.
+func (n *notificationChannels) deliverTicker(msg WSNotificationTickerResponse) {
+	n.closing.RLock()
+	defer n.closing.RUnlock()
+	n.mutex.Lock()
+	channel := n.TickerFeed[msg.Symbol]
+	if channel == nil && n.streamed[msg.Symbol] {
+		channel = n.TickerStream
+	}
+	overflow := n.overflow
+	n.mutex.Unlock()
+	if channel == nil {
+		return
+	}
+	switch overflow {
+	case OverflowDropNewest:
+		select {
+		case channel <- msg:
+		default:
+			n.drop(msg.Symbol)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case channel <- msg:
+				return
+			default:
+			}
+			select {
+			case oldest := <-channel:
+				n.drop(oldest.Symbol)
+			default:
+			}
+		}
+	default:
+		select {
+		case channel <- msg:
+		case <-n.done:
 		}
 	}
 }
 
-// WSClient represents a JSON RPC v2 Connection over Websocket,
+func (n *notificationChannels) drop(symbol string) {
+	n.mutex.Lock()
+	n.dropped[symbol]++
+	n.mutex.Unlock()
+}
+
+// WSClient represents a JSON RPC v2 Connection over Websocket, or a
+// connection to the v3 feed when v3 is set, or a recorded feed when replay
+// is set. v3Trading is the v3 trading feed opened by Login, under v3Mutex.
 type WSClient struct {
-	conn    *jsonrpc2.Conn
-	updates *responseChannels
+	conn      *jsonrpc2.Conn
+	v3        *wsConnV3
+	v3Trading *wsConnV3
+	v3Mutex   sync.Mutex
+	replay    *replayFeed
+	updates   *responseChannels
+}
+
+func newResponseChannels() *responseChannels {
+	return &responseChannels{
+		notifications: notificationChannels{
+			done:        make(chan struct{}),
+			mutex:       &sync.Mutex{},
+			overflow:    OverflowBlock,
+			TickerFeed:  make(map[string]chan WSNotificationTickerResponse),
+			TradesFeed:  make(map[string]chan WSNotificationTradesResponse),
+			CandlesFeed: make(map[string]chan WSNotificationCandlesResponse),
+			streamed:    make(map[string]bool),
+			dropped:     make(map[string]uint64),
+		},
+		ErrorFeed: make(chan error),
+	}
 }
 
 // NewWSClient creates a new WSClient
@@ -54,30 +202,81 @@ func NewWSClient() (*WSClient, error) {
 		return nil, err
 	}
 
-	handler := responseChannels{
-		notifications: notificationChannels{
-			TickerFeed: make(map[string]chan WSNotificationTickerResponse),
-		},
-		ErrorFeed: make(chan error),
-	}
-
+	handler := newResponseChannels()
 	return &WSClient{
-		conn:    jsonrpc2.NewConn(context.Background(), jsonrpc2ws.NewObjectStream(conn), jsonrpc2.AsyncHandler(&handler)),
-		updates: &handler,
+		conn:    jsonrpc2.NewConn(context.Background(), jsonrpc2ws.NewObjectStream(conn), jsonrpc2.AsyncHandler(handler)),
+		updates: handler,
 	}, nil
 }
 
+// SetTickerBuffer sets the buffer size and overflow policy of the ticker
+// channels. It applies to the channels created afterwards, the default is
+// unbuffered channels with OverflowBlock.
+func (c *WSClient) SetTickerBuffer(size int, overflow OverflowPolicy) {
+	c.updates.notifications.mutex.Lock()
+	c.updates.notifications.bufferSize = size
+	c.updates.notifications.overflow = overflow
+	c.updates.notifications.mutex.Unlock()
+}
+
+// TickerDrops returns the number of ticker notifications dropped per symbol
+// by the overflow policy.
+func (c *WSClient) TickerDrops() map[string]uint64 {
+	c.updates.notifications.mutex.Lock()
+	defer c.updates.notifications.mutex.Unlock()
+	drops := make(map[string]uint64, len(c.updates.notifications.dropped))
+	for symbol, count := range c.updates.notifications.dropped {
+		drops[symbol] = count
+	}
+	return drops
+}
+
 // Close closes the Websocket connected to the hitbtc api.
 func (c *WSClient) Close() {
-	c.conn.Close()
+	switch {
+	case c.v3 != nil:
+		c.v3.conn.Close()
+		c.v3Mutex.Lock()
+		if c.v3Trading != nil {
+			c.v3Trading.conn.Close()
+			c.v3Trading = nil
+		}
+		c.v3Mutex.Unlock()
+	case c.replay != nil:
+		c.replay.close()
+	default:
+		c.conn.Close()
+	}
 
+	// the deliveries in progress give up and return
+	c.updates.notifications.closeOnce.Do(func() { close(c.updates.notifications.done) })
+	c.updates.notifications.closing.Lock()
+	defer c.updates.notifications.closing.Unlock()
+	c.updates.notifications.mutex.Lock()
 	for _, channel := range c.updates.notifications.TickerFeed {
 		close(channel)
 	}
+	c.updates.notifications.TickerFeed = make(map[string]chan WSNotificationTickerResponse)
+	if c.updates.notifications.TickerStream != nil {
+		close(c.updates.notifications.TickerStream)
+		c.updates.notifications.TickerStream = nil
+	}
+	for _, channel := range c.updates.notifications.TradesFeed {
+		close(channel)
+	}
+	c.updates.notifications.TradesFeed = make(map[string]chan WSNotificationTradesResponse)
+	for _, channel := range c.updates.notifications.CandlesFeed {
+		close(channel)
+	}
+	c.updates.notifications.CandlesFeed = make(map[string]chan WSNotificationCandlesResponse)
+	if c.updates.notifications.ReportsFeed != nil {
+		close(c.updates.notifications.ReportsFeed)
+		c.updates.notifications.ReportsFeed = nil
+	}
+	c.updates.notifications.streamed = make(map[string]bool)
+	c.updates.notifications.mutex.Unlock()
 
 	close(c.updates.ErrorFeed)
-
-	c.updates.notifications.TickerFeed = make(map[string]chan WSNotificationTickerResponse)
 	c.updates.ErrorFeed = make(chan error)
 }
 
@@ -105,6 +304,12 @@ type WSGetCurrencyResponse struct {
 func (c *WSClient) GetCurrencyInfo(symbol string) (*WSGetCurrencyResponse, error) {
 	var request = WSGetCurrencyRequest{Currency: symbol}
 	var response WSGetCurrencyResponse
+	if c.v3 != nil {
+		return nil, errV3Unsupported
+	}
+	if c.replay != nil {
+		return nil, errReplayUnsupported
+	}
 
 	err := c.conn.Call(context.Background(), "getCurrency", request, &response)
 	if err != nil {
@@ -134,6 +339,12 @@ type WSGetSymbolResponse struct {
 func (c *WSClient) GetSymbol(symbol string) (*WSGetSymbolResponse, error) {
 	var request = WSGetSymbolRequest{Symbol: symbol}
 	var response WSGetSymbolResponse
+	if c.v3 != nil {
+		return nil, errV3Unsupported
+	}
+	if c.replay != nil {
+		return nil, errReplayUnsupported
+	}
 
 	err := c.conn.Call(context.Background(), "getSymbol", request, &response)
 	if err != nil {
@@ -158,29 +369,63 @@ type WSNotificationTickerResponse struct {
 
 // SubscribeTicker subscribes to the specified market ticker notifications.
 func (c *WSClient) SubscribeTicker(symbol string) (<-chan WSNotificationTickerResponse, error) {
-	err := c.subscriptionOp("subscribeTicker", symbol)
+	err := c.subscriptionOp("subscribeTicker", WSSubscriptionRequest{Symbol: symbol})
 	if err != nil {
 		return nil, errors.Annotate(err, "Hitbtc SubscribeTicker")
 	}
 
-	if c.updates.notifications.TickerFeed[symbol] == nil {
-		c.updates.notifications.TickerFeed[symbol] = make(chan WSNotificationTickerResponse)
+	notifications := &c.updates.notifications
+	notifications.mutex.Lock()
+	defer notifications.mutex.Unlock()
+	if notifications.TickerFeed[symbol] == nil {
+		notifications.TickerFeed[symbol] = make(chan WSNotificationTickerResponse, notifications.bufferSize)
+	}
+
+	return notifications.TickerFeed[symbol], nil
+}
+
+// TickerStream returns the channel multiplexing the ticker notifications of
+// every symbol subscribed with SubscribeTickerStream.
+func (c *WSClient) TickerStream() <-chan WSNotificationTickerResponse {
+	notifications := &c.updates.notifications
+	notifications.mutex.Lock()
+	defer notifications.mutex.Unlock()
+	if notifications.TickerStream == nil {
+		notifications.TickerStream = make(chan WSNotificationTickerResponse, notifications.bufferSize)
+	}
+	return notifications.TickerStream
+}
+
+// SubscribeTickerStream subscribes to the specified market ticker
+// notifications, delivered on TickerStream.
+func (c *WSClient) SubscribeTickerStream(symbol string) error {
+	err := c.subscriptionOp("subscribeTicker", WSSubscriptionRequest{Symbol: symbol})
+	if err != nil {
+		return errors.Annotate(err, "Hitbtc SubscribeTicker")
 	}
 
-	return c.updates.notifications.TickerFeed[symbol], nil
+	c.updates.notifications.mutex.Lock()
+	c.updates.notifications.streamed[symbol] = true
+	c.updates.notifications.mutex.Unlock()
+	return nil
 }
 
 // UnsubscribeTicker subscribes to the specified market ticker notifications.
 //
 // This closes also the connected channel of updates.
 func (c *WSClient) UnsubscribeTicker(symbol string) error {
-	err := c.subscriptionOp("unsubscribeTicker", symbol)
+	err := c.subscriptionOp("unsubscribeTicker", WSSubscriptionRequest{Symbol: symbol})
 	if err != nil {
 		return errors.Annotate(err, "Hitbtc UnsubscribeTicker")
 	}
 
-	close(c.updates.notifications.TickerFeed[symbol])
-	delete(c.updates.notifications.TickerFeed, symbol)
+	c.updates.notifications.mutex.Lock()
+	if channel, ok := c.updates.notifications.TickerFeed[symbol]; ok {
+		close(channel)
+		delete(c.updates.notifications.TickerFeed, symbol)
+	}
+	delete(c.updates.notifications.streamed, symbol)
+	c.updates.notifications.mutex.Unlock()
 
 	return nil
 }
@@ -193,15 +438,22 @@ type WSSubscriptionRequest struct {
 	Symbol string `json:"symbol,required"`
 }
 
-func (c *WSClient) subscriptionOp(op string, symbol string) error {
+func (c *WSClient) subscriptionOp(op string, request interface{}) (err error) {
+	ctx, span := tracer.Start(context.Background(), "hitbtc ws "+op, trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { endSpan(span, err) }()
+	if c.v3 != nil {
+		return c.subscriptionOpV3(op, request)
+	}
+	if c.replay != nil {
+		return c.replay.subscriptionOp(op, c.updates)
+	}
 	if c.conn == nil {
 		return errors.New("Connection is unitialized")
 	}
 
-	var request = WSSubscriptionRequest{Symbol: symbol}
 	var success wsSubscriptionResponse
 
-	err := c.conn.Call(context.Background(), op, request, &success)
+	err = c.conn.Call(ctx, op, request, &success)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,436 @@
+package wsclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+)
+
+const wsV3APIURL string = "wss://api.hitbtc.com/api/3/ws/public"
+
+// wsV3TradingURL is the v3 feed of the account, opened by Login.
+const wsV3TradingURL string = "wss://api.hitbtc.com/api/3/ws/trading"
+
+// The v3 channels of the ticker notifications, batched every second, of the
+// trades and of the candles, followed by their period.
+const (
+	tickerChannelV3  = "ticker/1s"
+	tradesChannelV3  = "trades"
+	candlesChannelV3 = "candles/"
+)
+
+var errV3Unsupported = errors.New("not supported by the HitBTC v3 feed")
+
+// wsTickerV3 is a ticker of the v3 feed, with single letter fields.
+type wsTickerV3 struct {
+	Timestamp   int64  `json:"t"` // Unix milliseconds
+	Ask         string `json:"a"`
+	Bid         string `json:"b"`
+	Last        string `json:"c"`
+	Open        string `json:"o"`
+	High        string `json:"h"`
+	Low         string `json:"l"`
+	Volume      string `json:"v"`
+	VolumeQuote string `json:"q"`
+}
+
+// notification maps the v3 ticker to the v2 notification.
+func (t wsTickerV3) notification(symbol string) WSNotificationTickerResponse {
+	return WSNotificationTickerResponse{
+		Ask:         t.Ask,
+		Bid:         t.Bid,
+		Last:        t.Last,
+		Open:        t.Open,
+		Low:         t.Low,
+		High:        t.High,
+		Volume:      t.Volume,
+		VolumeQuote: t.VolumeQuote,
+		Timestamp:   time.Unix(0, t.Timestamp*int64(time.Millisecond)).UTC().Format(time.RFC3339Nano),
+		Symbol:      symbol,
+	}
+}
+
+// wsTradeV3 is a trade of the v3 feed.
+type wsTradeV3 struct {
+	Timestamp int64           `json:"t"` // Unix milliseconds
+	ID        int64           `json:"i"`
+	Price     decimal.Decimal `json:"p"`
+	Quantity  decimal.Decimal `json:"q"`
+	Side      string          `json:"s"`
+}
+
+func (t wsTradeV3) trade() WSTrade {
+	return WSTrade{
+		ID:        t.ID,
+		Price:     t.Price,
+		Quantity:  t.Quantity,
+		Side:      t.Side,
+		Timestamp: time.Unix(0, t.Timestamp*int64(time.Millisecond)).UTC(),
+	}
+}
+
+// wsCandleV3 is a candle of the v3 feed.
+type wsCandleV3 struct {
+	Timestamp   int64           `json:"t"` // Unix milliseconds
+	Open        decimal.Decimal `json:"o"`
+	Close       decimal.Decimal `json:"c"`
+	High        decimal.Decimal `json:"h"`
+	Low         decimal.Decimal `json:"l"`
+	Volume      decimal.Decimal `json:"v"`
+	VolumeQuote decimal.Decimal `json:"q"`
+}
+
+func (c wsCandleV3) candle() WSCandle {
+	return WSCandle{
+		Timestamp:   time.Unix(0, c.Timestamp*int64(time.Millisecond)).UTC(),
+		Open:        c.Open,
+		Close:       c.Close,
+		Min:         c.Low,
+		Max:         c.High,
+		Volume:      c.Volume,
+		VolumeQuote: c.VolumeQuote,
+	}
+}
+
+// wsReportV3 is an execution report of the v3 trading feed.
+type wsReportV3 struct {
+	ID            int64           `json:"id"`
+	ClientOrderID string          `json:"client_order_id"`
+	Symbol        string          `json:"symbol"`
+	Side          string          `json:"side"`
+	Status        string          `json:"status"`
+	Type          string          `json:"type"`
+	TimeInForce   string          `json:"time_in_force"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	Price         decimal.Decimal `json:"price"`
+	CumQuantity   decimal.Decimal `json:"quantity_cumulative"`
+	PostOnly      bool            `json:"post_only"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	ReportType    string          `json:"report_type"`
+	TradeQuantity decimal.Decimal `json:"trade_quantity"`
+	TradePrice    decimal.Decimal `json:"trade_price"`
+	TradeID       int64           `json:"trade_id"`
+	TradeFee      decimal.Decimal `json:"trade_fee"`
+}
+
+func (r wsReportV3) report() WSReport {
+	return WSReport{
+		ID:            strconv.FormatInt(r.ID, 10),
+		ClientOrderID: r.ClientOrderID,
+		Symbol:        r.Symbol,
+		Side:          r.Side,
+		Status:        r.Status,
+		Type:          r.Type,
+		TimeInForce:   r.TimeInForce,
+		Quantity:      r.Quantity,
+		Price:         r.Price,
+		CumQuantity:   r.CumQuantity,
+		PostOnly:      r.PostOnly,
+		CreatedAt:     r.CreatedAt,
+		UpdatedAt:     r.UpdatedAt,
+		ReportType:    r.ReportType,
+		TradeQuantity: r.TradeQuantity,
+		TradePrice:    r.TradePrice,
+		TradeID:       r.TradeID,
+		TradeFee:      r.TradeFee,
+	}
+}
+
+// wsMessageV3 is any message of the v3 feeds: a notification of a channel
+// of the public feed, with the data of each symbol, a notification of a
+// method of the trading feed or the response to a request.
+type wsMessageV3 struct {
+	Channel  string                     `json:"ch"`
+	Data     map[string]json.RawMessage `json:"data"`
+	Snapshot map[string]json.RawMessage `json:"snapshot"`
+	Update   map[string]json.RawMessage `json:"update"`
+	Method   string                     `json:"method"`
+	Params   json.RawMessage            `json:"params"`
+	ID       *int64                     `json:"id"`
+	Error    *APIError                  `json:"error"`
+}
+
+// wsConnV3 is a connection to a v3 feed. The public feed isn't JSON-RPC.
+type wsConnV3 struct {
+	conn      *websocket.Conn
+	updates   *responseChannels
+	writeLock sync.Mutex
+	mutex     sync.Mutex
+	nextID    int64
+	pending   map[int64]chan error
+}
+
+// NewWSClientV3 creates a WSClient of the HitBTC v3 feed. The ticker, trades
+// and candles channels, and the reports once logged in, are supported, their
+// notifications are mapped to the v2 ones.
+func NewWSClientV3() (*WSClient, error) {
+	handler := newResponseChannels()
+	v3, err := dialV3(wsV3APIURL, handler)
+	if err != nil {
+		return nil, err
+	}
+	return &WSClient{v3: v3, updates: handler}, nil
+}
+
+// dialV3 connects to the v3 feed at url, delivering to updates.
+func dialV3(url string, updates *responseChannels) (*wsConnV3, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	v3 := &wsConnV3{conn: conn, updates: updates, pending: make(map[int64]chan error)}
+	go v3.read()
+	return v3, nil
+}
+
+// read dispatches the messages until the connection is closed.
+func (c *wsConnV3) read() {
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			c.mutex.Lock()
+			for id, pending := range c.pending {
+				pending <- err
+				delete(c.pending, id)
+			}
+			c.mutex.Unlock()
+			return
+		}
+		var msg wsMessageV3
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		if msg.ID != nil {
+			c.mutex.Lock()
+			pending := c.pending[*msg.ID]
+			delete(c.pending, *msg.ID)
+			c.mutex.Unlock()
+			if pending != nil {
+				if msg.Error != nil {
+					pending <- msg.Error
+				} else {
+					pending <- nil
+				}
+			}
+			continue
+		}
+		switch {
+		case msg.Method != "":
+			c.deliverReports(msg.Method, msg.Params)
+		case msg.Channel == tickerChannelV3:
+			c.deliverTickers(msg.Data)
+		case msg.Channel == tradesChannelV3:
+			c.deliverTrades(msg)
+		case strings.HasPrefix(msg.Channel, candlesChannelV3):
+			c.deliverCandles(msg)
+		}
+	}
+}
+
+func (c *wsConnV3) deliverTickers(data map[string]json.RawMessage) {
+	for symbol, raw := range data {
+		var ticker wsTickerV3
+		if err := json.Unmarshal(raw, &ticker); err != nil {
+			continue
+		}
+		notification := ticker.notification(symbol)
+		// recorded as v2 notifications, the format of the replay
+		if params, err := json.Marshal(notification); err == nil {
+			c.updates.notifications.record("ticker", params)
+		}
+		c.updates.notifications.deliverTicker(notification)
+	}
+}
+
+// symbolData returns the data by symbol of a trades or candles notification
+// and whether it is the snapshot following the subscription.
+func (msg wsMessageV3) symbolData() (map[string]json.RawMessage, bool) {
+	if msg.Snapshot != nil {
+		return msg.Snapshot, true
+	}
+	return msg.Update, false
+}
+
+func (c *wsConnV3) deliverTrades(msg wsMessageV3) {
+	data, snapshot := msg.symbolData()
+	for symbol, raw := range data {
+		var trades []wsTradeV3
+		if err := json.Unmarshal(raw, &trades); err != nil {
+			continue
+		}
+		notification := WSNotificationTradesResponse{Symbol: symbol, Snapshot: snapshot, Data: make([]WSTrade, len(trades))}
+		for i, trade := range trades {
+			notification.Data[i] = trade.trade()
+		}
+		c.updates.notifications.deliverTrades(notification)
+	}
+}
+
+func (c *wsConnV3) deliverCandles(msg wsMessageV3) {
+	period := strings.TrimPrefix(msg.Channel, candlesChannelV3)
+	data, snapshot := msg.symbolData()
+	for symbol, raw := range data {
+		var candles []wsCandleV3
+		if err := json.Unmarshal(raw, &candles); err != nil {
+			continue
+		}
+		notification := WSNotificationCandlesResponse{Symbol: symbol, Period: period, Snapshot: snapshot, Data: make([]WSCandle, len(candles))}
+		for i, candle := range candles {
+			notification.Data[i] = candle.candle()
+		}
+		c.updates.notifications.deliverCandles(notification)
+	}
+}
+
+// deliverReports delivers the spot_orders snapshot of the active orders and
+// the spot_order reports of the trading feed.
+func (c *wsConnV3) deliverReports(method string, params json.RawMessage) {
+	var reports []wsReportV3
+	switch method {
+	case "spot_orders":
+		if err := json.Unmarshal(params, &reports); err != nil {
+			return
+		}
+	case "spot_order":
+		var report wsReportV3
+		if err := json.Unmarshal(params, &report); err != nil {
+			return
+		}
+		reports = append(reports, report)
+	default:
+		return
+	}
+	mapped := make([]WSReport, len(reports))
+	for i, report := range reports {
+		mapped[i] = report.report()
+	}
+	c.updates.notifications.deliverReports(mapped)
+}
+
+// call sends a request of method on channel and waits for its response.
+func (c *wsConnV3) call(method string, channel string, params interface{}) error {
+	c.mutex.Lock()
+	c.nextID++
+	id := c.nextID
+	response := make(chan error, 1)
+	c.pending[id] = response
+	c.mutex.Unlock()
+
+	request := struct {
+		Method  string      `json:"method"`
+		Channel string      `json:"ch,omitempty"`
+		Params  interface{} `json:"params"`
+		ID      int64       `json:"id"`
+	}{method, channel, params, id}
+	c.writeLock.Lock()
+	err := c.conn.WriteJSON(request)
+	c.writeLock.Unlock()
+	if err != nil {
+		c.mutex.Lock()
+		delete(c.pending, id)
+		c.mutex.Unlock()
+		return err
+	}
+	select {
+	case err = <-response:
+		return err
+	case <-time.After(30 * time.Second):
+		c.mutex.Lock()
+		delete(c.pending, id)
+		c.mutex.Unlock()
+		return errors.New("timeout waiting for the response of request " + strconv.FormatInt(id, 10))
+	}
+}
+
+// symbolsParams are the parameters of a subscription to symbol, with limit
+// records in the snapshot unless zero.
+func symbolsParams(symbol string, limit int) map[string]interface{} {
+	params := map[string]interface{}{"symbols": []string{symbol}}
+	if limit > 0 {
+		params["limit"] = limit
+	}
+	return params
+}
+
+// subscriptionOp maps the v2 subscription operations to the v3 channels.
+func (c *wsConnV3) subscriptionOp(op string, request interface{}) error {
+	switch request := request.(type) {
+	case WSSubscriptionRequest:
+		switch op {
+		case "subscribeTicker":
+			return c.call("subscribe", tickerChannelV3, symbolsParams(request.Symbol, 0))
+		case "unsubscribeTicker":
+			return c.call("unsubscribe", tickerChannelV3, symbolsParams(request.Symbol, 0))
+		case "unsubscribeTrades":
+			return c.call("unsubscribe", tradesChannelV3, symbolsParams(request.Symbol, 0))
+		}
+	case WSSubscribeTradesRequest:
+		if op == "subscribeTrades" {
+			return c.call("subscribe", tradesChannelV3, symbolsParams(request.Symbol, request.Limit))
+		}
+	case WSSubscribeCandlesRequest:
+		channel := candlesChannelV3 + request.Period
+		switch op {
+		case "subscribeCandles":
+			return c.call("subscribe", channel, symbolsParams(request.Symbol, request.Limit))
+		case "unsubscribeCandles":
+			return c.call("unsubscribe", channel, symbolsParams(request.Symbol, 0))
+		}
+	}
+	return errV3Unsupported
+}
+
+// subscriptionOpV3 sends op to the v3 public feed, or to the trading feed
+// for the reports.
+func (c *WSClient) subscriptionOpV3(op string, request interface{}) error {
+	if op != "subscribeReports" {
+		return c.v3.subscriptionOp(op, request)
+	}
+	c.v3Mutex.Lock()
+	trading := c.v3Trading
+	c.v3Mutex.Unlock()
+	if trading == nil {
+		return errors.New("Login is required to subscribe to the reports")
+	}
+	return trading.call("spot_subscribe", "", struct{}{})
+}
+
+// loginV3 opens the trading feed and logs in with the API key, the
+// signature being the HS256 of the timestamp.
+func (c *WSClient) loginV3(apiKey string, apiSecret string) error {
+	trading, err := dialV3(wsV3TradingURL, c.updates)
+	if err != nil {
+		return errors.Annotate(err, "Hitbtc Login")
+	}
+	timestamp := time.Now().UnixNano() / int64(time.Millisecond)
+	mac := hmac.New(sha256.New, []byte(apiSecret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	params := map[string]interface{}{
+		"type":      "HS256",
+		"api_key":   apiKey,
+		"timestamp": timestamp,
+		"signature": hex.EncodeToString(mac.Sum(nil)),
+	}
+	if err := trading.call("login", "", params); err != nil {
+		trading.conn.Close()
+		return errors.Annotate(err, "Hitbtc Login")
+	}
+	c.v3Mutex.Lock()
+	previous := c.v3Trading
+	c.v3Trading = trading
+	c.v3Mutex.Unlock()
+	if previous != nil {
+		previous.conn.Close()
+	}
+	return nil
+}